@@ -0,0 +1,127 @@
+// Package apperrors defines the errors handlers report to API clients. Each
+// carries a stable code clients can branch on, an HTTP status and a message
+// safe to show to users; the underlying cause is only logged. Handlers attach
+// them with c.Error and return, and middleware.Errors writes the response.
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Code identifies the kind of error in responses
+type Code string
+
+const (
+	CodeValidation          Code = "validation_failed"    // The request is malformed or invalid
+	CodeUnauthorized        Code = "unauthorized"         // The request has no valid session
+	CodeForbidden           Code = "forbidden"            // The user may not do this
+	CodeNotFound            Code = "not_found"            // The player or resource doesn't exist
+	CodeConflict            Code = "conflict"             // The request clashes with the current state, e.g. a taken username
+	CodeNotLinked           Code = "not_linked"           // The user hasn't linked the platform account this needs
+	CodePayloadTooLarge     Code = "payload_too_large"    // The request body is over the size limit
+	CodeRateLimited         Code = "rate_limited"         // Too many requests, ours or to a provider; see Retry-After
+	CodeUpstreamUnavailable Code = "upstream_unavailable" // A third-party API failed or is switched off
+	CodeInternal            Code = "internal"             // Anything else
+)
+
+// Codes lists every code, for API documentation
+var Codes = []Code{CodeValidation, CodeUnauthorized, CodeForbidden, CodeNotFound, CodeConflict, CodeNotLinked,
+	CodePayloadTooLarge, CodeRateLimited, CodeUpstreamUnavailable, CodeInternal}
+
+// Error is an error with everything needed to answer the client
+type Error struct {
+	Code       Code
+	Status     int
+	Message    string         // Shown to the user
+	Details    map[string]any // Extra context for clients, e.g. per-field problems
+	RetryAfter time.Duration  // Sent as Retry-After when set
+	Err        error          // The cause; logged, never sent
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithDetail returns e with key set in its details
+func (e *Error) WithDetail(key string, value any) *Error {
+	if e.Details == nil {
+		e.Details = map[string]any{}
+	}
+	e.Details[key] = value
+	return e
+}
+
+// Validation reports an invalid request. fields maps each bad field to what is
+// wrong with it, and may be nil.
+func Validation(message string, fields map[string]string) *Error {
+	err := &Error{Code: CodeValidation, Status: http.StatusBadRequest, Message: message}
+	if len(fields) > 0 {
+		err.WithDetail("fields", fields)
+	}
+	return err
+}
+
+// Unauthorized reports a request without a valid session
+func Unauthorized(message string) *Error {
+	return &Error{Code: CodeUnauthorized, Status: http.StatusUnauthorized, Message: message}
+}
+
+// Forbidden reports that the user may not act on the resource
+func Forbidden(message string) *Error {
+	return &Error{Code: CodeForbidden, Status: http.StatusForbidden, Message: message}
+}
+
+// NotFound reports a missing player or resource
+func NotFound(message string) *Error {
+	return &Error{Code: CodeNotFound, Status: http.StatusNotFound, Message: message}
+}
+
+// Conflict reports a request the current state doesn't allow, such as a
+// duplicate or a change to something that has moved on
+func Conflict(message string) *Error {
+	return &Error{Code: CodeConflict, Status: http.StatusConflict, Message: message}
+}
+
+// PayloadTooLarge reports a request body over the limit
+func PayloadTooLarge(message string) *Error {
+	return &Error{Code: CodePayloadTooLarge, Status: http.StatusRequestEntityTooLarge, Message: message}
+}
+
+// NotLinked reports that the user must link an account on platform first
+func NotLinked(platform, message string) *Error {
+	return (&Error{Code: CodeNotLinked, Status: http.StatusConflict, Message: message}).WithDetail("platform", platform)
+}
+
+// RateLimited reports that the client, or we on its behalf, sent too many requests
+func RateLimited(message string, retryAfter time.Duration) *Error {
+	return &Error{Code: CodeRateLimited, Status: http.StatusTooManyRequests, Message: message, RetryAfter: retryAfter}
+}
+
+// UpstreamUnavailable reports that a provider's API failed or isn't configured
+func UpstreamUnavailable(provider, message string, err error) *Error {
+	return (&Error{Code: CodeUpstreamUnavailable, Status: http.StatusBadGateway, Message: message, Err: err}).WithDetail("provider", provider)
+}
+
+// Internal reports an unexpected failure without exposing it
+func Internal(err error) *Error {
+	return &Error{Code: CodeInternal, Status: http.StatusInternalServerError, Message: "Something went wrong", Err: err}
+}
+
+// From returns err as an *Error, treating anything unclassified as Internal
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
@@ -0,0 +1,236 @@
+// Package cache stores upstream API responses so dashboard loads don't call
+// Steam, Riot and tracker.gg every time. Entries are raw response bodies keyed
+// by provider, endpoint and identity (a PUUID, Steam ID, match ID...), so
+// every caller decoding the same response shares one entry.
+//
+// Each endpoint has a Policy: an entry is fresh for TTL, then served stale for
+// up to Stale more while one background fetch refreshes it. Concurrent fetches
+// of the same key are collapsed into one. Backends are pluggable: an LRU in
+// memory by default, or the database so replicas share entries; anything with
+// get/set-with-expiry semantics, such as Redis, fits the Backend interface.
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"elo-insight/backend/config"
+	"elo-insight/backend/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+// Status is how a lookup was answered
+type Status string
+
+const (
+	StatusHit    Status = "HIT"    // Fresh entry
+	StatusStale  Status = "STALE"  // Expired entry served while it is refreshed in the background
+	StatusMiss   Status = "MISS"   // Fetched from the provider
+	StatusBypass Status = "BYPASS" // Caching is switched off
+)
+
+// refreshTimeout bounds background refreshes, which outlive the request that started them
+const refreshTimeout = 30 * time.Second
+
+// Key identifies a cached response
+type Key struct {
+	Provider string // upstream.Steam, upstream.Riot, ...
+	Endpoint string // Which API, e.g. "lol.ranked"; also selects the Policy
+	Identity string // Whose data, e.g. a PUUID or match ID
+}
+
+func (k Key) String() string {
+	return k.Provider + ":" + k.Endpoint + ":" + k.Identity
+}
+
+// Policy is how long an endpoint's responses are kept
+type Policy struct {
+	TTL   time.Duration // How long an entry is served without asking the provider
+	Stale time.Duration // How long after TTL it is still served while being refreshed
+}
+
+// Entry is a stored response
+type Entry struct {
+	Value      []byte
+	StoredAt   time.Time
+	FreshUntil time.Time
+	ExpiresAt  time.Time // FreshUntil plus the stale window; backends may drop the entry after this
+}
+
+// Backend stores entries. Errors are logged and treated as misses, so a
+// broken backend slows requests down instead of failing them.
+type Backend interface {
+	// Get returns the entry, or false if there is none or it has expired
+	Get(ctx context.Context, key string) (Entry, bool, error)
+	Set(ctx context.Context, key string, entry Entry) error
+	Delete(ctx context.Context, key string) error
+}
+
+// Cache answers lookups from a backend, fetching on misses
+type Cache struct {
+	backend Backend // nil bypasses the cache
+	name    string  // Backend name for span attributes
+	group   singleflight.Group
+	now     func() time.Time
+}
+
+// New returns a cache storing entries in backend, or one that always fetches if backend is nil
+func New(backend Backend, name string) *Cache {
+	return &Cache{backend: backend, name: name, now: time.Now}
+}
+
+// Default is the cache for all upstream responses
+var Default = New(NewMemory(10000), "memory")
+
+// Init replaces Default with the configured backend
+func Init(cfg config.Cache, db *gorm.DB) {
+	switch cfg.Backend {
+	case "none":
+		Default = New(nil, "none")
+	case "database":
+		Default = New(NewDatabase(db), "database")
+	default:
+		Default = New(NewMemory(cfg.MaxEntries), "memory")
+	}
+}
+
+// Fetch returns the value for key, calling fetch on a miss. Only successful
+// fetches are stored.
+func (c *Cache) Fetch(ctx context.Context, key Key, policy Policy, fetch func(ctx context.Context) ([]byte, error)) ([]byte, Status, error) {
+	ctx, span := telemetry.StartSpan(ctx, "cache "+key.Endpoint)
+	defer span.End()
+
+	value, status, err := c.lookup(ctx, key, policy, fetch)
+	span.SetAttributes(
+		attribute.String("cache.backend", c.name),
+		attribute.String("cache.provider", key.Provider),
+		attribute.String("cache.endpoint", key.Endpoint),
+		attribute.String("cache.status", string(status)),
+	)
+	if err != nil {
+		span.RecordError(err)
+	}
+	recordLookup(ctx, status)
+	return value, status, err
+}
+
+func (c *Cache) lookup(ctx context.Context, key Key, policy Policy, fetch func(ctx context.Context) ([]byte, error)) ([]byte, Status, error) {
+	if c.backend == nil {
+		value, err := fetch(ctx)
+		return value, StatusBypass, err
+	}
+
+	id := key.String()
+	entry, found, err := c.backend.Get(ctx, id)
+	if err != nil {
+		slog.WarnContext(ctx, "Cache read failed", "cache_key", id, "error", err)
+	}
+	if found {
+		now := c.now()
+		if now.Before(entry.FreshUntil) {
+			return entry.Value, StatusHit, nil
+		}
+		if now.Before(entry.ExpiresAt) {
+			c.refresh(ctx, id, policy, fetch)
+			return entry.Value, StatusStale, nil
+		}
+	}
+
+	// Callers waiting on the same key share one fetch, but each can still give up
+	result := c.group.DoChan(id, func() (interface{}, error) {
+		return c.load(ctx, id, policy, fetch)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, StatusMiss, ctx.Err()
+	case res := <-result:
+		if res.Err != nil && errors.Is(res.Err, context.Canceled) && ctx.Err() == nil {
+			// The caller that led the shared fetch went away; fetch for ourselves
+			value, err := c.load(ctx, id, policy, fetch)
+			return value, StatusMiss, err
+		}
+		if res.Err != nil {
+			return nil, StatusMiss, res.Err
+		}
+		return res.Val.([]byte), StatusMiss, nil
+	}
+}
+
+// refresh fetches a stale entry again in the background, once per key
+func (c *Cache) refresh(ctx context.Context, id string, policy Policy, fetch func(ctx context.Context) ([]byte, error)) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), refreshTimeout)
+	go func() {
+		defer cancel()
+		_, err, _ := c.group.Do(id, func() (interface{}, error) {
+			return c.load(ctx, id, policy, fetch)
+		})
+		if err != nil {
+			slog.WarnContext(ctx, "Cache refresh failed, serving stale entry", "cache_key", id, "error", err)
+		}
+	}()
+}
+
+// load fetches and stores a value
+func (c *Cache) load(ctx context.Context, id string, policy Policy, fetch func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	value, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := c.now()
+	entry := Entry{
+		Value:      value,
+		StoredAt:   now,
+		FreshUntil: now.Add(policy.TTL),
+		ExpiresAt:  now.Add(policy.TTL + policy.Stale),
+	}
+	if err := c.backend.Set(ctx, id, entry); err != nil {
+		slog.WarnContext(ctx, "Cache write failed", "cache_key", id, "error", err)
+	}
+	return value, nil
+}
+
+// purger is a Backend that must delete expired entries itself
+type purger interface {
+	Purge(ctx context.Context) (int64, error)
+}
+
+// PurgeExpired deletes expired entries every interval until ctx is done, for
+// backends that don't evict on their own
+func (c *Cache) PurgeExpired(ctx context.Context, interval time.Duration) {
+	backend, ok := c.backend.(purger)
+	if !ok {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if removed, err := backend.Purge(ctx); err != nil {
+				slog.WarnContext(ctx, "Failed to purge expired cache entries", "error", err)
+			} else if removed > 0 {
+				slog.DebugContext(ctx, "Purged expired cache entries", "count", removed)
+			}
+		}
+	}
+}
+
+// Invalidate drops an entry, e.g. after the user changes the data it holds
+func (c *Cache) Invalidate(ctx context.Context, key Key) error {
+	if c.backend == nil {
+		return nil
+	}
+	if err := c.backend.Delete(ctx, key.String()); err != nil {
+		return fmt.Errorf("failed to invalidate %s: %w", key, err)
+	}
+	return nil
+}
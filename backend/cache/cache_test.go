@@ -0,0 +1,254 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"elo-insight/backend/config"
+	"elo-insight/backend/database"
+	"elo-insight/backend/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	telemetry.Initialize("cache-test")
+	os.Exit(m.Run())
+}
+
+// fakeClock is a clock that only moves when told to
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// newTestDatabase returns a Database backend on a migrated, throwaway in-memory database
+func newTestDatabase(t *testing.T, clock *fakeClock) *Database {
+	t.Helper()
+	db, err := database.Open(config.Database{URL: "sqlite://:memory:"})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	backend := NewDatabase(db)
+	backend.now = clock.now
+	return backend
+}
+
+// backends returns a cache on each backend, all on the clock
+func backends(t *testing.T, clock *fakeClock) map[string]*Cache {
+	memory := NewMemory(100)
+	memory.now = clock.now
+	caches := map[string]*Cache{
+		"memory":   New(memory, "memory"),
+		"database": New(newTestDatabase(t, clock), "database"),
+	}
+	for _, c := range caches {
+		c.now = clock.now
+	}
+	return caches
+}
+
+// eventually waits up to a second for cond, which a background refresh makes true
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+var testKey = Key{Provider: "riot", Endpoint: "lol.ranked", Identity: "puuid-1"}
+
+func TestFetch(t *testing.T) {
+	policy := Policy{TTL: time.Minute, Stale: 10 * time.Minute}
+	errUpstream := errors.New("upstream returned status 503")
+
+	// Each step moves the clock, then fetches; fetch answers with reply or replyErr
+	steps := []struct {
+		name        string
+		advance     time.Duration
+		invalidate  bool
+		reply       string
+		replyErr    error
+		want        string
+		wantStatus  Status
+		wantErr     error
+		wantFetches int32 // In total, once any background refresh has finished
+		wantStored  string
+	}{
+		{name: "empty cache fetches", reply: "v1", want: "v1", wantStatus: StatusMiss, wantFetches: 1, wantStored: "v1"},
+		{name: "fresh entry is a hit", advance: 59 * time.Second, reply: "unused", want: "v1", wantStatus: StatusHit, wantFetches: 1, wantStored: "v1"},
+		{name: "expired entry is served while it is refreshed", advance: time.Second, reply: "v2", want: "v1", wantStatus: StatusStale, wantFetches: 2, wantStored: "v2"},
+		{name: "refreshed entry is fresh", advance: 59 * time.Second, reply: "unused", want: "v2", wantStatus: StatusHit, wantFetches: 2, wantStored: "v2"},
+		{name: "invalidated entry is fetched", invalidate: true, reply: "v3", want: "v3", wantStatus: StatusMiss, wantFetches: 3, wantStored: "v3"},
+		{name: "entry past the stale window is fetched", advance: 11 * time.Minute, reply: "v4", want: "v4", wantStatus: StatusMiss, wantFetches: 4, wantStored: "v4"},
+		{name: "failed fetch returns the error", advance: 11 * time.Minute, replyErr: errUpstream, wantStatus: StatusMiss, wantErr: errUpstream, wantFetches: 5},
+		{name: "failed fetch is not stored", reply: "v5", want: "v5", wantStatus: StatusMiss, wantFetches: 6, wantStored: "v5"},
+		{name: "failed refresh keeps the stale entry", advance: 2 * time.Minute, replyErr: errUpstream, want: "v5", wantStatus: StatusStale, wantFetches: 7, wantStored: "v5"},
+	}
+
+	clock := newFakeClock()
+	for name, c := range backends(t, clock) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			var fetches atomic.Int32
+			for _, step := range steps {
+				clock.advance(step.advance)
+				if step.invalidate {
+					if err := c.Invalidate(ctx, testKey); err != nil {
+						t.Fatalf("%s: invalidate: %v", step.name, err)
+					}
+				}
+
+				value, status, err := c.Fetch(ctx, testKey, policy, func(ctx context.Context) ([]byte, error) {
+					fetches.Add(1)
+					if step.replyErr != nil {
+						return nil, step.replyErr
+					}
+					return []byte(step.reply), nil
+				})
+				if string(value) != step.want || status != step.wantStatus || !errors.Is(err, step.wantErr) {
+					t.Fatalf("%s: Fetch = %q, %s, %v; want %q, %s, %v", step.name, value, status, err, step.want, step.wantStatus, step.wantErr)
+				}
+
+				eventually(t, step.name+": fetches", func() bool { return fetches.Load() == step.wantFetches })
+				if step.wantStored != "" {
+					eventually(t, step.name+": stored "+step.wantStored, func() bool {
+						entry, found, _ := c.backend.Get(ctx, testKey.String())
+						return found && string(entry.Value) == step.wantStored
+					})
+				}
+			}
+		})
+	}
+}
+
+func TestFetchWithoutBackend(t *testing.T) {
+	c := New(nil, "none")
+	for range 2 {
+		value, status, err := c.Fetch(context.Background(), testKey, Policy{TTL: time.Minute}, func(ctx context.Context) ([]byte, error) {
+			return []byte("v1"), nil
+		})
+		if string(value) != "v1" || status != StatusBypass || err != nil {
+			t.Errorf("Fetch = %q, %s, %v; want v1, BYPASS, nil", value, status, err)
+		}
+	}
+}
+
+func TestFetchSharesConcurrentFetches(t *testing.T) {
+	c := New(NewMemory(100), "memory")
+	var fetches atomic.Int32
+	entered, release := make(chan struct{}), make(chan struct{})
+	fetch := func(ctx context.Context) ([]byte, error) {
+		if fetches.Add(1) == 1 {
+			close(entered)
+		}
+		<-release
+		return []byte("v1"), nil
+	}
+
+	const callers = 10
+	values := make(chan string, callers)
+	fetchValue := func() {
+		value, _, err := c.Fetch(context.Background(), testKey, Policy{TTL: time.Minute}, fetch)
+		if err != nil {
+			t.Errorf("Fetch: %v", err)
+		}
+		values <- string(value)
+	}
+	go fetchValue()
+	<-entered
+	for range callers - 1 {
+		go fetchValue()
+	}
+	time.Sleep(20 * time.Millisecond) // Let the others join the fetch in flight
+	close(release)
+
+	for range callers {
+		if value := <-values; value != "v1" {
+			t.Errorf("value = %q, want v1", value)
+		}
+	}
+	// Any caller that missed the shared fetch found its result stored
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("fetches = %d, want 1", got)
+	}
+}
+
+func TestFetchRefetchesWhenLeaderGivesUp(t *testing.T) {
+	c := New(NewMemory(100), "memory")
+	var fetches atomic.Int32
+	entered, proceed := make(chan struct{}), make(chan struct{})
+	fetch := func(ctx context.Context) ([]byte, error) {
+		if fetches.Add(1) == 1 {
+			// The leader's fetch fails with its cancellation
+			close(entered)
+			<-proceed
+			return nil, ctx.Err()
+		}
+		return []byte("v1"), nil
+	}
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, _, err := c.Fetch(leaderCtx, testKey, Policy{TTL: time.Minute}, fetch)
+		leaderErr <- err
+	}()
+	<-entered
+
+	type result struct {
+		value  string
+		status Status
+		err    error
+	}
+	follower := make(chan result, 1)
+	go func() {
+		value, status, err := c.Fetch(context.Background(), testKey, Policy{TTL: time.Minute}, fetch)
+		follower <- result{string(value), status, err}
+	}()
+	time.Sleep(20 * time.Millisecond) // Let the follower join the leader's fetch
+
+	cancelLeader()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader error = %v, want context.Canceled", err)
+	}
+	close(proceed)
+
+	got := <-follower
+	if got != (result{"v1", StatusMiss, nil}) {
+		t.Errorf("follower got %+v, want v1, MISS, nil", got)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("fetches = %d, want 2", n)
+	}
+}
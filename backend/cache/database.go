@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Database is a Backend storing entries in the cache_entries table, so every
+// replica shares them and they survive restarts
+type Database struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// cacheEntry is a row of cache_entries
+type cacheEntry struct {
+	Key        string `gorm:"primaryKey"`
+	Value      []byte
+	StoredAt   time.Time
+	FreshUntil time.Time
+	ExpiresAt  time.Time
+}
+
+func (cacheEntry) TableName() string { return "cache_entries" }
+
+// NewDatabase returns a backend using db
+func NewDatabase(db *gorm.DB) *Database {
+	return &Database{db: db, now: time.Now}
+}
+
+func (d *Database) Get(ctx context.Context, key string) (Entry, bool, error) {
+	var row cacheEntry
+	err := d.db.WithContext(ctx).Where("key = ? AND expires_at > ?", key, d.now()).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Entry{}, false, nil
+	}
+	if err != nil {
+		return Entry{}, false, err
+	}
+	return Entry{Value: row.Value, StoredAt: row.StoredAt, FreshUntil: row.FreshUntil, ExpiresAt: row.ExpiresAt}, true, nil
+}
+
+func (d *Database) Set(ctx context.Context, key string, entry Entry) error {
+	row := cacheEntry{Key: key, Value: entry.Value, StoredAt: entry.StoredAt, FreshUntil: entry.FreshUntil, ExpiresAt: entry.ExpiresAt}
+	return d.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "stored_at", "fresh_until", "expires_at"}),
+	}).Create(&row).Error
+}
+
+func (d *Database) Delete(ctx context.Context, key string) error {
+	return d.db.WithContext(ctx).Where("key = ?", key).Delete(&cacheEntry{}).Error
+}
+
+// Purge deletes expired entries, returning how many were removed
+func (d *Database) Purge(ctx context.Context) (int64, error) {
+	result := d.db.WithContext(ctx).Where("expires_at <= ?", d.now()).Delete(&cacheEntry{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDatabase(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	d := newTestDatabase(t, clock)
+	entry := func(value string, ttl time.Duration) Entry {
+		now := clock.now()
+		return Entry{Value: []byte(value), StoredAt: now, FreshUntil: now.Add(ttl), ExpiresAt: now.Add(2 * ttl)}
+	}
+	get := func(key string) string {
+		t.Helper()
+		got, found, err := d.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("get %s: %v", key, err)
+		}
+		if !found {
+			return ""
+		}
+		return string(got.Value)
+	}
+
+	stored := entry("v1", time.Minute)
+	if err := d.Set(ctx, "a", stored); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	got, found, err := d.Get(ctx, "a")
+	if err != nil || !found || string(got.Value) != "v1" || !got.FreshUntil.Equal(stored.FreshUntil) || !got.ExpiresAt.Equal(stored.ExpiresAt) {
+		t.Fatalf("Get = %+v, %v, %v; want %+v", got, found, err, stored)
+	}
+
+	// Setting an existing key replaces it
+	if err := d.Set(ctx, "a", entry("v2", time.Hour)); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	if value := get("a"); value != "v2" {
+		t.Errorf("after overwrite = %q, want v2", value)
+	}
+
+	if err := d.Set(ctx, "b", entry("short", time.Minute)); err != nil {
+		t.Fatalf("set b: %v", err)
+	}
+	clock.advance(2 * time.Minute)
+	if value := get("b"); value != "" {
+		t.Errorf("expired entry = %q, want none", value)
+	}
+
+	removed, err := d.Purge(ctx)
+	if err != nil || removed != 1 {
+		t.Errorf("Purge = %d, %v; want 1 removed", removed, err)
+	}
+	if value := get("a"); value != "v2" {
+		t.Errorf("after purge = %q, want v2 kept", value)
+	}
+
+	if err := d.Delete(ctx, "a"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if value := get("a"); value != "" {
+		t.Errorf("after delete = %q, want none", value)
+	}
+}
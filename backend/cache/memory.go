@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is a Backend holding up to a fixed number of entries in this
+// process, evicting the least recently used
+type Memory struct {
+	maxEntries int
+	now        func() time.Time
+
+	mu    sync.Mutex
+	order *list.List // Most recently used first
+	items map[string]*list.Element
+}
+
+type memoryItem struct {
+	key   string
+	entry Entry
+}
+
+// NewMemory returns an empty in-memory backend
+func NewMemory(maxEntries int) *Memory {
+	return &Memory{maxEntries: maxEntries, now: time.Now, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (m *Memory) Get(ctx context.Context, key string) (Entry, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.items[key]
+	if !ok {
+		return Entry{}, false, nil
+	}
+	item := element.Value.(*memoryItem)
+	if !m.now().Before(item.entry.ExpiresAt) {
+		m.remove(element)
+		return Entry{}, false, nil
+	}
+	m.order.MoveToFront(element)
+	return item.entry, true, nil
+}
+
+func (m *Memory) Set(ctx context.Context, key string, entry Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, ok := m.items[key]; ok {
+		element.Value.(*memoryItem).entry = entry
+		m.order.MoveToFront(element)
+		return nil
+	}
+	m.items[key] = m.order.PushFront(&memoryItem{key: key, entry: entry})
+	for m.order.Len() > m.maxEntries {
+		m.remove(m.order.Back())
+	}
+	return nil
+}
+
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if element, ok := m.items[key]; ok {
+		m.remove(element)
+	}
+	return nil
+}
+
+// Len returns the number of entries held, including expired ones not yet evicted
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+// remove drops an element; the caller holds the lock
+func (m *Memory) remove(element *list.Element) {
+	m.order.Remove(element)
+	delete(m.items, element.Value.(*memoryItem).key)
+}
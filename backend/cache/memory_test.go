@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMemoryEviction(t *testing.T) {
+	clock := newFakeClock()
+	fresh := Entry{Value: []byte("v"), ExpiresAt: clock.now().Add(time.Hour)}
+	tests := []struct {
+		name string
+		// Each op is "get:<key>" or "set:<key>"
+		ops  []string
+		want []string // Keys held afterwards
+	}{
+		{"under the limit", []string{"set:a", "set:b"}, []string{"a", "b"}},
+		{"oldest is evicted", []string{"set:a", "set:b", "set:c", "set:d"}, []string{"b", "c", "d"}},
+		{"a read keeps an entry", []string{"set:a", "set:b", "set:c", "get:a", "set:d"}, []string{"a", "c", "d"}},
+		{"a write keeps an entry", []string{"set:a", "set:b", "set:c", "set:a", "set:d"}, []string{"a", "c", "d"}},
+		{"a miss changes nothing", []string{"set:a", "set:b", "set:c", "get:x", "set:d"}, []string{"b", "c", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m := NewMemory(3)
+			m.now = clock.now
+			for _, op := range tt.ops {
+				switch key := op[4:]; op[:3] {
+				case "set":
+					m.Set(ctx, key, fresh)
+				case "get":
+					m.Get(ctx, key)
+				}
+			}
+
+			var held []string
+			for _, key := range []string{"a", "b", "c", "d"} {
+				if _, found := m.items[key]; found {
+					held = append(held, key)
+				}
+			}
+			if !slices.Equal(held, tt.want) || m.Len() != len(tt.want) {
+				t.Errorf("held %v (Len %d), want %v", held, m.Len(), tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryDropsExpiredEntries(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	m := NewMemory(3)
+	m.now = clock.now
+	m.Set(ctx, "a", Entry{Value: []byte("v"), ExpiresAt: clock.now().Add(time.Minute)})
+
+	clock.advance(time.Minute)
+	if _, found, _ := m.Get(ctx, "a"); found {
+		t.Error("entry found at its expiry, want it gone")
+	}
+	if m.Len() != 0 {
+		t.Errorf("Len = %d after reading an expired entry, want 0", m.Len())
+	}
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// recorderKey keys the Recorder stored in a request's context
+type recorderKey struct{}
+
+// Recorder collects the statuses of a request's lookups for its response headers
+type Recorder struct {
+	mu      sync.Mutex
+	lookups map[Status]int
+}
+
+// WithRecorder returns a context whose lookups are collected by the returned Recorder
+func WithRecorder(ctx context.Context) (context.Context, *Recorder) {
+	recorder := &Recorder{lookups: make(map[Status]int)}
+	return context.WithValue(ctx, recorderKey{}, recorder), recorder
+}
+
+// recordLookup adds a lookup to the context's Recorder, if it has one
+func recordLookup(ctx context.Context, status Status) {
+	recorder, ok := ctx.Value(recorderKey{}).(*Recorder)
+	if !ok {
+		return
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.lookups[status]++
+}
+
+// Summary is the request's overall status: MISS if anything was fetched, then
+// STALE, HIT or BYPASS. It is empty when nothing was looked up.
+func (r *Recorder) Summary() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, status := range []Status{StatusMiss, StatusStale, StatusHit, StatusBypass} {
+		if r.lookups[status] > 0 {
+			return status
+		}
+	}
+	return ""
+}
+
+// Counts describes every lookup, e.g. "hit=3, stale=1, miss=2"
+func (r *Recorder) Counts() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var parts []string
+	for _, status := range []Status{StatusHit, StatusStale, StatusMiss, StatusBypass} {
+		if count := r.lookups[status]; count > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", strings.ToLower(string(status)), count))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,787 @@
+// Package config loads the server's settings once at startup into a typed
+// Config. Values come from an optional YAML file, then a .env file, then the
+// environment, with later sources overriding earlier ones.
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Environments the server can run in
+const (
+	EnvDevelopment = "development"
+	EnvTest        = "test"
+	EnvProduction  = "production"
+)
+
+// defaultConfigFile is read if it exists and CONFIG_FILE isn't set
+const defaultConfigFile = "config.yaml"
+
+// minJWTSecretLength is the shortest JWT secret accepted outside development
+const minJWTSecretLength = 32
+
+// Placeholder secrets from examples and old defaults, refused outside development
+var insecureSecrets = map[string]bool{
+	"default-secret-key":  true,
+	"your_jwt_secret_key": true,
+	"secure_jwt_secret":   true,
+	"supersecretkey":      true,
+	"changeme":            true,
+	"secret":              true,
+}
+
+// Config holds every setting the server reads
+type Config struct {
+	Env         string    `yaml:"env"`          // APP_ENV: "development", "test" or "production"
+	Port        string    `yaml:"port"`         // PORT
+	FrontendURL string    `yaml:"frontend_url"` // FRONTEND_URL, where sign-in flows send the user back to
+	JWTSecret   string    `yaml:"jwt_secret"`   // JWT_SECRET
+	Server      Server    `yaml:"server"`
+	API         API       `yaml:"api"`
+	GraphQL     GraphQL   `yaml:"graphql"`
+	CORS        CORS      `yaml:"cors"`
+	Cookie      Cookie    `yaml:"cookie"`
+	Database    Database  `yaml:"database"`
+	Telemetry   Telemetry `yaml:"telemetry"`
+	Logging     Logging   `yaml:"logging"`
+	Cache       Cache     `yaml:"cache"`
+	RateLimit   RateLimit `yaml:"rate_limit"`
+	Steam       Steam     `yaml:"steam"`
+	Riot        Riot      `yaml:"riot"`
+	Tracker     Tracker   `yaml:"tracker"`
+
+	warnings []string // From loading and validating, logged by LogSummary once logging is set up
+}
+
+// Server configures the HTTP server's limits
+type Server struct {
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"` // SERVER_READ_HEADER_TIMEOUT
+	ReadTimeout       time.Duration `yaml:"read_timeout"`        // SERVER_READ_TIMEOUT, for the whole request including the body
+	WriteTimeout      time.Duration `yaml:"write_timeout"`       // SERVER_WRITE_TIMEOUT; stats endpoints wait on several upstream calls
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`        // SERVER_IDLE_TIMEOUT, for keep-alive connections
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`    // SHUTDOWN_TIMEOUT, for draining requests and stopping workers
+	HookTimeout       time.Duration `yaml:"hook_timeout"`        // SHUTDOWN_HOOK_TIMEOUT, for each step after that, such as flushing traces
+	MaxBodyBytes      int64         `yaml:"max_body_bytes"`      // MAX_BODY_BYTES, the largest request body accepted
+	// TRUSTED_PROXIES, comma separated IPs or CIDRs of the load balancers in
+	// front of the server. Client IPs are only read from X-Forwarded-For when
+	// the request comes from one of them; by default it is never read.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// API configures API versioning
+type API struct {
+	// LEGACY_ROUTES_DEPRECATED_AT, when the unversioned paths that predate
+	// /api/v1 were deprecated, as an RFC 3339 time or a date
+	LegacyDeprecatedAt time.Time `yaml:"legacy_deprecated_at"`
+	// LEGACY_ROUTES_SUNSET, when they will be removed
+	LegacySunset time.Time `yaml:"legacy_sunset"`
+}
+
+// GraphQL limits what one query to the GraphQL endpoint may ask for
+type GraphQL struct {
+	MaxDepth         int `yaml:"max_depth"`         // GRAPHQL_MAX_DEPTH, how deeply fields may nest
+	MaxComplexity    int `yaml:"max_complexity"`    // GRAPHQL_MAX_COMPLEXITY, the highest cost a query may have
+	StatsConcurrency int `yaml:"stats_concurrency"` // GRAPHQL_STATS_CONCURRENCY, players' game stats fetched at once
+}
+
+// CORS configures which browser origins may call the API with credentials
+type CORS struct {
+	// CORS_ALLOWED_ORIGINS, comma separated. Entries are origins such as
+	// https://app.example.com, https://*.example.com for any subdomain, or *
+	// for any origin (development only). Defaults to FRONTEND_URL.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// Cookie configures the auth cookie set on login
+type Cookie struct {
+	Domain   string `yaml:"domain"`    // COOKIE_DOMAIN; empty means only the API host
+	Secure   *bool  `yaml:"secure"`    // COOKIE_SECURE; defaults to true outside development
+	SameSite string `yaml:"same_site"` // COOKIE_SAMESITE: "lax", "strict" or "none"
+}
+
+// Database configures the database connection
+type Database struct {
+	URL         string `yaml:"url"` // DATABASE_URL, postgres://... or sqlite://...; overrides the fields below
+	Host        string `yaml:"host"`
+	Port        string `yaml:"port"`
+	User        string `yaml:"user"`
+	Password    string `yaml:"password"`
+	Name        string `yaml:"name"`
+	AutoMigrate bool   `yaml:"auto_migrate"` // AUTO_MIGRATE, apply pending migrations on startup
+}
+
+// Telemetry configures OpenTelemetry export
+type Telemetry struct {
+	OTLPEndpoint string `yaml:"otlp_endpoint"` // OTEL_EXPORTER_OTLP_ENDPOINT
+}
+
+// Logging configures the structured logger
+type Logging struct {
+	Level  string `yaml:"level"`  // LOG_LEVEL: "debug", "info", "warn" or "error"
+	Format string `yaml:"format"` // LOG_FORMAT: "json", or "text" for local development
+}
+
+// Cache configures the cache for upstream API responses
+type Cache struct {
+	Backend    string `yaml:"backend"`     // CACHE_BACKEND: "memory", "database" (shared by replicas) or "none"
+	MaxEntries int    `yaml:"max_entries"` // CACHE_MAX_ENTRIES, the memory backend's size before evicting
+}
+
+// RateLimit configures how many requests each client may make to each group of routes
+type RateLimit struct {
+	Backend string `yaml:"backend"` // RATE_LIMIT_BACKEND: "memory", "database" (shared by replicas) or "none"
+	Stats   Rate   `yaml:"stats"`   // RATE_LIMIT_STATS, for the public game stats, which call providers
+	Auth    Rate   `yaml:"auth"`    // RATE_LIMIT_AUTH, for registration, login and sign-in callbacks, per IP
+	GraphQL Rate   `yaml:"graphql"` // RATE_LIMIT_GRAPHQL
+	API     Rate   `yaml:"api"`     // RATE_LIMIT_API, for every other route
+	// RATE_LIMIT_API_TOKENS, comma separated name:token pairs. Requests with
+	// one of the tokens in X-API-Token are limited per token rather than per
+	// user or IP.
+	APITokens map[string]string `yaml:"api_tokens"`
+	// RATE_LIMIT_INTERNAL_TOKENS, comma separated names of API tokens whose
+	// requests aren't limited
+	InternalTokens []string `yaml:"internal_tokens"`
+	// RATE_LIMIT_INTERNAL_NETWORKS, comma separated CIDRs of internal callers
+	// whose requests aren't limited
+	InternalNetworks []string `yaml:"internal_networks"`
+}
+
+// Rate is how many requests are allowed per window, written as
+// requests/window, e.g. 30/1m
+type Rate struct {
+	Requests int
+	Window   time.Duration
+}
+
+// ParseRate reads a rate such as 30/1m
+func ParseRate(value string) (Rate, error) {
+	requests, window, ok := strings.Cut(value, "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("rate must be requests/window, e.g. 30/1m, got %q", value)
+	}
+	var rate Rate
+	var err error
+	if rate.Requests, err = strconv.Atoi(strings.TrimSpace(requests)); err != nil {
+		return Rate{}, fmt.Errorf("rate must be requests/window, e.g. 30/1m, got %q", value)
+	}
+	if rate.Window, err = time.ParseDuration(strings.TrimSpace(window)); err != nil {
+		return Rate{}, fmt.Errorf("rate must be requests/window, e.g. 30/1m, got %q", value)
+	}
+	return rate, nil
+}
+
+func (r Rate) String() string {
+	return fmt.Sprintf("%d/%s", r.Requests, r.Window)
+}
+
+// UnmarshalYAML reads a rate written like 30/1m
+func (r *Rate) UnmarshalYAML(node *yaml.Node) error {
+	rate, err := ParseRate(node.Value)
+	if err != nil {
+		return err
+	}
+	*r = rate
+	return nil
+}
+
+// Steam configures Steam sign-in and the Steam Web API
+type Steam struct {
+	Enabled     *bool         `yaml:"enabled"` // STEAM_ENABLED; unset means on when any Steam setting is given
+	APIKey      string        `yaml:"api_key"`
+	OpenIDURL   string        `yaml:"openid_url"`
+	CallbackURL string        `yaml:"callback_url"`
+	Timeout     time.Duration `yaml:"timeout"` // STEAM_TIMEOUT, per attempt of a Steam API call
+}
+
+// Riot configures the Riot Games API and Riot sign-on
+type Riot struct {
+	Enabled     *bool         `yaml:"enabled"`      // RIOT_ENABLED; unset means on when any Riot setting is given
+	APIKey      string        `yaml:"api_key"`      // RIOT_API_KEY
+	CallbackURL string        `yaml:"callback_url"` // RIOT_CALLBACK_URL, only needed for Riot sign-on
+	Timeout     time.Duration `yaml:"timeout"`      // RIOT_TIMEOUT, per attempt of a Riot API call
+	RateLimit   int           `yaml:"rate_limit"`   // RIOT_RATE_LIMIT, requests per second; match the API key's limit
+	// RIOT_MATCH_CONCURRENCY, match details one stats request downloads at once
+	MatchConcurrency int `yaml:"match_concurrency"`
+}
+
+// Tracker configures the tracker.gg API used for Apex Legends stats
+type Tracker struct {
+	Enabled *bool         `yaml:"enabled"` // TRACKER_ENABLED; unset means on when an API key is given
+	APIKey  string        `yaml:"api_key"`
+	Timeout time.Duration `yaml:"timeout"` // TRACKER_TIMEOUT, per attempt of a tracker.gg call
+}
+
+// IsEnabled reports whether Steam is switched on
+func (s Steam) IsEnabled() bool {
+	return enabled(s.Enabled, s.APIKey, s.CallbackURL)
+}
+
+// IsEnabled reports whether the Riot integration is switched on
+func (r Riot) IsEnabled() bool {
+	return enabled(r.Enabled, r.APIKey, r.CallbackURL)
+}
+
+// IsEnabled reports whether tracker.gg is switched on
+func (t Tracker) IsEnabled() bool {
+	return enabled(t.Enabled, t.APIKey)
+}
+
+// enabled uses the explicit switch if there is one, otherwise whether any value was given
+func enabled(explicit *bool, values ...string) bool {
+	if explicit != nil {
+		return *explicit
+	}
+	for _, value := range values {
+		if value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDevelopment reports whether insecure local defaults are allowed
+func (c *Config) IsDevelopment() bool {
+	return c.Env == EnvDevelopment || c.Env == EnvTest
+}
+
+// defaults returns the settings used when nothing overrides them
+func defaults() *Config {
+	return &Config{
+		Env:  EnvProduction, // Fails closed: a deploy that forgets APP_ENV gets the strict checks
+		Port: "8080",
+		Server: Server{
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+			HookTimeout:       5 * time.Second,
+			MaxBodyBytes:      1 << 20, // 1 MiB
+		},
+		API: API{
+			LegacyDeprecatedAt: time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC),
+			LegacySunset:       time.Date(2027, time.April, 18, 0, 0, 0, 0, time.UTC),
+		},
+		GraphQL: GraphQL{
+			MaxDepth:         10,
+			MaxComplexity:    1000,
+			StatsConcurrency: 4,
+		},
+		Cookie: Cookie{
+			SameSite: "lax",
+		},
+		Database: Database{
+			AutoMigrate: true,
+		},
+		Telemetry: Telemetry{
+			OTLPEndpoint: "otel-collector:4317",
+		},
+		Logging: Logging{
+			Level:  "info",
+			Format: "json",
+		},
+		Cache: Cache{
+			Backend:    "memory",
+			MaxEntries: 10000,
+		},
+		RateLimit: RateLimit{
+			Backend: "memory",
+			Stats:   Rate{Requests: 30, Window: time.Minute},
+			Auth:    Rate{Requests: 10, Window: time.Minute},
+			GraphQL: Rate{Requests: 60, Window: time.Minute},
+			API:     Rate{Requests: 300, Window: time.Minute},
+		},
+		Steam: Steam{
+			OpenIDURL: "https://steamcommunity.com/openid",
+			Timeout:   10 * time.Second,
+		},
+		Riot: Riot{
+			Timeout:          10 * time.Second,
+			RateLimit:        20,
+			MatchConcurrency: 5,
+		},
+		Tracker: Tracker{
+			Timeout: 15 * time.Second,
+		},
+	}
+}
+
+// Load reads the configuration from the YAML file, .env and the environment
+func Load() (*Config, error) {
+	cfg := defaults()
+
+	path, explicit := os.LookupEnv("CONFIG_FILE")
+	if !explicit {
+		path = defaultConfigFile
+	}
+	if err := cfg.loadFile(path, explicit); err != nil {
+		return nil, err
+	}
+
+	// .env never overrides variables that are already set
+	if err := godotenv.Load(); err != nil {
+		cfg.warnings = append(cfg.warnings, "No .env file found, using system environment variables")
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile reads a YAML config file; a missing file is only an error if it was asked for
+func (c *Config) loadFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv overrides settings with any environment variables that are set
+func (c *Config) loadEnv() error {
+	values := map[string]*string{
+		"APP_ENV":                     &c.Env,
+		"PORT":                        &c.Port,
+		"FRONTEND_URL":                &c.FrontendURL,
+		"JWT_SECRET":                  &c.JWTSecret,
+		"COOKIE_DOMAIN":               &c.Cookie.Domain,
+		"COOKIE_SAMESITE":             &c.Cookie.SameSite,
+		"DATABASE_URL":                &c.Database.URL,
+		"DB_HOST":                     &c.Database.Host,
+		"DB_PORT":                     &c.Database.Port,
+		"DB_USER":                     &c.Database.User,
+		"DB_PASSWORD":                 &c.Database.Password,
+		"DB_NAME":                     &c.Database.Name,
+		"OTEL_EXPORTER_OTLP_ENDPOINT": &c.Telemetry.OTLPEndpoint,
+		"LOG_LEVEL":                   &c.Logging.Level,
+		"LOG_FORMAT":                  &c.Logging.Format,
+		"CACHE_BACKEND":               &c.Cache.Backend,
+		"RATE_LIMIT_BACKEND":          &c.RateLimit.Backend,
+		"STEAM_API_KEY":               &c.Steam.APIKey,
+		"STEAM_OPENID_URL":            &c.Steam.OpenIDURL,
+		"STEAM_CALLBACK_URL":          &c.Steam.CallbackURL,
+		"RIOT_API_KEY":                &c.Riot.APIKey,
+		"RIOT_CALLBACK_URL":           &c.Riot.CallbackURL,
+		"TRACKER_API_KEY":             &c.Tracker.APIKey,
+	}
+	for name, field := range values {
+		if value, ok := os.LookupEnv(name); ok {
+			*field = value
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"SERVER_READ_HEADER_TIMEOUT": &c.Server.ReadHeaderTimeout,
+		"SERVER_READ_TIMEOUT":        &c.Server.ReadTimeout,
+		"SERVER_WRITE_TIMEOUT":       &c.Server.WriteTimeout,
+		"SERVER_IDLE_TIMEOUT":        &c.Server.IdleTimeout,
+		"SHUTDOWN_TIMEOUT":           &c.Server.ShutdownTimeout,
+		"SHUTDOWN_HOOK_TIMEOUT":      &c.Server.HookTimeout,
+		"STEAM_TIMEOUT":              &c.Steam.Timeout,
+		"RIOT_TIMEOUT":               &c.Riot.Timeout,
+		"TRACKER_TIMEOUT":            &c.Tracker.Timeout,
+	}
+	for name, field := range durations {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a duration such as 30s, got %q", name, value)
+		}
+		*field = duration
+	}
+
+	times := map[string]*time.Time{
+		"LEGACY_ROUTES_DEPRECATED_AT": &c.API.LegacyDeprecatedAt,
+		"LEGACY_ROUTES_SUNSET":        &c.API.LegacySunset,
+	}
+	for name, field := range times {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		parsed, err := parseTime(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a date such as 2027-04-18 or an RFC 3339 time, got %q", name, value)
+		}
+		*field = parsed
+	}
+
+	rates := map[string]*Rate{
+		"RATE_LIMIT_STATS":   &c.RateLimit.Stats,
+		"RATE_LIMIT_AUTH":    &c.RateLimit.Auth,
+		"RATE_LIMIT_GRAPHQL": &c.RateLimit.GraphQL,
+		"RATE_LIMIT_API":     &c.RateLimit.API,
+	}
+	for name, field := range rates {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		rate, err := ParseRate(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*field = rate
+	}
+
+	if value, ok := os.LookupEnv("MAX_BODY_BYTES"); ok {
+		maxBody, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("MAX_BODY_BYTES must be a number of bytes, got %q", value)
+		}
+		c.Server.MaxBodyBytes = maxBody
+	}
+
+	counts := map[string]*int{
+		"CACHE_MAX_ENTRIES":      &c.Cache.MaxEntries,
+		"RIOT_RATE_LIMIT":        &c.Riot.RateLimit,
+		"RIOT_MATCH_CONCURRENCY": &c.Riot.MatchConcurrency,
+
+		"GRAPHQL_MAX_DEPTH":         &c.GraphQL.MaxDepth,
+		"GRAPHQL_MAX_COMPLEXITY":    &c.GraphQL.MaxComplexity,
+		"GRAPHQL_STATS_CONCURRENCY": &c.GraphQL.StatsConcurrency,
+	}
+	for name, field := range counts {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a number, got %q", name, value)
+		}
+		*field = count
+	}
+
+	if value, ok := os.LookupEnv("AUTO_MIGRATE"); ok {
+		autoMigrate, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("AUTO_MIGRATE must be true or false, got %q", value)
+		}
+		c.Database.AutoMigrate = autoMigrate
+	}
+
+	lists := map[string]*[]string{
+		"CORS_ALLOWED_ORIGINS":         &c.CORS.AllowedOrigins,
+		"TRUSTED_PROXIES":              &c.Server.TrustedProxies,
+		"RATE_LIMIT_INTERNAL_TOKENS":   &c.RateLimit.InternalTokens,
+		"RATE_LIMIT_INTERNAL_NETWORKS": &c.RateLimit.InternalNetworks,
+	}
+	for name, field := range lists {
+		if value, ok := os.LookupEnv(name); ok {
+			*field = splitList(value)
+		}
+	}
+
+	if value, ok := os.LookupEnv("RATE_LIMIT_API_TOKENS"); ok {
+		c.RateLimit.APITokens = map[string]string{}
+		for _, pair := range splitList(value) {
+			name, token, ok := strings.Cut(pair, ":")
+			if !ok {
+				return fmt.Errorf("RATE_LIMIT_API_TOKENS must be comma separated name:token pairs")
+			}
+			c.RateLimit.APITokens[strings.TrimSpace(name)] = strings.TrimSpace(token)
+		}
+	}
+
+	switches := map[string]**bool{
+		"COOKIE_SECURE":   &c.Cookie.Secure,
+		"STEAM_ENABLED":   &c.Steam.Enabled,
+		"RIOT_ENABLED":    &c.Riot.Enabled,
+		"TRACKER_ENABLED": &c.Tracker.Enabled,
+	}
+	for name, field := range switches {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		on, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false, got %q", name, value)
+		}
+		*field = &on
+	}
+	return nil
+}
+
+// Validate checks that every enabled feature has what it needs and, outside
+// development, that no insecure defaults are in use. All problems are
+// reported together. In development a missing JWT secret is replaced with a
+// random one, which logs everyone out on restart.
+func (c *Config) Validate() error {
+	var problems []string
+	require := func(value, name string) {
+		if value == "" {
+			problems = append(problems, name+" is required")
+		}
+	}
+	requireFor := func(feature, value, name string) {
+		if value == "" {
+			problems = append(problems, name+" is required when "+feature+" is enabled")
+		}
+	}
+
+	switch c.Env {
+	case EnvDevelopment, EnvTest, EnvProduction:
+	default:
+		problems = append(problems, fmt.Sprintf("APP_ENV must be %q, %q or %q, got %q", EnvDevelopment, EnvTest, EnvProduction, c.Env))
+	}
+	require(c.Port, "PORT")
+
+	// Server and upstream limits; zero would mean no timeout at all
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"SERVER_READ_HEADER_TIMEOUT", c.Server.ReadHeaderTimeout},
+		{"SERVER_READ_TIMEOUT", c.Server.ReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", c.Server.WriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", c.Server.IdleTimeout},
+		{"SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout},
+		{"SHUTDOWN_HOOK_TIMEOUT", c.Server.HookTimeout},
+		{"STEAM_TIMEOUT", c.Steam.Timeout},
+		{"RIOT_TIMEOUT", c.Riot.Timeout},
+		{"TRACKER_TIMEOUT", c.Tracker.Timeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			problems = append(problems, timeout.name+" must be positive")
+		}
+	}
+	if c.Server.MaxBodyBytes <= 0 {
+		problems = append(problems, "MAX_BODY_BYTES must be positive")
+	}
+
+	// Versioning
+	if !c.API.LegacySunset.After(c.API.LegacyDeprecatedAt) {
+		problems = append(problems, "LEGACY_ROUTES_SUNSET must be after LEGACY_ROUTES_DEPRECATED_AT")
+	}
+
+	// GraphQL query limits
+	limits := []struct {
+		name  string
+		value int
+	}{
+		{"GRAPHQL_MAX_DEPTH", c.GraphQL.MaxDepth},
+		{"GRAPHQL_MAX_COMPLEXITY", c.GraphQL.MaxComplexity},
+		{"GRAPHQL_STATS_CONCURRENCY", c.GraphQL.StatsConcurrency},
+	}
+	for _, limit := range limits {
+		if limit.value <= 0 {
+			problems = append(problems, limit.name+" must be positive")
+		}
+	}
+
+	// Logging
+	c.Logging.Level = strings.ToLower(c.Logging.Level)
+	switch c.Logging.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL must be debug, info, warn or error, got %q", c.Logging.Level))
+	}
+	c.Logging.Format = strings.ToLower(c.Logging.Format)
+	if c.Logging.Format != "json" && c.Logging.Format != "text" {
+		problems = append(problems, fmt.Sprintf("LOG_FORMAT must be json or text, got %q", c.Logging.Format))
+	}
+
+	// Cache
+	c.Cache.Backend = strings.ToLower(c.Cache.Backend)
+	switch c.Cache.Backend {
+	case "memory", "database", "none":
+	default:
+		problems = append(problems, fmt.Sprintf("CACHE_BACKEND must be memory, database or none, got %q", c.Cache.Backend))
+	}
+	if c.Cache.Backend == "memory" && c.Cache.MaxEntries <= 0 {
+		problems = append(problems, "CACHE_MAX_ENTRIES must be positive")
+	}
+
+	// Rate limits
+	c.RateLimit.Backend = strings.ToLower(c.RateLimit.Backend)
+	switch c.RateLimit.Backend {
+	case "memory", "database", "none":
+	default:
+		problems = append(problems, fmt.Sprintf("RATE_LIMIT_BACKEND must be memory, database or none, got %q", c.RateLimit.Backend))
+	}
+	rates := []struct {
+		name  string
+		value Rate
+	}{
+		{"RATE_LIMIT_STATS", c.RateLimit.Stats},
+		{"RATE_LIMIT_AUTH", c.RateLimit.Auth},
+		{"RATE_LIMIT_GRAPHQL", c.RateLimit.GraphQL},
+		{"RATE_LIMIT_API", c.RateLimit.API},
+	}
+	for _, rate := range rates {
+		if rate.value.Requests <= 0 || rate.value.Window < time.Second {
+			problems = append(problems, rate.name+" must allow at least one request per window of a second or more")
+		}
+	}
+	for name, token := range c.RateLimit.APITokens {
+		if name == "" || token == "" {
+			problems = append(problems, "RATE_LIMIT_API_TOKENS entries need a name and a token")
+		}
+	}
+	for _, name := range c.RateLimit.InternalTokens {
+		if _, ok := c.RateLimit.APITokens[name]; !ok {
+			problems = append(problems, fmt.Sprintf("RATE_LIMIT_INTERNAL_TOKENS names %q, which isn't in RATE_LIMIT_API_TOKENS", name))
+		}
+	}
+	for _, network := range c.RateLimit.InternalNetworks {
+		if _, _, err := net.ParseCIDR(network); err != nil {
+			problems = append(problems, fmt.Sprintf("RATE_LIMIT_INTERNAL_NETWORKS must be CIDRs such as 10.0.0.0/8, got %q", network))
+		}
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			problems = append(problems, fmt.Sprintf("TRUSTED_PROXIES must be IPs or CIDRs, got %q", proxy))
+		}
+	}
+
+	// JWT secret
+	switch {
+	case c.JWTSecret == "" && c.IsDevelopment():
+		c.warnings = append(c.warnings, "JWT_SECRET is not set, using a random secret. Sessions will not survive a restart.")
+		c.JWTSecret = randomSecret()
+	case c.JWTSecret == "":
+		problems = append(problems, "JWT_SECRET is required")
+	case c.IsDevelopment():
+		if insecureSecrets[c.JWTSecret] || len(c.JWTSecret) < minJWTSecretLength {
+			c.warnings = append(c.warnings, "JWT_SECRET is weak. This is only allowed in development.")
+		}
+	case insecureSecrets[c.JWTSecret]:
+		problems = append(problems, "JWT_SECRET is a well-known placeholder value")
+	case len(c.JWTSecret) < minJWTSecretLength:
+		problems = append(problems, fmt.Sprintf("JWT_SECRET must be at least %d characters", minJWTSecretLength))
+	}
+
+	// Frontend
+	if c.FrontendURL == "" && c.IsDevelopment() {
+		c.FrontendURL = "http://localhost:3000"
+	}
+	require(c.FrontendURL, "FRONTEND_URL")
+	if c.FrontendURL != "" && !c.IsDevelopment() && !strings.HasPrefix(c.FrontendURL, "https://") {
+		problems = append(problems, "FRONTEND_URL must use https outside development")
+	}
+
+	// CORS; the origins themselves are parsed by the CORS middleware
+	if len(c.CORS.AllowedOrigins) == 0 && c.FrontendURL != "" {
+		c.CORS.AllowedOrigins = []string{c.FrontendURL}
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" && !c.IsDevelopment() {
+			problems = append(problems, "CORS_ALLOWED_ORIGINS cannot allow every origin outside development")
+		}
+	}
+
+	// Auth cookie
+	if c.Cookie.Secure == nil {
+		secure := !c.IsDevelopment()
+		c.Cookie.Secure = &secure
+	}
+	c.Cookie.SameSite = strings.ToLower(c.Cookie.SameSite)
+	switch c.Cookie.SameSite {
+	case "lax", "strict":
+	case "none":
+		// Browsers drop SameSite=None cookies that aren't Secure
+		if !*c.Cookie.Secure {
+			problems = append(problems, "COOKIE_SAMESITE=none requires COOKIE_SECURE=true")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("COOKIE_SAMESITE must be lax, strict or none, got %q", c.Cookie.SameSite))
+	}
+	if !*c.Cookie.Secure && !c.IsDevelopment() {
+		problems = append(problems, "COOKIE_SECURE cannot be false outside development")
+	}
+
+	// Database
+	if c.Database.URL == "" {
+		require(c.Database.Host, "DB_HOST")
+		require(c.Database.Name, "DB_NAME")
+		if !c.IsDevelopment() {
+			require(c.Database.Password, "DB_PASSWORD")
+		}
+	} else if !c.IsDevelopment() && strings.HasPrefix(c.Database.URL, "sqlite://:memory:") {
+		problems = append(problems, "DATABASE_URL cannot be an in-memory SQLite database outside development")
+	}
+
+	// Integrations, each checked only when it is switched on
+	if c.Steam.IsEnabled() {
+		requireFor("Steam", c.Steam.APIKey, "STEAM_API_KEY")
+		requireFor("Steam", c.Steam.OpenIDURL, "STEAM_OPENID_URL")
+		requireFor("Steam", c.Steam.CallbackURL, "STEAM_CALLBACK_URL")
+	}
+	if c.Riot.IsEnabled() {
+		requireFor("Riot", c.Riot.APIKey, "RIOT_API_KEY")
+		if c.Riot.RateLimit <= 0 {
+			problems = append(problems, "RIOT_RATE_LIMIT must be positive")
+		}
+		if c.Riot.MatchConcurrency <= 0 {
+			problems = append(problems, "RIOT_MATCH_CONCURRENCY must be positive")
+		}
+	}
+	if c.Tracker.IsEnabled() {
+		requireFor("tracker.gg", c.Tracker.APIKey, "TRACKER_API_KEY")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// LogSummary logs which environment and features are active, without
+// secrets, and any warnings from loading and validating
+func (c *Config) LogSummary() {
+	slog.Info("Configuration", "env", c.Env, "port", c.Port,
+		"steam", c.Steam.IsEnabled(), "riot", c.Riot.IsEnabled(), "tracker", c.Tracker.IsEnabled())
+	for _, warning := range c.warnings {
+		slog.Warn(warning)
+	}
+}
+
+// splitList reads a comma separated list, skipping empty entries
+func splitList(value string) []string {
+	var list []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// parseTime reads an RFC 3339 time, or a date meaning midnight UTC
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// randomSecret returns a new 32-byte hex secret
+func randomSecret() string {
+	secret := make([]byte, 32)
+	rand.Read(secret) // Never returns an error; it crashes the process instead
+	return hex.EncodeToString(secret)
+}
@@ -0,0 +1,276 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// isolate runs the test in an empty directory, with none of the server's
+// settings in the environment, and restores both afterwards
+func isolate(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		switch name {
+		case "PATH", "HOME", "TMPDIR":
+			continue
+		}
+		unsetenv(t, name)
+	}
+	return dir
+}
+
+// unsetenv removes a variable until the test ends, when any value it had is restored
+func unsetenv(t *testing.T, name string) {
+	t.Setenv(name, "")
+	os.Unsetenv(name)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string            // config.yaml in the working directory
+		other   string            // other.yaml, for CONFIG_FILE
+		dotenv  string            // .env in the working directory
+		env     map[string]string // Set in the environment
+		got     func(cfg *Config) []any
+		want    string // got, space separated
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			got:  func(cfg *Config) []any { return []any{cfg.Port, cfg.Env, cfg.Logging.Level} },
+			want: "8080 production info",
+		},
+		{
+			name: "YAML over defaults",
+			yaml: "port: \"7000\"\nlogging:\n  level: debug\nrate_limit:\n  stats: 5/10s\n",
+			got:  func(cfg *Config) []any { return []any{cfg.Port, cfg.Logging.Level, cfg.RateLimit.Stats} },
+			want: "7000 debug 5/10s",
+		},
+		{
+			name:   ".env over YAML",
+			yaml:   "port: \"7000\"\nlogging:\n  level: debug\n",
+			dotenv: "PORT=7001\n",
+			got:    func(cfg *Config) []any { return []any{cfg.Port, cfg.Logging.Level} },
+			want:   "7001 debug",
+		},
+		{
+			name:   "environment over .env",
+			yaml:   "port: \"7000\"\n",
+			dotenv: "PORT=7001\nLOG_LEVEL=warn\n",
+			env:    map[string]string{"PORT": "7002"},
+			got:    func(cfg *Config) []any { return []any{cfg.Port, cfg.Logging.Level} },
+			want:   "7002 warn",
+		},
+		{
+			name:  "CONFIG_FILE replaces config.yaml",
+			yaml:  "port: \"7000\"\n",
+			other: "port: \"7003\"\n",
+			env:   map[string]string{"CONFIG_FILE": "other.yaml"},
+			got:   func(cfg *Config) []any { return []any{cfg.Port} },
+			want:  "7003",
+		},
+		{
+			name: "every kind of variable",
+			env: map[string]string{
+				"SHUTDOWN_TIMEOUT":      "3s",
+				"RATE_LIMIT_API":        "100/1h",
+				"CORS_ALLOWED_ORIGINS":  "https://a.example, https://b.example",
+				"RATE_LIMIT_API_TOKENS": "ops:abc",
+				"COOKIE_SECURE":         "false",
+				"GRAPHQL_MAX_DEPTH":     "4",
+				"AUTO_MIGRATE":          "false",
+			},
+			got: func(cfg *Config) []any {
+				return []any{cfg.Server.ShutdownTimeout, cfg.RateLimit.API, cfg.CORS.AllowedOrigins,
+					cfg.RateLimit.APITokens["ops"], *cfg.Cookie.Secure, cfg.GraphQL.MaxDepth, cfg.Database.AutoMigrate}
+			},
+			want: "3s 100/1h0m0s [https://a.example https://b.example] abc false 4 false",
+		},
+		{name: "missing CONFIG_FILE", env: map[string]string{"CONFIG_FILE": "missing.yaml"}, wantErr: "failed to read config file"},
+		{name: "malformed YAML", yaml: "port: [\n", wantErr: "failed to parse config file"},
+		{name: "malformed duration", env: map[string]string{"SHUTDOWN_TIMEOUT": "soon"}, wantErr: "SHUTDOWN_TIMEOUT must be a duration"},
+		{name: "malformed rate", env: map[string]string{"RATE_LIMIT_STATS": "30"}, wantErr: "RATE_LIMIT_STATS"},
+		{name: "malformed switch", env: map[string]string{"STEAM_ENABLED": "yes please"}, wantErr: "STEAM_ENABLED must be true or false"},
+		{name: "malformed API tokens", env: map[string]string{"RATE_LIMIT_API_TOKENS": "abc"}, wantErr: "name:token pairs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolate(t)
+			if tt.yaml != "" {
+				writeFile(t, filepath.Join(dir, "config.yaml"), tt.yaml)
+			}
+			if tt.other != "" {
+				writeFile(t, filepath.Join(dir, "other.yaml"), tt.other)
+			}
+			if tt.dotenv != "" {
+				writeFile(t, filepath.Join(dir, ".env"), tt.dotenv)
+				// Load sets these; unset them again when the test ends
+				for _, line := range strings.Split(strings.TrimSpace(tt.dotenv), "\n") {
+					name, _, _ := strings.Cut(line, "=")
+					unsetenv(t, name)
+				}
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := Load()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if got := strings.TrimSuffix(fmt.Sprintln(tt.got(cfg)...), "\n"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			missingDotenv := slices.Contains(cfg.warnings, "No .env file found, using system environment variables")
+			if missingDotenv != (tt.dotenv == "") {
+				t.Errorf("warnings = %v, want the missing .env warning only without one", cfg.warnings)
+			}
+		})
+	}
+}
+
+// validConfig returns settings that pass Validate in env without any fallbacks
+func validConfig(env string) *Config {
+	cfg := defaults()
+	cfg.Env = env
+	cfg.JWTSecret = strings.Repeat("k", minJWTSecretLength)
+	cfg.FrontendURL = "https://app.example.com"
+	cfg.Database = Database{Host: "db", Name: "elo", User: "elo", Password: "db-password"}
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		name   string
+		env    string
+		change func(cfg *Config)
+		want   []string // Problems reported, in order; none if valid
+	}{
+		{"valid production", EnvProduction, func(cfg *Config) {}, nil},
+		{"unknown environment", "staging", func(cfg *Config) {}, []string{`APP_ENV must be "development", "test" or "production", got "staging"`}},
+
+		// Insecure settings refused outside development
+		{"production without a JWT secret", EnvProduction, func(cfg *Config) { cfg.JWTSecret = "" }, []string{"JWT_SECRET is required"}},
+		{"production with a placeholder JWT secret", EnvProduction, func(cfg *Config) { cfg.JWTSecret = "changeme" }, []string{"JWT_SECRET is a well-known placeholder value"}},
+		{"production with a short JWT secret", EnvProduction, func(cfg *Config) { cfg.JWTSecret = strings.Repeat("k", minJWTSecretLength-1) }, []string{"JWT_SECRET must be at least 32 characters"}},
+		{"development with a weak JWT secret", EnvDevelopment, func(cfg *Config) { cfg.JWTSecret = "changeme" }, nil},
+		{"production over http", EnvProduction, func(cfg *Config) { cfg.FrontendURL = "http://app.example.com" }, []string{"FRONTEND_URL must use https outside development"}},
+		{"production without FRONTEND_URL", EnvProduction, func(cfg *Config) { cfg.FrontendURL = "" }, []string{"FRONTEND_URL is required"}},
+		{"development over http", EnvDevelopment, func(cfg *Config) { cfg.FrontendURL = "http://localhost:5173" }, nil},
+		{"production with an insecure cookie", EnvProduction, func(cfg *Config) { cfg.Cookie.Secure = &off }, []string{"COOKIE_SECURE cannot be false outside development"}},
+		{"test with an insecure cookie", EnvTest, func(cfg *Config) { cfg.Cookie.Secure = &off }, nil},
+		{"SameSite=None without Secure", EnvDevelopment, func(cfg *Config) { cfg.Cookie.Secure, cfg.Cookie.SameSite = &off, "None" }, []string{"COOKIE_SAMESITE=none requires COOKIE_SECURE=true"}},
+		{"production allowing every origin", EnvProduction, func(cfg *Config) { cfg.CORS.AllowedOrigins = []string{"*"} }, []string{"CORS_ALLOWED_ORIGINS cannot allow every origin outside development"}},
+		{"development allowing every origin", EnvDevelopment, func(cfg *Config) { cfg.CORS.AllowedOrigins = []string{"*"} }, nil},
+		{"production without a database password", EnvProduction, func(cfg *Config) { cfg.Database.Password = "" }, []string{"DB_PASSWORD is required"}},
+		{"development without a database password", EnvDevelopment, func(cfg *Config) { cfg.Database.Password = "" }, nil},
+		{"production on in-memory SQLite", EnvProduction, func(cfg *Config) { cfg.Database.URL = "sqlite://:memory:" }, []string{"DATABASE_URL cannot be an in-memory SQLite database outside development"}},
+
+		// Integrations are only checked when switched on
+		{"Steam switched on by a setting", EnvProduction, func(cfg *Config) { cfg.Steam.APIKey = "key" }, []string{"STEAM_CALLBACK_URL is required when Steam is enabled"}},
+		{"Steam switched on explicitly", EnvProduction, func(cfg *Config) { cfg.Steam.Enabled = &on }, []string{
+			"STEAM_API_KEY is required when Steam is enabled", "STEAM_CALLBACK_URL is required when Steam is enabled"}},
+		{"Steam switched off with partial settings", EnvProduction, func(cfg *Config) { cfg.Steam.Enabled, cfg.Steam.APIKey = &off, "key" }, nil},
+		{"Steam complete", EnvProduction, func(cfg *Config) {
+			cfg.Steam.APIKey, cfg.Steam.CallbackURL = "key", "https://api.example.com/auth/steam/callback"
+		}, nil},
+		{"Riot switched on without a key", EnvProduction, func(cfg *Config) { cfg.Riot.CallbackURL = "https://api.example.com/auth/riot/callback" }, []string{"RIOT_API_KEY is required when Riot is enabled"}},
+		{"Riot switched on with no rate limit", EnvProduction, func(cfg *Config) { cfg.Riot.APIKey, cfg.Riot.RateLimit = "RGAPI-key", 0 }, []string{"RIOT_RATE_LIMIT must be positive"}},
+		{"Riot switched off with no rate limit", EnvProduction, func(cfg *Config) { cfg.Riot.RateLimit = 0 }, nil},
+		{"tracker.gg switched on without a key", EnvProduction, func(cfg *Config) { cfg.Tracker.Enabled = &on }, []string{"TRACKER_API_KEY is required when tracker.gg is enabled"}},
+
+		// Everything is reported at once
+		{"several problems", EnvProduction, func(cfg *Config) {
+			cfg.JWTSecret, cfg.FrontendURL, cfg.Logging.Level = "", "http://app.example.com", "verbose"
+		}, []string{`LOG_LEVEL must be debug, info, warn or error, got "verbose"`, "JWT_SECRET is required", "FRONTEND_URL must use https outside development"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig(tt.env)
+			tt.change(cfg)
+			err := cfg.Validate()
+
+			var got []string
+			if err != nil {
+				got = strings.Split(strings.TrimPrefix(err.Error(), "invalid configuration:\n  "), "\n  ")
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("problems = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          string
+		wantFrontend string
+		wantSecure   bool
+		wantWarning  bool // The random JWT secret warning
+	}{
+		{"development", EnvDevelopment, "http://localhost:3000", false, true},
+		{"test", EnvTest, "http://localhost:3000", false, true},
+		{"production", EnvProduction, "", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig(tt.env)
+			cfg.JWTSecret, cfg.FrontendURL = "", ""
+			err := cfg.Validate()
+			if tt.env == EnvProduction {
+				// Nothing is filled in for production, so both are missing
+				if err == nil {
+					t.Fatal("Validate = nil, want JWT_SECRET and FRONTEND_URL reported")
+				}
+			} else if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+
+			if cfg.FrontendURL != tt.wantFrontend {
+				t.Errorf("FRONTEND_URL = %q, want %q", cfg.FrontendURL, tt.wantFrontend)
+			}
+			if *cfg.Cookie.Secure != tt.wantSecure {
+				t.Errorf("COOKIE_SECURE = %v, want %v", *cfg.Cookie.Secure, tt.wantSecure)
+			}
+			if tt.wantFrontend != "" && !slices.Equal(cfg.CORS.AllowedOrigins, []string{tt.wantFrontend}) {
+				t.Errorf("CORS_ALLOWED_ORIGINS = %v, want the frontend", cfg.CORS.AllowedOrigins)
+			}
+			warned := slices.ContainsFunc(cfg.warnings, func(w string) bool { return strings.HasPrefix(w, "JWT_SECRET is not set") })
+			if warned != tt.wantWarning || warned && len(cfg.JWTSecret) < minJWTSecretLength {
+				t.Errorf("JWT secret %q, warnings %v; want a random secret: %v", cfg.JWTSecret, cfg.warnings, tt.wantWarning)
+			}
+		})
+	}
+
+	// Each start gets its own random secret
+	first, second := validConfig(EnvDevelopment), validConfig(EnvDevelopment)
+	first.JWTSecret, second.JWTSecret = "", ""
+	first.Validate()
+	second.Validate()
+	if first.JWTSecret == second.JWTSecret {
+		t.Errorf("two random JWT secrets are both %q", first.JWTSecret)
+	}
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"elo-insight/backend/config"
+	"elo-insight/backend/models"
+)
+
+func TestSQLiteMigrationsRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(config.Database{URL: "sqlite://:memory:"})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("migrator: %v", err)
+	}
+	latest, err := migrator.Latest()
+	if err != nil {
+		t.Fatalf("latest: %v", err)
+	}
+
+	for _, step := range []struct {
+		name string
+		run  func() (int, error)
+		want int
+	}{
+		{"up", func() (int, error) { return migrator.Up(ctx) }, latest},
+		{"down", func() (int, error) { return migrator.Down(ctx, latest) }, 0},
+		{"up again", func() (int, error) { return migrator.Up(ctx) }, latest},
+	} {
+		if _, err := step.run(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			t.Fatalf("%s: version: %v", step.name, err)
+		}
+		if version != step.want {
+			t.Errorf("%s: schema version %d, want %d", step.name, version, step.want)
+		}
+	}
+}
+
+func TestStatementLogLeavesOutValues(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	db, err := Open(config.Database{URL: "sqlite://:memory:"})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	const hash = "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"
+	user := models.User{Username: "logged", Email: "logged@example.com", Password: hash}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	if !strings.Contains(logs.String(), "INSERT INTO") {
+		t.Fatalf("the INSERT was not logged:\n%s", logs.String())
+	}
+	for _, value := range []string{hash, user.Email} {
+		if strings.Contains(logs.String(), value) {
+			t.Errorf("log contains %q:\n%s", value, logs.String())
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+
+	"elo-insight/backend/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// InitMetrics reports the connection pool's statistics each time metrics are collected
+func InitMetrics() error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+
+	meter := telemetry.Meter()
+	open, err := meter.Int64ObservableGauge("db.pool.connections", metric.WithDescription("Open database connections by state"))
+	if err != nil {
+		return err
+	}
+	maxOpen, err := meter.Int64ObservableGauge("db.pool.max_connections", metric.WithDescription("Maximum open database connections, 0 for unlimited"))
+	if err != nil {
+		return err
+	}
+	waits, err := meter.Int64ObservableCounter("db.pool.waits", metric.WithDescription("Times a query waited for a free connection"))
+	if err != nil {
+		return err
+	}
+	waitTime, err := meter.Float64ObservableCounter("db.pool.wait_duration", metric.WithUnit("s"), metric.WithDescription("Total time spent waiting for a free connection"))
+	if err != nil {
+		return err
+	}
+
+	inUse := metric.WithAttributes(attribute.String("state", "in_use"))
+	idle := metric.WithAttributes(attribute.String("state", "idle"))
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		stats := sqlDB.Stats()
+		o.ObserveInt64(open, int64(stats.InUse), inUse)
+		o.ObserveInt64(open, int64(stats.Idle), idle)
+		o.ObserveInt64(maxOpen, int64(stats.MaxOpenConnections))
+		o.ObserveInt64(waits, stats.WaitCount)
+		o.ObserveFloat64(waitTime, stats.WaitDuration.Seconds())
+		return nil
+	}, open, maxOpen, waits, waitTime)
+	return err
+}
@@ -2,14 +2,18 @@ package database
 
 import (
 	"context"
+	"elo-insight/backend/logging"
 	"elo-insight/backend/telemetry"
-	"fmt"
+	"log/slog"
 	"strings"
 
 	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 )
 
+// dbSystem names the database in span attributes; set from the dialector in InitTracing
+var dbSystem = "postgres"
+
 // QueryHook is a callback function called before a SQL query is executed
 func QueryHook(ctx context.Context, query string, args ...interface{}) {
 	// Extract and sanitize the SQL query for tracing
@@ -21,7 +25,7 @@ func QueryHook(ctx context.Context, query string, args ...interface{}) {
 	
 	// Add query details as attributes directly to span
 	span.SetAttributes(
-		attribute.String("db.system", "postgres"),
+		attribute.String("db.system", dbSystem),
 		attribute.String("db.operation", getOperationType(query)),
 		attribute.String("db.statement", sanitizedQuery),
 	)
@@ -34,6 +38,8 @@ func QueryHook(ctx context.Context, query string, args ...interface{}) {
 
 // Initialize GORM callbacks for query tracing
 func InitTracing() {
+	dbSystem = DB.Dialector.Name()
+
 	// Register callbacks for query monitoring
 	callbacks := DB.Callback()
 	
@@ -41,51 +47,49 @@ func InitTracing() {
 	if err := callbacks.Create().Before("gorm:create").Register("tracing:before_create", func(db *gorm.DB) {
 		QueryHook(db.Statement.Context, db.Statement.SQL.String(), db.Statement.Vars...)
 	}); err != nil {
-		fmt.Println("Error registering before_create callback:", err)
+		slog.Error("Failed to register tracing callback", "callback", "before_create", "error", err)
 	}
 	
 	// Register query operation callback
 	if err := callbacks.Query().Before("gorm:query").Register("tracing:before_query", func(db *gorm.DB) {
 		QueryHook(db.Statement.Context, db.Statement.SQL.String(), db.Statement.Vars...)
 	}); err != nil {
-		fmt.Println("Error registering before_query callback:", err)
+		slog.Error("Failed to register tracing callback", "callback", "before_query", "error", err)
 	}
 	
 	// Register update operation callback
 	if err := callbacks.Update().Before("gorm:update").Register("tracing:before_update", func(db *gorm.DB) {
 		QueryHook(db.Statement.Context, db.Statement.SQL.String(), db.Statement.Vars...)
 	}); err != nil {
-		fmt.Println("Error registering before_update callback:", err)
+		slog.Error("Failed to register tracing callback", "callback", "before_update", "error", err)
 	}
 	
 	// Register delete operation callback
 	if err := callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", func(db *gorm.DB) {
 		QueryHook(db.Statement.Context, db.Statement.SQL.String(), db.Statement.Vars...)
 	}); err != nil {
-		fmt.Println("Error registering before_delete callback:", err)
+		slog.Error("Failed to register tracing callback", "callback", "before_delete", "error", err)
 	}
 	
 	// Register row operation callback
 	if err := callbacks.Row().Before("gorm:row").Register("tracing:before_row", func(db *gorm.DB) {
 		QueryHook(db.Statement.Context, db.Statement.SQL.String(), db.Statement.Vars...)
 	}); err != nil {
-		fmt.Println("Error registering before_row callback:", err)
+		slog.Error("Failed to register tracing callback", "callback", "before_row", "error", err)
 	}
 	
 	// Register raw operation callback
 	if err := callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", func(db *gorm.DB) {
 		QueryHook(db.Statement.Context, db.Statement.SQL.String(), db.Statement.Vars...)
 	}); err != nil {
-		fmt.Println("Error registering before_raw callback:", err)
+		slog.Error("Failed to register tracing callback", "callback", "before_raw", "error", err)
 	}
 }
 
-// sanitizeSQL removes sensitive information from SQL queries
+// sanitizeSQL removes sensitive information from SQL queries. Values are
+// normally bound separately, but raw SQL can still inline emails or tokens.
 func sanitizeSQL(query string) string {
-	// For simplicity, we're just returning the query
-	// In a real implementation, you'd want to use a SQL parser to remove potentially
-	// sensitive data like passwords, tokens, etc.
-	return query
+	return logging.Redact(query)
 }
 
 // getOperationType returns the type of SQL operation (SELECT, INSERT, UPDATE, DELETE)
@@ -0,0 +1,292 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"elo-insight/backend/models"
+	"elo-insight/backend/store"
+	"elo-insight/backend/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Win streak lengths that produce a feed event
+var winStreakMilestones = map[int]bool{3: true, 5: true, 10: true}
+
+// Minimum number of earlier matches before we call something a personal best
+const personalBestMinHistory = 5
+
+// Ranked tiers from lowest to highest
+var tierOrder = []string{
+	"IRON", "BRONZE", "SILVER", "GOLD", "PLATINUM", "EMERALD",
+	"DIAMOND", "MASTER", "GRANDMASTER", "CHALLENGER",
+}
+
+// Divisions from lowest to highest
+var divisionOrder = []string{"IV", "III", "II", "I"}
+
+// Generator turns stored matches and rank changes into feed events
+type Generator struct {
+	store *store.Store
+}
+
+// NewGenerator returns a generator that reads and writes through the store
+func NewGenerator(s *store.Store) *Generator {
+	return &Generator{store: s}
+}
+
+// RecordMatch stores a newly fetched match and generates feed events for
+// every linked user who played in it. Matches that were already stored are
+// ignored, so it is safe to call on every fetch.
+func (g *Generator) RecordMatch(ctx context.Context, match *models.StoredMatch) error {
+	return telemetry.TimeJob(ctx, "feed.record_match", func() error {
+		return g.recordMatch(ctx, match)
+	})
+}
+
+// RecordMatches records matches in the order they were played, which win
+// streaks and personal bests depend on. It records every match it can and
+// returns the failures together.
+func (g *Generator) RecordMatches(ctx context.Context, matches []models.StoredMatch) error {
+	matches = slices.Clone(matches)
+	slices.SortStableFunc(matches, func(a, b models.StoredMatch) int { return a.PlayedAt.Compare(b.PlayedAt) })
+
+	var errs []error
+	for i := range matches {
+		if err := g.RecordMatch(ctx, &matches[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (g *Generator) recordMatch(ctx context.Context, match *models.StoredMatch) error {
+	ctx, span := telemetry.StartSpan(ctx, "feed.record_match")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("match.game", match.Game),
+		attribute.String("match.id", match.MatchID),
+	)
+
+	// Insert the match unless we already have it
+	inserted, err := g.store.Matches.Save(ctx, match)
+	if err != nil {
+		return fmt.Errorf("failed to store match %s: %w", match.MatchID, err)
+	}
+	if !inserted {
+		span.SetAttributes(attribute.Bool("match.already_stored", true))
+		return nil
+	}
+
+	// Find which participants are linked to one of our users
+	puuids := make([]string, 0, len(match.Participants))
+	for _, p := range match.Participants {
+		puuids = append(puuids, p.PUUID)
+	}
+
+	links, err := g.store.PlatformLinks.FindByExternalIDs(ctx, models.PlatformRiot, puuids)
+	if err != nil {
+		return fmt.Errorf("failed to look up linked users: %w", err)
+	}
+
+	for _, link := range links {
+		for _, p := range match.Participants {
+			if p.PUUID != link.ExternalID {
+				continue
+			}
+			if err := g.generateMatchEvents(ctx, link.UserID, match, p); err != nil {
+				slog.WarnContext(ctx, "Failed to generate feed events", "user_id", link.UserID, "match_id", match.MatchID, "error", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// generateMatchEvents creates pentakill, win streak and personal best events for one player
+func (g *Generator) generateMatchEvents(ctx context.Context, userID uint, match *models.StoredMatch, p models.MatchParticipant) error {
+	// Pentakills
+	if p.PentaKills > 0 {
+		summary := fmt.Sprintf("scored a pentakill on %s", p.Character)
+		if p.PentaKills > 1 {
+			summary = fmt.Sprintf("scored %d pentakills on %s", p.PentaKills, p.Character)
+		}
+		if err := g.createEvent(ctx, userID, models.FeedEventPentakill, match, summary, map[string]interface{}{
+			"character":   p.Character,
+			"penta_kills": p.PentaKills,
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Load what this player played in the same game before this match, newest
+	// first, so matches recorded late don't count against earlier ones
+	history, err := g.store.Matches.PlayerHistory(ctx, p.PUUID, match.Game, match.PlayedAt, 50)
+	if err != nil {
+		return fmt.Errorf("failed to load match history: %w", err)
+	}
+
+	// Win streaks, ending with this match
+	if p.Win {
+		streak := 1
+		for _, h := range history {
+			if !h.Win {
+				break
+			}
+			streak++
+		}
+		if winStreakMilestones[streak] {
+			if err := g.createEvent(ctx, userID, models.FeedEventWinStreak, match,
+				fmt.Sprintf("is on a %d game win streak", streak),
+				map[string]interface{}{"streak": streak},
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Personal best kills
+	previousBest := -1
+	previous := len(history)
+	for _, h := range history {
+		if h.Kills > previousBest {
+			previousBest = h.Kills
+		}
+	}
+	if previous >= personalBestMinHistory && p.Kills > previousBest {
+		if err := g.createEvent(ctx, userID, models.FeedEventPersonalBest, match,
+			fmt.Sprintf("set a new personal best of %d kills on %s", p.Kills, p.Character),
+			map[string]interface{}{
+				"stat":      "kills",
+				"value":     p.Kills,
+				"previous":  previousBest,
+				"character": p.Character,
+			},
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createEvent inserts a feed event for a match, skipping duplicates
+func (g *Generator) createEvent(ctx context.Context, userID uint, eventType string, match *models.StoredMatch, summary string, details map[string]interface{}) error {
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to encode event details: %w", err)
+	}
+
+	event := models.FeedEvent{
+		UserID:     userID,
+		Type:       eventType,
+		Game:       match.Game,
+		MatchID:    match.MatchID,
+		Summary:    summary,
+		Details:    string(detailsJSON),
+		OccurredAt: match.PlayedAt,
+		DedupeKey:  fmt.Sprintf("%s:%s:%d:%s", eventType, match.Game, userID, match.MatchID),
+	}
+
+	if err := g.store.Feed.CreateEvent(ctx, &event); err != nil {
+		return fmt.Errorf("failed to create %s event: %w", eventType, err)
+	}
+	return nil
+}
+
+// RecordRankSnapshot stores a rank snapshot if it differs from the previous one
+// and creates a promotion event when the player moved up.
+func (g *Generator) RecordRankSnapshot(ctx context.Context, snapshot *models.RankSnapshot) error {
+	return telemetry.TimeJob(ctx, "feed.record_rank_snapshot", func() error {
+		return g.recordRankSnapshot(ctx, snapshot)
+	})
+}
+
+func (g *Generator) recordRankSnapshot(ctx context.Context, snapshot *models.RankSnapshot) error {
+	ctx, span := telemetry.StartSpan(ctx, "feed.record_rank_snapshot")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int64("user.id", int64(snapshot.UserID)),
+		attribute.String("rank.queue", snapshot.Queue),
+	)
+
+	previous, err := g.store.Matches.LatestRankSnapshot(ctx, snapshot.UserID, snapshot.Game, snapshot.Queue)
+	hasPrevious := err == nil
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("failed to load previous rank snapshot: %w", err)
+	}
+
+	// Nothing changed since the last snapshot
+	if hasPrevious && previous.Tier == snapshot.Tier && previous.Division == snapshot.Division &&
+		previous.LeaguePoints == snapshot.LeaguePoints {
+		return nil
+	}
+
+	if err := g.store.Matches.CreateRankSnapshot(ctx, snapshot); err != nil {
+		return fmt.Errorf("failed to store rank snapshot: %w", err)
+	}
+
+	if !hasPrevious || rankValue(snapshot.Tier, snapshot.Division) <= rankValue(previous.Tier, previous.Division) {
+		return nil
+	}
+
+	rankName := strings.TrimSpace(titleCase(snapshot.Tier) + " " + snapshot.Division)
+	detailsJSON, err := json.Marshal(map[string]interface{}{
+		"queue":         snapshot.Queue,
+		"from_tier":     previous.Tier,
+		"from_division": previous.Division,
+		"to_tier":       snapshot.Tier,
+		"to_division":   snapshot.Division,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode event details: %w", err)
+	}
+
+	event := models.FeedEvent{
+		UserID:     snapshot.UserID,
+		Type:       models.FeedEventRankPromotion,
+		Game:       snapshot.Game,
+		Summary:    fmt.Sprintf("was promoted to %s", rankName),
+		Details:    string(detailsJSON),
+		OccurredAt: snapshot.CreatedAt,
+		DedupeKey: fmt.Sprintf("%s:%s:%d:%s:%d",
+			models.FeedEventRankPromotion, snapshot.Game, snapshot.UserID, snapshot.Queue, snapshot.ID),
+	}
+	if err := g.store.Feed.CreateEvent(ctx, &event); err != nil {
+		return fmt.Errorf("failed to create promotion event: %w", err)
+	}
+	return nil
+}
+
+// rankValue turns a tier and division into a comparable number
+func rankValue(tier, division string) int {
+	tierIndex := -1
+	for i, t := range tierOrder {
+		if strings.EqualFold(t, tier) {
+			tierIndex = i
+			break
+		}
+	}
+	divisionIndex := 0
+	for i, d := range divisionOrder {
+		if strings.EqualFold(d, division) {
+			divisionIndex = i
+			break
+		}
+	}
+	return tierIndex*len(divisionOrder) + divisionIndex
+}
+
+// titleCase turns "GOLD" into "Gold"
+func titleCase(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+}
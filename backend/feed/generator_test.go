@@ -0,0 +1,113 @@
+package feed_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"elo-insight/backend/feed"
+	"elo-insight/backend/models"
+	"elo-insight/backend/pagination"
+	"elo-insight/backend/store"
+	"elo-insight/backend/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	telemetry.Initialize("feed-test")
+	os.Exit(m.Run())
+}
+
+// playerMatches returns seven matches of the player "me", newest first as
+// Riot lists them. In play order they are L W W W L W W, and the last has
+// more kills than any before it.
+func playerMatches() []models.StoredMatch {
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	wins := []bool{false, true, true, true, false, true, true}
+	kills := []int{5, 4, 6, 3, 7, 2, 12}
+
+	var matches []models.StoredMatch
+	for i := range wins {
+		matches = append(matches, models.StoredMatch{
+			Game:     "lol",
+			MatchID:  fmt.Sprintf("EUW1_%d", i+1),
+			PlayedAt: start.Add(time.Duration(i) * time.Hour),
+			Participants: []models.MatchParticipant{
+				{PUUID: "me", TeamID: "100", Character: "Ahri", Win: wins[i], Kills: kills[i]},
+			},
+		})
+	}
+	slices.Reverse(matches)
+	return matches
+}
+
+func TestRecordMatchesInPlayOrder(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		record func(g *feed.Generator, matches []models.StoredMatch) error
+		want   []string // Events as type@match, oldest first
+	}{
+		{
+			"first fetch, newest first",
+			func(g *feed.Generator, matches []models.StoredMatch) error {
+				return g.RecordMatches(ctx, matches)
+			},
+			[]string{"win_streak@EUW1_4", "personal_best@EUW1_7"},
+		},
+		{
+			// The newest match doesn't count against the ones played before it
+			"newest match recorded first",
+			func(g *feed.Generator, matches []models.StoredMatch) error {
+				if err := g.RecordMatch(ctx, &matches[0]); err != nil {
+					return err
+				}
+				return g.RecordMatches(ctx, matches[1:])
+			},
+			[]string{"win_streak@EUW1_4"},
+		},
+		{
+			"one match per fetch",
+			func(g *feed.Generator, matches []models.StoredMatch) error {
+				for i := len(matches) - 1; i >= 0; i-- {
+					if err := g.RecordMatches(ctx, matches[i:i+1]); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			[]string{"win_streak@EUW1_4", "personal_best@EUW1_7"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := store.NewMemoryStore()
+			user := &models.User{Username: "me", Email: "me@example.com", Password: "x"}
+			if err := s.Users.Create(ctx, user); err != nil {
+				t.Fatalf("create user: %v", err)
+			}
+			link := &models.PlatformLink{UserID: user.ID, Platform: models.PlatformRiot, ExternalID: "me"}
+			if err := s.PlatformLinks.Save(ctx, link); err != nil {
+				t.Fatalf("link: %v", err)
+			}
+
+			if err := tt.record(feed.NewGenerator(s), playerMatches()); err != nil {
+				t.Fatalf("record: %v", err)
+			}
+
+			events, err := s.Feed.ListEvents(ctx, []uint{user.ID}, pagination.Keyset{Field: "occurred_at", Limit: 20})
+			if err != nil {
+				t.Fatalf("list events: %v", err)
+			}
+			var got []string
+			for _, event := range events {
+				got = append(got, event.Type+"@"+event.MatchID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// defaultListSize is how many items a list field without a first argument is
+// assumed to return
+const defaultListSize = 10
+
+// costs holds what fields cost on top of the 1 every field costs, by
+// "Type.field". Fields that call a provider are the expensive ones.
+type costs map[string]int
+
+// cost is what a query will cost to run, and how deeply its fields nest. A
+// list field multiplies the cost of its selections by its first argument, or
+// by defaultListSize. Introspection is free.
+func (c costs) cost(doc *ast.Document, operationName string, variables map[string]interface{}, query *graphql.Object) (cost, depth int, err error) {
+	fragments := map[string]*ast.FragmentDefinition{}
+	var operation *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		}
+	}
+	if operation == nil {
+		return 0, 0, fmt.Errorf("unknown operation %q", operationName)
+	}
+
+	m := measurer{costs: c, fragments: fragments, variables: variables}
+	cost, depth = m.selections(operation.SelectionSet, query, 1)
+	return cost, depth, nil
+}
+
+type measurer struct {
+	costs     costs
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// selections measures a selection set on parent, whose fields are at depth
+func (m measurer) selections(set *ast.SelectionSet, parent *graphql.Object, depth int) (cost, maxDepth int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var c, d int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			c, d = m.field(selection, parent, depth)
+		case *ast.InlineFragment:
+			// The schema has no interfaces or unions, so fragments are on parent
+			c, d = m.selections(selection.SelectionSet, parent, depth)
+		case *ast.FragmentSpread:
+			if fragment, ok := m.fragments[selection.Name.Value]; ok {
+				c, d = m.selections(fragment.SelectionSet, parent, depth)
+			}
+		}
+		cost += c
+		maxDepth = max(maxDepth, d)
+	}
+	return cost, maxDepth
+}
+
+func (m measurer) field(field *ast.Field, parent *graphql.Object, depth int) (cost, maxDepth int) {
+	name := field.Name.Value
+	definition, ok := parent.Fields()[name]
+	if strings.HasPrefix(name, "__") || !ok {
+		return 0, 0
+	}
+	cost = 1 + m.costs[parent.Name()+"."+name]
+
+	output, multiplier := definition.Type, 1
+	if nonNull, ok := output.(*graphql.NonNull); ok {
+		output = nonNull.OfType
+	}
+	if list, ok := output.(*graphql.List); ok {
+		multiplier = m.listSize(field, definition)
+		output = list.OfType
+		if nonNull, ok := output.(*graphql.NonNull); ok {
+			output = nonNull.OfType
+		}
+	}
+
+	object, ok := output.(*graphql.Object)
+	if !ok {
+		return cost, depth
+	}
+	childCost, childDepth := m.selections(field.SelectionSet, object, depth+1)
+	return cost + multiplier*childCost, max(depth, childDepth)
+}
+
+// listSize is the first argument of a list field, its default or defaultListSize
+func (m measurer) listSize(field *ast.Field, definition *graphql.FieldDefinition) int {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "first" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil {
+				return max(n, 0)
+			}
+		case *ast.Variable:
+			if n, ok := m.variables[value.Name.Value].(float64); ok {
+				return max(int(n), 0)
+			}
+		}
+	}
+	for _, argument := range definition.Args {
+		if argument.Name() == "first" {
+			if n, ok := argument.DefaultValue.(int); ok {
+				return n
+			}
+		}
+	}
+	return defaultListSize
+}
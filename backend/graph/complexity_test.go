@@ -0,0 +1,142 @@
+package graph
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"elo-insight/backend/config"
+
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// nestedFriends is nested six levels deep and costs far more than a dashboard needs
+const nestedFriends = `{ me { friends { user { friends { user { id } } } } } }`
+
+func TestCost(t *testing.T) {
+	s := &Server{}
+	schema, err := s.buildSchema()
+	if err != nil {
+		t.Fatalf("build schema: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		operation string
+		variables map[string]interface{}
+		wantCost  int
+		wantDepth int
+	}{
+		{"one field", `{ me { id } }`, "", nil, 2, 2},
+		{"introspection is free", `{ __typename me { id __typename } }`, "", nil, 2, 2},
+		{"provider field", `{ me { leagueStats { message } } }`, "", nil, 1 + 1 + statsCost + 1, 3},
+
+		// Lists multiply what each item selects
+		{"list with its default first", `{ me { friends { id } } }`, "", nil, 2 + defaultFriends, 3},
+		{"list without a first argument", `{ me { linkedAccounts { platform } } }`, "", nil, 2 + defaultListSize, 3},
+		{"list with first", `{ me { friends(first: 3) { comparison(game: LOL) { winsTogether } } } }`, "", nil, 2 + 3*(1+statsCost+1), 4},
+		{"empty list", `{ me { friends(first: 0) { comparison(game: LOL) { winsTogether } } } }`, "", nil, 2, 4},
+		{"negative first", `{ me { friends(first: -5) { id } } }`, "", nil, 2, 3},
+		{"lists within lists", nestedFriends, "", nil, 1 + 1 + defaultFriends*(1+1+defaultFriends*(1+1)), 6},
+
+		// first passed as a variable
+		{"first from a variable", `query($n: Int) { me { friends(first: $n) { id } } }`, "", map[string]interface{}{"n": float64(5)}, 2 + 5, 3},
+		{"first from a missing variable", `query($n: Int) { me { friends(first: $n) { id } } }`, "", nil, 2 + defaultFriends, 3},
+
+		// Fragments cost what they select where they're spread
+		{"fragment", `{ me { ...profile } } fragment profile on User { id username leagueStats { message } }`, "", nil, 1 + 1 + 1 + statsCost + 2, 3},
+		{"fragment in a list", `{ me { friends(first: 4) { ...friend } } } fragment friend on Friend { id since }`, "", nil, 2 + 4*2, 3},
+		{"inline fragment", `{ me { ... on User { id friends(first: 2) { user { id } } } } }`, "", nil, 1 + 1 + 1 + 2*2, 4},
+
+		{"named operation", `query A { me { id } } query B { me { id username } }`, "B", nil, 3, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			cost, depth, err := s.costs.cost(doc, tt.operation, tt.variables, schema.QueryType())
+			if err != nil {
+				t.Fatalf("cost: %v", err)
+			}
+			if cost != tt.wantCost || depth != tt.wantDepth {
+				t.Errorf("cost, depth = %d, %d; want %d, %d", cost, depth, tt.wantCost, tt.wantDepth)
+			}
+		})
+	}
+
+	t.Run("unknown operation", func(t *testing.T) {
+		doc, err := parser.Parse(parser.ParseParams{Source: `query A { me { id } }`})
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		if _, _, err := s.costs.cost(doc, "B", nil, schema.QueryType()); err == nil {
+			t.Error("cost of an unknown operation: err = nil")
+		}
+	})
+}
+
+func TestExecuteRejectsQueriesOverLimits(t *testing.T) {
+	s, viewer := newTestServer(t, config.GraphQL{MaxDepth: 5, MaxComplexity: 100, StatsConcurrency: 1})
+
+	// Each friend's comparison costs 12, plus 2 for me and friends
+	const comparisons = `query($n: Int) { me { friends(first: $n) { comparison(game: LOL) { winsTogether } } } }`
+	const games = `fragment games on User { leagueStats { message } cs2Stats dota2Stats apexStats }`
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      string // The error message, or empty if the query runs
+	}{
+		{"within limits", `{ me { id friends(first: 3) { user { username } } } }`, nil, ""},
+		{"too deep", nestedFriends, nil, "Query is nested 6 levels deep; the limit is 5"},
+		{"too costly", `{ me { friends(first: 10) { comparison(game: LOL) { winsTogether } } } }`, nil, "Query costs 122; the limit is 100"},
+		{"variable within budget", comparisons, map[string]interface{}{"n": float64(8)}, ""},
+		{"variable over budget", comparisons, map[string]interface{}{"n": float64(9)}, "Query costs 110; the limit is 100"},
+		{"fragment over budget", `{ me { friends(first: 3) { user { ...games } } } } ` + games, nil, "Query costs 140; the limit is 100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, ok := s.Execute(context.Background(), viewer, QueryRequest{Query: tt.query, Variables: tt.variables})
+			if tt.want == "" {
+				if !ok || len(response.Errors) != 0 {
+					t.Fatalf("Execute = %v, %+v; want it to run", ok, response.Errors)
+				}
+				return
+			}
+
+			if ok || response.Data != nil {
+				t.Errorf("Execute ran the query: %v, %+v", ok, response.Data)
+			}
+			if len(response.Errors) != 1 {
+				t.Fatalf("errors = %+v, want one", response.Errors)
+			}
+			err := response.Errors[0]
+			if err.Message != tt.want || err.Extensions["code"] != "query_too_complex" {
+				t.Errorf("error = %q (%v), want %q (query_too_complex)", err.Message, err.Extensions["code"], tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteRejectsInvalidQueries(t *testing.T) {
+	s, viewer := newTestServer(t, config.GraphQL{MaxDepth: 10, MaxComplexity: 1000, StatsConcurrency: 1})
+
+	tests := []struct {
+		name, query, operation, want string
+	}{
+		{"syntax error", `{ me { id }`, "", "Syntax Error"},
+		{"unknown field", `{ me { password } }`, "", `Cannot query field "password"`},
+		{"unknown operation", `query A { me { id } }`, "B", `unknown operation "B"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, ok := s.Execute(context.Background(), viewer, QueryRequest{Query: tt.query, OperationName: tt.operation})
+			if ok || len(response.Errors) == 0 || !strings.Contains(response.Errors[0].Message, tt.want) {
+				t.Errorf("Execute = %v, %+v; want rejected with %q", ok, response.Errors, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package graph
+
+import (
+	"context"
+	"sync"
+)
+
+// loader batches the keys that sibling fields ask for into one fetch, like a
+// dataloader. A resolver calls thunk and returns the result; the executor
+// resolves thunks breadth first, so by the time the first one runs every
+// sibling has queued its key and they are all fetched together. Results are
+// kept for the rest of the request.
+type loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	queued  []K
+	results map[K]V
+	errs    map[K]error
+}
+
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, results: map[K]V{}, errs: map[K]error{}}
+}
+
+// thunk queues key and returns a resolver result that loads it
+func (l *loader[K, V]) thunk(ctx context.Context, key K) func() (interface{}, error) {
+	l.mu.Lock()
+	l.queued = append(l.queued, key)
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		return l.load(ctx, key)
+	}
+}
+
+// load returns the value for key, fetching it along with everything queued
+func (l *loader[K, V]) load(ctx context.Context, key K) (V, error) {
+	values, err := l.loadAll(ctx, []K{key})
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return values[key], nil
+}
+
+// loadAll returns the values for keys, fetching the ones not loaded yet
+// along with everything queued. A key missing from the fetch has the zero value.
+func (l *loader[K, V]) loadAll(ctx context.Context, keys []K) (map[K]V, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	seen := make(map[K]bool)
+	var missing []K
+	for _, key := range append(l.queued, keys...) {
+		_, loaded := l.results[key]
+		if _, failed := l.errs[key]; !loaded && !failed && !seen[key] {
+			seen[key] = true
+			missing = append(missing, key)
+		}
+	}
+	l.queued = nil
+
+	if len(missing) > 0 {
+		fetched, err := l.fetch(ctx, missing)
+		for _, key := range missing {
+			if err != nil {
+				l.errs[key] = err
+			} else {
+				l.results[key] = fetched[key]
+			}
+		}
+	}
+
+	values := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if err := l.errs[key]; err != nil {
+			return nil, err
+		}
+		values[key] = l.results[key]
+	}
+	return values, nil
+}
+
+// then returns a thunk that passes what thunk loads through f
+func then[V any](thunk func() (interface{}, error), f func(V) (interface{}, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		value, err := thunk()
+		if err != nil {
+			return nil, err
+		}
+		return f(value.(V))
+	}
+}
@@ -0,0 +1,227 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"elo-insight/backend/config"
+	"elo-insight/backend/handlers"
+	"elo-insight/backend/models"
+	"elo-insight/backend/store"
+)
+
+// fetches records the keys each fetch of a loader asked for
+type fetches[K comparable] struct {
+	mu    sync.Mutex
+	calls [][]K
+}
+
+func (f *fetches[K]) record(keys []K) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, slices.Clone(keys))
+}
+
+func (f *fetches[K]) get() [][]K {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// countingUsers and countingLinks record the reads the loaders make
+type countingUsers struct {
+	store.UserStore
+	fetches[uint]
+}
+
+func (u *countingUsers) ListByIDs(ctx context.Context, ids []uint) ([]models.User, error) {
+	u.record(ids)
+	return u.UserStore.ListByIDs(ctx, ids)
+}
+
+type countingLinks struct {
+	store.PlatformLinkStore
+	fetches[uint]
+}
+
+func (l *countingLinks) ListByUsers(ctx context.Context, platform string, userIDs []uint) ([]models.PlatformLink, error) {
+	l.record(userIDs)
+	return l.PlatformLinkStore.ListByUsers(ctx, platform, userIDs)
+}
+
+// newTestServer returns a server backed by the in-memory store, and the
+// signed-in user
+func newTestServer(t *testing.T, cfg config.GraphQL) (*Server, uint) {
+	t.Helper()
+	s := store.NewMemoryStore()
+	viewer := models.User{Username: "me", Email: "me@example.com", Password: "x"}
+	if err := s.Users.Create(context.Background(), &viewer); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	server, err := New(handlers.New(s, &config.Config{}, nil), s, cfg)
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	return server, viewer.ID
+}
+
+// length is what the test loaders fetch for a key, 0 for one they don't find
+func length(key string) int {
+	if key == "missing" {
+		return 0
+	}
+	return len(key)
+}
+
+func TestLoader(t *testing.T) {
+	ctx := context.Background()
+	errFetch := errors.New("fetch failed")
+	tests := []struct {
+		name        string
+		fail        bool
+		queue       []string // Keys queued with thunk before any resolves
+		load        []string // Keys then loaded with loadAll
+		want        map[string]int
+		wantErr     bool
+		wantFetches [][]string
+	}{
+		{
+			name:        "siblings fetched together",
+			queue:       []string{"a", "bb", "a", "ccc"},
+			load:        []string{"a"},
+			want:        map[string]int{"a": 1},
+			wantFetches: [][]string{{"a", "bb", "ccc"}},
+		},
+		{
+			name:        "loaded keys are kept",
+			queue:       []string{"a"},
+			load:        []string{"a", "bb"},
+			want:        map[string]int{"a": 1, "bb": 2},
+			wantFetches: [][]string{{"a", "bb"}},
+		},
+		{
+			name:        "missing keys have the zero value",
+			queue:       []string{"missing"},
+			load:        []string{"missing", "a"},
+			want:        map[string]int{"missing": 0, "a": 1},
+			wantFetches: [][]string{{"missing", "a"}},
+		},
+		{
+			name:        "failed fetch",
+			fail:        true,
+			queue:       []string{"a", "bb"},
+			load:        []string{"bb"},
+			wantErr:     true,
+			wantFetches: [][]string{{"a", "bb"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetched fetches[string]
+			l := newLoader(func(ctx context.Context, keys []string) (map[string]int, error) {
+				fetched.record(keys)
+				if tt.fail {
+					return nil, errFetch
+				}
+				lengths := map[string]int{}
+				for _, key := range keys {
+					if key != "missing" {
+						lengths[key] = len(key)
+					}
+				}
+				return lengths, nil
+			})
+
+			var thunks []func() (interface{}, error)
+			for _, key := range tt.queue {
+				thunks = append(thunks, l.thunk(ctx, key))
+			}
+			got, err := l.loadAll(ctx, tt.load)
+			if tt.wantErr {
+				if !errors.Is(err, errFetch) {
+					t.Errorf("loadAll error = %v, want %v", err, errFetch)
+				}
+			} else if err != nil || fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("loadAll = %v, %v; want %v", got, err, tt.want)
+			}
+
+			// Resolving the queued thunks, and loading the keys again, is
+			// answered from what was fetched, errors included
+			for i, thunk := range thunks {
+				value, err := thunk()
+				if tt.wantErr != (err != nil) || !tt.wantErr && value != length(tt.queue[i]) {
+					t.Errorf("thunk %q = %v, %v", tt.queue[i], value, err)
+				}
+			}
+			if _, err := l.loadAll(ctx, tt.load); tt.wantErr != (err != nil) {
+				t.Errorf("loading again: err = %v", err)
+			}
+			if got := fetched.get(); fmt.Sprint(got) != fmt.Sprint(tt.wantFetches) {
+				t.Errorf("fetches = %v, want %v", got, tt.wantFetches)
+			}
+		})
+	}
+}
+
+func TestThen(t *testing.T) {
+	double := func(n int) (interface{}, error) { return 2 * n, nil }
+	errFailed := errors.New("failed")
+
+	value, err := then(func() (interface{}, error) { return 21, nil }, double)()
+	if value != 42 || err != nil {
+		t.Errorf("then = %v, %v; want 42", value, err)
+	}
+	if _, err := then(func() (interface{}, error) { return nil, errFailed }, double)(); !errors.Is(err, errFailed) {
+		t.Errorf("then error = %v, want %v", err, errFailed)
+	}
+}
+
+func TestExecuteBatchesSiblingFields(t *testing.T) {
+	ctx := context.Background()
+	s, viewer := newTestServer(t, config.GraphQL{MaxDepth: 10, MaxComplexity: 1000, StatsConcurrency: 2})
+	users := &countingUsers{UserStore: s.store.Users}
+	links := &countingLinks{PlatformLinkStore: s.store.PlatformLinks}
+
+	var friendIDs []uint
+	for _, name := range []string{"ana", "ben", "cat"} {
+		friend := models.User{Username: name, Email: name + "@example.com", Password: "x"}
+		if err := users.Create(ctx, &friend); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		f, err := s.store.Friendships.Request(ctx, viewer, friend.ID, time.Now())
+		if err == nil {
+			_, err = s.store.Friendships.Transition(ctx, f.ID, friend.ID, models.FriendshipActionAccept, time.Now())
+		}
+		if err != nil {
+			t.Fatalf("befriend %s: %v", name, err)
+		}
+		friendIDs = append(friendIDs, friend.ID)
+	}
+	s.store.Users, s.store.PlatformLinks = users, links
+
+	// Every friend's user, accounts and stats are read once for all of them.
+	// Nobody linked a Steam account, so cs2Stats is null without a provider call.
+	response, ok := s.Execute(ctx, viewer, QueryRequest{Query: `{
+		me { username friends { user { username linkedAccounts { platform } cs2Stats } } }
+	}`})
+	if !ok || len(response.Errors) != 0 {
+		t.Fatalf("Execute = %v, %+v", ok, response.Errors)
+	}
+	if got, want := fmt.Sprint(response.Data), "map[me:map[friends:[map[user:map[cs2Stats:<nil> linkedAccounts:[] username:ana]] "+
+		"map[user:map[cs2Stats:<nil> linkedAccounts:[] username:ben]] map[user:map[cs2Stats:<nil> linkedAccounts:[] username:cat]]] username:me]]"; got != want {
+		t.Errorf("data = %s\nwant %s", got, want)
+	}
+
+	if got, want := users.get(), [][]uint{{viewer}, friendIDs}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("users read = %v, want %v", got, want)
+	}
+	if got, want := links.get(), [][]uint{friendIDs}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("links read = %v, want %v", got, want)
+	}
+}
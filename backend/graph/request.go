@@ -0,0 +1,228 @@
+package graph
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/handlers"
+	"elo-insight/backend/models"
+)
+
+// Games whose stats a user has
+const (
+	gameLeague   = "lol"
+	gameValorant = "valorant"
+	gameCS2      = "cs2"
+	gameDota2    = "dota2"
+	gameApex     = "apex"
+)
+
+// statsKey is one player's stats in one game
+type statsKey struct {
+	game   string
+	userID uint
+}
+
+// comparisonKey is the viewer's synergy with a friend in a game
+type comparisonKey struct {
+	game     string
+	friendID uint
+}
+
+// outcome is a value that loaded, or the error that stopped it, for loaders
+// whose keys fail independently
+type outcome struct {
+	value interface{}
+	err   error
+}
+
+// request is what one query has loaded so far
+type request struct {
+	viewer      uint
+	users       *loader[uint, *models.User]
+	links       *loader[uint, []models.PlatformLink]
+	stats       *loader[statsKey, outcome]
+	comparisons *loader[comparisonKey, handlers.SynergyStats]
+
+	friendsOnce sync.Once
+	friends     []models.Friendship
+	friendsErr  error
+}
+
+type requestKey struct{}
+
+func withRequest(ctx context.Context, r *request) context.Context {
+	return context.WithValue(ctx, requestKey{}, r)
+}
+
+func requestFrom(ctx context.Context) *request {
+	return ctx.Value(requestKey{}).(*request)
+}
+
+func (s *Server) newRequest(viewer uint) *request {
+	r := &request{viewer: viewer}
+	r.users = newLoader(s.loadUsers)
+	r.links = newLoader(s.loadLinks)
+	r.stats = newLoader(func(ctx context.Context, keys []statsKey) (map[statsKey]outcome, error) {
+		return s.loadStats(ctx, r, keys)
+	})
+	r.comparisons = newLoader(func(ctx context.Context, keys []comparisonKey) (map[comparisonKey]handlers.SynergyStats, error) {
+		return s.loadComparisons(ctx, r, keys)
+	})
+	return r
+}
+
+// acceptedFriendships returns the viewer's friendships, oldest first, read once per query
+func (r *request) acceptedFriendships(ctx context.Context, s *Server) ([]models.Friendship, error) {
+	r.friendsOnce.Do(func() {
+		r.friends, r.friendsErr = s.store.Friendships.ListAccepted(ctx, r.viewer)
+		slices.SortFunc(r.friends, func(a, b models.Friendship) int { return cmp.Compare(a.ID, b.ID) })
+	})
+	return r.friends, r.friendsErr
+}
+
+func (s *Server) loadUsers(ctx context.Context, ids []uint) (map[uint]*models.User, error) {
+	users, err := s.store.Users.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, apperrors.Internal(err)
+	}
+	byID := make(map[uint]*models.User, len(users))
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+	return byID, nil
+}
+
+func (s *Server) loadLinks(ctx context.Context, userIDs []uint) (map[uint][]models.PlatformLink, error) {
+	links, err := s.store.PlatformLinks.ListByUsers(ctx, "", userIDs)
+	if err != nil {
+		return nil, apperrors.Internal(err)
+	}
+	byUser := make(map[uint][]models.PlatformLink, len(userIDs))
+	for _, link := range links {
+		byUser[link.UserID] = append(byUser[link.UserID], link)
+	}
+	return byUser, nil
+}
+
+// loadStats fetches game stats through the REST handlers' methods, for the
+// accounts the players have linked, with at most StatsConcurrency in flight.
+// A player without the account has null stats.
+func (s *Server) loadStats(ctx context.Context, r *request, keys []statsKey) (map[statsKey]outcome, error) {
+	userIDs := make([]uint, 0, len(keys))
+	for _, key := range keys {
+		userIDs = append(userIDs, key.userID)
+	}
+	links, err := r.links.loadAll(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[statsKey]outcome, len(keys))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, s.cfg.StatsConcurrency)
+	for _, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			value, err := s.gameStats(ctx, key, links[key.userID])
+			mu.Lock()
+			results[key] = outcome{value: value, err: err}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// gameStats returns one player's stats in a game, or nil if they haven't
+// linked the account it needs
+func (s *Server) gameStats(ctx context.Context, key statsKey, links []models.PlatformLink) (interface{}, error) {
+	platform := models.PlatformSteam
+	switch key.game {
+	case gameLeague, gameValorant:
+		platform = models.PlatformRiot
+	case gameApex:
+		platform = models.PlatformEA
+	}
+	var link *models.PlatformLink
+	for i := range links {
+		if links[i].Platform == platform {
+			link = &links[i]
+		}
+	}
+	if link == nil {
+		return nil, nil
+	}
+
+	switch key.game {
+	case gameLeague:
+		gameName, tagline := link.RiotNameAndTag()
+		return s.h.LeagueStats(ctx, handlers.LeagueStatsQuery{RiotGameName: gameName, RiotTagline: tagline, RiotPUUID: link.ExternalID}, key.userID)
+	case gameValorant:
+		riotID := ""
+		if gameName, tagline := link.RiotNameAndTag(); gameName != "" && tagline != "" {
+			riotID = gameName + "#" + tagline
+		}
+		return s.h.ValorantStats(ctx, riotID, key.userID)
+	case gameCS2:
+		return s.h.CS2Stats(ctx, link.ExternalID)
+	case gameDota2:
+		return s.h.Dota2Stats(ctx, link.ExternalID)
+	default:
+		return s.h.ApexStats(ctx, link.ExternalID, key.userID)
+	}
+}
+
+// loadComparisons works out the viewer's synergy with each friend, reading
+// each game's stored matches once for all of them
+func (s *Server) loadComparisons(ctx context.Context, r *request, keys []comparisonKey) (map[comparisonKey]handlers.SynergyStats, error) {
+	byGame := map[string][]uint{}
+	userIDs := []uint{r.viewer}
+	for _, key := range keys {
+		byGame[key.game] = append(byGame[key.game], key.friendID)
+		userIDs = append(userIDs, key.friendID)
+	}
+
+	users, err := r.users.loadAll(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	links, err := r.links.loadAll(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	puuids := make(map[uint]string, len(links))
+	for userID, userLinks := range links {
+		for _, link := range userLinks {
+			if link.Platform == models.PlatformRiot {
+				puuids[userID] = link.ExternalID
+			}
+		}
+	}
+
+	results := make(map[comparisonKey]handlers.SynergyStats, len(keys))
+	for game, friendIDs := range byGame {
+		partners := make([]models.User, 0, len(friendIDs))
+		for _, id := range friendIDs {
+			if user := users[id]; user != nil {
+				partners = append(partners, *user)
+			}
+		}
+		synergies, err := s.h.Synergy(ctx, game, r.viewer, partners, puuids)
+		if err != nil {
+			return nil, apperrors.Internal(err)
+		}
+		for _, synergy := range synergies {
+			results[comparisonKey{game: game, friendID: synergy.PartnerID}] = synergy
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,212 @@
+package graph
+
+import (
+	"elo-insight/backend/handlers"
+	"elo-insight/backend/models"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Friends returned by User.friends without a first argument, and at most
+const (
+	defaultFriends = 20
+	maxFriends     = 50
+)
+
+// statsCost is what each game stats field adds to a query's cost, since it
+// calls a provider
+const statsCost = 10
+
+// friend is an accepted friendship from the viewer's side
+type friend struct {
+	friendship models.Friendship
+	userID     uint
+}
+
+// buildSchema defines the schema and what each field costs
+func (s *Server) buildSchema() (graphql.Schema, error) {
+	structs := newStructTypes()
+
+	linkedAccount := structs.object(models.PlatformLinkResponse{}, "LinkedAccount")
+	leagueStats := structs.object(handlers.LeagueStatsResponse{}, "LeagueStats")
+	valorantStats := structs.object(handlers.ValorantStatsResponse{}, "ValorantStats")
+	synergy := structs.object(handlers.SynergyStats{}, "Synergy")
+
+	game := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "Game",
+		Description: "A game with stored matches to compare players in",
+		Values: graphql.EnumValueConfigMap{
+			"LOL":      {Value: gameLeague, Description: "League of Legends"},
+			"VALORANT": {Value: gameValorant},
+		},
+	})
+
+	statCard := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "StatCard",
+		Description: "A game the user pinned to their dashboard",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: statCardField(func(card models.UserStat) interface{} { return card.ID })},
+			"game":      &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: statCardField(func(card models.UserStat) interface{} { return card.Game })},
+			"platform":  &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: statCardField(func(card models.UserStat) interface{} { return card.Platform })},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: statCardField(func(card models.UserStat) interface{} { return card.CreatedAt })},
+		},
+	})
+
+	user := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "User",
+		Description: "A user; statCards and friends are only shown for the signed-in user",
+		Fields:      graphql.Fields{},
+	})
+	friendType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Friend",
+		Description: "An accepted friendship",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "The friendship",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(friend).friendship.ID, nil
+				},
+			},
+			"since": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.DateTime),
+				Description: "When the friendship was accepted",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					friendship := p.Source.(friend).friendship
+					if friendship.RespondedAt != nil {
+						return *friendship.RespondedAt, nil
+					}
+					return friendship.CreatedAt, nil
+				},
+			},
+			"user": &graphql.Field{
+				Type: graphql.NewNonNull(user),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return requestFrom(p.Context).users.thunk(p.Context, p.Source.(friend).userID), nil
+				},
+			},
+			"comparison": &graphql.Field{
+				Type:        graphql.NewNonNull(synergy),
+				Description: "How the signed-in user does with and without this friend, from stored matches",
+				Args: graphql.FieldConfigArgument{
+					"game": &graphql.ArgumentConfig{Type: graphql.NewNonNull(game), DefaultValue: gameLeague},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					key := comparisonKey{game: p.Args["game"].(string), friendID: p.Source.(friend).userID}
+					return requestFrom(p.Context).comparisons.thunk(p.Context, key), nil
+				},
+			},
+		},
+	})
+
+	user.AddFieldConfig("id", &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: userField(func(u *models.User) interface{} { return u.ID })})
+	user.AddFieldConfig("username", &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: userField(func(u *models.User) interface{} { return u.Username })})
+	user.AddFieldConfig("email", &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: userField(func(u *models.User) interface{} { return u.Email })})
+	user.AddFieldConfig("linkedAccounts", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(linkedAccount))),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			thunk := requestFrom(p.Context).links.thunk(p.Context, p.Source.(*models.User).ID)
+			return then(thunk, func(links []models.PlatformLink) (interface{}, error) {
+				response := make([]models.PlatformLinkResponse, 0, len(links))
+				for _, link := range links {
+					response = append(response, link.Response())
+				}
+				return response, nil
+			}), nil
+		},
+	})
+	user.AddFieldConfig("statCards", &graphql.Field{
+		Type: graphql.NewList(graphql.NewNonNull(statCard)),
+		Resolve: viewerOnly(func(p graphql.ResolveParams, viewer uint) (interface{}, error) {
+			return s.store.StatCards.ListByUser(p.Context, viewer)
+		}),
+	})
+	user.AddFieldConfig("friends", &graphql.Field{
+		Type:        graphql.NewList(graphql.NewNonNull(friendType)),
+		Description: "Accepted friends, oldest friendship first",
+		Args: graphql.FieldConfigArgument{
+			"first": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultFriends, Description: "How many to return, at most 50"},
+		},
+		Resolve: viewerOnly(func(p graphql.ResolveParams, viewer uint) (interface{}, error) {
+			friendships, err := requestFrom(p.Context).acceptedFriendships(p.Context, s)
+			if err != nil {
+				return nil, err
+			}
+			first := min(max(p.Args["first"].(int), 0), maxFriends)
+
+			friends := make([]friend, 0, min(first, len(friendships)))
+			for _, friendship := range friendships[:min(first, len(friendships))] {
+				friendID := friendship.FriendID
+				if friendID == viewer {
+					friendID = friendship.UserID
+				}
+				friends = append(friends, friend{friendship: friendship, userID: friendID})
+			}
+			return friends, nil
+		}),
+	})
+	gameStats := []struct {
+		field, game, description string
+		output                   graphql.Output
+	}{
+		{"leagueStats", gameLeague, "League of Legends stats for the linked Riot account", leagueStats},
+		{"valorantStats", gameValorant, "Valorant stats for the linked Riot account", valorantStats},
+		{"cs2Stats", gameCS2, "CS2 stats by name for the linked Steam account", JSON},
+		{"dota2Stats", gameDota2, "Dota 2 stats for the linked Steam account", JSON},
+		{"apexStats", gameApex, "tracker.gg's Apex Legends results for the linked EA account", JSON},
+	}
+	s.costs = costs{"Friend.comparison": statsCost}
+	for _, stats := range gameStats {
+		s.costs["User."+stats.field] = statsCost
+		user.AddFieldConfig(stats.field, &graphql.Field{
+			Type:        stats.output,
+			Description: stats.description + "; null if it isn't linked",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				thunk := requestFrom(p.Context).stats.thunk(p.Context, statsKey{game: stats.game, userID: p.Source.(*models.User).ID})
+				return then(thunk, func(result outcome) (interface{}, error) {
+					return result.value, result.err
+				}), nil
+			},
+		})
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"me": &graphql.Field{
+				Type:        graphql.NewNonNull(user),
+				Description: "The signed-in user",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					r := requestFrom(p.Context)
+					return r.users.thunk(p.Context, r.viewer), nil
+				},
+			},
+		},
+	})
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// userField resolves a field of a User from the model
+func userField(get func(*models.User) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(*models.User)), nil
+	}
+}
+
+// statCardField resolves a field of a StatCard from the model
+func statCardField(get func(models.UserStat) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(models.UserStat)), nil
+	}
+}
+
+// viewerOnly resolves a User field that is null for anyone but the signed-in user
+func viewerOnly(resolve func(p graphql.ResolveParams, viewer uint) (interface{}, error)) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		viewer := requestFrom(p.Context).viewer
+		if p.Source.(*models.User).ID != viewer {
+			return nil, nil
+		}
+		return resolve(p, viewer)
+	}
+}
@@ -0,0 +1,165 @@
+// Package graph serves the dashboard's data over GraphQL, so a page can load
+// the signed-in user, their linked accounts, stat cards, game stats and
+// friends in one request. Fields resolve through the same handler methods as
+// the REST routes, with per-request loaders batching the database reads and
+// provider calls that sibling fields need.
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/config"
+	"elo-insight/backend/handlers"
+	"elo-insight/backend/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// QueryRequest is the body of a GraphQL request
+type QueryRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// QueryResponse is the body of a GraphQL response. Data is left out if the query
+// was rejected; otherwise errors lists the fields that failed, which are null.
+type QueryResponse struct {
+	Data   interface{}                `json:"data,omitempty"`
+	Errors []gqlerrors.FormattedError `json:"errors,omitempty"`
+}
+
+// Server runs GraphQL queries
+type Server struct {
+	h      *handlers.Handler
+	store  *store.Store
+	cfg    config.GraphQL
+	schema graphql.Schema
+	costs  costs
+}
+
+// New returns a Server resolving fields with h and the stores it was built on
+func New(h *handlers.Handler, s *store.Store, cfg config.GraphQL) (*Server, error) {
+	server := &Server{h: h, store: s, cfg: cfg}
+	schema, err := server.buildSchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build GraphQL schema: %w", err)
+	}
+	server.schema = schema
+	return server, nil
+}
+
+// Handler serves queries posted as JSON for the signed-in user, so it must
+// run after middleware.RequireAuth. Queries that don't parse, fail validation
+// or exceed the depth or complexity limit are rejected with 400 before any
+// field runs.
+func (s *Server) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req QueryRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(apperrors.Validation("Request body must be a GraphQL request with a query", map[string]string{"query": "required"}))
+			return
+		}
+
+		userID, _ := c.Get("userID")
+		viewer, _ := userID.(uint)
+
+		response, ok := s.Execute(c.Request.Context(), viewer, req)
+		if !ok {
+			c.JSON(http.StatusBadRequest, response)
+			return
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// Execute runs a query as viewer. It reports false if the query was
+// rejected without running.
+func (s *Server) Execute(ctx context.Context, viewer uint, req QueryRequest) (QueryResponse, bool) {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		return QueryResponse{Errors: gqlerrors.FormatErrors(err)}, false
+	}
+	if validation := graphql.ValidateDocument(&s.schema, doc, nil); !validation.IsValid {
+		return QueryResponse{Errors: validation.Errors}, false
+	}
+
+	cost, depth, err := s.costs.cost(doc, req.OperationName, req.Variables, s.schema.QueryType())
+	if err != nil {
+		return QueryResponse{Errors: gqlerrors.FormatErrors(err)}, false
+	}
+	if depth > s.cfg.MaxDepth {
+		return limitExceeded(fmt.Sprintf("Query is nested %d levels deep; the limit is %d", depth, s.cfg.MaxDepth)), false
+	}
+	if cost > s.cfg.MaxComplexity {
+		return limitExceeded(fmt.Sprintf("Query costs %d; the limit is %d", cost, s.cfg.MaxComplexity)), false
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withRequest(ctx, s.newRequest(viewer)),
+	})
+	return QueryResponse{Data: result.Data, Errors: s.fieldErrors(ctx, result.Errors)}, true
+}
+
+// limitExceeded reports a query over a limit
+func limitExceeded(message string) QueryResponse {
+	err := gqlerrors.NewFormattedError(message)
+	err.Extensions = map[string]interface{}{"code": "query_too_complex"}
+	return QueryResponse{Errors: []gqlerrors.FormattedError{err}}
+}
+
+// fieldErrors gives each failed field the message and code of the
+// apperrors.Error behind it, like the REST error envelope. Anything else a
+// field returned is reported as internal.
+func (s *Server) fieldErrors(ctx context.Context, errs []gqlerrors.FormattedError) []gqlerrors.FormattedError {
+	for i, err := range errs {
+		if len(err.Path) == 0 {
+			continue // Not from a field, e.g. a bad variable
+		}
+		appErr := apperrors.From(cause(err))
+		if appErr.Status >= http.StatusInternalServerError {
+			slog.ErrorContext(ctx, "GraphQL field failed", "path", err.Path, "code", appErr.Code, "error", appErr)
+		} else {
+			slog.DebugContext(ctx, "GraphQL field rejected", "path", err.Path, "code", appErr.Code, "error", appErr)
+		}
+
+		errs[i].Message = appErr.Message
+		errs[i].Extensions = map[string]interface{}{"code": appErr.Code}
+		if len(appErr.Details) > 0 {
+			errs[i].Extensions["details"] = appErr.Details
+		}
+	}
+	return errs
+}
+
+// cause is the error a resolver returned, from under graphql-go's wrappers
+func cause(err error) error {
+	for {
+		var next error
+		switch wrapper := err.(type) {
+		case gqlerrors.FormattedError:
+			next = wrapper.OriginalError()
+		case *gqlerrors.Error:
+			next = wrapper.OriginalError
+		}
+		if next == nil {
+			return err
+		}
+		err = next
+	}
+}
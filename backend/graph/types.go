@@ -0,0 +1,143 @@
+package graph
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// JSON is any JSON value. Fields whose REST body has no fixed shape, like
+// provider responses passed through, use it.
+var JSON = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "Any JSON value",
+	Serialize: func(value interface{}) interface{} {
+		if raw, ok := value.(json.RawMessage); ok {
+			var decoded interface{}
+			if json.Unmarshal(raw, &decoded) != nil {
+				return nil
+			}
+			return decoded
+		}
+		return value
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return value
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		return nil
+	},
+})
+
+// structTypes builds output types from the REST response structs, so a
+// GraphQL field has the same name and shape as the JSON key it mirrors
+type structTypes struct {
+	objects map[reflect.Type]*graphql.Object
+}
+
+func newStructTypes() *structTypes {
+	return &structTypes{objects: map[reflect.Type]*graphql.Object{}}
+}
+
+// object returns the object type for the struct of v, named name
+func (t *structTypes) object(v any, name string) *graphql.Object {
+	typ := reflect.TypeOf(v)
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return t.named(typ, name)
+}
+
+func (t *structTypes) named(typ reflect.Type, name string) *graphql.Object {
+	if object, ok := t.objects[typ]; ok {
+		return object
+	}
+
+	fields := graphql.Fields{}
+	object := graphql.NewObject(graphql.ObjectConfig{
+		Name:   name,
+		Fields: (graphql.FieldsThunk)(func() graphql.Fields { return fields }),
+	})
+	t.objects[typ] = object
+
+	for _, field := range reflect.VisibleFields(typ) {
+		if !field.IsExported() {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonName == "-" || (field.Anonymous && jsonName == "" && field.Type.Kind() == reflect.Struct) {
+			continue // Embedded structs are flattened by VisibleFields
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		fields[jsonName] = &graphql.Field{
+			Type:    t.output(field.Type, name+field.Name),
+			Resolve: fieldResolver(field.Index),
+		}
+	}
+	return object
+}
+
+// output maps a Go type to a GraphQL one; name is used for anonymous structs
+func (t *structTypes) output(typ reflect.Type, name string) graphql.Output {
+	switch typ.Kind() {
+	case reflect.Pointer:
+		return nullable(t.output(typ.Elem(), name))
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return JSON // json.RawMessage
+		}
+		return graphql.NewList(t.output(typ.Elem(), name))
+	case reflect.Map, reflect.Interface:
+		return JSON
+	case reflect.Struct:
+		if typ == reflect.TypeOf(time.Time{}) {
+			return graphql.NewNonNull(graphql.DateTime)
+		}
+		if typ.Name() != "" {
+			name = typ.Name()
+		}
+		return graphql.NewNonNull(t.named(typ, name))
+	case reflect.String:
+		return graphql.NewNonNull(graphql.String)
+	case reflect.Bool:
+		return graphql.NewNonNull(graphql.Boolean)
+	case reflect.Int64, reflect.Uint64, reflect.Float32, reflect.Float64:
+		// GraphQL's Int is 32 bits; timestamps in milliseconds don't fit
+		return graphql.NewNonNull(graphql.Float)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return graphql.NewNonNull(graphql.Int)
+	}
+	return JSON
+}
+
+// nullable strips a non-null wrapper
+func nullable(output graphql.Output) graphql.Output {
+	if nonNull, ok := output.(*graphql.NonNull); ok {
+		return nonNull.OfType
+	}
+	return output
+}
+
+// fieldResolver reads the struct field at index from the source
+func fieldResolver(index []int) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		value := reflect.ValueOf(p.Source)
+		for value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				return nil, nil
+			}
+			value = value.Elem()
+		}
+		field, err := value.FieldByIndexErr(index)
+		if err != nil {
+			return nil, nil // Through a nil embedded pointer
+		}
+		return field.Interface(), nil
+	}
+}
@@ -1,13 +1,17 @@
 package handlers
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 
-	"elo-insight/backend/database"
+	"elo-insight/backend/apperrors"
 	"elo-insight/backend/middleware"
 	"elo-insight/backend/models"
+	"elo-insight/backend/store"
 	"elo-insight/backend/telemetry"
 
 	"github.com/gin-gonic/gin"
@@ -16,7 +20,7 @@ import (
 )
 
 // Function to register a new user
-func Register(c *gin.Context) {
+func (h *Handler) Register(c *gin.Context) {
 	// Start a new span for the registration process
 	ctx, span := telemetry.StartSpan(c.Request.Context(), "user.register")
 	defer span.End()
@@ -38,15 +42,14 @@ func Register(c *gin.Context) {
 	telemetry.RecordRegistrationValidated(ctx, "beginning_validation")
 
 	// Parse request data
-	var user models.User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	var input RegisterRequest
+	if !bindJSON(c, &input) {
 		// Record error in the span
 		span.SetAttributes(attribute.String("error", "validation_error"))
-		span.SetAttributes(attribute.String("error.message", err.Error()))
-		
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		span.SetAttributes(attribute.String("error.message", c.Errors.Last().Error()))
 		return
 	}
+	user := models.User{Username: input.Username, Email: input.Email, Password: input.Password}
 
 	// Extract client-side timing metrics from headers if available
 	clientTotalTimeStr := c.GetHeader("X-Registration-Time-Ms")
@@ -66,9 +69,8 @@ func Register(c *gin.Context) {
 		}
 	}
 
-	// Add user information to the span
+	// Add user information to the span; the email is personal data and stays out of traces
 	span.SetAttributes(
-		attribute.String("user.email", user.Email),
 		attribute.String("user.username", user.Username),
 	)
 	
@@ -135,14 +137,13 @@ func Register(c *gin.Context) {
 	// Start a child span for parsing the request
 	_, parseSpan := telemetry.StartSpan(ctx, "user.register.parse_request")
 	parseSpan.SetAttributes(
-		attribute.String("user.email", user.Email),
 		attribute.String("user.username", user.Username),
 		attribute.String("validation.result", "success"),
 	)
 	parseSpan.End()
 
 	// Log the registration attempt
-	log.Printf("Registering user: %s", user.Email)
+	slog.InfoContext(ctx, "Registering user", "username", user.Username)
 
 	// Add processing event
 	telemetry.RecordRegistrationProcessing(ctx)
@@ -159,8 +160,9 @@ func Register(c *gin.Context) {
 	if err != nil {
 		hashSpan.SetAttributes(attribute.String("error", "hashing_error"))
 		hashSpan.SetAttributes(attribute.String("error.message", err.Error()))
-		
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		hashSpan.End()
+
+		c.Error(apperrors.Internal(fmt.Errorf("failed to hash password: %w", err)))
 		return
 	}
 	
@@ -179,86 +181,92 @@ func Register(c *gin.Context) {
 	dbSpan.SetAttributes(
 		attribute.String("db.operation", "insert"),
 		attribute.String("db.table", "users"),
-		attribute.String("user.email", user.Email),
 	)
 
 	// Create user in database - pass the trace context
-	result := database.DB.WithContext(dbCtx).Create(&user)
-	if result.Error != nil {
+	if err := h.store.Users.Create(dbCtx, &user); err != nil {
 		dbSpan.SetAttributes(attribute.String("error", "database_error"))
-		dbSpan.SetAttributes(attribute.String("error.message", result.Error.Error()))
-		
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		dbSpan.SetAttributes(attribute.String("error.message", err.Error()))
+		dbSpan.End()
+
+		if errors.Is(err, store.ErrDuplicate) {
+			c.Error(h.duplicateUserError(ctx, user))
+			return
+		}
+		c.Error(apperrors.Internal(fmt.Errorf("failed to create user: %w", err)))
 		return
 	}
 
 	// Record success metrics
 	dbSpan.SetAttributes(
-		attribute.Int64("db.rows_affected", result.RowsAffected),
 		attribute.Int64("user.id", int64(user.ID)),
 	)
 	dbSpan.End()
 
 	// Record successful registration completion with timing data
 	telemetry.RecordRegistrationCompleted(ctx, user.ID, true)
+	telemetry.CountRegistration(ctx)
 
 	// Also record legacy completion event
 	telemetry.TraceRegistrationComplete(ctx, user.ID)
 
 	// Log success
-	log.Printf("User registered successfully: %s\n", user.Email)
+	slog.InfoContext(ctx, "User registered", "user_id", user.ID)
 
 	// Return success
-	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
+	c.JSON(http.StatusCreated, MessageResponse{Message: "User registered successfully"})
 }
 
 // Function to login user and issue JWT token
-func Login(c *gin.Context) {
-	var input struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
-
-	if err := c.ShouldBindJSON(&input); err != nil {
-		log.Println("ERROR: Invalid request data:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+func (h *Handler) Login(c *gin.Context) {
+	var input LoginRequest
+	if !bindJSON(c, &input) {
 		return
 	}
 
-	log.Println("User attempting login:", input.Email)
+	ctx := c.Request.Context()
 
-	var user models.User
-	result := database.DB.Where("email = ?", input.Email).First(&user)
-	if result.Error != nil {
-		log.Println("ERROR: User not found for email:", input.Email)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	user, err := h.store.Users.GetByEmail(ctx, input.Email)
+	if err != nil {
+		slog.InfoContext(ctx, "Login failed: unknown email")
+		c.Error(apperrors.Unauthorized("Invalid credentials"))
 		return
 	}
 
 	if !user.CheckPassword(input.Password) {
-		log.Println("ERROR: Password mismatch for user:", input.Email)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		slog.InfoContext(ctx, "Login failed: wrong password", "user_id", user.ID)
+		c.Error(apperrors.Unauthorized("Invalid credentials"))
 		return
 	}
 
 	// Generate JWT token
-	token, err := middleware.GenerateJWT(user)
+	token, err := middleware.GenerateJWT(*user)
 	if err != nil {
-		log.Println("ERROR: Failed to generate JWT:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		c.Error(apperrors.Internal(fmt.Errorf("failed to generate JWT for user %d: %w", user.ID, err)))
 		return
 	}
 
-	log.Println("User logged in successfully:", input.Email)
+	slog.InfoContext(ctx, "User logged in", "user_id", user.ID)
 
 	// Send JWT as an HttpOnly cookie
-	c.SetCookie("token", token, 86400, "/", "localhost", false, true)
-	c.JSON(http.StatusOK, gin.H{"message": "Login successful"})
+	middleware.SetTokenCookie(c, token, 86400)
+	c.JSON(http.StatusOK, MessageResponse{Message: "Login successful"})
 }
 
 // Function to logout user
-func Logout(c *gin.Context) {
-	// Expire the token cookie by setting MaxAge to -1
-	c.SetCookie("token", "", -1, "/", "localhost", false, true)
-	c.JSON(http.StatusOK, gin.H{"message": "Logout successful"})
+func (h *Handler) Logout(c *gin.Context) {
+	// Expire the token cookie
+	middleware.ClearTokenCookie(c)
+	c.JSON(http.StatusOK, MessageResponse{Message: "Logout successful"})
+}
+
+// duplicateUserError says which of the new user's username and email is taken
+func (h *Handler) duplicateUserError(ctx context.Context, user models.User) *apperrors.Error {
+	field := "username"
+	if _, err := h.store.Users.GetByEmail(ctx, user.Email); err == nil {
+		field = "email"
+	}
+	conflict := apperrors.Conflict("An account with this " + field + " already exists")
+	conflict.Err = store.ErrDuplicate
+	return conflict.WithDetail("fields", map[string]string{field: "is already taken"})
 }
@@ -0,0 +1,96 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/handlers"
+	"elo-insight/backend/middleware"
+)
+
+func TestRegister(t *testing.T) {
+	api := newTestAPI(t)
+	api.route(http.MethodPost, "/auth/register", api.h.Register)
+	api.user("taken")
+
+	tests := []struct {
+		name       string
+		body       handlers.RegisterRequest
+		wantStatus int
+		wantCode   apperrors.Code
+		wantField  string // The field a validation or conflict error names
+	}{
+		{"new user", handlers.RegisterRequest{Username: "newbie", Email: "newbie@example.com", Password: "password1"},
+			http.StatusCreated, "", ""},
+		{"taken email", handlers.RegisterRequest{Username: "other", Email: "taken@example.com", Password: "password1"},
+			http.StatusConflict, apperrors.CodeConflict, "email"},
+		{"taken username", handlers.RegisterRequest{Username: "taken", Email: "other@example.com", Password: "password1"},
+			http.StatusConflict, apperrors.CodeConflict, "username"},
+		{"weak password", handlers.RegisterRequest{Username: "weak", Email: "weak@example.com", Password: "password"},
+			http.StatusBadRequest, apperrors.CodeValidation, "password"},
+		{"invalid email", handlers.RegisterRequest{Username: "bademail", Email: "not-an-email", Password: "password1"},
+			http.StatusBadRequest, apperrors.CodeValidation, "email"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := api.do(0, http.MethodPost, "/auth/register", tt.body)
+			if tt.wantCode == "" {
+				decode[handlers.MessageResponse](t, w, tt.wantStatus)
+				return
+			}
+			body := wantError(t, w, tt.wantStatus, tt.wantCode)
+			fields, _ := body.Details["fields"].(map[string]any)
+			if _, ok := fields[tt.wantField]; !ok {
+				t.Errorf("details = %v, want fields.%s", body.Details, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	api := newTestAPI(t)
+	api.route(http.MethodPost, "/auth/login", api.h.Login)
+	api.route(http.MethodPost, "/auth/logout", api.h.Logout)
+	api.user("player")
+
+	tests := []struct {
+		name       string
+		body       handlers.LoginRequest
+		wantStatus int
+	}{
+		{"signed in", handlers.LoginRequest{Email: "player@example.com", Password: "password1"}, http.StatusOK},
+		{"wrong password", handlers.LoginRequest{Email: "player@example.com", Password: "password2"}, http.StatusUnauthorized},
+		{"unknown email", handlers.LoginRequest{Email: "nobody@example.com", Password: "password1"}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := api.do(0, http.MethodPost, "/auth/login", tt.body)
+			cookies := w.Result().Cookies()
+			if tt.wantStatus != http.StatusOK {
+				body := wantError(t, w, tt.wantStatus, apperrors.CodeUnauthorized)
+				// The same answer for both, so emails can't be probed
+				if body.Error != "Invalid credentials" {
+					t.Errorf("error = %q, want Invalid credentials", body.Error)
+				}
+				if len(cookies) != 0 {
+					t.Errorf("cookies = %v, want none", cookies)
+				}
+				return
+			}
+			decode[handlers.MessageResponse](t, w, tt.wantStatus)
+			if len(cookies) != 1 || cookies[0].Name != middleware.TokenCookie || cookies[0].Value == "" || !cookies[0].HttpOnly {
+				t.Errorf("cookies = %v, want an HttpOnly %s cookie", cookies, middleware.TokenCookie)
+			}
+		})
+	}
+
+	t.Run("logout", func(t *testing.T) {
+		w := api.do(0, http.MethodPost, "/auth/logout", nil)
+		decode[handlers.MessageResponse](t, w, http.StatusOK)
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != middleware.TokenCookie || cookies[0].MaxAge >= 0 {
+			t.Errorf("cookies = %v, want the %s cookie expired", cookies, middleware.TokenCookie)
+		}
+	})
+}
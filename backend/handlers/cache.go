@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"elo-insight/backend/cache"
+	"elo-insight/backend/upstream"
+)
+
+// Cached upstream endpoints
+const (
+	endpointRiotAccount      = "riot.account"        // Riot ID to PUUID
+	endpointLoLSummoner      = "lol.summoner"        // By PUUID
+	endpointLoLMastery       = "lol.mastery"         // By summoner ID
+	endpointLoLRanked        = "lol.ranked"          // By summoner ID
+	endpointLoLRankedByPUUID = "lol.ranked-by-puuid" // By PUUID
+	endpointLoLMatchIDs      = "lol.match-ids"       // By PUUID
+	endpointLoLMatch         = "lol.match"           // By match ID
+	endpointValMatchIDs      = "val.match-ids"       // By PUUID
+	endpointValMatch         = "val.match"           // By match ID
+	endpointSteamPlayer      = "steam.player-summary"
+	endpointCS2Stats         = "cs2.stats"
+	endpointDota2Stats       = "dota2.stats"
+	endpointApexSearch       = "apex.search" // By EA username
+)
+
+// How long each endpoint's responses are kept. Finished matches never change;
+// ranks, match lists and lifetime stats change at most once per game.
+var cachePolicies = map[string]cache.Policy{
+	endpointRiotAccount:      {TTL: 24 * time.Hour, Stale: 7 * 24 * time.Hour},
+	endpointLoLSummoner:      {TTL: time.Hour, Stale: 24 * time.Hour},
+	endpointLoLMastery:       {TTL: 30 * time.Minute, Stale: 24 * time.Hour},
+	endpointLoLRanked:        {TTL: 5 * time.Minute, Stale: time.Hour},
+	endpointLoLRankedByPUUID: {TTL: 5 * time.Minute, Stale: time.Hour},
+	endpointLoLMatchIDs:      {TTL: 2 * time.Minute, Stale: 30 * time.Minute},
+	endpointLoLMatch:         {TTL: 30 * 24 * time.Hour},
+	endpointValMatchIDs:      {TTL: 2 * time.Minute, Stale: 30 * time.Minute},
+	endpointValMatch:         {TTL: 30 * 24 * time.Hour},
+	endpointSteamPlayer:      {TTL: time.Hour, Stale: 24 * time.Hour},
+	endpointCS2Stats:         {TTL: 10 * time.Minute, Stale: time.Hour},
+	endpointDota2Stats:       {TTL: 10 * time.Minute, Stale: time.Hour},
+	endpointApexSearch:       {TTL: 10 * time.Minute, Stale: time.Hour},
+}
+
+// riotHeader authenticates a Riot API request
+func riotHeader(apiKey string) http.Header {
+	return http.Header{"X-Riot-Token": {apiKey}}
+}
+
+// fetchCached returns the body of the response from url, from the cache when it can
+func fetchCached(ctx context.Context, key cache.Key, url string, header http.Header) ([]byte, error) {
+	body, _, err := cache.Default.Fetch(ctx, key, cachePolicies[key.Endpoint], func(ctx context.Context) ([]byte, error) {
+		return upstream.GetBody(ctx, url, header)
+	})
+	return body, err
+}
+
+// fetchCachedJSON decodes the response from url into out, from the cache when it can
+func fetchCachedJSON(ctx context.Context, key cache.Key, url string, header http.Header, out interface{}) error {
+	body, err := fetchCached(ctx, key, url, header)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", key.Endpoint, err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/models"
+	"elo-insight/backend/upstream"
+)
+
+// notLinked reports that the user must link their account on platform first
+func notLinked(platform string) *apperrors.Error {
+	return apperrors.NotLinked(platform, fmt.Sprintf("Link your %s account first", platformName(platform)))
+}
+
+// upstreamUnavailable reports that a provider's API failed or isn't configured
+func upstreamUnavailable(provider string, err error) *apperrors.Error {
+	return apperrors.UpstreamUnavailable(provider, fmt.Sprintf("%s is unavailable, try again later", providerName(provider)), err)
+}
+
+// fromUpstream classifies an error from a provider call: its 404s become
+// NotFound and its 429s RateLimited; everything else, including our own
+// timeouts and open circuit breakers, is UpstreamUnavailable.
+func fromUpstream(provider string, err error) *apperrors.Error {
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	var statusErr *upstream.StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusNotFound:
+			notFound := apperrors.NotFound(fmt.Sprintf("Player not found on %s", providerName(provider)))
+			notFound.Err = err
+			return notFound.WithDetail("provider", provider)
+		case http.StatusTooManyRequests:
+			limited := apperrors.RateLimited(fmt.Sprintf("%s is rate limiting us, try again shortly", providerName(provider)), statusErr.RetryAfter)
+			limited.Err = err
+			return limited.WithDetail("provider", provider)
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return upstreamUnavailable(provider, err).WithDetail("reason", "timeout")
+	}
+	if errors.Is(err, upstream.ErrCircuitOpen) {
+		return upstreamUnavailable(provider, err).WithDetail("reason", "circuit_open")
+	}
+	return upstreamUnavailable(provider, err)
+}
+
+// providerName is how a provider is named in messages
+func providerName(provider string) string {
+	switch provider {
+	case upstream.Steam:
+		return "Steam"
+	case upstream.Riot:
+		return "Riot Games"
+	case upstream.Tracker:
+		return "Tracker.gg"
+	}
+	return provider
+}
+
+// platformName is how a linked platform is named in messages
+func platformName(platform string) string {
+	switch platform {
+	case models.PlatformSteam:
+		return "Steam"
+	case models.PlatformRiot:
+		return "Riot"
+	case models.PlatformEA:
+		return "EA"
+	case models.PlatformXbox:
+		return "Xbox"
+	case models.PlatformPlayStation:
+		return "PlayStation"
+	}
+	return platform
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/models"
+	"elo-insight/backend/upstream"
+)
+
+func TestFromUpstream(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantStatus     int
+		wantCode       apperrors.Code
+		wantMessage    string
+		wantDetails    string
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:        "player not found",
+			err:         fmt.Errorf("get account: %w", &upstream.StatusError{StatusCode: http.StatusNotFound}),
+			wantStatus:  http.StatusNotFound,
+			wantCode:    apperrors.CodeNotFound,
+			wantMessage: "Player not found on Riot Games",
+			wantDetails: "map[provider:riot]",
+		},
+		{
+			name:           "rate limited",
+			err:            &upstream.StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 30 * time.Second},
+			wantStatus:     http.StatusTooManyRequests,
+			wantCode:       apperrors.CodeRateLimited,
+			wantMessage:    "Riot Games is rate limiting us, try again shortly",
+			wantDetails:    "map[provider:riot]",
+			wantRetryAfter: 30 * time.Second,
+		},
+		{
+			name:        "server error",
+			err:         &upstream.StatusError{StatusCode: http.StatusServiceUnavailable},
+			wantStatus:  http.StatusBadGateway,
+			wantCode:    apperrors.CodeUpstreamUnavailable,
+			wantMessage: "Riot Games is unavailable, try again later",
+			wantDetails: "map[provider:riot]",
+		},
+		{
+			name:        "timeout",
+			err:         fmt.Errorf("get matches: %w", context.DeadlineExceeded),
+			wantStatus:  http.StatusBadGateway,
+			wantCode:    apperrors.CodeUpstreamUnavailable,
+			wantMessage: "Riot Games is unavailable, try again later",
+			wantDetails: "map[provider:riot reason:timeout]",
+		},
+		{
+			name:        "circuit open",
+			err:         fmt.Errorf("get matches: %w", upstream.ErrCircuitOpen),
+			wantStatus:  http.StatusBadGateway,
+			wantCode:    apperrors.CodeUpstreamUnavailable,
+			wantMessage: "Riot Games is unavailable, try again later",
+			wantDetails: "map[provider:riot reason:circuit_open]",
+		},
+		{
+			name:        "already classified",
+			err:         fmt.Errorf("lookup: %w", notLinked(models.PlatformRiot)),
+			wantStatus:  http.StatusConflict,
+			wantCode:    apperrors.CodeNotLinked,
+			wantMessage: "Link your Riot account first",
+			wantDetails: "map[platform:riot]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fromUpstream(upstream.Riot, tt.err)
+			if err.Status != tt.wantStatus || err.Code != tt.wantCode || err.Message != tt.wantMessage {
+				t.Errorf("fromUpstream = %d %s %q, want %d %s %q", err.Status, err.Code, err.Message, tt.wantStatus, tt.wantCode, tt.wantMessage)
+			}
+			if got := fmt.Sprint(err.Details); got != tt.wantDetails {
+				t.Errorf("details = %s, want %s", got, tt.wantDetails)
+			}
+			if err.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("RetryAfter = %v, want %v", err.RetryAfter, tt.wantRetryAfter)
+			}
+			if !errors.Is(err, tt.err) && !errors.Is(tt.err, err) {
+				t.Errorf("fromUpstream lost the cause %v", tt.err)
+			}
+		})
+	}
+}
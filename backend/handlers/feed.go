@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/models"
+	"elo-insight/backend/pagination"
+	"elo-insight/backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxFeedLimit caps feed pages below pagination.MaxLimit, since each event
+// loads its reactions and comment counts
+const maxFeedLimit = 50
+
+// feedOrder sorts feed events newest first
+var feedOrder = pagination.Order[models.FeedEvent]{
+	Sort: "-occurred_at",
+	Key:  func(event models.FeedEvent) string { return pagination.TimeKey(event.OccurredAt) },
+	ID:   func(event models.FeedEvent) uint { return event.ID },
+}
+
+// FeedPageResponse is one page of GET /feed
+type FeedPageResponse struct {
+	Events     []models.FeedEventResponse `json:"events"`
+	NextCursor string                     `json:"next_cursor"` // Empty on the last page
+}
+
+// GetFeed returns notable events from the user's friends, newest first
+func (h *Handler) GetFeed(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var query FeedQuery
+	if !bindQuery(c, &query) {
+		return
+	}
+	query.Limit = min(query.Limit, maxFeedLimit)
+	keyset, err := feedOrder.Keyset(query.Query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Friends are resolved on every read, so new friendships show up immediately
+	friendIDs, err := h.acceptedFriendIDs(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch friendships: %w", err)))
+		return
+	}
+	if len(friendIDs) == 0 {
+		c.JSON(http.StatusOK, FeedPageResponse{Events: []models.FeedEventResponse{}})
+		return
+	}
+
+	events, err := h.store.Feed.ListEvents(c.Request.Context(), friendIDs, keyset)
+	if err != nil {
+		c.Error(apperrors.From(fmt.Errorf("failed to fetch feed events: %w", err)))
+		return
+	}
+	page := feedOrder.Page(events, keyset)
+
+	responses, err := h.buildFeedResponses(c.Request.Context(), page.Items)
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to build feed responses: %w", err)))
+		return
+	}
+
+	page.SetLinks(c)
+	c.JSON(http.StatusOK, FeedPageResponse{Events: responses, NextCursor: page.Next})
+}
+
+// ReactToFeedEvent adds a reaction from the user to a feed event
+func (h *Handler) ReactToFeedEvent(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
+		return
+	}
+
+	event, ok := h.loadVisibleFeedEvent(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	var input FeedReactionRequest
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	reaction := models.FeedReaction{
+		FeedEventID: event.ID,
+		UserID:      userID.(uint),
+		Kind:        input.Reaction,
+	}
+	if err := h.store.Feed.AddReaction(c.Request.Context(), &reaction); err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to save reaction: %w", err)))
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Reaction saved successfully"})
+}
+
+// RemoveFeedReaction removes one of the user's reactions from a feed event
+func (h *Handler) RemoveFeedReaction(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
+		return
+	}
+
+	event, ok := h.loadVisibleFeedEvent(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	if err := h.store.Feed.RemoveReaction(c.Request.Context(), event.ID, userID.(uint), c.Param("reaction")); err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to remove reaction: %w", err)))
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Reaction removed successfully"})
+}
+
+// GetFeedComments lists the comments on a feed event, oldest first
+func (h *Handler) GetFeedComments(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
+		return
+	}
+
+	event, ok := h.loadVisibleFeedEvent(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	comments, err := h.store.Feed.ListComments(c.Request.Context(), event.ID)
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch comments: %w", err)))
+		return
+	}
+
+	authorIDs := make([]uint, 0, len(comments))
+	for _, comment := range comments {
+		authorIDs = append(authorIDs, comment.UserID)
+	}
+	usernames, err := h.usernamesByID(c.Request.Context(), authorIDs)
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch comment authors: %w", err)))
+		return
+	}
+
+	responses := make([]models.FeedCommentResponse, 0, len(comments))
+	for _, comment := range comments {
+		responses = append(responses, models.FeedCommentResponse{
+			ID:        comment.ID,
+			UserID:    comment.UserID,
+			Username:  usernames[comment.UserID],
+			Body:      comment.Body,
+			CreatedAt: comment.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	c.JSON(http.StatusOK, responses)
+}
+
+// CommentOnFeedEvent adds a comment from the user to a feed event
+func (h *Handler) CommentOnFeedEvent(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
+		return
+	}
+
+	event, ok := h.loadVisibleFeedEvent(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	var input FeedCommentRequest
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	body := strings.TrimSpace(input.Body)
+	if body == "" {
+		c.Error(apperrors.Validation("Comment must be between 1 and 500 characters", map[string]string{"body": "is required"}))
+		return
+	}
+
+	comment := models.FeedComment{
+		FeedEventID: event.ID,
+		UserID:      userID.(uint),
+		Body:        body,
+	}
+	if err := h.store.Feed.CreateComment(c.Request.Context(), &comment); err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to save comment: %w", err)))
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreatedResponse{Message: "Comment added successfully", ID: comment.ID})
+}
+
+// loadVisibleFeedEvent loads the event in the :id param if the user may see it.
+// It writes the error response itself and returns false on failure.
+func (h *Handler) loadVisibleFeedEvent(c *gin.Context, userID uint) (*models.FeedEvent, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(apperrors.Validation("Invalid feed event ID", map[string]string{"id": "must be a number"}))
+		return nil, false
+	}
+
+	event, err := h.store.Feed.GetEvent(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.Error(apperrors.NotFound("Feed event not found"))
+		} else {
+			c.Error(apperrors.Internal(fmt.Errorf("database error: %w", err)))
+		}
+		return nil, false
+	}
+
+	// Users can see their own events and their friends' events
+	if event.UserID != userID {
+		friendIDs, err := h.acceptedFriendIDs(c.Request.Context(), userID)
+		if err != nil {
+			c.Error(apperrors.Internal(fmt.Errorf("failed to fetch friendships: %w", err)))
+			return nil, false
+		}
+		visible := false
+		for _, id := range friendIDs {
+			if id == event.UserID {
+				visible = true
+				break
+			}
+		}
+		if !visible {
+			c.Error(apperrors.NotFound("Feed event not found"))
+			return nil, false
+		}
+	}
+
+	return event, true
+}
+
+// buildFeedResponses attaches usernames, reaction counts and comment counts to events
+func (h *Handler) buildFeedResponses(ctx context.Context, events []models.FeedEvent) ([]models.FeedEventResponse, error) {
+	responses := make([]models.FeedEventResponse, 0, len(events))
+	if len(events) == 0 {
+		return responses, nil
+	}
+
+	eventIDs := make([]uint, 0, len(events))
+	actorIDs := make([]uint, 0, len(events))
+	for _, event := range events {
+		eventIDs = append(eventIDs, event.ID)
+		actorIDs = append(actorIDs, event.UserID)
+	}
+
+	usernames, err := h.usernamesByID(ctx, actorIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	reactions, err := h.store.Feed.ReactionCounts(ctx, eventIDs)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := h.store.Feed.CommentCounts(ctx, eventIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, event := range events {
+		eventReactions := reactions[event.ID]
+		if eventReactions == nil {
+			eventReactions = map[string]int{}
+		}
+		responses = append(responses, models.FeedEventResponse{
+			ID:           event.ID,
+			UserID:       event.UserID,
+			Username:     usernames[event.UserID],
+			Type:         event.Type,
+			Game:         event.Game,
+			MatchID:      event.MatchID,
+			Summary:      event.Summary,
+			Details:      event.Details,
+			OccurredAt:   event.OccurredAt.Format(time.RFC3339),
+			Reactions:    eventReactions,
+			CommentCount: comments[event.ID],
+		})
+	}
+
+	return responses, nil
+}
+
+// usernamesByID looks up usernames for a set of user IDs
+func (h *Handler) usernamesByID(ctx context.Context, ids []uint) (map[uint]string, error) {
+	usernames := make(map[uint]string)
+	if len(ids) == 0 {
+		return usernames, nil
+	}
+
+	users, err := h.store.Users.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		usernames[user.ID] = user.Username
+	}
+	return usernames, nil
+}
+
+// usersByID looks up users for a set of user IDs; missing users are left out
+func (h *Handler) usersByID(ctx context.Context, ids []uint) (map[uint]models.User, error) {
+	byID := make(map[uint]models.User)
+	if len(ids) == 0 {
+		return byID, nil
+	}
+
+	users, err := h.store.Users.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	return byID, nil
+}
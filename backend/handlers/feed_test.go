@@ -0,0 +1,141 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/handlers"
+	"elo-insight/backend/models"
+)
+
+func feedAPI(t *testing.T) *testAPI {
+	api := newTestAPI(t)
+	api.route(http.MethodGet, "/feed/", api.h.GetFeed)
+	api.route(http.MethodPost, "/feed/:id/reactions", api.h.ReactToFeedEvent)
+	api.route(http.MethodDelete, "/feed/:id/reactions/:reaction", api.h.RemoveFeedReaction)
+	api.route(http.MethodGet, "/feed/:id/comments", api.h.GetFeedComments)
+	api.route(http.MethodPost, "/feed/:id/comments", api.h.CommentOnFeedEvent)
+	return api
+}
+
+// event stores a feed event about the user that happened ago before now
+func (a *testAPI) event(user models.User, summary string, ago time.Duration) models.FeedEvent {
+	a.t.Helper()
+	event := models.FeedEvent{
+		UserID:     user.ID,
+		Type:       models.FeedEventWinStreak,
+		Game:       "lol",
+		Summary:    summary,
+		OccurredAt: time.Now().Add(-ago),
+		DedupeKey:  fmt.Sprintf("%d:%s", user.ID, summary),
+	}
+	if err := a.store.Feed.CreateEvent(context.Background(), &event); err != nil {
+		a.t.Fatalf("create event: %v", err)
+	}
+	return event
+}
+
+func TestGetFeed(t *testing.T) {
+	api := feedAPI(t)
+	me, friend, stranger := api.user("me"), api.user("friend"), api.user("stranger")
+
+	empty := decode[handlers.FeedPageResponse](t, api.do(me.ID, http.MethodGet, "/feed/", nil), http.StatusOK)
+	if empty.Events == nil || len(empty.Events) != 0 || empty.NextCursor != "" {
+		t.Errorf("feed without friends = %+v, want an empty list", empty)
+	}
+
+	api.befriend(me, friend)
+	for i := 1; i <= 3; i++ {
+		api.event(friend, fmt.Sprintf("win %d", i), time.Duration(i)*time.Hour)
+	}
+	api.event(stranger, "not my friend", time.Minute)
+	api.event(me, "my own", time.Minute)
+
+	var summaries []string
+	path := "/feed/?limit=2"
+	for pages := 0; path != ""; pages++ {
+		if pages > 2 {
+			t.Fatalf("more than 2 pages; last: %s", path)
+		}
+		page := decode[handlers.FeedPageResponse](t, api.do(me.ID, http.MethodGet, path, nil), http.StatusOK)
+		for _, event := range page.Events {
+			summaries = append(summaries, event.Summary)
+			if event.Username != "friend" {
+				t.Errorf("event %d username = %q, want friend", event.ID, event.Username)
+			}
+		}
+		path = ""
+		if page.NextCursor != "" {
+			path = "/feed/?limit=2&cursor=" + url.QueryEscape(page.NextCursor)
+		}
+	}
+	if want := "[win 1 win 2 win 3]"; fmt.Sprint(summaries) != want {
+		t.Errorf("feed = %v, want %s, newest first", summaries, want)
+	}
+
+	w := api.do(me.ID, http.MethodGet, "/feed/?cursor=forged", nil)
+	wantError(t, w, http.StatusBadRequest, apperrors.CodeValidation)
+	w = api.do(0, http.MethodGet, "/feed/", nil)
+	wantError(t, w, http.StatusUnauthorized, apperrors.CodeUnauthorized)
+}
+
+func TestFeedReactionsAndComments(t *testing.T) {
+	api := feedAPI(t)
+	me, friend, stranger := api.user("me"), api.user("friend"), api.user("stranger")
+	api.befriend(me, friend)
+	event := api.event(friend, "pentakill", time.Hour)
+	eventPath := fmt.Sprintf("/feed/%d", event.ID)
+
+	// Events are only visible to their user and the user's friends
+	tests := []struct {
+		name       string
+		as         models.User
+		method     string
+		path       string
+		body       any
+		wantStatus int
+		wantCode   apperrors.Code
+	}{
+		{"friend reacts", me, http.MethodPost, eventPath + "/reactions", handlers.FeedReactionRequest{Reaction: "fire"}, http.StatusOK, ""},
+		{"reaction repeated", me, http.MethodPost, eventPath + "/reactions", handlers.FeedReactionRequest{Reaction: "fire"}, http.StatusOK, ""},
+		{"owner reacts", friend, http.MethodPost, eventPath + "/reactions", handlers.FeedReactionRequest{Reaction: "gg"}, http.StatusOK, ""},
+		{"unknown reaction", me, http.MethodPost, eventPath + "/reactions", handlers.FeedReactionRequest{Reaction: "boo"}, http.StatusBadRequest, apperrors.CodeValidation},
+		{"stranger reacts", stranger, http.MethodPost, eventPath + "/reactions", handlers.FeedReactionRequest{Reaction: "fire"}, http.StatusNotFound, apperrors.CodeNotFound},
+		{"unknown event", me, http.MethodPost, "/feed/999/reactions", handlers.FeedReactionRequest{Reaction: "fire"}, http.StatusNotFound, apperrors.CodeNotFound},
+		{"invalid event", me, http.MethodPost, "/feed/x/reactions", handlers.FeedReactionRequest{Reaction: "fire"}, http.StatusBadRequest, apperrors.CodeValidation},
+		{"friend comments", me, http.MethodPost, eventPath + "/comments", handlers.FeedCommentRequest{Body: "  nice  "}, http.StatusCreated, ""},
+		{"blank comment", me, http.MethodPost, eventPath + "/comments", handlers.FeedCommentRequest{Body: "   "}, http.StatusBadRequest, apperrors.CodeValidation},
+		{"stranger comments", stranger, http.MethodPost, eventPath + "/comments", handlers.FeedCommentRequest{Body: "hi"}, http.StatusNotFound, apperrors.CodeNotFound},
+		{"stranger reads comments", stranger, http.MethodGet, eventPath + "/comments", nil, http.StatusNotFound, apperrors.CodeNotFound},
+		{"owner removes reaction", friend, http.MethodDelete, eventPath + "/reactions/gg", nil, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := api.do(tt.as.ID, tt.method, tt.path, tt.body)
+			if tt.wantCode != "" {
+				wantError(t, w, tt.wantStatus, tt.wantCode)
+			} else if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d; body: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+
+	comments := decode[[]models.FeedCommentResponse](t, api.do(friend.ID, http.MethodGet, eventPath+"/comments", nil), http.StatusOK)
+	if len(comments) != 1 || comments[0].Body != "nice" || comments[0].Username != "me" {
+		t.Errorf("comments = %+v, want one from me saying nice", comments)
+	}
+
+	page := decode[handlers.FeedPageResponse](t, api.do(me.ID, http.MethodGet, "/feed/", nil), http.StatusOK)
+	if len(page.Events) != 1 {
+		t.Fatalf("feed = %+v, want the one event", page.Events)
+	}
+	got := page.Events[0]
+	if fmt.Sprint(got.Reactions) != "map[fire:1]" || got.CommentCount != 1 {
+		t.Errorf("reactions = %v, comments = %d; want one fire and one comment", got.Reactions, got.CommentCount)
+	}
+}
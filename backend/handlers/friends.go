@@ -1,319 +1,275 @@
 package handlers
 
 import (
+	"context"
 	"errors"
-	"log"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
-	"elo-insight/backend/database"
+	"elo-insight/backend/apperrors"
 	"elo-insight/backend/models"
+	"elo-insight/backend/pagination"
+	"elo-insight/backend/store"
+	"elo-insight/backend/telemetry"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
-// GetFriends retrieves the list of friends for the authenticated user
-func GetFriends(c *gin.Context) {
+// FriendRequestResponse is the outcome of sending, answering or cancelling a friend request
+type FriendRequestResponse struct {
+	Message string `json:"message"`
+	ID      uint   `json:"id"`     // The friendship
+	Status  string `json:"status"` // pending, accepted, declined or cancelled
+}
+
+// GetFriends retrieves a page of the authenticated user's friends
+func (h *Handler) GetFriends(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
-	// Get all accepted friendships where the user is either the requester or the recipient
-	var friendships []models.Friendship
-	if err := database.DB.Where(
-		"(user_id = ? OR friend_id = ?) AND status = ?", 
-		userID, userID, "accepted").Find(&friendships).Error; err != nil {
-		log.Println("Failed to fetch friendships:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch friends"})
+	var query FriendsQuery
+	if !bindQuery(c, &query) {
 		return
 	}
-
-	var friendResponses []models.FriendshipResponse
-	for _, friendship := range friendships {
-		var friendID uint
-		if friendship.UserID == userID.(uint) {
-			friendID = friendship.FriendID
-		} else {
-			friendID = friendship.UserID
-		}
-
-		// Get friend's details
-		var friend models.User
-		if err := database.DB.Select("id, username, email").Where("id = ?", friendID).First(&friend).Error; err != nil {
-			log.Println("Failed to fetch friend details:", err)
-			continue
-		}
-
-		friendResponses = append(friendResponses, models.FriendshipResponse{
-			ID:          friendship.ID,
-			UserID:      friendship.UserID,
-			FriendID:    friendship.FriendID,
-			Username:    friend.Username,
-			Email:       friend.Email,
-			Status:      friendship.Status,
-			RequestedBy: friendship.RequestedBy,
-			CreatedAt:   friendship.CreatedAt.Format("2006-01-02 15:04:05"),
-		})
+	if query.Sort == "" {
+		query.Sort = "created_at"
 	}
 
-	c.JSON(http.StatusOK, friendResponses)
+	// Accepted friendships where the user is either the requester or the recipient
+	filter := store.FriendshipFilter{Status: models.FriendshipAccepted}
+	page, err := h.friendshipPage(c.Request.Context(), userID.(uint), filter, query.Sort, "", query.Query)
+	if err != nil {
+		c.Error(apperrors.From(fmt.Errorf("failed to fetch friends: %w", err)))
+		return
+	}
+	page.SetLinks(c)
+	c.JSON(http.StatusOK, page.Items)
 }
 
-// SendFriendRequest creates a new friend request
-func SendFriendRequest(c *gin.Context) {
+// SendFriendRequest creates a new friend request, or accepts one the other user already sent
+func (h *Handler) SendFriendRequest(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
-	var input struct {
-		FriendID uint `json:"friend_id" binding:"required"`
+	var input NewFriendRequest
+	if !bindJSON(c, &input) {
+		return
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		log.Println("Invalid request data:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+	if input.FriendID == userID.(uint) {
+		c.Error(apperrors.Validation("You cannot send a friend request to yourself", nil))
 		return
 	}
 
 	// Check if the friend exists
-	var friend models.User
-	if err := database.DB.Where("id = ?", input.FriendID).First(&friend).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if _, err := h.store.Users.Get(c.Request.Context(), input.FriendID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.Error(apperrors.NotFound("User not found"))
 		} else {
-			log.Println("Database error:", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user"})
+			c.Error(apperrors.Internal(fmt.Errorf("database error: %w", err)))
 		}
 		return
 	}
 
-	// Check if friend request already exists
-	var existingFriendship models.Friendship
-	result := database.DB.Where(
-		"(user_id = ? AND friend_id = ?) OR (user_id = ? AND friend_id = ?)", 
-		userID, input.FriendID, input.FriendID, userID).First(&existingFriendship)
-	
-	if result.Error == nil {
-		// Request already exists
-		c.JSON(http.StatusConflict, gin.H{"error": "Friend request already exists"})
-		return
-	} else if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		// Database error
-		log.Println("Database error:", result.Error)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing requests"})
+	friendship, err := h.store.Friendships.Request(c.Request.Context(), userID.(uint), input.FriendID, time.Now())
+	if err != nil {
+		respondFriendshipError(c, err, "Failed to send friend request")
 		return
 	}
 
-	// Create friend request
-	friendship := models.Friendship{
-		UserID:      userID.(uint),
-		FriendID:    input.FriendID,
-		Status:      "pending",
-		RequestedBy: userID.(uint),
-	}
-
-	if err := database.DB.Create(&friendship).Error; err != nil {
-		log.Println("Failed to create friend request:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send friend request"})
+	// A mutual request is accepted straight away
+	if friendship.Status == models.FriendshipAccepted {
+		telemetry.CountFriendRequest(c.Request.Context(), models.FriendshipActionAccept)
+		c.JSON(http.StatusOK, FriendRequestResponse{Message: "Friend request accepted successfully", ID: friendship.ID, Status: friendship.Status})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Friend request sent successfully"})
+	telemetry.CountFriendRequest(c.Request.Context(), models.FriendshipActionRequest)
+	c.JSON(http.StatusOK, FriendRequestResponse{Message: "Friend request sent successfully", ID: friendship.ID, Status: friendship.Status})
 }
 
-// RespondToFriendRequest handles accepting or rejecting a friend request
-func RespondToFriendRequest(c *gin.Context) {
+// RespondToFriendRequest handles accepting or declining a friend request
+func (h *Handler) RespondToFriendRequest(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
-	// Get the friendship ID from URL
-	friendshipID := c.Param("id")
-	if friendshipID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing friendship ID"})
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(apperrors.Validation("Invalid friendship ID", map[string]string{"id": "must be a number"}))
 		return
 	}
 
-	var input struct {
-		Action string `json:"action" binding:"required"` // "accept" or "reject"
-	}
-
-	if err := c.ShouldBindJSON(&input); err != nil {
-		log.Println("Invalid request data:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+	var input FriendActionRequest
+	if !bindJSON(c, &input) {
 		return
 	}
 
-	// Validate action
-	if input.Action != "accept" && input.Action != "reject" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Action must be 'accept' or 'reject'"})
-		return
+	// "reject" is kept as an alias for older clients
+	action := input.Action
+	if action == "reject" {
+		action = models.FriendshipActionDecline
 	}
 
-	// Get the friendship
-	var friendship models.Friendship
-	id, err := strconv.ParseUint(friendshipID, 10, 32)
+	friendship, err := h.store.Friendships.Transition(c.Request.Context(), uint(id), userID.(uint), action, time.Now())
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid friendship ID"})
+		respondFriendshipError(c, err, "Failed to update request")
 		return
 	}
 
-	// Find the friendship and check if the user is the recipient
-	if err := database.DB.Where("id = ? AND friend_id = ? AND status = ?", 
-		id, userID, "pending").First(&friendship).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Friend request not found or you're not authorized to respond"})
-		} else {
-			log.Println("Database error:", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check friend request"})
-		}
+	telemetry.CountFriendRequest(c.Request.Context(), action)
+	c.JSON(http.StatusOK, FriendRequestResponse{Message: "Friend request " + friendship.Status + " successfully", ID: friendship.ID, Status: friendship.Status})
+}
+
+// CancelFriendRequest withdraws a pending request the user sent
+func (h *Handler) CancelFriendRequest(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
-	// Update the friendship status
-	status := "rejected"
-	if input.Action == "accept" {
-		status = "accepted"
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(apperrors.Validation("Invalid friendship ID", map[string]string{"id": "must be a number"}))
+		return
 	}
 
-	if err := database.DB.Model(&friendship).Update("status", status).Error; err != nil {
-		log.Println("Failed to update friendship status:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update request"})
+	friendship, err := h.store.Friendships.Transition(c.Request.Context(), uint(id), userID.(uint), models.FriendshipActionCancel, time.Now())
+	if err != nil {
+		respondFriendshipError(c, err, "Failed to cancel request")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Friend request " + status + " successfully"})
+	telemetry.CountFriendRequest(c.Request.Context(), models.FriendshipActionCancel)
+	c.JSON(http.StatusOK, FriendRequestResponse{Message: "Friend request cancelled successfully", ID: friendship.ID, Status: friendship.Status})
 }
 
-// RemoveFriend deletes a friendship relationship
-func RemoveFriend(c *gin.Context) {
+// RemoveFriend ends an accepted friendship
+func (h *Handler) RemoveFriend(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
 	// Get the friendship ID from URL
 	friendshipID := c.Param("id")
 	if friendshipID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing friendship ID"})
+		c.Error(apperrors.Validation("Missing friendship ID", map[string]string{"id": "required"}))
 		return
 	}
 
 	id, err := strconv.ParseUint(friendshipID, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid friendship ID"})
+		c.Error(apperrors.Validation("Invalid friendship ID", map[string]string{"id": "must be a number"}))
 		return
 	}
 
-	// Verify the user is part of this friendship
-	var friendship models.Friendship
-	if err := database.DB.Where(
-		"id = ? AND (user_id = ? OR friend_id = ?)", 
-		id, userID, userID).First(&friendship).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Friendship not found or you're not authorized to remove it"})
+	// Only an accepted friendship the user is part of can be removed
+	if err := h.store.Friendships.DeleteAccepted(c.Request.Context(), uint(id), userID.(uint)); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.Error(apperrors.NotFound("Friendship not found or you're not authorized to remove it"))
 		} else {
-			log.Println("Database error:", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check friendship"})
+			c.Error(apperrors.Internal(fmt.Errorf("failed to delete friendship: %w", err)))
 		}
 		return
 	}
 
-	// Delete the friendship
-	if err := database.DB.Delete(&friendship).Error; err != nil {
-		log.Println("Failed to delete friendship:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove friend"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Friend removed successfully"})
+	c.JSON(http.StatusOK, MessageResponse{Message: "Friend removed successfully"})
 }
 
-// GetFriendRequests retrieves pending friend requests for the user
-func GetFriendRequests(c *gin.Context) {
+// GetFriendRequests retrieves a page of the user's friend requests, by default
+// the pending ones sent to them
+func (h *Handler) GetFriendRequests(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
-	// Get all pending friendship requests where the user is the recipient
-	var friendships []models.Friendship
-	if err := database.DB.Where(
-		"friend_id = ? AND status = ?", 
-		userID, "pending").Find(&friendships).Error; err != nil {
-		log.Println("Failed to fetch friend requests:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch friend requests"})
+	var query FriendRequestsQuery
+	if !bindQuery(c, &query) {
 		return
 	}
+	if query.Status == "" {
+		query.Status = models.FriendshipPending
+	}
+	if query.Direction == "" {
+		query.Direction = store.FriendshipsIncoming
+	}
+	if query.Sort == "" {
+		query.Sort = "-created_at"
+	}
 
-	var requestResponses []models.FriendshipResponse
-	for _, friendship := range friendships {
-		// Get requester's details
-		var requester models.User
-		if err := database.DB.Select("id, username, email").Where("id = ?", friendship.UserID).First(&requester).Error; err != nil {
-			log.Println("Failed to fetch requester details:", err)
-			continue
-		}
-
-		requestResponses = append(requestResponses, models.FriendshipResponse{
-			ID:          friendship.ID,
-			UserID:      friendship.UserID,
-			FriendID:    friendship.FriendID,
-			Username:    requester.Username,
-			Email:       requester.Email,
-			Status:      friendship.Status,
-			RequestedBy: friendship.RequestedBy,
-			CreatedAt:   friendship.CreatedAt.Format("2006-01-02 15:04:05"),
-		})
+	// The user sent the outgoing requests and answers the incoming ones
+	filter := store.FriendshipFilter{Status: query.Status, Direction: query.Direction}
+	cursorFilter := "status=" + query.Status + "&direction=" + query.Direction
+	page, err := h.friendshipPage(c.Request.Context(), userID.(uint), filter, query.Sort, cursorFilter, query.Query)
+	if err != nil {
+		c.Error(apperrors.From(fmt.Errorf("failed to fetch friend requests: %w", err)))
+		return
 	}
+	page.SetLinks(c)
+	c.JSON(http.StatusOK, page.Items)
+}
 
-	c.JSON(http.StatusOK, requestResponses)
+// UserResponse is a user found by SearchUsers, without the password or other sensitive fields
+type UserResponse struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
 }
 
 // SearchUsers searches for users by username or email for adding as friends
-func SearchUsers(c *gin.Context) {
+func (h *Handler) SearchUsers(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
-	// Get search query
-	query := c.Query("q")
-	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing search query"})
+	var query UserSearchQuery
+	if !bindQuery(c, &query) {
 		return
 	}
+	if query.Sort == "" {
+		query.Sort = "username"
+	}
 
-	// Search for users (excluding the current user)
-	var users []models.User
-	if err := database.DB.Select("id, username, email").
-		Where("(username ILIKE ? OR email ILIKE ?) AND id != ?", 
-			"%"+query+"%", "%"+query+"%", userID).
-		Limit(10).Find(&users).Error; err != nil {
-		log.Println("Failed to search users:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search users"})
+	order := pagination.Order[models.User]{
+		Sort:   query.Sort,
+		Filter: "q=" + query.Q,
+		Key:    func(user models.User) string { return pagination.TextKey(user.Username) },
+		ID:     func(user models.User) uint { return user.ID },
+	}
+	keyset, err := order.Keyset(query.Query)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	// Don't expose password or other sensitive fields
-	type UserResponse struct {
-		ID       uint   `json:"id"`
-		Username string `json:"username"`
-		Email    string `json:"email"`
+	// Search for users (excluding the current user)
+	users, err := h.store.Users.Search(c.Request.Context(), query.Q, userID.(uint), keyset)
+	if err != nil {
+		c.Error(apperrors.From(fmt.Errorf("failed to search users: %w", err)))
+		return
 	}
 
-	var userResponses []UserResponse
-	for _, user := range users {
+	page := order.Page(users, keyset)
+	userResponses := []UserResponse{}
+	for _, user := range page.Items {
 		userResponses = append(userResponses, UserResponse{
 			ID:       user.ID,
 			Username: user.Username,
@@ -321,5 +277,111 @@ func SearchUsers(c *gin.Context) {
 		})
 	}
 
+	page.SetLinks(c)
 	c.JSON(http.StatusOK, userResponses)
 }
+
+// friendshipResponses describes friendships from the user's side, with the
+// other user's details from others. Friendships with a user who isn't there are left out.
+func friendshipResponses(userID uint, friendships []models.Friendship, others map[uint]models.User) []models.FriendshipResponse {
+	responses := []models.FriendshipResponse{}
+	for _, friendship := range friendships {
+		other, ok := others[friendship.OtherUser(userID)]
+		if !ok {
+			continue
+		}
+		responses = append(responses, models.FriendshipResponse{
+			ID:          friendship.ID,
+			UserID:      friendship.UserID,
+			FriendID:    friendship.FriendID,
+			Username:    other.Username,
+			Email:       other.Email,
+			Status:      friendship.Status,
+			RequestedBy: friendship.RequestedBy,
+			CreatedAt:   friendship.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return responses
+}
+
+// friendshipPage fetches the page q asks for of the user's friendships that
+// match filter, sorted by created_at or the other user's username.
+// cursorFilter describes filter in the page's cursors.
+func (h *Handler) friendshipPage(ctx context.Context, userID uint, filter store.FriendshipFilter, sort, cursorFilter string, q pagination.Query) (pagination.Page[models.FriendshipResponse], error) {
+	var others map[uint]models.User
+	order := pagination.Order[models.Friendship]{
+		Sort:   sort,
+		Filter: cursorFilter,
+		Key:    func(friendship models.Friendship) string { return pagination.TimeKey(friendship.CreatedAt) },
+		ID:     func(friendship models.Friendship) uint { return friendship.ID },
+	}
+	if strings.TrimPrefix(sort, "-") == "username" {
+		order.Key = func(friendship models.Friendship) string {
+			return pagination.TextKey(others[friendship.OtherUser(userID)].Username)
+		}
+	}
+
+	keyset, err := order.Keyset(q)
+	if err != nil {
+		return pagination.Page[models.FriendshipResponse]{}, err
+	}
+	friendships, err := h.store.Friendships.Page(ctx, userID, filter, keyset)
+	if err != nil {
+		return pagination.Page[models.FriendshipResponse]{}, err
+	}
+
+	otherIDs := make([]uint, 0, len(friendships))
+	for _, friendship := range friendships {
+		otherIDs = append(otherIDs, friendship.OtherUser(userID))
+	}
+	if others, err = h.usersByID(ctx, otherIDs); err != nil {
+		return pagination.Page[models.FriendshipResponse]{}, fmt.Errorf("failed to fetch user details: %w", err)
+	}
+
+	page := order.Page(friendships, keyset)
+	return pagination.Page[models.FriendshipResponse]{
+		Items: friendshipResponses(userID, page.Items, others),
+		Next:  page.Next,
+		Prev:  page.Prev,
+	}, nil
+}
+
+// acceptedFriendIDs returns the IDs of every user with an accepted friendship with userID
+func (h *Handler) acceptedFriendIDs(ctx context.Context, userID uint) ([]uint, error) {
+	friendships, err := h.store.Friendships.ListAccepted(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(friendships))
+	for _, friendship := range friendships {
+		if friendship.UserID == userID {
+			ids = append(ids, friendship.FriendID)
+		} else {
+			ids = append(ids, friendship.UserID)
+		}
+	}
+	return ids, nil
+}
+
+// respondFriendshipError maps friendship errors to HTTP responses
+func respondFriendshipError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.Error(apperrors.NotFound("Friend request not found or you're not authorized to respond"))
+	case errors.Is(err, models.ErrFriendRequestToSelf):
+		c.Error(apperrors.Validation("You cannot send a friend request to yourself", nil))
+	case errors.Is(err, models.ErrAlreadyFriends):
+		c.Error(apperrors.Conflict("You are already friends"))
+	case errors.Is(err, models.ErrFriendRequestCooldown):
+		c.Error(apperrors.Conflict("Your friend request was declined recently; try again later"))
+	case errors.Is(err, models.ErrNotFriendRequestTarget),
+		errors.Is(err, models.ErrNotFriendRequestSender),
+		errors.Is(err, models.ErrNotFriendshipParticipant):
+		c.Error(apperrors.Forbidden(err.Error()))
+	case errors.Is(err, models.ErrInvalidFriendshipAction):
+		c.Error(apperrors.Conflict("Friend request is no longer pending"))
+	default:
+		c.Error(apperrors.Internal(fmt.Errorf("%s: %w", fallback, err)))
+	}
+}
@@ -0,0 +1,178 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/handlers"
+	"elo-insight/backend/models"
+)
+
+// nextLink is the URL of the next page in a Link header
+var nextLink = regexp.MustCompile(`<([^>]+)>; rel="next"`)
+
+func friendsAPI(t *testing.T) *testAPI {
+	api := newTestAPI(t)
+	api.route(http.MethodGet, "/friends/", api.h.GetFriends)
+	api.route(http.MethodGet, "/friends/requests", api.h.GetFriendRequests)
+	api.route(http.MethodPost, "/friends/request", api.h.SendFriendRequest)
+	api.route(http.MethodPut, "/friends/request/:id", api.h.RespondToFriendRequest)
+	api.route(http.MethodDelete, "/friends/request/:id", api.h.CancelFriendRequest)
+	api.route(http.MethodDelete, "/friends/:id", api.h.RemoveFriend)
+	return api
+}
+
+func TestFriendRequestFlow(t *testing.T) {
+	api := friendsAPI(t)
+	alice, bob, carol := api.user("alice"), api.user("bob"), api.user("carol")
+
+	// Each step runs on the state the previous ones left
+	steps := []struct {
+		name       string
+		as         models.User
+		method     string
+		path       string
+		body       any
+		wantStatus int
+		wantCode   apperrors.Code // For errors
+		wantState  string         // The friendship's status, for successes
+	}{
+		{"signed out", models.User{}, http.MethodPost, "/friends/request", handlers.NewFriendRequest{FriendID: bob.ID},
+			http.StatusUnauthorized, apperrors.CodeUnauthorized, ""},
+		{"to self", alice, http.MethodPost, "/friends/request", handlers.NewFriendRequest{FriendID: alice.ID},
+			http.StatusBadRequest, apperrors.CodeValidation, ""},
+		{"to unknown user", alice, http.MethodPost, "/friends/request", handlers.NewFriendRequest{FriendID: 999},
+			http.StatusNotFound, apperrors.CodeNotFound, ""},
+		{"missing friend", alice, http.MethodPost, "/friends/request", map[string]any{},
+			http.StatusBadRequest, apperrors.CodeValidation, ""},
+		{"request", alice, http.MethodPost, "/friends/request", handlers.NewFriendRequest{FriendID: bob.ID},
+			http.StatusOK, "", models.FriendshipPending},
+		{"repeated request", alice, http.MethodPost, "/friends/request", handlers.NewFriendRequest{FriendID: bob.ID},
+			http.StatusOK, "", models.FriendshipPending},
+		{"sender accepts", alice, http.MethodPut, "/friends/request/%d", handlers.FriendActionRequest{Action: "accept"},
+			http.StatusForbidden, apperrors.CodeForbidden, ""},
+		{"stranger declines", carol, http.MethodPut, "/friends/request/%d", handlers.FriendActionRequest{Action: "decline"},
+			http.StatusNotFound, apperrors.CodeNotFound, ""},
+		{"unknown action", bob, http.MethodPut, "/friends/request/%d", map[string]string{"action": "block"},
+			http.StatusBadRequest, apperrors.CodeValidation, ""},
+		{"recipient cancels", bob, http.MethodDelete, "/friends/request/%d", nil,
+			http.StatusForbidden, apperrors.CodeForbidden, ""},
+		{"decline", bob, http.MethodPut, "/friends/request/%d", handlers.FriendActionRequest{Action: "reject"},
+			http.StatusOK, "", models.FriendshipDeclined},
+		{"request during cooldown", alice, http.MethodPost, "/friends/request", handlers.NewFriendRequest{FriendID: bob.ID},
+			http.StatusConflict, apperrors.CodeConflict, ""},
+		{"decliner asks instead", bob, http.MethodPost, "/friends/request", handlers.NewFriendRequest{FriendID: alice.ID},
+			http.StatusOK, "", models.FriendshipPending},
+		{"mutual request accepts", alice, http.MethodPost, "/friends/request", handlers.NewFriendRequest{FriendID: bob.ID},
+			http.StatusOK, "", models.FriendshipAccepted},
+		{"request between friends", bob, http.MethodPost, "/friends/request", handlers.NewFriendRequest{FriendID: alice.ID},
+			http.StatusConflict, apperrors.CodeConflict, ""},
+		{"cancel after accept", bob, http.MethodDelete, "/friends/request/%d", nil,
+			http.StatusConflict, apperrors.CodeConflict, ""},
+	}
+
+	var friendshipID uint
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			path := step.path
+			if strings.Contains(path, "%d") {
+				path = fmt.Sprintf(path, friendshipID)
+			}
+			w := api.do(step.as.ID, step.method, path, step.body)
+			if step.wantCode != "" {
+				wantError(t, w, step.wantStatus, step.wantCode)
+				return
+			}
+			body := decode[handlers.FriendRequestResponse](t, w, step.wantStatus)
+			if body.Status != step.wantState {
+				t.Errorf("status = %q, want %q", body.Status, step.wantState)
+			}
+			// There is one row per pair of users, whoever asked
+			if friendshipID != 0 && body.ID != friendshipID {
+				t.Errorf("friendship %d, want %d", body.ID, friendshipID)
+			}
+			friendshipID = body.ID
+		})
+	}
+
+	friends := decode[[]models.FriendshipResponse](t, api.do(alice.ID, http.MethodGet, "/friends/", nil), http.StatusOK)
+	if len(friends) != 1 || friends[0].Username != "bob" || friends[0].Status != models.FriendshipAccepted {
+		t.Fatalf("alice's friends = %+v, want bob", friends)
+	}
+
+	w := api.do(carol.ID, http.MethodDelete, fmt.Sprintf("/friends/%d", friendshipID), nil)
+	wantError(t, w, http.StatusNotFound, apperrors.CodeNotFound)
+	w = api.do(bob.ID, http.MethodDelete, fmt.Sprintf("/friends/%d", friendshipID), nil)
+	decode[handlers.MessageResponse](t, w, http.StatusOK)
+	friends = decode[[]models.FriendshipResponse](t, api.do(alice.ID, http.MethodGet, "/friends/", nil), http.StatusOK)
+	if len(friends) != 0 {
+		t.Errorf("alice's friends after removal = %+v, want none", friends)
+	}
+}
+
+func TestGetFriendsPages(t *testing.T) {
+	api := friendsAPI(t)
+	me := api.user("me")
+	for _, name := range []string{"dave", "bea", "cal", "abe", "eve"} {
+		api.befriend(me, api.user(name))
+	}
+
+	var names []string
+	path := "/friends/?sort=username&limit=2"
+	for pages := 0; path != ""; pages++ {
+		if pages > 3 {
+			t.Fatalf("more than 3 pages; last: %s", path)
+		}
+		w := api.do(me.ID, http.MethodGet, path, nil)
+		page := decode[[]models.FriendshipResponse](t, w, http.StatusOK)
+		if len(page) > 2 {
+			t.Errorf("page of %d, want at most 2", len(page))
+		}
+		for _, friend := range page {
+			names = append(names, friend.Username)
+		}
+
+		path = ""
+		if match := nextLink.FindStringSubmatch(w.Header().Get("Link")); match != nil {
+			path = match[1]
+		}
+	}
+	if want := []string{"abe", "bea", "cal", "dave", "eve"}; fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Errorf("friends = %v, want %v", names, want)
+	}
+
+	w := api.do(me.ID, http.MethodGet, "/friends/?cursor=forged", nil)
+	wantError(t, w, http.StatusBadRequest, apperrors.CodeValidation)
+	w = api.do(me.ID, http.MethodGet, "/friends/?sort=email", nil)
+	wantError(t, w, http.StatusBadRequest, apperrors.CodeValidation)
+}
+
+func TestGetFriendRequestsFilters(t *testing.T) {
+	api := friendsAPI(t)
+	me, fan, idol := api.user("me"), api.user("fan"), api.user("idol")
+	api.do(fan.ID, http.MethodPost, "/friends/request", handlers.NewFriendRequest{FriendID: me.ID})
+	api.do(me.ID, http.MethodPost, "/friends/request", handlers.NewFriendRequest{FriendID: idol.ID})
+
+	tests := []struct {
+		query string
+		want  string // The other user
+	}{
+		{"", "fan"},
+		{"?direction=incoming", "fan"},
+		{"?direction=outgoing", "idol"},
+	}
+	for _, tt := range tests {
+		w := api.do(me.ID, http.MethodGet, "/friends/requests"+tt.query, nil)
+		requests := decode[[]models.FriendshipResponse](t, w, http.StatusOK)
+		if len(requests) != 1 || requests[0].Username != tt.want {
+			t.Errorf("requests%s = %+v, want one with %s", tt.query, requests, tt.want)
+		}
+	}
+
+	w := api.do(me.ID, http.MethodGet, "/friends/requests?status=accepted", nil)
+	wantError(t, w, http.StatusBadRequest, apperrors.CodeValidation)
+}
@@ -1,183 +1,197 @@
 package handlers
 
 import (
-	"log"
-	"net/http"
-	"os"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"net/url"
-	"encoding/json"
+	"strings"
 
-	"elo-insight/backend/database"
+	"elo-insight/backend/apperrors"
 	"elo-insight/backend/models"
+	"elo-insight/backend/store"
+	"elo-insight/backend/telemetry"
+	"elo-insight/backend/upstream"
+	"elo-insight/backend/validation"
 
 	"github.com/gin-gonic/gin"
 )
 
-// LinkXboxAccount links an Xbox Live Gamertag to the user's profile
-func LinkXboxAccount(c *gin.Context) {
-	// Get user ID from the context (set by the auth middleware)
-	userID, exists := c.Get("userID")
-	if !exists {
-		log.Println("ERROR: User ID not found in context")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
+// Riot routing region used to resolve Riot IDs
+const riotAccountRegion = "americas"
 
-	// Parse request body
-	var input struct {
-		XboxID string `json:"xbox_id" binding:"required"`
-	}
+// platformLinker turns the account a user entered into a link for that platform
+type platformLinker func(h *Handler, ctx context.Context, accountID, region string) (*models.PlatformLink, error)
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		log.Println("ERROR: Invalid request data:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
-		return
-	}
+// Platforms that can be linked through POST /link/:platform
+var platformLinkers = map[string]platformLinker{
+	models.PlatformRiot:        (*Handler).resolveRiotLink,
+	models.PlatformEA:          gamertagLinker(models.PlatformEA),
+	models.PlatformXbox:        gamertagLinker(models.PlatformXbox),
+	models.PlatformPlayStation: gamertagLinker(models.PlatformPlayStation),
+}
+
+// Every platform a user can have a link for
+var supportedPlatforms = map[string]bool{
+	models.PlatformSteam:       true,
+	models.PlatformRiot:        true,
+	models.PlatformEA:          true,
+	models.PlatformXbox:        true,
+	models.PlatformPlayStation: true,
+}
 
-	// Validate Xbox ID (basic validation)
-	if len(input.XboxID) < 3 {
-		log.Println("ERROR: Xbox ID too short:", input.XboxID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Xbox ID must be at least 3 characters"})
+// GetPlatformLinks lists the user's linked gaming accounts
+func (h *Handler) GetPlatformLinks(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
-	log.Println("Linking Xbox account:", input.XboxID, "to user ID:", userID)
-
-	// Retrieve user from database
-	var user models.User
-	result := database.DB.First(&user, userID)
-	if result.Error != nil {
-		log.Println("ERROR: User not found:", result.Error)
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	links, err := h.userPlatformLinks(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch platform links: %w", err)))
 		return
 	}
 
-	// Update user with Xbox ID
-	user.XboxID = input.XboxID
-	
-	// Save user
-	if err := database.DB.Save(&user).Error; err != nil {
-		log.Println("ERROR: Failed to save Xbox ID to user:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save Xbox ID"})
-		return
+	response := make([]models.PlatformLinkResponse, 0, len(links))
+	for _, link := range links {
+		response = append(response, link.Response())
 	}
+	c.JSON(http.StatusOK, response)
+}
 
-	log.Println("Xbox account successfully linked to user:", userID)
-	c.JSON(http.StatusOK, gin.H{"message": "Xbox account linked successfully"})
+// LinkPlatformResponse is the body of POST /link/:platform
+type LinkPlatformResponse struct {
+	Message string                      `json:"message"`
+	Link    models.PlatformLinkResponse `json:"link"`
 }
 
-// LinkPlayStationAccount links a PlayStation Network ID to the user's profile
-func LinkPlayStationAccount(c *gin.Context) {
+// LinkPlatform links an account on the platform in the URL to the user's profile
+func (h *Handler) LinkPlatform(c *gin.Context) {
 	// Get user ID from the context (set by the auth middleware)
 	userID, exists := c.Get("userID")
 	if !exists {
-		log.Println("ERROR: User ID not found in context")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
-	// Parse request body
-	var input struct {
-		PlayStationID string `json:"playstation_id" binding:"required"`
+	platform := c.Param("platform")
+	if platform == models.PlatformSteam {
+		c.Error(apperrors.Validation("Steam accounts are linked by signing in through /steam/login", nil))
+		return
 	}
-
-	if err := c.ShouldBindJSON(&input); err != nil {
-		log.Println("ERROR: Invalid request data:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+	linker, ok := platformLinkers[platform]
+	if !ok {
+		c.Error(apperrors.Validation("Unsupported platform", map[string]string{"platform": "is not supported"}))
 		return
 	}
 
-	// Validate PlayStation ID (basic validation)
-	if len(input.PlayStationID) < 3 {
-		log.Println("ERROR: PlayStation ID too short:", input.PlayStationID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "PlayStation ID must be at least 3 characters"})
+	// Parse request body
+	var input LinkPlatformRequest
+	if !bindJSON(c, &input) {
 		return
 	}
-
-	log.Println("Linking PlayStation account:", input.PlayStationID, "to user ID:", userID)
-
-	// Retrieve user from database
-	var user models.User
-	result := database.DB.First(&user, userID)
-	if result.Error != nil {
-		log.Println("ERROR: User not found:", result.Error)
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	accountID := strings.TrimSpace(input.AccountID)
+	if fields := validation.Value("account_id", accountID, accountIDRules[platform]); fields != nil {
+		c.Error(apperrors.Validation("Invalid account ID", fields))
 		return
 	}
 
-	// Update user with PlayStation ID
-	user.PlayStationID = input.PlayStationID
-	
-	// Save user
-	if err := database.DB.Save(&user).Error; err != nil {
-		log.Println("ERROR: Failed to save PlayStation ID to user:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save PlayStation ID"})
+	link, err := linker(h, c.Request.Context(), accountID, input.Region)
+	if err == nil {
+		link.UserID = userID.(uint)
+		err = h.savePlatformLink(c.Request.Context(), link)
+	}
+
+	if err != nil {
+		c.Error(apperrors.From(fmt.Errorf("failed to link %s account: %w", platform, err)))
 		return
 	}
 
-	log.Println("PlayStation account successfully linked to user:", userID)
-	c.JSON(http.StatusOK, gin.H{"message": "PlayStation account linked successfully"})
+	slog.InfoContext(c.Request.Context(), "Platform account linked", "platform", platform, "user_id", userID)
+	c.JSON(http.StatusOK, LinkPlatformResponse{Message: "Account linked successfully", Link: link.Response()})
 }
 
-// LinkRiotAccount links a Riot account (GameName, Tagline, PUUID) to the user's profile
-func LinkRiotAccount(c *gin.Context) {
+// UnlinkPlatform removes the user's link for the platform in the URL
+func (h *Handler) UnlinkPlatform(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
-		log.Println("ERROR: User ID not found in context")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
-	var input struct {
-		RiotGameName string `json:"riot_game_name" binding:"required"`
-		RiotTagline  string `json:"riot_tagline" binding:"required"`
+	platform := c.Param("platform")
+	if !supportedPlatforms[platform] {
+		c.Error(apperrors.Validation("Unsupported platform", map[string]string{"platform": "is not supported"}))
+		return
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		log.Println("ERROR: Invalid request data:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+	err := h.store.PlatformLinks.Delete(c.Request.Context(), userID.(uint), platform)
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(apperrors.NotFound("No linked account for this platform"))
 		return
 	}
-
-	// Fetch user from DB
-	var user models.User
-	result := database.DB.First(&user, userID)
-	if result.Error != nil {
-		log.Println("ERROR: User not found:", result.Error)
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to unlink account: %w", err)))
 		return
 	}
 
+	slog.InfoContext(c.Request.Context(), "Platform account unlinked", "platform", platform, "user_id", userID)
+	telemetry.CountPlatformLink(c.Request.Context(), platform, "unlink")
+	c.JSON(http.StatusOK, MessageResponse{Message: "Account unlinked successfully"})
+}
+
+// gamertagLinker links platforms where we can only store what the user typed,
+// once it has passed the platform's rule in accountIDRules
+func gamertagLinker(platform string) platformLinker {
+	return func(h *Handler, ctx context.Context, accountID, region string) (*models.PlatformLink, error) {
+		return &models.PlatformLink{
+			Platform:     platform,
+			ExternalID:   accountID,
+			DisplayName:  accountID,
+			Region:       region,
+			Verification: models.LinkUnverified,
+		}, nil
+	}
+}
+
+// resolveRiotLink looks up a "GameName#Tagline" Riot ID to find the account's PUUID
+func (h *Handler) resolveRiotLink(ctx context.Context, accountID, region string) (*models.PlatformLink, error) {
+	gameName, tagline, ok := strings.Cut(accountID, "#")
+	if !ok || gameName == "" || tagline == "" {
+		return nil, apperrors.Validation("Riot ID must be in the form GameName#Tagline",
+			map[string]string{"account_id": "must be in the form GameName#Tagline"})
+	}
+
 	// Call Riot API to get PUUID
-	riotAPIKey := os.Getenv("RIOT_API_KEY")
+	riotAPIKey := h.cfg.Riot.APIKey
 	if riotAPIKey == "" {
-		log.Println("ERROR: Riot API key not set in environment")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Riot API key missing"})
-		return
+		return nil, upstreamUnavailable(upstream.Riot, errors.New("Riot API key not configured"))
 	}
 
-	accountURL := fmt.Sprintf("https://americas.api.riotgames.com/riot/account/v1/accounts/by-riot-id/%s/%s", url.PathEscape(input.RiotGameName), url.PathEscape(input.RiotTagline))
-	req, err := http.NewRequest("GET", accountURL, nil)
+	accountURL := fmt.Sprintf("https://%s.api.riotgames.com/riot/account/v1/accounts/by-riot-id/%s/%s",
+		riotAccountRegion, url.PathEscape(gameName), url.PathEscape(tagline))
+	req, err := http.NewRequestWithContext(ctx, "GET", accountURL, nil)
 	if err != nil {
-		log.Println("ERROR: Failed to create Riot API request:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create Riot API request"})
-		return
+		return nil, fmt.Errorf("failed to create Riot API request: %w", err)
 	}
 	req.Header.Set("X-Riot-Token", riotAPIKey)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := upstream.Client.Do(req)
 	if err != nil {
-		log.Println("ERROR: Riot API request failed:", err)
-		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to contact Riot API"})
-		return
+		return nil, fromUpstream(upstream.Riot, err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		log.Printf("ERROR: Riot API returned status %d", resp.StatusCode)
-		c.JSON(http.StatusBadGateway, gin.H{"error": "Riot API error", "status": resp.StatusCode})
-		return
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, apperrors.NotFound("Riot account not found")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, upstreamUnavailable(upstream.Riot, fmt.Errorf("Riot API returned status %d", resp.StatusCode))
 	}
 
 	var riotResp struct {
@@ -186,22 +200,59 @@ func LinkRiotAccount(c *gin.Context) {
 		TagLine  string `json:"tagLine"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&riotResp); err != nil {
-		log.Println("ERROR: Failed to decode Riot API response:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode Riot API response"})
-		return
+		return nil, fmt.Errorf("failed to decode Riot API response: %w", err)
 	}
 
-	// Save to user profile
-	user.RiotGameName = riotResp.GameName
-	user.RiotTagline = riotResp.TagLine
-	user.RiotPUUID = riotResp.PUUID
+	if region == "" {
+		region = riotAccountRegion
+	}
+	return &models.PlatformLink{
+		Platform:     models.PlatformRiot,
+		ExternalID:   riotResp.PUUID,
+		DisplayName:  riotResp.GameName + "#" + riotResp.TagLine,
+		Region:       region,
+		Verification: models.LinkUnverified,
+	}, nil
+}
 
-	if err := database.DB.Save(&user).Error; err != nil {
-		log.Println("ERROR: Failed to save Riot account to user:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save Riot account"})
-		return
+// userPlatformLinks returns all of a user's linked accounts
+func (h *Handler) userPlatformLinks(ctx context.Context, userID uint) ([]models.PlatformLink, error) {
+	return h.store.PlatformLinks.ListByUser(ctx, userID)
+}
+
+// userPlatformLink returns the user's link for a platform, or nil if there isn't one
+func (h *Handler) userPlatformLink(ctx context.Context, userID uint, platform string) (*models.PlatformLink, error) {
+	link, err := h.store.PlatformLinks.Get(ctx, userID, platform)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
 	}
+	return link, err
+}
 
-	log.Printf("Riot account linked: %s#%s (PUUID: %s) for user %v", riotResp.GameName, riotResp.TagLine, riotResp.PUUID, userID)
-	c.JSON(http.StatusOK, gin.H{"message": "Riot account linked successfully"})
+// riotPUUIDsByUser maps each of the users with a linked Riot account to its PUUID
+func (h *Handler) riotPUUIDsByUser(ctx context.Context, userIDs []uint) (map[uint]string, error) {
+	links, err := h.store.PlatformLinks.ListByUsers(ctx, models.PlatformRiot, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	puuids := make(map[uint]string, len(links))
+	for _, link := range links {
+		puuids[link.UserID] = link.ExternalID
+	}
+	return puuids, nil
+}
+
+// savePlatformLink saves a link, turning a clash with another user into a conflict
+func (h *Handler) savePlatformLink(ctx context.Context, link *models.PlatformLink) error {
+	err := h.store.PlatformLinks.Save(ctx, link)
+	if errors.Is(err, store.ErrPlatformAccountTaken) {
+		conflict := apperrors.Conflict("This account is already linked to another user")
+		conflict.Err = err
+		return conflict.WithDetail("platform", link.Platform)
+	}
+	if err == nil {
+		telemetry.CountPlatformLink(ctx, link.Platform, "link")
+	}
+	return err
 }
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"elo-insight/backend/config"
+	"elo-insight/backend/feed"
+	"elo-insight/backend/health"
+	"elo-insight/backend/store"
+)
+
+// Handler serves the API routes using the stores and configuration it was given
+type Handler struct {
+	store  *store.Store
+	feed   *feed.Generator
+	cfg    *config.Config
+	health *health.Checker
+}
+
+// New returns a Handler backed by the given stores, reporting readiness from checker
+func New(s *store.Store, cfg *config.Config, checker *health.Checker) *Handler {
+	return &Handler{
+		store:  s,
+		feed:   feed.NewGenerator(s),
+		cfg:    cfg,
+		health: checker,
+	}
+}
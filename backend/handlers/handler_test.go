@@ -0,0 +1,145 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/config"
+	"elo-insight/backend/handlers"
+	"elo-insight/backend/middleware"
+	"elo-insight/backend/models"
+	"elo-insight/backend/store"
+	"elo-insight/backend/telemetry"
+	"elo-insight/backend/validation"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testUserHeader names the user a test request is signed in as
+const testUserHeader = "X-Test-User"
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	telemetry.Initialize("handlers-test")
+	if err := validation.Register(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// testAPI serves handlers backed by the in-memory store. Routes stand in for
+// RequireAuth by signing in the user named in testUserHeader.
+type testAPI struct {
+	t      *testing.T
+	store  *store.Store
+	h      *handlers.Handler
+	router *gin.Engine
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	t.Setenv("APP_ENV", config.EnvTest)
+	t.Setenv("DATABASE_URL", "sqlite://:memory:")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("validate config: %v", err)
+	}
+	middleware.Init(cfg)
+
+	s := store.NewMemoryStore()
+	r := gin.New()
+	r.Use(middleware.Errors())
+	return &testAPI{t: t, store: s, h: handlers.New(s, cfg, nil), router: r}
+}
+
+// route registers a handler behind the stand-in for RequireAuth
+func (a *testAPI) route(method, path string, handler gin.HandlerFunc) {
+	a.router.Handle(method, path, func(c *gin.Context) {
+		if id, err := strconv.ParseUint(c.GetHeader(testUserHeader), 10, 32); err == nil {
+			c.Set("userID", uint(id))
+		}
+	}, handler)
+}
+
+// user stores a user with the password "password1"
+func (a *testAPI) user(username string) models.User {
+	a.t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("password1"), bcrypt.MinCost)
+	if err != nil {
+		a.t.Fatalf("hash password: %v", err)
+	}
+	user := models.User{Username: username, Email: username + "@example.com", Password: string(hash)}
+	if err := a.store.Users.Create(context.Background(), &user); err != nil {
+		a.t.Fatalf("create user %s: %v", username, err)
+	}
+	return user
+}
+
+// befriend stores an accepted friendship between two users
+func (a *testAPI) befriend(from, to models.User) models.Friendship {
+	a.t.Helper()
+	ctx, now := context.Background(), time.Now()
+	f, err := a.store.Friendships.Request(ctx, from.ID, to.ID, now)
+	if err == nil {
+		f, err = a.store.Friendships.Transition(ctx, f.ID, to.ID, models.FriendshipActionAccept, now)
+	}
+	if err != nil {
+		a.t.Fatalf("befriend %s and %s: %v", from.Username, to.Username, err)
+	}
+	return *f
+}
+
+// do sends a request as the user, or signed out if as is 0, with body encoded as JSON
+func (a *testAPI) do(as uint, method, path string, body any) *httptest.ResponseRecorder {
+	a.t.Helper()
+	var encoded []byte
+	if body != nil {
+		var err error
+		if encoded, err = json.Marshal(body); err != nil {
+			a.t.Fatalf("encode body: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(encoded))
+	req.Header.Set("Content-Type", "application/json")
+	if as != 0 {
+		req.Header.Set(testUserHeader, strconv.FormatUint(uint64(as), 10))
+	}
+	w := httptest.NewRecorder()
+	a.router.ServeHTTP(w, req)
+	return w
+}
+
+// decode reads a JSON response body, failing the test unless it has the wanted status
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder, status int) T {
+	t.Helper()
+	var body T
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d; body: %s", w.Code, status, w.Body)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %T: %v; body: %s", body, err, w.Body)
+	}
+	return body
+}
+
+// wantError checks a response is the error envelope with status and code
+func wantError(t *testing.T, w *httptest.ResponseRecorder, status int, code apperrors.Code) middleware.ErrorResponse {
+	t.Helper()
+	body := decode[middleware.ErrorResponse](t, w, status)
+	if body.Code != code {
+		t.Errorf("code = %q, want %q; body: %s", body.Code, code, w.Body)
+	}
+	return body
+}
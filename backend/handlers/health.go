@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+
+	"elo-insight/backend/upstream"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProviderStatusResponse is one external API's configuration plus what recent calls saw
+type ProviderStatusResponse struct {
+	upstream.ProviderStatus
+	Enabled       bool `json:"enabled"`
+	KeyConfigured bool `json:"keyConfigured"`
+}
+
+// ProviderStatusListResponse is the body of GET /status/providers
+type ProviderStatusListResponse struct {
+	Providers []ProviderStatusResponse `json:"providers"`
+}
+
+// Healthz reports that the process is up. It checks nothing else, so a
+// failing dependency never gets the process restarted.
+func (h *Handler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, StatusResponse{Status: "ok"})
+}
+
+// Readyz checks the database, schema version and trace exporter. It returns
+// 503 when a critical dependency is down so load balancers stop sending traffic.
+func (h *Handler) Readyz(c *gin.Context) {
+	report := h.health.Run(c.Request.Context())
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// GetProviderStatus reports each external API's configuration, last
+// success and failure, rate-limit headroom and circuit-breaker state
+func (h *Handler) GetProviderStatus(c *gin.Context) {
+	configured := map[string]ProviderStatusResponse{
+		upstream.Steam:   {Enabled: h.cfg.Steam.IsEnabled(), KeyConfigured: h.cfg.Steam.APIKey != ""},
+		upstream.Riot:    {Enabled: h.cfg.Riot.IsEnabled(), KeyConfigured: h.cfg.Riot.APIKey != ""},
+		upstream.Tracker: {Enabled: h.cfg.Tracker.IsEnabled(), KeyConfigured: h.cfg.Tracker.APIKey != ""},
+	}
+
+	providers := []ProviderStatusResponse{}
+	for _, status := range upstream.Default.Snapshot() {
+		provider := configured[status.Provider]
+		provider.ProviderStatus = status
+		providers = append(providers, provider)
+	}
+	c.JSON(http.StatusOK, ProviderStatusListResponse{Providers: providers})
+}
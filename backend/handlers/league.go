@@ -1,16 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
-	"os"
+	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/cache"
+	"elo-insight/backend/models"
+	"elo-insight/backend/upstream"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -22,91 +29,65 @@ const (
 )
 
 // GetLeagueOfLegendsStats fetches LoL stats using the Riot API
-func GetLeagueOfLegendsStats(c *gin.Context) {
-	log.Println("➡️ Received request for League of Legends stats")
-
+func (h *Handler) GetLeagueOfLegendsStats(c *gin.Context) {
 	// Check for ALL possible Riot identifiers in the query parameters
-	riotID := c.Query("riot_id")
-	riotGameName := c.Query("riot_game_name")
-	riotTagline := c.Query("riot_tagline")
-	riotPUUID := c.Query("riot_puuid")
+	var query LeagueStatsQuery
+	if !bindQuery(c, &query) {
+		return
+	}
+
+	// If no identifiers are provided, the authenticated user's are used
+	userID, _ := c.Get("userID")
+	signedIn, _ := userID.(uint)
+
+	stats, err := h.LeagueStats(c.Request.Context(), query, signedIn)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// LeagueStats returns the League stats of the player query selects. With no
+// identifiers it uses the Riot account linked to userID, or fails if userID is 0.
+func (h *Handler) LeagueStats(ctx context.Context, query LeagueStatsQuery, userID uint) (*LeagueStatsResponse, error) {
+	riotID := query.RiotID
+	riotGameName := query.RiotGameName
+	riotTagline := query.RiotTagline
+	riotPUUID := query.RiotPUUID
 	
-	log.Printf("Riot identifiers from query: ID=%s, GameName=%s, Tagline=%s, PUUID=%s",
-		riotID, riotGameName, riotTagline, riotPUUID)
+	slog.DebugContext(ctx, "League stats requested",
+		"has_riot_id", riotID != "", "has_name_and_tag", riotGameName != "" && riotTagline != "", "has_puuid", riotPUUID != "")
 	
 	// Check if we have at least one valid identifier
 	hasValidIdentifier := riotID != "" || (riotGameName != "" && riotTagline != "") || riotPUUID != ""
 	
 	if !hasValidIdentifier {
 		// If no identifiers provided, try to get from the authenticated user
-		userIDValue, exists := c.Get("userID")
-		if !exists {
-			log.Println("ERROR: User ID not found in context and no Riot identifiers provided")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Riot account information is required"})
-			return
-		}
-		
-		// Convert and log the user ID that we would use to query the database
-		userID, ok := userIDValue.(uint)
-		if !ok {
-			log.Printf("ERROR: User ID is not of the expected type: %v", userIDValue)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID"})
-			return
+		if userID == 0 {
+			return nil, apperrors.Validation("Riot account information is required", map[string]string{"riot_id": "required"})
 		}
 		
-		// Log that we would query the database with this user ID
-		log.Printf("Would query database for Riot info with user ID: %d", userID)
-
-		// Check if user has a linked Riot account - expanded struct to include all fields
-		var user struct {
-			RiotID       string `json:"riot_id"`
-			RiotGameName string `json:"riot_game_name"`
-			RiotTagline  string `json:"riot_tagline"`
-			RiotPUUID    string `json:"riot_puuid"`
+		// Check if user has a linked Riot account
+		link, err := h.userPlatformLink(ctx, userID, models.PlatformRiot)
+		if err != nil {
+			return nil, apperrors.Internal(fmt.Errorf("failed to retrieve Riot link: %w", err))
 		}
-
-		// In a real implementation, we'd query the database for the user's Riot info
-		// For example:
-		// if err := database.DB.Where("id = ?", userID).First(&user).Error; err != nil {
-		//     log.Printf("ERROR: Failed to retrieve user: %v", err)
-		//     c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
-		//     return
-		// }
-		
-		// For testing only - simulate empty values
-		user.RiotID = ""
-		user.RiotGameName = ""
-		user.RiotTagline = ""
-		user.RiotPUUID = ""
-
-		// Check if any Riot identifiers are available
-		hasAnyIdentifier := user.RiotID != "" || 
-			(user.RiotGameName != "" && user.RiotTagline != "") || 
-			user.RiotPUUID != ""
-
-		if !hasAnyIdentifier {
-			log.Println("ERROR: User has no linked Riot account information")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Riot account not linked"})
-			return
+		if link == nil {
+			return nil, notLinked(models.PlatformRiot)
 		}
 
 		// Set any available identifiers
-		riotID = user.RiotID
-		riotGameName = user.RiotGameName
-		riotTagline = user.RiotTagline
-		riotPUUID = user.RiotPUUID
+		riotGameName, riotTagline = link.RiotNameAndTag()
+		riotPUUID = link.ExternalID
 	}
 
-	// Log all the Riot identifiers we'll be using
-	log.Printf("Fetching League of Legends stats using identifiers: ID=%s, GameName=%s, Tagline=%s, PUUID=%s",
-		riotID, riotGameName, riotTagline, riotPUUID)
+	slog.InfoContext(ctx, "Fetching League of Legends stats", "user_id", userID, "by_puuid", riotPUUID != "")
 
-	// Get the Riot API key from environment variables
-	riotAPIKey := os.Getenv("RIOT_API_KEY")
+	// Get the Riot API key from the configuration
+	riotAPIKey := h.cfg.Riot.APIKey
 	if riotAPIKey == "" {
-		log.Println("ERROR: Riot API key not set")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Riot API key not configured"})
-		return
+		return nil, upstreamUnavailable(upstream.Riot, errors.New("Riot API key not configured"))
 	}
 
 	// Validate API key (remove 'RGAPI-' prefix for simpler validation)
@@ -116,133 +97,96 @@ func GetLeagueOfLegendsStats(c *gin.Context) {
 	}
 
 	if len(APIKeyWithoutPrefix) != 36 { // UUID is typically 36 chars
-		log.Println("ERROR: Riot API key appears to be invalid format")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid Riot API key format"})
-		return
+		return nil, upstreamUnavailable(upstream.Riot, errors.New("Riot API key has an invalid format"))
 	}
 
-	log.Printf("Using Riot API key: %s...[REDACTED]", riotAPIKey[:10])
-
 	// Prioritize using PUUID if available, otherwise fall back to riot_id
 	var summoner *Summoner
 	var err error
 	
 	// Check if PUUID is available first
 	if riotPUUID != "" {
-		log.Printf("Using saved PUUID directly: %s", riotPUUID)
-		// Create a direct request to get summoner by PUUID
-		summonerURL := fmt.Sprintf("%s/lol/summoner/v4/summoners/by-puuid/%s", RiotAPIBaseURL, riotPUUID)
-		
-		client := &http.Client{}
-		req, err := http.NewRequest("GET", summonerURL, nil)
+		summoner, err = getSummonerByPUUID(ctx, riotPUUID, riotAPIKey)
 		if err != nil {
-			log.Printf("ERROR: Failed to create request: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API request"})
-			return
+			return nil, fromUpstream(upstream.Riot, err)
 		}
-		
-		req.Header.Add("X-Riot-Token", riotAPIKey)
-		
-		resp, err := client.Do(req)
-		if err != nil {
-			log.Printf("ERROR: Failed to send request: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to communicate with Riot API"})
-			return
-		}
-		defer resp.Body.Close()
-		
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			log.Printf("ERROR: API returned status %d: %s", resp.StatusCode, string(body))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch summoner data from Riot API"})
-			return
-		}
-		
-		summoner = &Summoner{}
-		if err := json.NewDecoder(resp.Body).Decode(summoner); err != nil {
-			log.Printf("ERROR: Failed to decode response: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse API response"})
-			return
-		}
-		
-		log.Printf("Successfully fetched summoner data using PUUID: %s", summoner.Name)
 	} else if riotID != "" {
 		// Fall back to using riot_id if no PUUID is available
-		log.Printf("No PUUID available, falling back to riot_id: %s", riotID)
-		summoner, err = getSummonerByName(riotID, riotAPIKey)
+		slog.DebugContext(ctx, "No PUUID available, looking the player up by Riot ID")
+		summoner, err = getSummonerByName(ctx, riotID, riotAPIKey)
 		if err != nil {
-			log.Printf("ERROR: Failed to get summoner by name: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch summoner data from Riot API"})
-			return
+			return nil, fromUpstream(upstream.Riot, err)
 		}
 	} else {
 		// No valid identifiers at all
-		log.Println("ERROR: No Riot PUUID or ID available")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Riot account information is required"})
-		return
+		return nil, apperrors.Validation("Riot account information is required", map[string]string{"riot_id": "required"})
 	}
 	
 	// Handle any errors from the summoner lookup
 	if err != nil {
-		log.Printf("ERROR: Failed to get summoner: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch summoner data from Riot API"})
-		return
+		return nil, fromUpstream(upstream.Riot, err)
 	}
-	log.Printf("Found summoner: %s (PUUID: %s)", summoner.Name, summoner.PUUID)
+	slog.DebugContext(ctx, "Found summoner", "summoner_level", summoner.SummonerLevel)
 
 	// Get champion mastery data
-	log.Println("Fetching champion mastery data")
-	championMasteries, err := getChampionMasteries(summoner.ID, riotAPIKey)
+	championMasteries, err := getChampionMasteries(ctx, summoner.ID, riotAPIKey)
 	if err != nil {
-		log.Printf("Warning: Failed to get champion mastery data: %v", err)
+		slog.WarnContext(ctx, "Failed to get champion mastery data", "error", err)
 		// Continue without mastery data - not a critical failure
 	}
 
 	// Step 2: Get ranked data for the summoner using multiple endpoints
-	rankedData, err := getRankedData(summoner.ID, riotAPIKey)
+	rankedData, err := getRankedData(ctx, summoner.ID, riotAPIKey)
 	if err != nil {
-		log.Printf("WARNING: Failed to get ranked data via summoner ID, trying PUUID: %v", err)
+		slog.WarnContext(ctx, "Failed to get ranked data by summoner ID, trying PUUID", "error", err)
 		// Try alternative endpoint using PUUID
-		rankedData, err = getRankedDataByPUUID(summoner.PUUID, riotAPIKey)
+		rankedData, err = getRankedDataByPUUID(ctx, summoner.PUUID, riotAPIKey)
 		if err != nil {
-			log.Printf("WARNING: Failed to get ranked data, proceeding anyway: %v", err)
+			slog.WarnContext(ctx, "Failed to get ranked data, proceeding without it", "error", err)
 			// Continue even if we can't get ranked data - we'll focus on other stats
 			rankedData = []RankedEntry{}
 		}
 	}
 
+	// Snapshot ranks for linked users so promotions show up in the activity feed
+	h.recordLeagueRanks(ctx, summoner.PUUID, rankedData)
+
 	// Step 3: Get match history
-	matchIDs, err := getMatchHistory(summoner.PUUID, riotAPIKey)
+	matchIDs, err := getMatchHistory(ctx, summoner.PUUID, riotAPIKey)
 	if err != nil {
-		log.Printf("ERROR: Failed to get match history: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get match history: %v", err)})
-		return
+		return nil, fromUpstream(upstream.Riot, err)
 	}
 
 	// Check for empty match history
 	if len(matchIDs) == 0 {
-		log.Println("WARNING: No matches found for summoner")
-		c.JSON(http.StatusOK, gin.H{
-			"summoner": summoner,
-			"ranked":   rankedData,
-			"matches":  nil,
-			"message":  "No recent matches found",
-		})
-		return
+		slog.InfoContext(ctx, "No recent League matches found")
+		return &LeagueStatsResponse{
+			Summoner:  summoner,
+			Ranked:    rankedData,
+			Champions: []ChampionStats{},
+			Message:   "No recent matches found",
+		}, nil
 	}
 
+	// Download the matches once for both the aggregate and per-champion stats
+	if len(matchIDs) > leagueChampionMatches {
+		matchIDs = matchIDs[:leagueChampionMatches]
+	}
+	fetched := fetchMatches(ctx, matchIDs, h.cfg.Riot.MatchConcurrency, leagueMatchFetcher(riotAPIKey))
+
+	// Store the matches so the activity feed can pick them up
+	h.recordLeagueMatches(ctx, fetched.Matches)
+
 	// Step 4: Process match data to calculate statistics with enhanced KDA calculation
-	matchStats, err := processMatches(summoner.PUUID, matchIDs, riotAPIKey)
+	matchStats, err := processMatches(ctx, summoner.PUUID, fetched.within(leagueAggregateMatches))
 	if err != nil {
-		log.Printf("ERROR: Failed to process matches: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to process match data: %v", err)})
-		return
+		return nil, upstreamUnavailable(upstream.Riot, err).WithDetail("warnings", fetched.Warnings)
 	}
 
 	// Step 5: Get champion-specific stats like win rates and KDA per champion
-	championStats, err := calculateChampionStats(summoner.PUUID, matchIDs, riotAPIKey)
+	championStats, err := calculateChampionStats(ctx, summoner.PUUID, fetched.Matches)
 	if err != nil {
-		log.Printf("WARNING: Failed to calculate champion-specific stats: %v", err)
+		slog.WarnContext(ctx, "Failed to calculate champion stats", "error", err)
 		// Continue even without champion stats
 	} else {
 		// Add the champion stats to the match stats
@@ -288,13 +232,31 @@ func GetLeagueOfLegendsStats(c *gin.Context) {
 		}
 	}
 	
-	// Return response with all data
-	c.JSON(http.StatusOK, gin.H{
-		"summoner":  summoner,
-		"ranked":    rankedData,
-		"matches":   matchStats,
-		"champions": championMasteryWithNames,
-	})
+	// Return response with all data; warnings list matches left out of the stats
+	return &LeagueStatsResponse{
+		Summoner:  summoner,
+		Ranked:    rankedData,
+		Matches:   matchStats,
+		Champions: championMasteryWithNames,
+		Warnings:  fetched.Warnings,
+	}, nil
+}
+
+// How many recent matches the League stats use. Champion stats look at more
+// games than the aggregate; both come from one download.
+const (
+	leagueAggregateMatches = 10
+	leagueChampionMatches  = 20
+)
+
+// LeagueStatsResponse is the body of GET /api/v1/stats/lol
+type LeagueStatsResponse struct {
+	Summoner  *Summoner       `json:"summoner"`
+	Ranked    []RankedEntry   `json:"ranked"`
+	Matches   *MatchStats     `json:"matches"` // Null when there are no recent matches
+	Champions []ChampionStats `json:"champions"`
+	Warnings  []string        `json:"warnings,omitempty"` // Matches left out of the stats
+	Message   string          `json:"message,omitempty"`
 }
 
 // Summoner represents a League of Legends player
@@ -337,7 +299,7 @@ type MatchStats struct {
 	AverageVision     float64         `json:"averageVision,averageVisionScore"`
 	AverageDamage     float64         `json:"averageDamage"`                           // Average damage per game
 	WinRate           float64         `json:"winRate"`                                 // Win percentage
-	KillParticipation float64         `json:"killParticipation"`                       // Kill participation percentage
+	KillParticipation float64         `json:"killParticipation"`                       // Kill store.Participation percentage
 	ObjectiveControl  float64         `json:"objectiveControl,objectiveParticipation"` // Objective control score
 	TopChampions      []ChampionStats `json:"topChampions"`
 	RecentMatches     []MatchDetails  `json:"recentMatches"`
@@ -414,52 +376,23 @@ type ChampionMastery struct {
 }
 
 // getChampionMasteries retrieves champion mastery data for a summoner using summoner ID
-func getChampionMasteries(summonerID string, apiKey string) ([]ChampionMastery, error) {
-	log.Println("Fetching champion mastery data for summoner ID:", summonerID)
-	
-	// Construct the champion mastery API URL
+func getChampionMasteries(ctx context.Context, summonerID string, apiKey string) ([]ChampionMastery, error) {
 	url := fmt.Sprintf("%s/lol/champion-mastery/v4/champion-masteries/by-summoner/%s", RiotAPIBaseURL, summonerID)
-	
-	// Create HTTP request with Riot API key
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
-	}
-	req.Header.Set("X-Riot-Token", apiKey)
-	
-	// Execute request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %v", err)
-	}
-	defer resp.Body.Close()
-	
-	// Check response status code
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Champion mastery API error (status %d): %s", resp.StatusCode, string(body))
-	}
-	
-	// Parse response
+	key := cache.Key{Provider: upstream.Riot, Endpoint: endpointLoLMastery, Identity: summonerID}
+
 	var masteries []ChampionMastery
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response: %v", err)
+	if err := fetchCachedJSON(ctx, key, url, riotHeader(apiKey), &masteries); err != nil {
+		return nil, fmt.Errorf("champion mastery API error: %w", err)
 	}
-	
-	if err := json.Unmarshal(body, &masteries); err != nil {
-		return nil, fmt.Errorf("error unmarshaling mastery response: %v", err)
-	}
-	
-	log.Printf("Retrieved %d champion masteries for summoner ID %s", len(masteries), summonerID)
-	
+
+	slog.DebugContext(ctx, "Retrieved champion masteries", "count", len(masteries))
+
 	return masteries, nil
 }
 
 // getSummonerByName retrieves summoner data using the Riot API
 // This uses the modern two-step approach: first get PUUID, then get summoner
-func getSummonerByName(riotID string, apiKey string) (*Summoner, error) {
+func getSummonerByName(ctx context.Context, riotID string, apiKey string) (*Summoner, error) {
 	// Step 1: Parse the Riot ID into gameName and tagLine
 	gameName := riotID
 	tagLine := "NA1" // Default region tag
@@ -469,138 +402,82 @@ func getSummonerByName(riotID string, apiKey string) (*Summoner, error) {
 		tagLine = riotID[idx+1:]
 	}
 
-	log.Printf("Looking up Riot account for %s#%s", gameName, tagLine)
-
 	// Step 2: Use the Account V1 API to get the PUUID
-	accountURL := fmt.Sprintf("https://americas.api.riotgames.com/riot/account/v1/accounts/by-riot-id/%s/%s",
-		gameName, tagLine)
-
-	req, err := http.NewRequest("GET", accountURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Add("X-Riot-Token", apiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	riotAccount, err := getRiotAccount(ctx, gameName, tagLine, apiKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Account API returned status code %d: %s", resp.StatusCode, string(body))
-	}
-
-	var riotAccount RiotAccount
-	if err := json.NewDecoder(resp.Body).Decode(&riotAccount); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
-	}
-
-	log.Printf("Found Riot account with PUUID: %s", riotAccount.PUUID)
 
 	// Step 3: Use the PUUID to get the summoner data
-	summonerURL := fmt.Sprintf("%s/lol/summoner/v4/summoners/by-puuid/%s", RiotAPIBaseURL, riotAccount.PUUID)
-
-	req, err = http.NewRequest("GET", summonerURL, nil)
+	summoner, err := getSummonerByPUUID(ctx, riotAccount.PUUID, apiKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Add("X-Riot-Token", apiKey)
-
-	resp, err = client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Summoner API returned status code %d: %s", resp.StatusCode, string(body))
-	}
-
-	var summoner Summoner
-	if err := json.NewDecoder(resp.Body).Decode(&summoner); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+		return nil, err
 	}
 
 	// Set the name field to use the gameName from the Riot account
 	// This ensures the display name is correctly shown in the UI
 	if summoner.Name == "" {
 		summoner.Name = riotAccount.GameName
-		log.Printf("Using gameName '%s' for summoner display name", riotAccount.GameName)
 	}
 
-	return &summoner, nil
+	return summoner, nil
 }
 
-// getRankedData retrieves ranked queue data for a summoner using summoner ID
-func getRankedData(summonerID string, apiKey string) ([]RankedEntry, error) {
-	url := fmt.Sprintf("%s/lol/league/v4/entries/by-summoner/%s", RiotAPIBaseURL, summonerID)
+// getRiotAccount looks up a Riot account by game name and tag line
+func getRiotAccount(ctx context.Context, gameName, tagLine, apiKey string) (*RiotAccount, error) {
+	accountURL := fmt.Sprintf("https://americas.api.riotgames.com/riot/account/v1/accounts/by-riot-id/%s/%s",
+		url.PathEscape(gameName), url.PathEscape(tagLine))
+	// Riot IDs are case-insensitive
+	key := cache.Key{Provider: upstream.Riot, Endpoint: endpointRiotAccount, Identity: strings.ToLower(gameName + "#" + tagLine)}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+	var riotAccount RiotAccount
+	if err := fetchCachedJSON(ctx, key, accountURL, riotHeader(apiKey), &riotAccount); err != nil {
+		return nil, fmt.Errorf("account API error: %w", err)
 	}
+	return &riotAccount, nil
+}
 
-	req.Header.Add("X-Riot-Token", apiKey)
+// getSummonerByPUUID retrieves summoner data for a Riot account
+func getSummonerByPUUID(ctx context.Context, puuid string, apiKey string) (*Summoner, error) {
+	summonerURL := fmt.Sprintf("%s/lol/summoner/v4/summoners/by-puuid/%s", RiotAPIBaseURL, puuid)
+	key := cache.Key{Provider: upstream.Riot, Endpoint: endpointLoLSummoner, Identity: puuid}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
+	var summoner Summoner
+	if err := fetchCachedJSON(ctx, key, summonerURL, riotHeader(apiKey), &summoner); err != nil {
+		return nil, fmt.Errorf("summoner API error: %w", err)
 	}
-	defer resp.Body.Close()
+	return &summoner, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status code %d", resp.StatusCode)
-	}
+// getRankedData retrieves ranked queue data for a summoner using summoner ID
+func getRankedData(ctx context.Context, summonerID string, apiKey string) ([]RankedEntry, error) {
+	url := fmt.Sprintf("%s/lol/league/v4/entries/by-summoner/%s", RiotAPIBaseURL, summonerID)
+	key := cache.Key{Provider: upstream.Riot, Endpoint: endpointLoLRanked, Identity: summonerID}
 
 	var rankedEntries []RankedEntry
-	if err := json.NewDecoder(resp.Body).Decode(&rankedEntries); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+	if err := fetchCachedJSON(ctx, key, url, riotHeader(apiKey), &rankedEntries); err != nil {
+		return nil, err
 	}
 
 	return rankedEntries, nil
 }
 
 // getRankedDataByPUUID retrieves ranked queue data for a summoner using PUUID
-func getRankedDataByPUUID(puuid string, apiKey string) ([]RankedEntry, error) {
+func getRankedDataByPUUID(ctx context.Context, puuid string, apiKey string) ([]RankedEntry, error) {
 	url := fmt.Sprintf("%s/lol/league/v4/entries/by-puuid/%s", RiotAPIBaseURL, puuid)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Add("X-Riot-Token", apiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status code %d: %s", resp.StatusCode, string(body))
-	}
+	key := cache.Key{Provider: upstream.Riot, Endpoint: endpointLoLRankedByPUUID, Identity: puuid}
 
 	var rankedEntries []RankedEntry
-	if err := json.NewDecoder(resp.Body).Decode(&rankedEntries); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+	if err := fetchCachedJSON(ctx, key, url, riotHeader(apiKey), &rankedEntries); err != nil {
+		return nil, err
 	}
 
 	return rankedEntries, nil
 }
 
 // calculateChampionStats calculates statistics per champion from match data
-func calculateChampionStats(puuid string, matchIDs []string, apiKey string) ([]ChampionStats, error) {
-	log.Printf("Calculating champion stats for PUUID: %s from %d matches", puuid, len(matchIDs))
+func calculateChampionStats(ctx context.Context, puuid string, matches []fetchedMatch) ([]ChampionStats, error) {
+	slog.DebugContext(ctx, "Calculating champion stats", "matches", len(matches))
 
 	// Map to track stats per champion
 	champStats := make(map[int]*struct {
@@ -616,21 +493,14 @@ func calculateChampionStats(puuid string, matchIDs []string, apiKey string) ([]C
 	})
 
 	// Process each match to extract champion data
-	processedMatches := 0
-	for _, matchID := range matchIDs {
-		if processedMatches >= 20 { // Limit to last 20 matches
-			break
-		}
-
-		// Get match details
-		match, err := getMatchDetails(matchID, apiKey)
-		if err != nil {
-			log.Printf("WARNING: Failed to get match details for %s: %v", matchID, err)
+	for _, fetched := range matches {
+		match := &MatchDetailResponse{}
+		if err := json.Unmarshal(fetched.Body, match); err != nil {
+			slog.WarnContext(ctx, "Failed to decode match details", "match_id", fetched.ID, "error", err)
 			continue
 		}
 
 		// Find player in participants
-		var found bool
 		for _, p := range match.Info.Participants {
 			if p.PUUID == puuid {
 				championID := p.ChampionID
@@ -676,14 +546,9 @@ func calculateChampionStats(puuid string, matchIDs []string, apiKey string) ([]C
 					stats.Losses++
 				}
 
-				found = true
 				break
 			}
 		}
-
-		if found {
-			processedMatches++
-		}
 	}
 
 	// Convert map to sorted slice
@@ -766,6 +631,7 @@ type Participant struct {
 	SummonerLevel               int    `json:"summonerLevel"`
 	SummonerName                string `json:"summonerName"`
 	TeamID                      int    `json:"teamId"`
+	TeamPosition                string `json:"teamPosition"`
 	TotalDamageDealtToChampions int    `json:"totalDamageDealtToChampions"`
 	TotalMinionsKilled          int    `json:"totalMinionsKilled"`
 	TurretKills                 int    `json:"turretKills"`
@@ -773,78 +639,104 @@ type Participant struct {
 	Win                         bool   `json:"win"`
 }
 
-// getMatchDetails retrieves detailed information about a match
-func getMatchDetails(matchID string, apiKey string) (*MatchDetailResponse, error) {
-	url := fmt.Sprintf("%s/lol/match/v5/matches/%s", MatchV5BaseURL, matchID)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+// recordLeagueMatches stores fetched matches for the activity feed, logging any failure
+func (h *Handler) recordLeagueMatches(ctx context.Context, matches []fetchedMatch) {
+	stored := make([]models.StoredMatch, 0, len(matches))
+	for _, fetched := range matches {
+		match := &MatchDetailResponse{}
+		if err := json.Unmarshal(fetched.Body, match); err != nil {
+			slog.WarnContext(ctx, "Failed to decode match details", "match_id", fetched.ID, "error", err)
+			continue
+		}
+		stored = append(stored, storedLeagueMatch(match))
 	}
 
-	req.Header.Add("X-Riot-Token", apiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
+	if err := h.feed.RecordMatches(ctx, stored); err != nil {
+		slog.WarnContext(ctx, "Failed to record matches", "game", "lol", "error", err)
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Match API returned status code %d: %s", resp.StatusCode, string(body))
+// storedLeagueMatch turns a match-v5 match into the form the activity feed stores
+func storedLeagueMatch(match *MatchDetailResponse) models.StoredMatch {
+	stored := models.StoredMatch{
+		Game:     "lol",
+		MatchID:  match.Metadata.MatchID,
+		QueueID:  strconv.Itoa(match.Info.QueueID),
+		PlayedAt: time.UnixMilli(match.Info.GameCreation),
+		Duration: match.Info.GameDuration,
+	}
+	for _, p := range match.Info.Participants {
+		// teamPosition is the most reliable role field; lane is a fallback for older matches
+		role := p.TeamPosition
+		if role == "" || role == "NONE" {
+			role = p.Lane
+		}
+		stored.Participants = append(stored.Participants, models.MatchParticipant{
+			PUUID:      p.PUUID,
+			TeamID:     strconv.Itoa(p.TeamID),
+			Character:  p.ChampionName,
+			Role:       role,
+			Win:        p.Win,
+			Kills:      p.Kills,
+			Deaths:     p.Deaths,
+			Assists:    p.Assists,
+			PentaKills: p.PentaKills,
+		})
 	}
+	return stored
+}
 
-	var matchDetail MatchDetailResponse
-	if err := json.NewDecoder(resp.Body).Decode(&matchDetail); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+// recordLeagueRanks snapshots ranked entries when the PUUID belongs to one of our users
+func (h *Handler) recordLeagueRanks(ctx context.Context, puuid string, entries []RankedEntry) {
+	if puuid == "" || len(entries) == 0 {
+		return
 	}
 
-	return &matchDetail, nil
+	links, err := h.store.PlatformLinks.FindByExternalIDs(ctx, models.PlatformRiot, []string{puuid})
+	if err != nil || len(links) == 0 {
+		// Not a linked user, nothing to record
+		return
+	}
+	link := links[0]
+
+	for _, entry := range entries {
+		snapshot := models.RankSnapshot{
+			UserID:       link.UserID,
+			Game:         "lol",
+			Queue:        entry.QueueType,
+			Tier:         entry.Tier,
+			Division:     entry.Rank,
+			LeaguePoints: entry.LeaguePoints,
+		}
+		if err := h.feed.RecordRankSnapshot(ctx, &snapshot); err != nil {
+			slog.WarnContext(ctx, "Failed to record rank snapshot", "user_id", link.UserID, "queue", entry.QueueType, "error", err)
+		}
+	}
 }
 
 // getMatchHistory retrieves match IDs for a player
-func getMatchHistory(puuid string, apiKey string) ([]string, error) {
+func getMatchHistory(ctx context.Context, puuid string, apiKey string) ([]string, error) {
 	// Get last 25 matches
 	url := fmt.Sprintf("%s/lol/match/v5/matches/by-puuid/%s/ids?count=25", MatchV5BaseURL, puuid)
-	log.Printf("Getting match history from: %s", url)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Add("X-Riot-Token", apiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Match API returned status code %d: %s", resp.StatusCode, string(bodyBytes))
-	}
+	key := cache.Key{Provider: upstream.Riot, Endpoint: endpointLoLMatchIDs, Identity: puuid}
+	slog.DebugContext(ctx, "Getting League match history")
 
 	var matchIDs []string
-	if err := json.NewDecoder(resp.Body).Decode(&matchIDs); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+	if err := fetchCachedJSON(ctx, key, url, riotHeader(apiKey), &matchIDs); err != nil {
+		return nil, fmt.Errorf("match API error: %w", err)
 	}
 
-	log.Printf("Found %d matches for PUUID %s", len(matchIDs), puuid)
+	slog.DebugContext(ctx, "Found League matches", "count", len(matchIDs))
 	return matchIDs, nil
 }
 
 // processMatches processes match data to calculate statistics
-func processMatches(puuid string, matchIDs []string, apiKey string) (*MatchStats, error) {
-	if len(matchIDs) == 0 {
-		return nil, fmt.Errorf("no matches found for player")
+func processMatches(ctx context.Context, puuid string, matches []fetchedMatch) (*MatchStats, error) {
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no matches could be loaded for player")
 	}
 
-	log.Printf("Processing %d matches for PUUID: %s", len(matchIDs), puuid)
+	slog.DebugContext(ctx, "Processing League matches", "count", len(matches))
 
 	// Initialize statistics
 	stats := &MatchStats{
@@ -875,47 +767,15 @@ func processMatches(puuid string, matchIDs []string, apiKey string) (*MatchStats
 	totalCS := 0
 	totalVision := 0
 	totalDamage := 0
-	totalTeamKills := 0 // For kill participation calculation
+	totalTeamKills := 0 // For kill store.Participation calculation
 	totalObjectiveScore := 0
 	totalGames := 0
 
-	// Process each match (limit to 10 for API call efficiency)
-	maxMatches := 10
-	if len(matchIDs) < maxMatches {
-		maxMatches = len(matchIDs)
-	}
-
 	// Track which game modes we've seen
 	seenGameModes := make(map[string]int)
 
-	for i := 0; i < maxMatches; i++ {
-		totalGames++
-		matchID := matchIDs[i]
-
-		// Get detailed match data from the Riot API
-		url := fmt.Sprintf("%s/lol/match/v5/matches/%s", MatchV5BaseURL, matchID)
-
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			log.Printf("Error creating match request: %v", err)
-			continue
-		}
-
-		req.Header.Add("X-Riot-Token", apiKey)
-
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			log.Printf("Error fetching match data: %v", err)
-			continue
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			log.Printf("API error for match %s: %d %s", matchID, resp.StatusCode, string(body))
-			continue
-		}
+	for _, fetched := range matches {
+		matchID := fetched.ID
 
 		// Decode the match data
 		var match struct {
@@ -970,10 +830,11 @@ func processMatches(puuid string, matchIDs []string, apiKey string) (*MatchStats
 			} `json:"info"`
 		}
 
-		if err := json.NewDecoder(resp.Body).Decode(&match); err != nil {
-			log.Printf("Error decoding match data: %v", err)
+		if err := json.Unmarshal(fetched.Body, &match); err != nil {
+			slog.WarnContext(ctx, "Failed to decode match data", "match_id", matchID, "error", err)
 			continue
 		}
+		totalGames++
 
 		// Track game modes seen
 		seenGameModes[match.Info.GameMode]++
@@ -1053,7 +914,7 @@ func processMatches(puuid string, matchIDs []string, apiKey string) (*MatchStats
 		}
 
 		if playerData == nil {
-			log.Printf("Player not found in match %s", matchID)
+			slog.WarnContext(ctx, "Player not found in match", "game", "lol", "match_id", matchID)
 			continue
 		}
 
@@ -1196,7 +1057,7 @@ func processMatches(puuid string, matchIDs []string, apiKey string) (*MatchStats
 	stats.QuickPlayStats.Wins = stats.Wins
 	stats.QuickPlayStats.WinRate = stats.WinRate
 
-	// Kill participation
+	// Kill store.Participation
 	if totalTeamKills > 0 {
 		stats.KillParticipation = float64(totalKills+totalAssists) / float64(totalTeamKills) * 100
 	}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/models"
+	"elo-insight/backend/pagination"
+	"elo-insight/backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MatchHistoryEntry is one of the user's stored matches
+type MatchHistoryEntry struct {
+	ID        uint      `json:"id"`       // The stored match
+	MatchID   string    `json:"match_id"` // Upstream match ID
+	Game      string    `json:"game"`
+	PlayedAt  time.Time `json:"played_at"`
+	Character string    `json:"character"`
+	Role      string    `json:"role"`
+	Win       bool      `json:"win"`
+	Kills     int       `json:"kills"`
+	Deaths    int       `json:"deaths"`
+	Assists   int       `json:"assists"`
+	KDA       float64   `json:"kda"`
+}
+
+// GetMatchHistory returns a page of the matches stored for the user's linked
+// Riot account, newest first unless sorted otherwise
+func (h *Handler) GetMatchHistory(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var query MatchHistoryQuery
+	if !bindQuery(c, &query) {
+		return
+	}
+	if query.Sort == "" {
+		query.Sort = "-played_at"
+	}
+
+	link, err := h.store.PlatformLinks.Get(c.Request.Context(), userID.(uint), models.PlatformRiot)
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(notLinked(models.PlatformRiot))
+		return
+	} else if err != nil {
+		c.Error(apperrors.Internal(err))
+		return
+	}
+
+	games := []string{query.Game}
+	if query.Game == "" {
+		games = []string{"lol", "valorant"}
+	}
+	entries := []MatchHistoryEntry{}
+	for _, game := range games {
+		participations, err := h.store.Matches.Participations(c.Request.Context(), []string{link.ExternalID}, game)
+		if err != nil {
+			c.Error(apperrors.Internal(err))
+			return
+		}
+		for _, p := range participations {
+			entries = append(entries, MatchHistoryEntry{
+				ID:        p.StoredMatchID,
+				MatchID:   p.UpstreamMatchID,
+				Game:      game,
+				PlayedAt:  p.PlayedAt,
+				Character: p.Character,
+				Role:      p.Role,
+				Win:       p.Win,
+				Kills:     p.Kills,
+				Deaths:    p.Deaths,
+				Assists:   p.Assists,
+				KDA:       calculateKDA(p.Kills, p.Deaths, p.Assists),
+			})
+		}
+	}
+
+	page, err := pagination.Paginate(entries, query.Query, pagination.Order[MatchHistoryEntry]{
+		Sort:   query.Sort,
+		Filter: "game=" + query.Game,
+		Key:    func(entry MatchHistoryEntry) string { return pagination.TimeKey(entry.PlayedAt) },
+		ID:     func(entry MatchHistoryEntry) uint { return entry.ID },
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	page.SetLinks(c)
+	c.JSON(http.StatusOK, page.Items)
+}
+
+// calculateKDA returns (kills + assists) / deaths, treating zero deaths as one
+func calculateKDA(kills, deaths, assists int) float64 {
+	if deaths == 0 {
+		return float64(kills + assists)
+	}
+	return float64(kills+assists) / float64(deaths)
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"elo-insight/backend/cache"
+	"elo-insight/backend/telemetry"
+	"elo-insight/backend/upstream"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// fetchedMatch is one downloaded match
+type fetchedMatch struct {
+	Index int // Position in the requested match IDs, newest first
+	ID    string
+	Body  []byte // Raw response; each computation decodes the fields it needs
+}
+
+// matchFetch is the outcome of downloading a player's recent matches
+type matchFetch struct {
+	Matches  []fetchedMatch // In requested order, without the ones that failed
+	Warnings []string       // One per match that failed, safe to show to the user
+}
+
+// within returns the fetched matches among the first n requested
+func (f matchFetch) within(n int) []fetchedMatch {
+	var matches []fetchedMatch
+	for _, match := range f.Matches {
+		if match.Index < n {
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// fetchMatches downloads matches with at most concurrency in flight. Failed
+// matches are left out and described in Warnings, so callers can show partial
+// stats. Every download still waits for the provider's rate limiter in the
+// upstream client, so a higher concurrency can't exceed the API key's limit.
+func fetchMatches(ctx context.Context, ids []string, concurrency int, fetch func(ctx context.Context, id string) ([]byte, error)) matchFetch {
+	ctx, span := telemetry.StartSpan(ctx, "fetch matches")
+	defer span.End()
+
+	bodies := make([][]byte, len(ids))
+	errs := make([]error, len(ids))
+	jobs := make(chan int)
+
+	workers := min(max(concurrency, 1), len(ids))
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				bodies[i], errs[i] = fetch(ctx, ids[i])
+			}
+		}()
+	}
+
+	// Stop handing out matches once the client has gone away
+	sent := 0
+queue:
+	for ; sent < len(ids); sent++ {
+		select {
+		case jobs <- sent:
+		case <-ctx.Done():
+			break queue
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	for i := sent; i < len(ids); i++ {
+		errs[i] = ctx.Err()
+	}
+
+	var result matchFetch
+	for i, id := range ids {
+		if errs[i] != nil {
+			slog.WarnContext(ctx, "Failed to fetch match", "match_id", id, "error", errs[i])
+			result.Warnings = append(result.Warnings, fmt.Sprintf("match %s could not be loaded: %s", id, describeFetchError(errs[i])))
+			continue
+		}
+		result.Matches = append(result.Matches, fetchedMatch{Index: i, ID: id, Body: bodies[i]})
+	}
+
+	span.SetAttributes(
+		attribute.Int("matches.requested", len(ids)),
+		attribute.Int("matches.fetched", len(result.Matches)),
+		attribute.Int("matches.concurrency", workers),
+	)
+	return result
+}
+
+// describeFetchError summarises why a match failed without exposing provider responses
+func describeFetchError(err error) string {
+	var statusErr *upstream.StatusError
+	switch {
+	case errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound:
+		return "not found"
+	case errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusTooManyRequests:
+		return "rate limited"
+	case errors.Is(err, upstream.ErrCircuitOpen):
+		return "provider unavailable"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timed out"
+	case errors.Is(err, context.Canceled):
+		return "request cancelled"
+	default:
+		return "provider error"
+	}
+}
+
+// leagueMatchFetcher downloads League match details through the cache
+func leagueMatchFetcher(apiKey string) func(ctx context.Context, id string) ([]byte, error) {
+	return func(ctx context.Context, id string) ([]byte, error) {
+		url := fmt.Sprintf("%s/lol/match/v5/matches/%s", MatchV5BaseURL, id)
+		key := cache.Key{Provider: upstream.Riot, Endpoint: endpointLoLMatch, Identity: id}
+		return fetchCached(ctx, key, url, riotHeader(apiKey))
+	}
+}
+
+// valorantMatchFetcher downloads Valorant match details through the cache
+func valorantMatchFetcher(apiKey string) func(ctx context.Context, id string) ([]byte, error) {
+	return func(ctx context.Context, id string) ([]byte, error) {
+		url := fmt.Sprintf("%s/val/match/v1/matches/%s", ValorantAPIBaseURL, id)
+		key := cache.Key{Provider: upstream.Riot, Endpoint: endpointValMatch, Identity: id}
+		return fetchCached(ctx, key, url, riotHeader(apiKey))
+	}
+}
@@ -1,41 +1,78 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 
-	"elo-insight/backend/database"
+	"elo-insight/backend/apperrors"
 	"elo-insight/backend/models"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ProfileResponse is the body of GET /user/profile
+type ProfileResponse struct {
+	Username      string                        `json:"username"`
+	Email         string                        `json:"email"`
+	PlatformLinks []models.PlatformLinkResponse `json:"platform_links"`
+	// Flat per-platform fields kept for existing clients; empty when not linked
+	SteamID       string `json:"steam_id"`
+	EAUsername    string `json:"ea_username"`
+	RiotID        string `json:"riot_id"` // Only once the Riot account is verified
+	RiotGameName  string `json:"riot_game_name"`
+	RiotTagline   string `json:"riot_tagline"`
+	RiotPUUID     string `json:"riot_puuid"`
+	XboxID        string `json:"xbox_id"`
+	PlayStationID string `json:"playstation_id"`
+}
+
 // Returns the authenticated user's profile
-func GetProfile(c *gin.Context) {
+func (h *Handler) GetProfile(c *gin.Context) {
 	// Extract user ID from jwt claims
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
 	// Find user in the database
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	user, err := h.store.Users.Get(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.Error(apperrors.NotFound("User not found"))
+		return
+	}
+
+	links, err := h.userPlatformLinks(c.Request.Context(), user.ID)
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch platform links: %w", err)))
 		return
 	}
 
+	profile := ProfileResponse{
+		Username:      user.Username,
+		Email:         user.Email,
+		PlatformLinks: make([]models.PlatformLinkResponse, 0, len(links)),
+	}
+	for _, link := range links {
+		profile.PlatformLinks = append(profile.PlatformLinks, link.Response())
+		switch link.Platform {
+		case models.PlatformSteam:
+			profile.SteamID = link.ExternalID
+		case models.PlatformEA:
+			profile.EAUsername = link.ExternalID
+		case models.PlatformXbox:
+			profile.XboxID = link.ExternalID
+		case models.PlatformPlayStation:
+			profile.PlayStationID = link.ExternalID
+		case models.PlatformRiot:
+			profile.RiotGameName, profile.RiotTagline = link.RiotNameAndTag()
+			profile.RiotPUUID = link.ExternalID
+			if link.Verification == models.LinkVerified {
+				profile.RiotID = link.ExternalID
+			}
+		}
+	}
+
 	// Return user profile data
-	c.JSON(http.StatusOK, gin.H{
-		"username":        user.Username,
-		"email":           user.Email,
-		"steam_id":        user.SteamID,
-		"ea_username":     user.EAUsername,
-		"riot_id":         user.RiotID,
-		"riot_game_name":  user.RiotGameName,
-		"riot_tagline":    user.RiotTagline,
-		"riot_puuid":      user.RiotPUUID,
-		"xbox_id":         user.XboxID,
-		"playstation_id":  user.PlayStationID,
-	})
+	c.JSON(http.StatusOK, profile)
 }
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/models"
+	"elo-insight/backend/pagination"
+	"elo-insight/backend/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Request bodies. Handlers bind into these rather than models, so clients can
+// only set the fields listed here. Custom rules are in the validation package.
+
+// RegisterRequest is the body of POST /auth/register
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required,username"`
+	Email    string `json:"email" binding:"required,email,max=254"`
+	Password string `json:"password" binding:"required,password,nefield=Username"`
+}
+
+// LoginRequest is the body of POST /auth/login. Only the register rules
+// decide what a valid password is; here anything non-empty may be tried.
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,max=254"`
+	Password string `json:"password" binding:"required,max=72"`
+}
+
+// LinkPlatformRequest is the body of POST /link/:platform. account_id is
+// checked against the platform's rule in accountIDRules.
+type LinkPlatformRequest struct {
+	AccountID string `json:"account_id" binding:"required,max=64"`
+	Region    string `json:"region" binding:"omitempty,max=16,alphanum"`
+}
+
+// SaveStatRequest is the body of POST /user/stats/save
+type SaveStatRequest struct {
+	Game     string `json:"game" binding:"required,max=50"`
+	Platform string `json:"platform" binding:"required,max=50"`
+}
+
+// NewFriendRequest is the body of POST /friends/request
+type NewFriendRequest struct {
+	FriendID uint `json:"friend_id" binding:"required,gt=0"`
+}
+
+// FriendActionRequest is the body of PUT /friends/request/:id; "reject" is
+// kept as an alias of "decline" for older clients
+type FriendActionRequest struct {
+	Action string `json:"action" binding:"required,oneof=accept decline reject"`
+}
+
+// CreateSquadRequest is the body of POST /squads
+type CreateSquadRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+// SquadInviteRequest is the body of POST /squads/:id/invites
+type SquadInviteRequest struct {
+	UserID uint `json:"user_id" binding:"required,gt=0"`
+}
+
+// InviteActionRequest is the body of PUT /squads/invites/:id
+type InviteActionRequest struct {
+	Action string `json:"action" binding:"required,oneof=accept decline"`
+}
+
+// FeedReactionRequest is the body of POST /feed/:id/reactions, with the
+// reactions users can leave on feed events
+type FeedReactionRequest struct {
+	Reaction string `json:"reaction" binding:"required,oneof=gg fire clap laugh"`
+}
+
+// FeedCommentRequest is the body of POST /feed/:id/comments
+type FeedCommentRequest struct {
+	Body string `json:"body" binding:"required,max=500"`
+}
+
+// Query parameters
+
+// SteamStatsQuery selects the player for the CS2 and Dota 2 stats
+type SteamStatsQuery struct {
+	SteamID string `form:"steam_id" binding:"required,steam_id64"`
+}
+
+// LeagueStatsQuery selects the player for League stats; with none of them the
+// signed-in user's linked account is used
+type LeagueStatsQuery struct {
+	RiotID       string `form:"riot_id" binding:"omitempty,riot_id"`
+	RiotGameName string `form:"riot_game_name" binding:"omitempty,max=16"`
+	RiotTagline  string `form:"riot_tagline" binding:"omitempty,max=5,alphanumunicode"`
+	RiotPUUID    string `form:"riot_puuid" binding:"omitempty,max=78"`
+}
+
+// ValorantStatsQuery selects the player for Valorant stats
+type ValorantStatsQuery struct {
+	RiotID string `form:"riot_id" binding:"omitempty,riot_id"`
+}
+
+// ApexStatsQuery selects the player for Apex Legends stats
+type ApexStatsQuery struct {
+	Username string `form:"username" binding:"omitempty,ea_id"`
+}
+
+// FriendsQuery pages through friends
+type FriendsQuery struct {
+	pagination.Query
+	Sort string `form:"sort" binding:"omitempty,oneof=created_at -created_at username -username"` // Defaults to created_at
+}
+
+// FriendRequestsQuery pages through friend requests. Pending requests sent to
+// the user are listed unless status or direction say otherwise.
+type FriendRequestsQuery struct {
+	pagination.Query
+	Status    string `form:"status" binding:"omitempty,oneof=pending declined cancelled"`
+	Direction string `form:"direction" binding:"omitempty,oneof=incoming outgoing"`
+	Sort      string `form:"sort" binding:"omitempty,oneof=created_at -created_at username -username"` // Defaults to -created_at
+}
+
+// UserSearchQuery pages through the users matching a search
+type UserSearchQuery struct {
+	Q string `form:"q" binding:"required,max=254"` // Part of a username or email
+	pagination.Query
+	Sort string `form:"sort" binding:"omitempty,oneof=username -username"`
+}
+
+// FeedQuery pages through the activity feed, newest first
+type FeedQuery struct {
+	pagination.Query
+}
+
+// StatCardsQuery pages through the user's stat cards
+type StatCardsQuery struct {
+	pagination.Query
+	Game string `form:"game" binding:"omitempty,max=50"`
+	Sort string `form:"sort" binding:"omitempty,oneof=created_at -created_at"` // Defaults to created_at
+}
+
+// MatchHistoryQuery pages through the user's stored matches
+type MatchHistoryQuery struct {
+	pagination.Query
+	Game string `form:"game" binding:"omitempty,oneof=lol valorant"`         // Both games if empty
+	Sort string `form:"sort" binding:"omitempty,oneof=played_at -played_at"` // Defaults to -played_at
+}
+
+// StoredGameQuery picks the game for stats from stored matches
+type StoredGameQuery struct {
+	Game string `form:"game" binding:"omitempty,oneof=lol valorant"` // Defaults to lol
+}
+
+// SynergyQuery picks the friend to compare the user with
+type SynergyQuery struct {
+	With uint `form:"with" binding:"required,gt=0"` // The friend's user ID
+	StoredGameQuery
+}
+
+// FriendSuggestionsQuery limits how many suggestions are returned
+type FriendSuggestionsQuery struct {
+	Limit int `form:"limit" binding:"omitempty,min=1"` // Defaults to 10; larger values are capped at 25
+}
+
+// accountIDRules is the validation tag for each linkable platform's account ID
+var accountIDRules = map[string]string{
+	models.PlatformRiot:        validation.TagRiotID,
+	models.PlatformEA:          validation.TagEAID,
+	models.PlatformXbox:        validation.TagXboxGamertag,
+	models.PlatformPlayStation: validation.TagPSNID,
+}
+
+// bindJSON decodes and validates the request body into req. If that fails it
+// reports a validation error with a message per field and returns false.
+func bindJSON(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.Error(bindError(err, "Request body must be valid JSON"))
+		return false
+	}
+	return true
+}
+
+// bindQuery is bindJSON for query parameters
+func bindQuery(c *gin.Context, req any) bool {
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.Error(bindError(err, "Invalid query parameters"))
+		return false
+	}
+	return true
+}
+
+// bindError describes a binding failure; unparsed is the message when no field could be blamed
+func bindError(err error, unparsed string) *apperrors.Error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		unparsed = "Request body too large"
+	}
+
+	fields := validation.Fields(err)
+	message := "Some fields are invalid"
+	if fields == nil {
+		message = unparsed
+	}
+	validationErr := apperrors.Validation(message, fields)
+	validationErr.Err = err
+	return validationErr
+}
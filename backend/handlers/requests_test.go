@@ -0,0 +1,63 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/handlers"
+)
+
+func TestQueryParameters(t *testing.T) {
+	api := newTestAPI(t)
+	api.route(http.MethodGet, "/synergy", api.h.GetSynergy)
+	api.route(http.MethodGet, "/squads/:id/stats", api.h.GetSquadStats)
+	api.route(http.MethodGet, "/friends/suggestions", api.h.GetFriendSuggestions)
+	me, friend := api.user("me"), api.user("friend")
+	api.befriend(me, friend)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantField  string // The parameter a validation error names, if it can tell
+	}{
+		{"synergy defaults to lol", fmt.Sprintf("/synergy?with=%d", friend.ID), http.StatusOK, ""},
+		{"synergy with valorant", fmt.Sprintf("/synergy?with=%d&game=valorant", friend.ID), http.StatusOK, ""},
+		{"synergy without a friend", "/synergy", http.StatusBadRequest, "with"},
+		{"synergy with user 0", "/synergy?with=0", http.StatusBadRequest, "with"},
+		{"synergy with a name", "/synergy?with=friend", http.StatusBadRequest, ""},
+		{"synergy for another game", fmt.Sprintf("/synergy?with=%d&game=dota2", friend.ID), http.StatusBadRequest, "game"},
+		{"synergy with yourself", fmt.Sprintf("/synergy?with=%d", me.ID), http.StatusBadRequest, ""},
+		{"squad stats for another game", "/squads/1/stats?game=cs2", http.StatusBadRequest, "game"},
+		{"suggestions", "/friends/suggestions", http.StatusOK, ""},
+		{"suggestions over the cap", "/friends/suggestions?limit=1000", http.StatusOK, ""},
+		{"suggestions with a negative limit", "/friends/suggestions?limit=-1", http.StatusBadRequest, "limit"},
+		{"suggestions with a word for a limit", "/friends/suggestions?limit=ten", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := api.do(me.ID, http.MethodGet, tt.path, nil)
+			if tt.wantStatus == http.StatusOK {
+				if w.Code != http.StatusOK {
+					t.Fatalf("status = %d, want 200; body: %s", w.Code, w.Body)
+				}
+				return
+			}
+			body := wantError(t, w, tt.wantStatus, apperrors.CodeValidation)
+			if tt.wantField == "" {
+				return
+			}
+			fields, _ := body.Details["fields"].(map[string]any)
+			if _, ok := fields[tt.wantField]; !ok {
+				t.Errorf("details = %v, want fields.%s", body.Details, tt.wantField)
+			}
+		})
+	}
+
+	stats := decode[handlers.SynergyStats](t, api.do(me.ID, http.MethodGet, fmt.Sprintf("/synergy?with=%d", friend.ID), nil), http.StatusOK)
+	if stats.Game != "lol" || stats.PartnerID != friend.ID {
+		t.Errorf("synergy = %+v, want lol with %d", stats, friend.ID)
+	}
+}
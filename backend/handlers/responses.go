@@ -0,0 +1,21 @@
+package handlers
+
+// Response bodies shared by several handlers. Those specific to one handler
+// live next to it, e.g. LeagueStatsResponse. Fields are part of the v1 API,
+// so rename or remove them only in a new version.
+
+// MessageResponse confirms an action
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// CreatedResponse confirms that a resource was created
+type CreatedResponse struct {
+	Message string `json:"message"`
+	ID      uint   `json:"id"`
+}
+
+// StatusResponse is the body of GET /healthz
+type StatusResponse struct {
+	Status string `json:"status"`
+}
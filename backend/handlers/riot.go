@@ -2,38 +2,35 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
 
-	"elo-insight/backend/database"
+	"elo-insight/backend/apperrors"
 	"elo-insight/backend/models"
+	"elo-insight/backend/upstream"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 )
 
 // RiotLogin redirects the user to the Riot OpenID authentication page
-func RiotLogin(c *gin.Context) {
-	if err := godotenv.Load(); err != nil {
-		log.Println("WARNING: Failed to load .env file. Using system environment variables.")
-	}
+func (h *Handler) RiotLogin(c *gin.Context) {
+	ctx := c.Request.Context()
 
 	token := c.Query("token")
 	if token == "" {
-		log.Println("Missing token in Riot login request")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		slog.WarnContext(ctx, "Missing token in Riot login request")
+		c.Error(apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
 	riotAuthURL := "https://auth.riotgames.com/authorize"
-	riotRedirectURI := os.Getenv("RIOT_CALLBACK_URL")
+	riotRedirectURI := h.cfg.Riot.CallbackURL
 
-	if riotRedirectURI == "" {
-		log.Println("🚨 ERROR: Riot OpenID environment variables not set!")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Riot OpenID variables not set"})
+	if !h.cfg.Riot.IsEnabled() || riotRedirectURI == "" {
+		c.Error(upstreamUnavailable(upstream.Riot, errors.New("Riot sign-on is not configured")))
 		return
 	}
 
@@ -42,37 +39,34 @@ func RiotLogin(c *gin.Context) {
 		riotAuthURL, url.QueryEscape(riotRedirectURI), url.QueryEscape(token),
 	)
 
-	log.Println("✅ Redirecting to Riot OpenID:", authURL)
+	slog.DebugContext(ctx, "Redirecting to Riot sign-on")
 	c.Redirect(http.StatusFound, authURL)
 }
 
 // RiotCallback handles the Riot OpenID callback and links the Riot ID to the user
-func RiotCallback(c *gin.Context) {
-	if err := godotenv.Load(); err != nil {
-		log.Println("🚨 WARNING: No .env file found, using system environment variables.")
-	}
+func (h *Handler) RiotCallback(c *gin.Context) {
+	ctx := c.Request.Context()
 
 	code := c.Query("code")
 	state := c.Query("state") // This is the token passed earlier
 
 	if code == "" || state == "" {
-		log.Println("🚨 ERROR: Missing authorization code or state in Riot callback")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization failed"})
+		slog.WarnContext(ctx, "Missing authorization code or state in Riot callback")
+		c.Error(apperrors.Unauthorized("Authorization failed"))
 		return
 	}
 
 	riotTokenURL := "https://auth.riotgames.com/token"
-	riotRedirectURI := os.Getenv("RIOT_CALLBACK_URL")
+	riotRedirectURI := h.cfg.Riot.CallbackURL
 
 	data := url.Values{}
 	data.Set("grant_type", "authorization_code")
 	data.Set("code", code)
 	data.Set("redirect_uri", riotRedirectURI)
 
-	resp, err := http.PostForm(riotTokenURL, data)
+	resp, err := upstream.PostForm(ctx, riotTokenURL, data)
 	if err != nil {
-		log.Println("🚨 ERROR: Failed to request Riot access token:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate with Riot"})
+		c.Error(apperrors.Internal(fmt.Errorf("failed to request Riot access token: %w", err)))
 		return
 	}
 	defer resp.Body.Close()
@@ -81,21 +75,19 @@ func RiotCallback(c *gin.Context) {
 		AccessToken string `json:"access_token"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
-		log.Println("🚨 ERROR: Failed to decode Riot token response:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process Riot response"})
+		c.Error(apperrors.Internal(fmt.Errorf("failed to decode Riot token response (status %d): %w", resp.StatusCode, err)))
 		return
 	}
 
 	// Get Riot ID from user info endpoint
 	riotUserURL := "https://auth.riotgames.com/userinfo"
-	req, _ := http.NewRequest("GET", riotUserURL, nil)
+	req, _ := http.NewRequestWithContext(ctx, "GET", riotUserURL, nil)
 	req.Header.Set("Authorization", "Bearer "+tokenResponse.AccessToken)
 
-	client := &http.Client{}
+	client := upstream.Client
 	userResp, err := client.Do(req)
 	if err != nil {
-		log.Println("🚨 ERROR: Failed to fetch Riot user info:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get Riot user info"})
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch Riot user info: %w", err)))
 		return
 	}
 	defer userResp.Body.Close()
@@ -104,44 +96,44 @@ func RiotCallback(c *gin.Context) {
 		RiotID string `json:"sub"`
 	}
 	if err := json.NewDecoder(userResp.Body).Decode(&userInfo); err != nil {
-		log.Println("🚨 ERROR: Failed to decode Riot user response:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process Riot user data"})
+		c.Error(apperrors.Internal(fmt.Errorf("failed to decode Riot user info (status %d): %w", userResp.StatusCode, err)))
 		return
 	}
 
-	log.Println("✅ Riot Authentication Successful! Riot ID:", userInfo.RiotID)
+	slog.InfoContext(ctx, "Riot sign-on succeeded")
 
 	// Extract user ID from JWT token
-	userID, err := extractUserIDFromToken(state)
+	userID, err := h.extractUserIDFromToken(state)
 	if err != nil {
-		log.Println("🚨 ERROR: Failed to extract user ID from token:", err)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		slog.WarnContext(ctx, "Invalid token in Riot callback state", "error", err)
+		c.Error(apperrors.Unauthorized("Invalid token"))
 		return
 	}
 
-	log.Println("🔍 Associating Riot ID with User ID:", userID)
-
 	// Update the user in the database to associate with Riot ID
-	var user models.User
-	result := database.DB.First(&user, userID)
-	if result.Error != nil {
-		log.Println("🚨 ERROR: User not found:", result.Error)
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	user, err := h.store.Users.Get(ctx, userID)
+	if err != nil {
+		slog.WarnContext(ctx, "User not found for Riot link", "user_id", userID, "error", err)
+		c.Error(apperrors.NotFound("User not found"))
 		return
 	}
 
-	user.RiotID = userInfo.RiotID
-	if err := database.DB.Save(&user).Error; err != nil {
-		log.Println("🚨 ERROR: Failed to save Riot ID to user:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save Riot ID"})
+	// Riot sign-on proves ownership, so the link is verified
+	link := &models.PlatformLink{
+		UserID:       user.ID,
+		Platform:     models.PlatformRiot,
+		ExternalID:   userInfo.RiotID,
+		Verification: models.LinkVerified,
+	}
+	if err := h.savePlatformLink(ctx, link); err != nil {
+		c.Error(apperrors.From(fmt.Errorf("failed to save Riot link: %w", err)))
 		return
 	}
 
-	log.Println("✅ Riot ID successfully linked to user:", userID)
+	slog.InfoContext(ctx, "Riot account linked", "user_id", userID)
 
 	// Redirect user to frontend
-	frontendURL := os.Getenv("FRONTEND_URL")
+	frontendURL := h.cfg.FrontendURL
 	redirectTo := fmt.Sprintf("%s/profile", frontendURL)
-	log.Println("✅ Redirecting user to:", redirectTo)
 	c.Redirect(http.StatusFound, redirectTo)
 }
@@ -0,0 +1,688 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/models"
+	"elo-insight/backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Largest squad we allow, counting the owner
+const maxSquadSize = 10
+
+// League roles as reported by match-v5 teamPosition/lane
+var leagueRoles = []string{"TOP", "JUNGLE", "MIDDLE", "BOTTOM", "UTILITY"}
+
+// Valorant agent roles, since match data only reports the agent
+var valorantAgentRoles = map[string]string{
+	"Jett": "Duelist", "Reyna": "Duelist", "Phoenix": "Duelist", "Raze": "Duelist",
+	"Yoru": "Duelist", "Neon": "Duelist", "Iso": "Duelist", "Waylay": "Duelist",
+	"Sova": "Initiator", "Breach": "Initiator", "Skye": "Initiator", "KAY/O": "Initiator",
+	"Fade": "Initiator", "Gekko": "Initiator", "Tejo": "Initiator",
+	"Brimstone": "Controller", "Omen": "Controller", "Viper": "Controller", "Astra": "Controller",
+	"Harbor": "Controller", "Clove": "Controller",
+	"Sage": "Sentinel", "Cypher": "Sentinel", "Killjoy": "Sentinel", "Chamber": "Sentinel",
+	"Deadlock": "Sentinel", "Vyse": "Sentinel",
+}
+
+// Valorant roles in display order
+var valorantRoles = []string{"Duelist", "Initiator", "Controller", "Sentinel"}
+
+// SquadStats is the aggregate dashboard for a squad in one game
+type SquadStats struct {
+	SquadID         uint                          `json:"squadId"`
+	Game            string                        `json:"game"`
+	Members         []SquadMemberStats            `json:"members"`
+	Together        SquadTogetherStats            `json:"together"`
+	RoleCoverage    []RoleCoverage                `json:"roleCoverage"`
+	Leaderboards    map[string][]LeaderboardEntry `json:"leaderboards"`
+	UnlinkedMembers []string                      `json:"unlinkedMembers"`
+}
+
+// SquadMemberStats summarises one member's stored matches
+type SquadMemberStats struct {
+	UserID   uint    `json:"userId"`
+	Username string  `json:"username"`
+	Games    int     `json:"games"`
+	Wins     int     `json:"wins"`
+	WinRate  float64 `json:"winRate"`
+	KDA      float64 `json:"kda"`
+	MainRole string  `json:"mainRole"`
+}
+
+// SquadTogetherStats covers matches where two or more members were on the same team
+type SquadTogetherStats struct {
+	Games       int                    `json:"games"`
+	Wins        int                    `json:"wins"`
+	WinRate     float64                `json:"winRate"`
+	ByGroupSize map[int]GroupSizeStats `json:"byGroupSize"`
+}
+
+// GroupSizeStats is the record for a given number of members queued together
+type GroupSizeStats struct {
+	Games   int     `json:"games"`
+	Wins    int     `json:"wins"`
+	WinRate float64 `json:"winRate"`
+}
+
+// RoleCoverage shows who on the squad plays a role
+type RoleCoverage struct {
+	Role    string   `json:"role"`
+	Games   int      `json:"games"`
+	Players []string `json:"players"`
+}
+
+// LeaderboardEntry is one row on a squad leaderboard
+type LeaderboardEntry struct {
+	UserID   uint    `json:"userId"`
+	Username string  `json:"username"`
+	Value    float64 `json:"value"`
+}
+
+// CreateSquad creates a new squad owned by the authenticated user
+func (h *Handler) CreateSquad(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var input CreateSquadRequest
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if len(name) < 2 {
+		c.Error(apperrors.Validation("Squad name must be between 2 and 50 characters", map[string]string{"name": "must be at least 2 characters"}))
+		return
+	}
+
+	squad := models.Squad{
+		Name:    name,
+		OwnerID: userID.(uint),
+		Members: []models.SquadMember{{UserID: userID.(uint), Role: models.SquadRoleOwner}},
+	}
+	if err := h.store.Squads.Create(c.Request.Context(), &squad); err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to create squad: %w", err)))
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreatedResponse{Message: "Squad created successfully", ID: squad.ID})
+}
+
+// GetSquads lists the squads the authenticated user belongs to
+func (h *Handler) GetSquads(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
+		return
+	}
+
+	squads, err := h.store.Squads.ListForMember(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch squads: %w", err)))
+		return
+	}
+
+	responses := make([]models.SquadResponse, 0, len(squads))
+	for _, squad := range squads {
+		response, err := h.buildSquadResponse(c.Request.Context(), squad)
+		if err != nil {
+			c.Error(apperrors.Internal(fmt.Errorf("failed to fetch squad members: %w", err)))
+			return
+		}
+		responses = append(responses, response)
+	}
+
+	c.JSON(http.StatusOK, responses)
+}
+
+// GetSquad returns a single squad the user belongs to
+func (h *Handler) GetSquad(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
+		return
+	}
+
+	squad, ok := h.loadMemberSquad(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	response, err := h.buildSquadResponse(c.Request.Context(), *squad)
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch squad members: %w", err)))
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteSquad disbands a squad; only the owner may do this
+func (h *Handler) DeleteSquad(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
+		return
+	}
+
+	squad, ok := h.loadMemberSquad(c, userID.(uint))
+	if !ok {
+		return
+	}
+	if squad.OwnerID != userID.(uint) {
+		c.Error(apperrors.Forbidden("Only the squad owner can delete the squad"))
+		return
+	}
+
+	if err := h.store.Squads.Delete(c.Request.Context(), squad.ID); err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to delete squad: %w", err)))
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Squad deleted successfully"})
+}
+
+// InviteToSquad invites one of the owner's friends to the squad
+func (h *Handler) InviteToSquad(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
+		return
+	}
+
+	squad, ok := h.loadMemberSquad(c, userID.(uint))
+	if !ok {
+		return
+	}
+	if squad.OwnerID != userID.(uint) {
+		c.Error(apperrors.Forbidden("Only the squad owner can invite members"))
+		return
+	}
+
+	var input SquadInviteRequest
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	// Squads are made of friends
+	friendIDs, err := h.acceptedFriendIDs(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch friendships: %w", err)))
+		return
+	}
+	isFriend := false
+	for _, id := range friendIDs {
+		if id == input.UserID {
+			isFriend = true
+			break
+		}
+	}
+	if !isFriend {
+		c.Error(apperrors.Validation("You can only invite friends to a squad", nil))
+		return
+	}
+
+	for _, member := range squad.Members {
+		if member.UserID == input.UserID {
+			c.Error(apperrors.Conflict("User is already a squad member"))
+			return
+		}
+	}
+	if len(squad.Members) >= maxSquadSize {
+		c.Error(apperrors.Conflict("Squad is full"))
+		return
+	}
+
+	_, err = h.store.Squads.FindPendingInvite(c.Request.Context(), squad.ID, input.UserID)
+	if err == nil {
+		c.Error(apperrors.Conflict("Invite already pending"))
+		return
+	} else if !errors.Is(err, store.ErrNotFound) {
+		c.Error(apperrors.Internal(fmt.Errorf("database error: %w", err)))
+		return
+	}
+
+	invite := models.SquadInvite{
+		SquadID:   squad.ID,
+		InviterID: userID.(uint),
+		InviteeID: input.UserID,
+		Status:    "pending",
+	}
+	if err := h.store.Squads.CreateInvite(c.Request.Context(), &invite); err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to create squad invite: %w", err)))
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Squad invite sent successfully"})
+}
+
+// GetSquadInvites lists pending squad invites for the authenticated user
+func (h *Handler) GetSquadInvites(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
+		return
+	}
+
+	invites, err := h.store.Squads.ListPendingInvites(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch squad invites: %w", err)))
+		return
+	}
+
+	inviteResponses := make([]models.SquadInviteResponse, 0, len(invites))
+	for _, invite := range invites {
+		squad, err := h.store.Squads.Get(c.Request.Context(), invite.SquadID)
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "Skipping invite: failed to fetch its squad", "invite_id", invite.ID, "error", err)
+			continue
+		}
+		inviter, err := h.store.Users.Get(c.Request.Context(), invite.InviterID)
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "Skipping invite: failed to fetch its inviter", "invite_id", invite.ID, "error", err)
+			continue
+		}
+
+		inviteResponses = append(inviteResponses, models.SquadInviteResponse{
+			ID:              invite.ID,
+			SquadID:         squad.ID,
+			SquadName:       squad.Name,
+			InviterID:       inviter.ID,
+			InviterUsername: inviter.Username,
+			Status:          invite.Status,
+			CreatedAt:       invite.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	c.JSON(http.StatusOK, inviteResponses)
+}
+
+// RespondToSquadInvite accepts or declines a squad invite
+func (h *Handler) RespondToSquadInvite(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(apperrors.Validation("Invalid invite ID", map[string]string{"id": "must be a number"}))
+		return
+	}
+
+	var input InviteActionRequest
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	invite, err := h.store.Squads.GetPendingInvite(c.Request.Context(), uint(id), userID.(uint))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.Error(apperrors.NotFound("Squad invite not found"))
+		} else {
+			c.Error(apperrors.Internal(fmt.Errorf("database error: %w", err)))
+		}
+		return
+	}
+
+	err = h.store.Squads.RespondToInvite(c.Request.Context(), invite, input.Action == "accept", maxSquadSize)
+	if errors.Is(err, store.ErrSquadFull) {
+		c.Error(apperrors.Conflict("Squad is full"))
+		return
+	}
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to respond to squad invite: %w", err)))
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Squad invite " + invite.Status + " successfully"})
+}
+
+// RemoveSquadMember removes a member; owners can remove anyone, members can leave
+func (h *Handler) RemoveSquadMember(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
+		return
+	}
+
+	squad, ok := h.loadMemberSquad(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	memberID, err := strconv.ParseUint(c.Param("userID"), 10, 32)
+	if err != nil {
+		c.Error(apperrors.Validation("Invalid user ID", map[string]string{"userID": "must be a number"}))
+		return
+	}
+
+	if uint(memberID) != userID.(uint) && squad.OwnerID != userID.(uint) {
+		c.Error(apperrors.Forbidden("Only the squad owner can remove other members"))
+		return
+	}
+	if uint(memberID) == squad.OwnerID {
+		c.Error(apperrors.Validation("The owner cannot leave the squad; delete it instead", nil))
+		return
+	}
+
+	err = h.store.Squads.RemoveMember(c.Request.Context(), squad.ID, uint(memberID))
+	if errors.Is(err, store.ErrNotFound) {
+		c.Error(apperrors.NotFound("User is not a squad member"))
+		return
+	}
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to remove squad member: %w", err)))
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Squad member removed successfully"})
+}
+
+// GetSquadStats aggregates members' stored League or Valorant matches
+func (h *Handler) GetSquadStats(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var query StoredGameQuery
+	if !bindQuery(c, &query) {
+		return
+	}
+	game := query.Game
+	if game == "" {
+		game = "lol"
+	}
+
+	squad, ok := h.loadMemberSquad(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	memberIDs := make([]uint, 0, len(squad.Members))
+	for _, member := range squad.Members {
+		memberIDs = append(memberIDs, member.UserID)
+	}
+
+	users, err := h.store.Users.ListByIDs(c.Request.Context(), memberIDs)
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch squad members: %w", err)))
+		return
+	}
+	puuidByUser, err := h.riotPUUIDsByUser(c.Request.Context(), memberIDs)
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch Riot links: %w", err)))
+		return
+	}
+
+	stats := SquadStats{
+		SquadID:         squad.ID,
+		Game:            game,
+		Members:         []SquadMemberStats{},
+		UnlinkedMembers: []string{},
+		Together:        SquadTogetherStats{ByGroupSize: map[int]GroupSizeStats{}},
+		Leaderboards:    map[string][]LeaderboardEntry{},
+	}
+
+	// Map PUUIDs back to members; members without a Riot account are listed separately
+	userByPUUID := make(map[string]models.User)
+	puuids := make([]string, 0, len(users))
+	for _, user := range users {
+		puuid := puuidByUser[user.ID]
+		if puuid == "" {
+			stats.UnlinkedMembers = append(stats.UnlinkedMembers, user.Username)
+			continue
+		}
+		userByPUUID[puuid] = user
+		puuids = append(puuids, puuid)
+	}
+
+	rows, err := h.store.Matches.Participations(c.Request.Context(), puuids, game)
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch squad matches: %w", err)))
+		return
+	}
+
+	stats.Members = squadMemberStats(rows, userByPUUID, game)
+	stats.Together = squadTogetherStats(rows)
+	stats.RoleCoverage = squadRoleCoverage(rows, userByPUUID, game)
+	stats.Leaderboards = squadLeaderboards(stats.Members)
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// squadMemberStats totals each member's games, wins, KDA and most played role
+func squadMemberStats(rows []store.Participation, userByPUUID map[string]models.User, game string) []SquadMemberStats {
+	type totals struct {
+		games, wins, kills, deaths, assists int
+		roles                               map[string]int
+	}
+	byPUUID := make(map[string]*totals)
+	for _, row := range rows {
+		t, exists := byPUUID[row.PUUID]
+		if !exists {
+			t = &totals{roles: make(map[string]int)}
+			byPUUID[row.PUUID] = t
+		}
+		t.games++
+		if row.Win {
+			t.wins++
+		}
+		t.kills += row.Kills
+		t.deaths += row.Deaths
+		t.assists += row.Assists
+		if role := participationRole(row, game); role != "" {
+			t.roles[role]++
+		}
+	}
+
+	members := make([]SquadMemberStats, 0, len(userByPUUID))
+	for puuid, user := range userByPUUID {
+		member := SquadMemberStats{UserID: user.ID, Username: user.Username}
+		if t, exists := byPUUID[puuid]; exists {
+			member.Games = t.games
+			member.Wins = t.wins
+			member.WinRate = float64(t.wins) / float64(t.games) * 100
+			member.KDA = calculateKDA(t.kills, t.deaths, t.assists)
+			best := 0
+			for role, count := range t.roles {
+				if count > best || (count == best && role < member.MainRole) {
+					best = count
+					member.MainRole = role
+				}
+			}
+		}
+		members = append(members, member)
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Username < members[j].Username
+	})
+	return members
+}
+
+// squadTogetherStats finds matches where members shared a team and tallies the results
+func squadTogetherStats(rows []store.Participation) SquadTogetherStats {
+	type teamKey struct {
+		match uint
+		team  string
+	}
+	teams := make(map[teamKey][]store.Participation)
+	for _, row := range rows {
+		key := teamKey{match: row.StoredMatchID, team: row.TeamID}
+		teams[key] = append(teams[key], row)
+	}
+
+	together := SquadTogetherStats{ByGroupSize: map[int]GroupSizeStats{}}
+	for _, members := range teams {
+		if len(members) < 2 {
+			continue
+		}
+		won := members[0].Win
+
+		together.Games++
+		group := together.ByGroupSize[len(members)]
+		group.Games++
+		if won {
+			together.Wins++
+			group.Wins++
+		}
+		group.WinRate = float64(group.Wins) / float64(group.Games) * 100
+		together.ByGroupSize[len(members)] = group
+	}
+	if together.Games > 0 {
+		together.WinRate = float64(together.Wins) / float64(together.Games) * 100
+	}
+	return together
+}
+
+// squadRoleCoverage reports how many games the squad has in each role and who plays it
+func squadRoleCoverage(rows []store.Participation, userByPUUID map[string]models.User, game string) []RoleCoverage {
+	roles := leagueRoles
+	if game == "valorant" {
+		roles = valorantRoles
+	}
+
+	games := make(map[string]int)
+	players := make(map[string]map[string]bool)
+	for _, row := range rows {
+		role := participationRole(row, game)
+		if role == "" {
+			continue
+		}
+		games[role]++
+		if players[role] == nil {
+			players[role] = make(map[string]bool)
+		}
+		players[role][userByPUUID[row.PUUID].Username] = true
+	}
+
+	coverage := make([]RoleCoverage, 0, len(roles))
+	for _, role := range roles {
+		names := make([]string, 0, len(players[role]))
+		for name := range players[role] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		coverage = append(coverage, RoleCoverage{Role: role, Games: games[role], Players: names})
+	}
+	return coverage
+}
+
+// squadLeaderboards ranks members by win rate, KDA and games played
+func squadLeaderboards(members []SquadMemberStats) map[string][]LeaderboardEntry {
+	board := func(value func(SquadMemberStats) float64) []LeaderboardEntry {
+		entries := make([]LeaderboardEntry, 0, len(members))
+		for _, member := range members {
+			if member.Games == 0 {
+				continue
+			}
+			entries = append(entries, LeaderboardEntry{
+				UserID:   member.UserID,
+				Username: member.Username,
+				Value:    value(member),
+			})
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Value > entries[j].Value
+		})
+		return entries
+	}
+
+	return map[string][]LeaderboardEntry{
+		"winRate": board(func(m SquadMemberStats) float64 { return m.WinRate }),
+		"kda":     board(func(m SquadMemberStats) float64 { return m.KDA }),
+		"games":   board(func(m SquadMemberStats) float64 { return float64(m.Games) }),
+	}
+}
+
+// participationRole returns the role for a store.Participation, deriving it from the agent in Valorant
+func participationRole(row store.Participation, game string) string {
+	if game == "valorant" {
+		return valorantAgentRoles[row.Character]
+	}
+	role := strings.ToUpper(row.Role)
+	if role == "MID" {
+		role = "MIDDLE"
+	}
+	for _, known := range leagueRoles {
+		if role == known {
+			return role
+		}
+	}
+	return ""
+}
+
+// loadMemberSquad loads the squad in the :id param if the user is a member.
+// It writes the error response itself and returns false on failure.
+func (h *Handler) loadMemberSquad(c *gin.Context, userID uint) (*models.Squad, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(apperrors.Validation("Invalid squad ID", map[string]string{"id": "must be a number"}))
+		return nil, false
+	}
+
+	squad, err := h.store.Squads.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.Error(apperrors.NotFound("Squad not found"))
+		} else {
+			c.Error(apperrors.Internal(fmt.Errorf("database error: %w", err)))
+		}
+		return nil, false
+	}
+
+	for _, member := range squad.Members {
+		if member.UserID == userID {
+			return squad, true
+		}
+	}
+
+	// Don't reveal squads the user isn't part of
+	c.Error(apperrors.NotFound("Squad not found"))
+	return nil, false
+}
+
+// buildSquadResponse attaches member usernames to a squad
+func (h *Handler) buildSquadResponse(ctx context.Context, squad models.Squad) (models.SquadResponse, error) {
+	memberIDs := make([]uint, 0, len(squad.Members))
+	for _, member := range squad.Members {
+		memberIDs = append(memberIDs, member.UserID)
+	}
+	usernames, err := h.usernamesByID(ctx, memberIDs)
+	if err != nil {
+		return models.SquadResponse{}, err
+	}
+
+	response := models.SquadResponse{
+		ID:        squad.ID,
+		Name:      squad.Name,
+		OwnerID:   squad.OwnerID,
+		Members:   make([]models.SquadMemberResponse, 0, len(squad.Members)),
+		CreatedAt: squad.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	for _, member := range squad.Members {
+		response.Members = append(response.Members, models.SquadMemberResponse{
+			UserID:   member.UserID,
+			Username: usernames[member.UserID],
+			Role:     member.Role,
+		})
+	}
+	return response, nil
+}
@@ -0,0 +1,147 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/handlers"
+	"elo-insight/backend/models"
+)
+
+func squadsAPI(t *testing.T) *testAPI {
+	api := newTestAPI(t)
+	api.route(http.MethodPost, "/squads/", api.h.CreateSquad)
+	api.route(http.MethodGet, "/squads/", api.h.GetSquads)
+	api.route(http.MethodGet, "/squads/invites", api.h.GetSquadInvites)
+	api.route(http.MethodPut, "/squads/invites/:id", api.h.RespondToSquadInvite)
+	api.route(http.MethodGet, "/squads/:id", api.h.GetSquad)
+	api.route(http.MethodDelete, "/squads/:id", api.h.DeleteSquad)
+	api.route(http.MethodPost, "/squads/:id/invites", api.h.InviteToSquad)
+	api.route(http.MethodDelete, "/squads/:id/members/:userID", api.h.RemoveSquadMember)
+	return api
+}
+
+func TestCreateSquad(t *testing.T) {
+	api := squadsAPI(t)
+	owner := api.user("owner")
+
+	tests := []struct {
+		name       string
+		body       any
+		wantStatus int
+		wantCode   apperrors.Code
+	}{
+		{"created", handlers.CreateSquadRequest{Name: "  Duo Queue  "}, http.StatusCreated, ""},
+		{"name too short", handlers.CreateSquadRequest{Name: " x "}, http.StatusBadRequest, apperrors.CodeValidation},
+		{"name missing", map[string]any{}, http.StatusBadRequest, apperrors.CodeValidation},
+		{"malformed body", "not an object", http.StatusBadRequest, apperrors.CodeValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := api.do(owner.ID, http.MethodPost, "/squads/", tt.body)
+			if tt.wantCode != "" {
+				wantError(t, w, tt.wantStatus, tt.wantCode)
+				return
+			}
+			created := decode[handlers.CreatedResponse](t, w, tt.wantStatus)
+
+			squad := decode[models.SquadResponse](t, api.do(owner.ID, http.MethodGet, fmt.Sprintf("/squads/%d", created.ID), nil), http.StatusOK)
+			if squad.Name != "Duo Queue" || squad.OwnerID != owner.ID {
+				t.Errorf("squad = %+v, want Duo Queue owned by %d", squad, owner.ID)
+			}
+			if len(squad.Members) != 1 || squad.Members[0].UserID != owner.ID {
+				t.Errorf("members = %+v, want only the owner", squad.Members)
+			}
+		})
+	}
+}
+
+func TestSquadInvites(t *testing.T) {
+	api := squadsAPI(t)
+	owner, friend, stranger := api.user("owner"), api.user("friend"), api.user("stranger")
+	api.befriend(owner, friend)
+
+	created := decode[handlers.CreatedResponse](t, api.do(owner.ID, http.MethodPost, "/squads/", handlers.CreateSquadRequest{Name: "Stack"}), http.StatusCreated)
+	squadPath := fmt.Sprintf("/squads/%d", created.ID)
+
+	// Only the owner invites, and only friends
+	w := api.do(owner.ID, http.MethodPost, squadPath+"/invites", handlers.SquadInviteRequest{UserID: stranger.ID})
+	wantError(t, w, http.StatusBadRequest, apperrors.CodeValidation)
+	w = api.do(stranger.ID, http.MethodPost, squadPath+"/invites", handlers.SquadInviteRequest{UserID: friend.ID})
+	wantError(t, w, http.StatusNotFound, apperrors.CodeNotFound)
+	w = api.do(owner.ID, http.MethodPost, "/squads/999/invites", handlers.SquadInviteRequest{UserID: friend.ID})
+	wantError(t, w, http.StatusNotFound, apperrors.CodeNotFound)
+
+	decode[handlers.MessageResponse](t, api.do(owner.ID, http.MethodPost, squadPath+"/invites", handlers.SquadInviteRequest{UserID: friend.ID}), http.StatusOK)
+	w = api.do(owner.ID, http.MethodPost, squadPath+"/invites", handlers.SquadInviteRequest{UserID: friend.ID})
+	wantError(t, w, http.StatusConflict, apperrors.CodeConflict)
+
+	invites := decode[[]models.SquadInviteResponse](t, api.do(friend.ID, http.MethodGet, "/squads/invites", nil), http.StatusOK)
+	if len(invites) != 1 || invites[0].SquadName != "Stack" || invites[0].InviterUsername != "owner" {
+		t.Fatalf("invites = %+v, want one to Stack from owner", invites)
+	}
+	invitePath := fmt.Sprintf("/squads/invites/%d", invites[0].ID)
+
+	// Only the invitee can answer
+	w = api.do(owner.ID, http.MethodPut, invitePath, handlers.InviteActionRequest{Action: "accept"})
+	wantError(t, w, http.StatusNotFound, apperrors.CodeNotFound)
+	decode[handlers.MessageResponse](t, api.do(friend.ID, http.MethodPut, invitePath, handlers.InviteActionRequest{Action: "accept"}), http.StatusOK)
+	w = api.do(friend.ID, http.MethodPut, invitePath, handlers.InviteActionRequest{Action: "accept"})
+	wantError(t, w, http.StatusNotFound, apperrors.CodeNotFound)
+
+	squads := decode[[]models.SquadResponse](t, api.do(friend.ID, http.MethodGet, "/squads/", nil), http.StatusOK)
+	if len(squads) != 1 || len(squads[0].Members) != 2 {
+		t.Fatalf("friend's squads = %+v, want Stack with two members", squads)
+	}
+	w = api.do(owner.ID, http.MethodPost, squadPath+"/invites", handlers.SquadInviteRequest{UserID: friend.ID})
+	wantError(t, w, http.StatusConflict, apperrors.CodeConflict)
+}
+
+func TestSquadMembership(t *testing.T) {
+	api := squadsAPI(t)
+	owner, member, other := api.user("owner"), api.user("member"), api.user("other")
+	for _, user := range []models.User{member, other} {
+		api.befriend(owner, user)
+	}
+	created := decode[handlers.CreatedResponse](t, api.do(owner.ID, http.MethodPost, "/squads/", handlers.CreateSquadRequest{Name: "Trio"}), http.StatusCreated)
+	squadPath := fmt.Sprintf("/squads/%d", created.ID)
+	for _, user := range []models.User{member, other} {
+		api.do(owner.ID, http.MethodPost, squadPath+"/invites", handlers.SquadInviteRequest{UserID: user.ID})
+		invites := decode[[]models.SquadInviteResponse](t, api.do(user.ID, http.MethodGet, "/squads/invites", nil), http.StatusOK)
+		api.do(user.ID, http.MethodPut, fmt.Sprintf("/squads/invites/%d", invites[0].ID), handlers.InviteActionRequest{Action: "accept"})
+	}
+	members := func(userID uint) string { return fmt.Sprintf("%s/members/%d", squadPath, userID) }
+
+	// Each step runs on the state the previous ones left
+	steps := []struct {
+		name       string
+		as         models.User
+		method     string
+		path       string
+		wantStatus int
+		wantCode   apperrors.Code
+	}{
+		{"member removes another", member, http.MethodDelete, members(other.ID), http.StatusForbidden, apperrors.CodeForbidden},
+		{"owner leaves", owner, http.MethodDelete, members(owner.ID), http.StatusBadRequest, apperrors.CodeValidation},
+		{"invalid member", owner, http.MethodDelete, squadPath + "/members/x", http.StatusBadRequest, apperrors.CodeValidation},
+		{"member disbands", member, http.MethodDelete, squadPath, http.StatusForbidden, apperrors.CodeForbidden},
+		{"member leaves", member, http.MethodDelete, members(member.ID), http.StatusOK, ""},
+		{"former member views", member, http.MethodGet, squadPath, http.StatusNotFound, apperrors.CodeNotFound},
+		{"owner removes a member", owner, http.MethodDelete, members(other.ID), http.StatusOK, ""},
+		{"removed twice", owner, http.MethodDelete, members(other.ID), http.StatusNotFound, apperrors.CodeNotFound},
+		{"owner disbands", owner, http.MethodDelete, squadPath, http.StatusOK, ""},
+		{"disbanded", owner, http.MethodGet, squadPath, http.StatusNotFound, apperrors.CodeNotFound},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			w := api.do(step.as.ID, step.method, step.path, nil)
+			if step.wantCode != "" {
+				wantError(t, w, step.wantStatus, step.wantCode)
+			} else if w.Code != step.wantStatus {
+				t.Errorf("status = %d, want %d; body: %s", w.Code, step.wantStatus, w.Body)
+			}
+		})
+	}
+}
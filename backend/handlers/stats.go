@@ -1,81 +1,73 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
-	"os"
+	"slices"
 	"strconv"
+	"strings"
 
-	"elo-insight/backend/database"
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/cache"
 	"elo-insight/backend/models"
+	"elo-insight/backend/pagination"
+	"elo-insight/backend/store"
+	"elo-insight/backend/upstream"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
 // Fetch CS2 stats from Steam Web API
-func GetCS2Stats(c *gin.Context) {
-	log.Println("➡️ Received request for CS2 stats")
-
-	steamID := c.Query("steam_id")
-	if steamID == "" {
-		log.Println("Missing steam_id in request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing steam_id"})
+func (h *Handler) GetCS2Stats(c *gin.Context) {
+	var query SteamStatsQuery
+	if !bindQuery(c, &query) {
 		return
 	}
 
-	apiKey := os.Getenv("STEAM_API_KEY")
-	if apiKey == "" {
-		log.Println("Missing Steam API key")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Missing Steam API key"})
+	cs2Stats, err := h.CS2Stats(c.Request.Context(), query.SteamID)
+	if err != nil {
+		c.Error(err)
 		return
 	}
+	c.JSON(http.StatusOK, cs2Stats)
+}
 
-	apiURL := fmt.Sprintf("https://api.steampowered.com/ISteamUserStats/GetUserStatsForGame/v2/?appid=730&key=%s&steamid=%s", apiKey, steamID)
-	log.Println("Fetching CS2 stats from:", apiURL)
-
-	resp, err := http.Get(apiURL)
-	if err != nil {
-		log.Println("Failed to fetch CS2 stats:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stats"})
-		return
+// CS2Stats returns a player's CS2 stats by stat name
+func (h *Handler) CS2Stats(ctx context.Context, steamID string) (map[string]interface{}, error) {
+	apiKey := h.cfg.Steam.APIKey
+	if apiKey == "" {
+		return nil, upstreamUnavailable(upstream.Steam, errors.New("Steam API key not configured"))
 	}
-	defer resp.Body.Close()
 
-	log.Println("Steam API Response Status:", resp.StatusCode)
+	apiURL := fmt.Sprintf("https://api.steampowered.com/ISteamUserStats/GetUserStatsForGame/v2/?appid=730&key=%s&steamid=%s", apiKey, steamID)
+	slog.DebugContext(ctx, "Fetching CS2 stats")
 
-	body, err := io.ReadAll(resp.Body)
+	key := cache.Key{Provider: upstream.Steam, Endpoint: endpointCS2Stats, Identity: steamID}
+	body, status, err := cache.Default.Fetch(ctx, key, cachePolicies[endpointCS2Stats], func(ctx context.Context) ([]byte, error) {
+		return upstream.GetBody(ctx, apiURL, nil)
+	})
 	if err != nil {
-		log.Println("Failed to read response body:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read API response"})
-		return
+		return nil, fromUpstream(upstream.Steam, err)
 	}
-
-	log.Println("📝 Raw Steam API Response:", string(body))
+	slog.DebugContext(ctx, "Steam API responded", "cache", status, "bytes", len(body))
 
 	var result map[string]interface{}
 	if err := json.Unmarshal(body, &result); err != nil {
-		log.Println("Failed to decode API response:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding API response"})
-		return
+		return nil, upstreamUnavailable(upstream.Steam, fmt.Errorf("failed to decode CS2 stats: %w", err))
 	}
 
 	playerStats, ok := result["playerstats"].(map[string]interface{})
 	if !ok {
-		log.Println("No 'playerstats' field in API response")
-		c.JSON(http.StatusNotFound, gin.H{"error": "No stats found for this user"})
-		return
+		return nil, apperrors.NotFound("No stats found for this user")
 	}
 
 	statsList, ok := playerStats["stats"].([]interface{})
 	if !ok {
-		log.Println("No 'stats' array in playerstats")
-		c.JSON(http.StatusNotFound, gin.H{"error": "No stats available for this player"})
-		return
+		return nil, apperrors.NotFound("No stats available for this player")
 	}
 
 	cs2Stats := make(map[string]interface{})
@@ -89,22 +81,40 @@ func GetCS2Stats(c *gin.Context) {
 		cs2Stats[name] = value
 	}
 
-	log.Println("Fetched CS2 Stats:", cs2Stats)
+	slog.DebugContext(ctx, "Fetched CS2 stats", "stats", len(cs2Stats))
+	return cs2Stats, nil
+}
 
-	c.JSON(http.StatusOK, cs2Stats)
+// statCardPlatforms lists the platforms each game's stat card can be shown for
+var statCardPlatforms = map[string][]string{
+	"CS2":               {"Steam"},
+	"Dota 2":            {"Steam"},
+	"Apex Legends":      {"EA", "PlayStation", "Xbox"},
+	"Valorant":          {"Riot"},
+	"League of Legends": {"Riot"},
+	"Call of Duty":      {"PlayStation", "Xbox", "Battle.net"},
 }
 
-func SaveStatSelection(c *gin.Context) {
+func (h *Handler) SaveStatSelection(c *gin.Context) {
 	userID, exists := c.Get("userID") // ✅ Get authenticated user ID
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
-	var input models.UserStat
-	if err := c.ShouldBindJSON(&input); err != nil {
-		log.Println("Invalid request:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+	var input SaveStatRequest
+	if !bindJSON(c, &input) {
+		return
+	}
+	platforms, ok := statCardPlatforms[input.Game]
+	if !ok {
+		c.Error(apperrors.Validation("Unsupported game", map[string]string{"game": "is not supported"}))
+		return
+	}
+	if !slices.Contains(platforms, input.Platform) {
+		c.Error(apperrors.Validation("Unsupported platform", map[string]string{
+			"platform": "must be one of: " + strings.Join(platforms, ", "),
+		}))
 		return
 	}
 
@@ -114,115 +124,124 @@ func SaveStatSelection(c *gin.Context) {
 		Platform: input.Platform,
 	}
 
-	if err := database.DB.Create(&userStat).Error; err != nil {
-		log.Println("Failed to save user stat:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save stat"})
+	if err := h.store.StatCards.Create(c.Request.Context(), &userStat); err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to save user stat: %w", err)))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Stat saved successfully"})
+	c.JSON(http.StatusOK, MessageResponse{Message: "Stat saved successfully"})
 }
 
-// Fetch user stats
-func GetUserStats(c *gin.Context) {
+// Fetch a page of the user's stat cards
+func (h *Handler) GetUserStats(c *gin.Context) {
 	userID, exists := c.Get("userID") // ✅ Get `ID` from JWT
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
-	var stats []models.UserStat
-	if err := database.DB.Where("user_id = ?", userID).Find(&stats).Error; err != nil {
-		log.Println("Failed to fetch user stats:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stats"})
+	var query StatCardsQuery
+	if !bindQuery(c, &query) {
 		return
 	}
+	if query.Sort == "" {
+		query.Sort = "created_at"
+	}
+
+	stats, err := h.store.StatCards.ListByUser(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch user stats: %w", err)))
+		return
+	}
+	if query.Game != "" {
+		stats = slices.DeleteFunc(stats, func(stat models.UserStat) bool { return !strings.EqualFold(stat.Game, query.Game) })
+	}
 
-	c.JSON(http.StatusOK, stats)
+	page, err := pagination.Paginate(stats, query.Query, pagination.Order[models.UserStat]{
+		Sort:   query.Sort,
+		Filter: "game=" + strings.ToLower(query.Game),
+		Key:    func(stat models.UserStat) string { return pagination.TimeKey(stat.CreatedAt) },
+		ID:     func(stat models.UserStat) uint { return stat.ID },
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	page.SetLinks(c)
+	c.JSON(http.StatusOK, page.Items)
 }
 
 // Delete a user stat card
-func DeleteStatCard(c *gin.Context) {
+func (h *Handler) DeleteStatCard(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
 	// Get the stat ID from the URL
 	statID := c.Param("id")
 	if statID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing stat ID"})
+		c.Error(apperrors.Validation("Missing stat ID", map[string]string{"id": "required"}))
 		return
 	}
 
 	// Convert string ID to uint
 	id, err := strconv.ParseUint(statID, 10, 64)
 	if err != nil {
-		log.Println("Invalid stat ID:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stat ID"})
+		slog.WarnContext(c.Request.Context(), "Invalid stat ID", "error", err)
+		c.Error(apperrors.Validation("Invalid stat ID", map[string]string{"id": "must be a number"}))
 		return
 	}
 
-	// Find the stat and check if it belongs to the user
-	var stat models.UserStat
-	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&stat).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Stat not found or doesn't belong to user"})
+	// Delete the stat if it belongs to the user
+	if err := h.store.StatCards.Delete(c.Request.Context(), uint(id), userID.(uint)); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.Error(apperrors.NotFound("Stat not found or doesn't belong to user"))
 		} else {
-			log.Println("Failed to find stat:", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find stat"})
+			c.Error(apperrors.Internal(fmt.Errorf("failed to delete stat: %w", err)))
 		}
 		return
 	}
 
-	// Delete the stat
-	if err := database.DB.Delete(&stat).Error; err != nil {
-		log.Println("Failed to delete stat:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete stat"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Stat deleted successfully"})
+	c.JSON(http.StatusOK, MessageResponse{Message: "Stat deleted successfully"})
 }
 
 // Fetch Dota 2 stats from Steam Web API with fallback to mock data
-func GetDota2Stats(c *gin.Context) {
-	log.Println("➡️ Received request for Dota 2 stats")
+func (h *Handler) GetDota2Stats(c *gin.Context) {
+	var query SteamStatsQuery
+	if !bindQuery(c, &query) {
+		return
+	}
 
-	steamID := c.Query("steam_id")
-	if steamID == "" {
-		log.Println("Missing steam_id in request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing steam_id"})
+	dota2Stats, err := h.Dota2Stats(c.Request.Context(), query.SteamID)
+	if err != nil {
+		c.Error(err)
 		return
 	}
+	c.JSON(http.StatusOK, dota2Stats)
+}
 
-	apiKey := os.Getenv("STEAM_API_KEY")
+// Dota2Stats returns a player's Dota 2 stats, which are mock data for now
+func (h *Handler) Dota2Stats(ctx context.Context, steamID string) (map[string]interface{}, error) {
+	apiKey := h.cfg.Steam.APIKey
 	if apiKey == "" {
-		log.Println("Missing Steam API key")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Missing Steam API key"})
-		return
+		return nil, upstreamUnavailable(upstream.Steam, errors.New("Steam API key not configured"))
 	}
 
 	// Try to get player summary to get the player name
 	playerName := "Dota 2 Player"
 	playerSummaryURL := fmt.Sprintf("https://api.steampowered.com/ISteamUser/GetPlayerSummaries/v2/?key=%s&steamids=%s", apiKey, steamID)
-	log.Println("Fetching player summary from:", playerSummaryURL)
-
-	summaryResp, err := http.Get(playerSummaryURL)
-	if err == nil {
-		defer summaryResp.Body.Close()
-		summaryBody, err := io.ReadAll(summaryResp.Body)
-		if err == nil {
-			var summaryResult map[string]interface{}
-			if json.Unmarshal(summaryBody, &summaryResult) == nil {
-				if response, ok := summaryResult["response"].(map[string]interface{}); ok {
-					if players, ok := response["players"].([]interface{}); ok && len(players) > 0 {
-						if playerInfo, ok := players[0].(map[string]interface{}); ok {
-							if name, ok := playerInfo["personaname"].(string); ok {
-								playerName = name
-							}
-						}
+	slog.DebugContext(ctx, "Fetching Steam player summary")
+
+	summaryKey := cache.Key{Provider: upstream.Steam, Endpoint: endpointSteamPlayer, Identity: steamID}
+	var summaryResult map[string]interface{}
+	if fetchCachedJSON(ctx, summaryKey, playerSummaryURL, nil, &summaryResult) == nil {
+		if response, ok := summaryResult["response"].(map[string]interface{}); ok {
+			if players, ok := response["players"].([]interface{}); ok && len(players) > 0 {
+				if playerInfo, ok := players[0].(map[string]interface{}); ok {
+					if name, ok := playerInfo["personaname"].(string); ok {
+						playerName = name
 					}
 				}
 			}
@@ -231,25 +250,20 @@ func GetDota2Stats(c *gin.Context) {
 
 	// Attempt to fetch Dota 2 stats from Steam API
 	dota2ApiURL := fmt.Sprintf("https://api.steampowered.com/ISteamUserStats/GetUserStatsForGame/v2/?appid=570&key=%s&steamid=%s", apiKey, steamID)
-	log.Println("Fetching Dota 2 stats from:", dota2ApiURL)
+	slog.DebugContext(ctx, "Fetching Dota 2 stats")
 
-	resp, err := http.Get(dota2ApiURL)
+	dota2Key := cache.Key{Provider: upstream.Steam, Endpoint: endpointDota2Stats, Identity: steamID}
+	body, status, err := cache.Default.Fetch(ctx, dota2Key, cachePolicies[endpointDota2Stats], func(ctx context.Context) ([]byte, error) {
+		return upstream.GetBody(ctx, dota2ApiURL, nil)
+	})
 	if err != nil {
-		log.Println("Failed to fetch Dota 2 stats, using mock data:", err)
+		slog.WarnContext(ctx, "Failed to fetch Dota 2 stats, using mock data", "error", err)
 	} else {
-		defer resp.Body.Close()
-		log.Println("Steam API Response Status:", resp.StatusCode)
-
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			log.Println("Failed to read response body, using mock data:", err)
-		} else {
-			log.Println("📝 Raw Steam API Response:", string(body))
-		}
+		slog.DebugContext(ctx, "Steam API responded", "cache", status, "bytes", len(body))
 	}
 
 	// Generate mock data for Dota 2 stats
-	log.Println("Generating mock Dota 2 stats for player:", playerName)
+	slog.DebugContext(ctx, "Generating mock Dota 2 stats")
 
 	// Create realistic mock data that matches the frontend's expected structure
 	dota2Stats := map[string]interface{}{
@@ -435,8 +449,6 @@ func GetDota2Stats(c *gin.Context) {
 		},
 	}
 
-	log.Println("Generated mock Dota 2 stats:", dota2Stats)
-
-	log.Println("Processed Dota 2 Stats:", dota2Stats)
-	c.JSON(http.StatusOK, dota2Stats)
+	slog.DebugContext(ctx, "Processed Dota 2 stats")
+	return dota2Stats, nil
 }
@@ -0,0 +1,57 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/handlers"
+	"elo-insight/backend/models"
+)
+
+func TestStatCards(t *testing.T) {
+	api := newTestAPI(t)
+	api.route(http.MethodPost, "/user/stats/save", api.h.SaveStatSelection)
+	api.route(http.MethodGet, "/user/stats/", api.h.GetUserStats)
+	api.route(http.MethodDelete, "/user/stats/:id", api.h.DeleteStatCard)
+	me, other := api.user("me"), api.user("other")
+
+	saves := []struct {
+		body       handlers.SaveStatRequest
+		wantStatus int
+		wantCode   apperrors.Code
+	}{
+		{handlers.SaveStatRequest{Game: "League of Legends", Platform: "Riot"}, http.StatusOK, ""},
+		{handlers.SaveStatRequest{Game: "CS2", Platform: "Steam"}, http.StatusOK, ""},
+		{handlers.SaveStatRequest{Game: "CS2", Platform: "Riot"}, http.StatusBadRequest, apperrors.CodeValidation},
+		{handlers.SaveStatRequest{Game: "Tetris", Platform: "Steam"}, http.StatusBadRequest, apperrors.CodeValidation},
+		{handlers.SaveStatRequest{Platform: "Steam"}, http.StatusBadRequest, apperrors.CodeValidation},
+	}
+	for _, tt := range saves {
+		w := api.do(me.ID, http.MethodPost, "/user/stats/save", tt.body)
+		if tt.wantCode != "" {
+			wantError(t, w, tt.wantStatus, tt.wantCode)
+		} else {
+			decode[handlers.MessageResponse](t, w, tt.wantStatus)
+		}
+	}
+
+	cards := decode[[]models.UserStat](t, api.do(me.ID, http.MethodGet, "/user/stats/", nil), http.StatusOK)
+	if len(cards) != 2 {
+		t.Fatalf("cards = %+v, want the two saved", cards)
+	}
+	cs2 := decode[[]models.UserStat](t, api.do(me.ID, http.MethodGet, "/user/stats/?game=cs2", nil), http.StatusOK)
+	if len(cs2) != 1 || cs2[0].Game != "CS2" {
+		t.Errorf("cards for cs2 = %+v, want the CS2 card", cs2)
+	}
+	if others := decode[[]models.UserStat](t, api.do(other.ID, http.MethodGet, "/user/stats/", nil), http.StatusOK); len(others) != 0 {
+		t.Errorf("other user's cards = %+v, want none", others)
+	}
+
+	path := fmt.Sprintf("/user/stats/%d", cards[0].ID)
+	wantError(t, api.do(other.ID, http.MethodDelete, path, nil), http.StatusNotFound, apperrors.CodeNotFound)
+	wantError(t, api.do(me.ID, http.MethodDelete, "/user/stats/x", nil), http.StatusBadRequest, apperrors.CodeValidation)
+	decode[handlers.MessageResponse](t, api.do(me.ID, http.MethodDelete, path, nil), http.StatusOK)
+	wantError(t, api.do(me.ID, http.MethodDelete, path, nil), http.StatusNotFound, apperrors.CodeNotFound)
+}
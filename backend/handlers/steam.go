@@ -1,104 +1,119 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
+	"strings"
 
-	"elo-insight/backend/database"
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/middleware"
 	"elo-insight/backend/models"
+	"elo-insight/backend/upstream"
+	"elo-insight/backend/validation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/yohcop/openid-go"
 )
 
-func SteamLogin(c *gin.Context) {
+// Steam sign-in assertions are checked through the upstream client. Nonces are
+// kept in memory, so an instance accepts each assertion once.
+var (
+	steamOpenID      = openid.NewOpenID(upstream.Client)
+	steamDiscoveries = openid.NewSimpleDiscoveryCache()
+	steamNonces      = openid.NewSimpleNonceStore()
+)
+
+func (h *Handler) SteamLogin(c *gin.Context) {
+	ctx := c.Request.Context()
 
-	// Get Steam OpenID info from env
-	redirectURL := os.Getenv("STEAM_CALLBACK_URL")
-	openIDURL := os.Getenv("STEAM_OPENID_URL")
+	// Get Steam OpenID info from the configuration
+	redirectURL := h.cfg.Steam.CallbackURL
+	openIDURL := h.cfg.Steam.OpenIDURL
 
-	if openIDURL == "" || redirectURL == "" {
-		log.Println("ERROR: Steam OpenID environment variables not set!")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Steam OpenID variables not set"})
+	if !h.cfg.Steam.IsEnabled() || openIDURL == "" || redirectURL == "" {
+		c.Error(upstreamUnavailable(upstream.Steam, errors.New("Steam sign-in is not configured")))
 		return
 	}
 
 	// Generate OpenID redirect URL
 	authURL, err := openid.RedirectURL(openIDURL, redirectURL, "http://localhost:8080")
 	if err != nil {
-		log.Println("ERROR: Failed to generate OpenID URL:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate OpenID URL"})
+		c.Error(apperrors.Internal(fmt.Errorf("failed to generate Steam OpenID URL: %w", err)))
 		return
 	}
 
-	log.Println("Redirecting user to Steam:", authURL)
+	slog.DebugContext(ctx, "Redirecting to Steam sign-in")
 	c.Redirect(http.StatusFound, authURL)
 }
 
-func SteamCallback(c *gin.Context) {
+func (h *Handler) SteamCallback(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	// ✅ Extract JWT from cookies (since Steam doesn’t send our cookies back)
-	token, err := c.Cookie("token")
+	token, err := c.Cookie(middleware.TokenCookie)
 	if err != nil {
-		log.Println("ERROR: Missing JWT cookie in Steam callback request")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		slog.WarnContext(ctx, "Missing JWT cookie in Steam callback")
+		c.Error(apperrors.Unauthorized("Unauthorized"))
 		return
 	}
 
 	// ✅ Extract user ID from JWT
-	userID, err := extractUserIDFromToken(token)
+	userID, err := h.extractUserIDFromToken(token)
 	if err != nil {
-		log.Println("ERROR: Invalid JWT token:", err)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		slog.WarnContext(ctx, "Invalid JWT in Steam callback", "error", err)
+		c.Error(apperrors.Unauthorized("Invalid token"))
 		return
 	}
 
-	// ✅ Extract Steam ID from OpenID response
-	steamID := extractSteamID(c.Query("openid.claimed_id"))
-	if steamID == "" {
-		log.Println("ERROR: Failed to extract Steam ID from OpenID response")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Steam authentication failed"})
+	// ✅ Have Steam confirm the OpenID assertion before trusting its Steam ID
+	steamID, err := h.verifySteamAssertion(c.Request.URL.RawQuery)
+	if err != nil {
+		slog.WarnContext(ctx, "Steam OpenID assertion rejected", "error", err)
+		c.Error(apperrors.Unauthorized("Steam authentication failed"))
 		return
 	}
 
-	log.Println("Steam Authentication Successful! Steam ID:", steamID)
+	slog.InfoContext(ctx, "Steam sign-in succeeded")
 
 	// ✅ Retrieve user from database
-	var user models.User
-	result := database.DB.First(&user, userID)
-	if result.Error != nil {
-		log.Println("ERROR: User not found:", result.Error)
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	user, err := h.store.Users.Get(ctx, userID)
+	if err != nil {
+		slog.WarnContext(ctx, "User not found for Steam link", "user_id", userID, "error", err)
+		c.Error(apperrors.NotFound("User not found"))
 		return
 	}
 
-	// ✅ Store Steam ID in the database
-	user.SteamID = steamID
-	if err := database.DB.Save(&user).Error; err != nil {
-		log.Println("ERROR: Failed to save Steam ID to user:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save Steam ID"})
+	// ✅ Store the Steam link; Steam confirmed the user signed in as this account
+	link := &models.PlatformLink{
+		UserID:       user.ID,
+		Platform:     models.PlatformSteam,
+		ExternalID:   steamID,
+		Verification: models.LinkVerified,
+	}
+	if err := h.savePlatformLink(ctx, link); err != nil {
+		c.Error(apperrors.From(fmt.Errorf("failed to save Steam link: %w", err)))
 		return
 	}
 
-	log.Println("Steam ID successfully linked to user:", userID)
+	slog.InfoContext(ctx, "Steam account linked", "user_id", userID)
 
 	// ✅ Redirect user back to frontend profile page
-	frontendURL := os.Getenv("FRONTEND_URL")
+	frontendURL := h.cfg.FrontendURL
 	redirectTo := fmt.Sprintf("%s/profile", frontendURL)
-	log.Println("Redirecting user to:", redirectTo)
 	c.Redirect(http.StatusFound, redirectTo)
 }
 
 // ✅ Extract User ID from JWT stored in Cookies
-func extractUserIDFromToken(tokenString string) (uint, error) {
+func (h *Handler) extractUserIDFromToken(tokenString string) (uint, error) {
 	claims := jwt.MapClaims{}
 
 	// ✅ Parse JWT with claims
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
+		return []byte(h.cfg.JWTSecret), nil
 	})
 	if err != nil || !token.Valid {
 		return 0, fmt.Errorf("invalid or expired token")
@@ -113,18 +128,52 @@ func extractUserIDFromToken(tokenString string) (uint, error) {
 	return uint(userIDFloat), nil
 }
 
-// ✅ Extract Steam ID from OpenID response
-func extractSteamID(claimedID string) string {
-	if claimedID == "" {
-		return ""
+// verifySteamAssertion checks the OpenID assertion in a Steam callback's query
+// with Steam, and returns the SteamID64 it proves the user signed in as
+func (h *Handler) verifySteamAssertion(rawQuery string) (string, error) {
+	provider, err := url.Parse(h.cfg.Steam.OpenIDURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid Steam OpenID URL: %w", err)
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", err
 	}
 
-	parsedURL, err := url.Parse(claimedID)
+	// Only ask Steam to check the signature, not a server named in the query
+	endpoint, err := url.Parse(query.Get("openid.op_endpoint"))
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host != provider.Host {
+		return "", fmt.Errorf("assertion from %q, not %s", query.Get("openid.op_endpoint"), provider.Host)
+	}
+
+	// The assertion is checked against the URL Steam was told to return to
+	callback, err := url.Parse(h.cfg.Steam.CallbackURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid Steam callback URL: %w", err)
+	}
+	callback.RawQuery = rawQuery
+	claimedID, err := steamOpenID.Verify(callback.String(), steamDiscoveries, steamNonces)
 	if err != nil {
-		log.Println("ERROR: Failed to parse Steam OpenID URL:", err)
-		return ""
+		return "", err
+	}
+
+	steamID, ok := extractSteamID(claimedID, provider.Host)
+	if !ok {
+		return "", fmt.Errorf("claimed ID %q is not a Steam account", claimedID)
 	}
+	return steamID, nil
+}
 
-	steamID := parsedURL.Path[len("/openid/id/"):]
-	return steamID
+// extractSteamID returns the SteamID64 in a claimed ID issued by host, like
+// https://steamcommunity.com/openid/id/76561197960287930
+func extractSteamID(claimedID, host string) (string, bool) {
+	parsedURL, err := url.Parse(claimedID)
+	if err != nil || parsedURL.Scheme != "https" || parsedURL.Host != host {
+		return "", false
+	}
+	steamID, ok := strings.CutPrefix(parsedURL.Path, "/openid/id/")
+	if !ok || validation.Value("steam_id", steamID, validation.TagSteamID64) != nil {
+		return "", false
+	}
+	return steamID, true
 }
@@ -0,0 +1,53 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/middleware"
+)
+
+func TestSteamCallbackRejectsUnverifiedAssertions(t *testing.T) {
+	api := newTestAPI(t)
+	api.route(http.MethodGet, "/auth/steam/callback", api.h.SteamCallback)
+	me := api.user("me")
+	token, err := middleware.GenerateJWT(me)
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+
+	// None of these reach Steam: they fail before the signature is checked
+	const claimedID = "https://steamcommunity.com/openid/id/76561197960287930"
+	tests := []struct {
+		name  string
+		query url.Values
+	}{
+		{"claimed ID alone", url.Values{"openid.claimed_id": {claimedID}}},
+		{"another provider", url.Values{
+			"openid.op_endpoint": {"https://openid.example.com/login"},
+			"openid.claimed_id":  {claimedID},
+		}},
+		{"unsigned fields", url.Values{
+			"openid.op_endpoint": {"https://steamcommunity.com/openid/login"},
+			"openid.claimed_id":  {claimedID},
+			"openid.signed":      {"op_endpoint"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/auth/steam/callback?"+tt.query.Encode(), nil)
+			req.AddCookie(&http.Cookie{Name: middleware.TokenCookie, Value: token})
+			w := httptest.NewRecorder()
+			api.router.ServeHTTP(w, req)
+
+			wantError(t, w, http.StatusUnauthorized, apperrors.CodeUnauthorized)
+			if links, _ := api.store.PlatformLinks.ListByUser(context.Background(), me.ID); len(links) != 0 {
+				t.Errorf("links = %+v, want none", links)
+			}
+		})
+	}
+}
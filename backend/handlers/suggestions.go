@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Friend suggestion tuning
+const (
+	defaultSuggestionLimit  = 10
+	maxSuggestionLimit      = 25
+	suggestionRecentMatches = 20 // Recent matches per game scanned for co-players
+	mutualFriendWeight      = 3  // A mutual friend counts for more than one shared match
+	coPlayerWeight          = 2
+)
+
+// Display names used in suggestion reasons
+var gameDisplayNames = map[string]string{
+	"lol":      "League of Legends",
+	"valorant": "Valorant",
+}
+
+// GetFriendSuggestions ranks users the authenticated user may know
+func (h *Handler) GetFriendSuggestions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var query FriendSuggestionsQuery
+	if !bindQuery(c, &query) {
+		return
+	}
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultSuggestionLimit
+	}
+	limit = min(limit, maxSuggestionLimit)
+
+	// Friends and pending requests either way are excluded from suggestions;
+	// declined and cancelled requests don't rule a user out
+	related, err := h.store.Friendships.ListForUser(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch friendships: %w", err)))
+		return
+	}
+
+	excluded := map[uint]bool{userID.(uint): true}
+	var friendIDs []uint
+	for _, friendship := range related {
+		otherID := friendship.FriendID
+		if otherID == userID.(uint) {
+			otherID = friendship.UserID
+		}
+		switch friendship.Status {
+		case models.FriendshipAccepted:
+			excluded[otherID] = true
+			friendIDs = append(friendIDs, otherID)
+		case models.FriendshipPending:
+			excluded[otherID] = true
+		}
+	}
+
+	candidates := make(map[uint]*models.FriendSuggestionResponse)
+	candidate := func(id uint) *models.FriendSuggestionResponse {
+		if candidates[id] == nil {
+			candidates[id] = &models.FriendSuggestionResponse{UserID: id}
+		}
+		return candidates[id]
+	}
+
+	// Friends of friends
+	mutuals, err := h.mutualFriendCounts(c.Request.Context(), friendIDs)
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch mutual friends: %w", err)))
+		return
+	}
+	for id, count := range mutuals {
+		if excluded[id] {
+			continue
+		}
+		candidate(id).MutualFriends = count
+	}
+
+	// Linked users who showed up in our recent matches
+	link, err := h.userPlatformLink(c.Request.Context(), userID.(uint), models.PlatformRiot)
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch Riot link: %w", err)))
+		return
+	}
+	coPlayReasons := make(map[uint][]string)
+	if link != nil {
+		for _, game := range []string{"lol", "valorant"} {
+			counts, err := h.recentCoPlayerCounts(c.Request.Context(), link.ExternalID, game)
+			if err != nil {
+				c.Error(apperrors.Internal(fmt.Errorf("failed to fetch co-players: %w", err)))
+				return
+			}
+			for id, count := range counts {
+				if excluded[id] {
+					continue
+				}
+				candidate(id).RecentMatches += count
+				coPlayReasons[id] = append(coPlayReasons[id],
+					fmt.Sprintf("Played %s with you in %s", pluralize(count, "recent match", "recent matches"), gameDisplayNames[game]))
+			}
+		}
+	}
+
+	suggestions := make([]models.FriendSuggestionResponse, 0, len(candidates))
+	for id, suggestion := range candidates {
+		suggestion.Score = suggestion.MutualFriends*mutualFriendWeight + suggestion.RecentMatches*coPlayerWeight
+		suggestion.Reasons = []string{}
+		if suggestion.MutualFriends > 0 {
+			suggestion.Reasons = append(suggestion.Reasons,
+				pluralize(suggestion.MutualFriends, "mutual friend", "mutual friends"))
+		}
+		suggestion.Reasons = append(suggestion.Reasons, coPlayReasons[id]...)
+		suggestions = append(suggestions, *suggestion)
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].UserID < suggestions[j].UserID
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	// Fill in usernames for the suggestions we return
+	ids := make([]uint, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		ids = append(ids, suggestion.UserID)
+	}
+	usernames, err := h.usernamesByID(c.Request.Context(), ids)
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch suggested users: %w", err)))
+		return
+	}
+	for i := range suggestions {
+		suggestions[i].Username = usernames[suggestions[i].UserID]
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// mutualFriendCounts counts, for every friend of one of friendIDs, how many of friendIDs they know
+func (h *Handler) mutualFriendCounts(ctx context.Context, friendIDs []uint) (map[uint]int, error) {
+	counts := make(map[uint]int)
+	if len(friendIDs) == 0 {
+		return counts, nil
+	}
+
+	friendships, err := h.store.Friendships.ListAcceptedAmong(ctx, friendIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	isFriend := make(map[uint]bool, len(friendIDs))
+	for _, id := range friendIDs {
+		isFriend[id] = true
+	}
+	for _, friendship := range friendships {
+		// A row between two of our friends is a mutual for both of them
+		if isFriend[friendship.UserID] {
+			counts[friendship.FriendID]++
+		}
+		if isFriend[friendship.FriendID] {
+			counts[friendship.UserID]++
+		}
+	}
+	return counts, nil
+}
+
+// recentCoPlayerCounts counts how many of the player's recent matches each linked user appeared in
+func (h *Handler) recentCoPlayerCounts(ctx context.Context, puuid string, game string) (map[uint]int, error) {
+	counts := make(map[uint]int)
+
+	rows, err := h.store.Matches.Participations(ctx, []string{puuid}, game)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > suggestionRecentMatches {
+		rows = rows[:suggestionRecentMatches]
+	}
+	if len(rows) == 0 {
+		return counts, nil
+	}
+
+	matchIDs := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		matchIDs = append(matchIDs, row.StoredMatchID)
+	}
+
+	participants, err := h.store.Matches.ParticipantsInMatches(ctx, matchIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// The matches each other player appeared in
+	matchesByPUUID := make(map[string]map[uint]bool)
+	for _, p := range participants {
+		if p.PUUID == puuid {
+			continue
+		}
+		if matchesByPUUID[p.PUUID] == nil {
+			matchesByPUUID[p.PUUID] = make(map[uint]bool)
+		}
+		matchesByPUUID[p.PUUID][p.StoredMatchID] = true
+	}
+	if len(matchesByPUUID) == 0 {
+		return counts, nil
+	}
+
+	// Keep the players who are linked to one of our users
+	puuids := make([]string, 0, len(matchesByPUUID))
+	for coPUUID := range matchesByPUUID {
+		puuids = append(puuids, coPUUID)
+	}
+	links, err := h.store.PlatformLinks.FindByExternalIDs(ctx, models.PlatformRiot, puuids)
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		counts[link.UserID] = len(matchesByPUUID[link.ExternalID])
+	}
+	return counts, nil
+}
+
+// pluralize formats a count with the singular or plural noun
+func pluralize(count int, singular, plural string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, singular)
+	}
+	return fmt.Sprintf("%d %s", count, plural)
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/models"
+	"elo-insight/backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Number of champion/agent pairings returned by the synergy endpoint
+const maxSynergyPairings = 5
+
+// SynergyStats compares how two players do with and without each other
+type SynergyStats struct {
+	Game            string           `json:"game"`
+	UserID          uint             `json:"userId"`
+	PartnerID       uint             `json:"partnerId"`
+	PartnerUsername string           `json:"partnerUsername"`
+	GamesTogether   int              `json:"gamesTogether"`
+	WinsTogether    int              `json:"winsTogether"`
+	WinRateTogether float64          `json:"winRateTogether"`
+	GamesApart      int              `json:"gamesApart"`
+	WinsApart       int              `json:"winsApart"`
+	WinRateApart    float64          `json:"winRateApart"`
+	GamesAgainst    int              `json:"gamesAgainst"`
+	CombinedKDA     float64          `json:"combinedKda"`
+	BestPairings    []SynergyPairing `json:"bestPairings"`
+	MatchesAnalyzed int              `json:"matchesAnalyzed"`
+	PartnerIsLinked bool             `json:"partnerIsLinked"`
+	UserIsLinked    bool             `json:"userIsLinked"`
+}
+
+// SynergyPairing is the record for one champion/agent combination played together
+type SynergyPairing struct {
+	Character        string  `json:"character"`
+	PartnerCharacter string  `json:"partnerCharacter"`
+	Games            int     `json:"games"`
+	Wins             int     `json:"wins"`
+	WinRate          float64 `json:"winRate"`
+}
+
+// GetSynergy reports how the user performs with another user from stored matches
+func (h *Handler) GetSynergy(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.Error(apperrors.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var query SynergyQuery
+	if !bindQuery(c, &query) {
+		return
+	}
+	game, partnerID := query.Game, query.With
+	if game == "" {
+		game = "lol"
+	}
+	if partnerID == userID.(uint) {
+		c.Error(apperrors.Validation("Cannot compare a user with themselves", nil))
+		return
+	}
+
+	// Synergy is only shown between friends
+	friendIDs, err := h.acceptedFriendIDs(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch friendships: %w", err)))
+		return
+	}
+	isFriend := false
+	for _, id := range friendIDs {
+		if id == partnerID {
+			isFriend = true
+			break
+		}
+	}
+	if !isFriend {
+		c.Error(apperrors.Forbidden("Synergy is only available for friends"))
+		return
+	}
+
+	partner, err := h.store.Users.Get(c.Request.Context(), partnerID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.Error(apperrors.NotFound("User not found"))
+		} else {
+			c.Error(apperrors.Internal(fmt.Errorf("failed to fetch partner: %w", err)))
+		}
+		return
+	}
+
+	puuids, err := h.riotPUUIDsByUser(c.Request.Context(), []uint{userID.(uint), partner.ID})
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch Riot links: %w", err)))
+		return
+	}
+
+	stats, err := h.Synergy(c.Request.Context(), game, userID.(uint), []models.User{*partner}, puuids)
+	if err != nil {
+		c.Error(apperrors.Internal(fmt.Errorf("failed to fetch stored matches: %w", err)))
+		return
+	}
+	c.JSON(http.StatusOK, stats[0])
+}
+
+// Synergy compares the user with each partner in the stored matches of game,
+// lol or valorant, reading the matches once for all of them. puuids maps user
+// IDs to Riot PUUIDs like riotPUUIDsByUser; a player missing from it is
+// reported as unlinked. Callers check that the partners are friends.
+func (h *Handler) Synergy(ctx context.Context, game string, userID uint, partners []models.User, puuids map[uint]string) ([]SynergyStats, error) {
+	userPUUID := puuids[userID]
+
+	all := make([]SynergyStats, len(partners))
+	wanted := []string{userPUUID}
+	for i, partner := range partners {
+		all[i] = SynergyStats{
+			Game:            game,
+			UserID:          userID,
+			PartnerID:       partner.ID,
+			PartnerUsername: partner.Username,
+			BestPairings:    []SynergyPairing{},
+			UserIsLinked:    userPUUID != "",
+			PartnerIsLinked: puuids[partner.ID] != "",
+		}
+		if all[i].PartnerIsLinked {
+			wanted = append(wanted, puuids[partner.ID])
+		}
+	}
+	if userPUUID == "" || len(wanted) == 1 {
+		return all, nil
+	}
+
+	rows, err := h.store.Matches.Participations(ctx, wanted, game)
+	if err != nil {
+		return nil, err
+	}
+	for i, stats := range all {
+		if stats.PartnerIsLinked {
+			all[i] = calculateSynergy(stats, rows, userPUUID, puuids[stats.PartnerID])
+		}
+	}
+	return all, nil
+}
+
+// calculateSynergy fills in stats from both players' participations
+func calculateSynergy(stats SynergyStats, rows []store.Participation, userPUUID, partnerPUUID string) SynergyStats {
+	mine := make(map[uint]store.Participation)
+	theirs := make(map[uint]store.Participation)
+	for _, row := range rows {
+		if row.PUUID == userPUUID {
+			mine[row.StoredMatchID] = row
+		} else if row.PUUID == partnerPUUID {
+			theirs[row.StoredMatchID] = row
+		}
+	}
+
+	type pairKey struct{ mine, theirs string }
+	pairings := make(map[pairKey]*SynergyPairing)
+	kills, deaths, assists := 0, 0, 0
+
+	for matchID, me := range mine {
+		stats.MatchesAnalyzed++
+		them, together := theirs[matchID]
+
+		if together && them.TeamID != me.TeamID {
+			stats.GamesAgainst++
+			continue
+		}
+		if !together {
+			stats.GamesApart++
+			if me.Win {
+				stats.WinsApart++
+			}
+			continue
+		}
+
+		stats.GamesTogether++
+		if me.Win {
+			stats.WinsTogether++
+		}
+		kills += me.Kills + them.Kills
+		deaths += me.Deaths + them.Deaths
+		assists += me.Assists + them.Assists
+
+		key := pairKey{mine: me.Character, theirs: them.Character}
+		pairing, exists := pairings[key]
+		if !exists {
+			pairing = &SynergyPairing{Character: me.Character, PartnerCharacter: them.Character}
+			pairings[key] = pairing
+		}
+		pairing.Games++
+		if me.Win {
+			pairing.Wins++
+		}
+	}
+
+	if stats.GamesTogether > 0 {
+		stats.WinRateTogether = float64(stats.WinsTogether) / float64(stats.GamesTogether) * 100
+		stats.CombinedKDA = calculateKDA(kills, deaths, assists)
+	}
+	if stats.GamesApart > 0 {
+		stats.WinRateApart = float64(stats.WinsApart) / float64(stats.GamesApart) * 100
+	}
+
+	for _, pairing := range pairings {
+		pairing.WinRate = float64(pairing.Wins) / float64(pairing.Games) * 100
+		stats.BestPairings = append(stats.BestPairings, *pairing)
+	}
+
+	// Best pairings first, breaking ties by sample size and then name for a stable order
+	sort.Slice(stats.BestPairings, func(i, j int) bool {
+		a, b := stats.BestPairings[i], stats.BestPairings[j]
+		if a.WinRate != b.WinRate {
+			return a.WinRate > b.WinRate
+		}
+		if a.Games != b.Games {
+			return a.Games > b.Games
+		}
+		return a.Character+a.PartnerCharacter < b.Character+b.PartnerCharacter
+	})
+	if len(stats.BestPairings) > maxSynergyPairings {
+		stats.BestPairings = stats.BestPairings[:maxSynergyPairings]
+	}
+
+	return stats
+}
@@ -1,16 +1,20 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
-	"os"
 	"sort"
 	"strings"
 	"time"
 
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/cache"
+	"elo-insight/backend/models"
+	"elo-insight/backend/upstream"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,23 +25,39 @@ const (
 )
 
 // GetValorantStats fetches Valorant stats using the Riot API with fallback to mock data
-func GetValorantStats(c *gin.Context) {
-	riotID := c.Query("riot_id")
+func (h *Handler) GetValorantStats(c *gin.Context) {
+	var query ValorantStatsQuery
+	if !bindQuery(c, &query) {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	signedIn, _ := userID.(uint)
+
+	stats, err := h.ValorantStats(c.Request.Context(), query.RiotID, signedIn)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// ValorantStats returns the Valorant stats for a Riot ID, or for userID when
+// riotID is empty. It fails only without either.
+func (h *Handler) ValorantStats(ctx context.Context, riotID string, userID uint) (*ValorantStatsResponse, error) {
 	if riotID == "" {
-		userID, exists := c.Get("userID")
-		if !exists {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Riot ID is required"})
-			return
+		if userID == 0 {
+			return nil, apperrors.Validation("Riot ID is required", map[string]string{"riot_id": "required"})
 		}
 		riotID = fmt.Sprintf("user-%v#NA1", userID)
 	}
 
-	log.Printf("Fetching Valorant stats for Riot ID: %s", riotID)
+	slog.InfoContext(ctx, "Fetching Valorant stats", "user_id", userID)
 
-	// Get the Riot API key from environment variables
-	riotAPIKey := os.Getenv("RIOT_API_KEY")
+	// Get the Riot API key from the configuration
+	riotAPIKey := h.cfg.Riot.APIKey
 	if riotAPIKey == "" {
-		log.Println("Riot API key not set, using mock data")
+		slog.WarnContext(ctx, "Riot API key not set, using mock Valorant data")
 	}
 
 	// Try to get account data from Riot API
@@ -57,13 +77,13 @@ func GetValorantStats(c *gin.Context) {
 	var realAccount *ValorantAccount
 	var err error
 	if riotAPIKey != "" {
-		realAccount, err = getAccountByRiotID(riotID, riotAPIKey)
+		realAccount, err = getAccountByRiotID(ctx, riotID, riotAPIKey)
 		if err == nil && realAccount != nil {
 			playerName = realAccount.GameName
 			playerTag = realAccount.TagLine
-			log.Printf("Found real account: %s#%s", playerName, playerTag)
+			slog.DebugContext(ctx, "Found Riot account for Valorant stats")
 		} else {
-			log.Printf("Failed to get real account data, using mock data: %v", err)
+			slog.WarnContext(ctx, "Failed to get Riot account, using mock Valorant data", "error", err)
 		}
 	}
 
@@ -276,63 +296,64 @@ func GetValorantStats(c *gin.Context) {
 	}
 
 	// Add performance metrics for radar chart
-	performanceMetrics := []map[string]interface{}{
-		{"subject": "Accuracy", "A": 76, "fullMark": 100},
-		{"subject": "First Blood", "A": 68, "fullMark": 100},
-		{"subject": "Clutch", "A": 72, "fullMark": 100},
-		{"subject": "Economy", "A": 65, "fullMark": 100},
-		{"subject": "Support", "A": 58, "fullMark": 100},
+	performanceMetrics := []PerformanceMetric{
+		{Subject: "Accuracy", Score: 76, FullMark: 100},
+		{Subject: "First Blood", Score: 68, FullMark: 100},
+		{Subject: "Clutch", Score: 72, FullMark: 100},
+		{Subject: "Economy", Score: 65, FullMark: 100},
+		{Subject: "Support", Score: 58, FullMark: 100},
 	}
 
 	// Add map statistics
-	mapStats := []map[string]interface{}{
-		{
-			"name":     "Ascent",
-			"wins":     36,
-			"games":    62,
-			"win_rate": 58.1,
-		},
-		{
-			"name":     "Bind",
-			"wins":     28,
-			"games":    51,
-			"win_rate": 54.9,
-		},
-		{
-			"name":     "Haven",
-			"wins":     32,
-			"games":    58,
-			"win_rate": 55.2,
-		},
-		{
-			"name":     "Split",
-			"wins":     34,
-			"games":    56,
-			"win_rate": 60.7,
-		},
-		{
-			"name":     "Icebox",
-			"wins":     33,
-			"games":    60,
-			"win_rate": 55.0,
-		},
+	mapStats := []MapStats{
+		{Name: "Ascent", Wins: 36, Games: 62, WinRate: 58.1},
+		{Name: "Bind", Wins: 28, Games: 51, WinRate: 54.9},
+		{Name: "Haven", Wins: 32, Games: 58, WinRate: 55.2},
+		{Name: "Split", Wins: 34, Games: 56, WinRate: 60.7},
+		{Name: "Icebox", Wins: 33, Games: 60, WinRate: 55.0},
 	}
 
 	// Prepare the response with all the mock data in the structure expected by the frontend
-	response := gin.H{
-		"account":              account,
-		"profile":              profile,
-		"matches":              matchStats,  // Frontend expects 'matches' not 'stats'
-		"total_kills":          4312,
-		"win_rate":             56.8,
-		"headshot_percentage":  33.0,
-		"performance_metrics":  performanceMetrics,
-		"map_stats":            mapStats,
+	response := ValorantStatsResponse{
+		Account:            account,
+		Profile:            profile,
+		Matches:            matchStats, // Frontend expects 'matches' not 'stats'
+		TotalKills:         4312,
+		WinRate:            56.8,
+		HeadshotPercentage: 33.0,
+		PerformanceMetrics: performanceMetrics,
+		MapStats:           mapStats,
 	}
 
-	log.Println("Returning mock Valorant stats for player:", playerName)
-	c.JSON(http.StatusOK, response)
-	return
+	slog.DebugContext(ctx, "Returning mock Valorant stats")
+	return &response, nil
+}
+
+// ValorantStatsResponse is the body of GET /api/v1/stats/valorant
+type ValorantStatsResponse struct {
+	Account            *ValorantAccount    `json:"account"`
+	Profile            *ValorantProfile    `json:"profile"`
+	Matches            *ValorantMatchStats `json:"matches"`
+	TotalKills         int                 `json:"total_kills"`
+	WinRate            float64             `json:"win_rate"`
+	HeadshotPercentage float64             `json:"headshot_percentage"`
+	PerformanceMetrics []PerformanceMetric `json:"performance_metrics"`
+	MapStats           []MapStats          `json:"map_stats"`
+}
+
+// PerformanceMetric is one axis of the performance radar chart
+type PerformanceMetric struct {
+	Subject  string `json:"subject"`
+	Score    int    `json:"A"` // Named for the chart library's data key
+	FullMark int    `json:"fullMark"`
+}
+
+// MapStats is the player's record on one map
+type MapStats struct {
+	Name    string  `json:"name"`
+	Wins    int     `json:"wins"`
+	Games   int     `json:"games"`
+	WinRate float64 `json:"win_rate"`
 }
 
 // ValorantAccount represents a Valorant player's Riot account
@@ -422,7 +443,7 @@ type AgentStats struct {
 }
 
 // getAccountByRiotID retrieves the Riot account data using the Riot API
-func getAccountByRiotID(riotID string, apiKey string) (*ValorantAccount, error) {
+func getAccountByRiotID(ctx context.Context, riotID string, apiKey string) (*ValorantAccount, error) {
 	// Parse the Riot ID into gameName and tagLine
 	gameName := riotID
 	tagLine := "NA1" // Default region tag
@@ -432,38 +453,12 @@ func getAccountByRiotID(riotID string, apiKey string) (*ValorantAccount, error)
 		tagLine = riotID[idx+1:]
 	}
 
-	log.Printf("Looking up Riot account for %s#%s", gameName, tagLine)
-
-	// Use the Account V1 API to get the PUUID
-	accountURL := fmt.Sprintf("https://americas.api.riotgames.com/riot/account/v1/accounts/by-riot-id/%s/%s",
-		gameName, tagLine)
-
-	req, err := http.NewRequest("GET", accountURL, nil)
+	// Use the Account V1 API to get the PUUID; shares the cached response with League lookups
+	riotAccount, err := getRiotAccount(ctx, gameName, tagLine, apiKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		return nil, err
 	}
-
-	req.Header.Add("X-Riot-Token", apiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Account API returned status code %d: %s", resp.StatusCode, string(body))
-	}
-
-	var account ValorantAccount
-	if err := json.NewDecoder(resp.Body).Decode(&account); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
-	}
-
-	log.Printf("Found Riot account with PUUID: %s", account.PUUID)
-
+	account := ValorantAccount(*riotAccount)
 	return &account, nil
 }
 
@@ -486,29 +481,12 @@ func getValorantProfile(puuid string, apiKey string) (*ValorantProfile, error) {
 }
 
 // getValorantMatchHistory retrieves match IDs for a player using the official Valorant API endpoint
-func getValorantMatchHistory(puuid string, apiKey string) ([]string, error) {
+func getValorantMatchHistory(ctx context.Context, puuid string, apiKey string) ([]string, error) {
 	// Use the official Valorant match history endpoint
 	url := fmt.Sprintf("%s/val/match/v1/matchlists/by-puuid/%s", ValorantAPIBaseURL, puuid)
-	log.Printf("Getting Valorant match history from: %s", url)
+	slog.DebugContext(ctx, "Getting Valorant match history")
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Add("X-Riot-Token", apiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("valorant API returned status code %d: %s", resp.StatusCode, string(bodyBytes))
-	}
+	key := cache.Key{Provider: upstream.Riot, Endpoint: endpointValMatchIDs, Identity: puuid}
 
 	// Parse the response to get match IDs
 	var matchListResponse struct {
@@ -519,8 +497,8 @@ func getValorantMatchHistory(puuid string, apiKey string) ([]string, error) {
 		} `json:"history"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&matchListResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+	if err := fetchCachedJSON(ctx, key, url, riotHeader(apiKey), &matchListResponse); err != nil {
+		return nil, fmt.Errorf("valorant API error: %w", err)
 	}
 
 	// Extract the match IDs from the response
@@ -536,17 +514,17 @@ func getValorantMatchHistory(puuid string, apiKey string) ([]string, error) {
 		matchIDs = matchIDs[:25]
 	}
 
-	log.Printf("Found %d matches for PUUID %s", len(matchIDs), puuid)
+	slog.DebugContext(ctx, "Found Valorant matches", "count", len(matchIDs))
 	return matchIDs, nil
 }
 
 // processValorantMatches processes match data to calculate statistics
-func processValorantMatches(puuid string, matchIDs []string, apiKey string) (*ValorantMatchStats, error) {
-	if len(matchIDs) == 0 {
-		return nil, fmt.Errorf("no matches found for player")
+func processValorantMatches(ctx context.Context, puuid string, matches []fetchedMatch) (*ValorantMatchStats, error) {
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no matches could be loaded for player")
 	}
 
-	log.Printf("Processing %d Valorant matches for PUUID: %s", len(matchIDs), puuid)
+	slog.DebugContext(ctx, "Processing Valorant matches", "count", len(matches))
 
 	// Initialize statistics
 	stats := &ValorantMatchStats{
@@ -592,31 +570,8 @@ func processValorantMatches(puuid string, matchIDs []string, apiKey string) (*Va
 	})
 
 	// Process each match
-	for _, matchID := range matchIDs {
-		// Get match details
-		matchURL := fmt.Sprintf("%s/val/match/v1/matches/%s", ValorantAPIBaseURL, matchID)
-		log.Printf("Getting match details from: %s", matchURL)
-
-		req, err := http.NewRequest("GET", matchURL, nil)
-		if err != nil {
-			log.Printf("Error creating request for match %s: %v", matchID, err)
-			continue
-		}
-
-		req.Header.Add("X-Riot-Token", apiKey)
-
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			log.Printf("Error fetching match %s: %v", matchID, err)
-			continue
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			log.Printf("Match API returned status code %d for match %s", resp.StatusCode, matchID)
-			continue
-		}
+	for _, fetched := range matches {
+		matchID := fetched.ID
 
 		// Parse match data
 		var matchData struct {
@@ -664,8 +619,8 @@ func processValorantMatches(puuid string, matchIDs []string, apiKey string) (*Va
 			} `json:"rounds"`
 		}
 
-		if err := json.NewDecoder(resp.Body).Decode(&matchData); err != nil {
-			log.Printf("Error decoding match %s: %v", matchID, err)
+		if err := json.Unmarshal(fetched.Body, &matchData); err != nil {
+			slog.WarnContext(ctx, "Failed to decode Valorant match", "match_id", matchID, "error", err)
 			continue
 		}
 
@@ -738,7 +693,7 @@ func processValorantMatches(puuid string, matchIDs []string, apiKey string) (*Va
 		}
 
 		if playerData == nil {
-			log.Printf("Player not found in match %s", matchID)
+			slog.WarnContext(ctx, "Player not found in match", "game", "valorant", "match_id", matchID)
 			continue
 		}
 
@@ -768,7 +723,7 @@ func processValorantMatches(puuid string, matchIDs []string, apiKey string) (*Va
 		}
 
 		if playerTeam == nil {
-			log.Printf("Player's team not found in match %s", matchID)
+			slog.WarnContext(ctx, "Player's team not found in match", "game", "valorant", "match_id", matchID)
 			continue
 		}
 
@@ -944,8 +899,71 @@ func processValorantMatches(puuid string, matchIDs []string, apiKey string) (*Va
 	return stats, nil
 }
 
-// calculateAgentStats calculates statistics by agent
-func calculateAgentStats(puuid string, matchIDs []string, apiKey string) ([]AgentStats, error) {
+// valorantMatchRecord is the part of a match-v1 match the activity feed stores
+type valorantMatchRecord struct {
+	MatchInfo struct {
+		QueueID   string `json:"queueId"`
+		StartTime int64  `json:"gameStartMillis"`
+		Teams     []struct {
+			TeamID string `json:"teamId"`
+			Won    bool   `json:"won"`
+		} `json:"teams"`
+	} `json:"matchInfo"`
+	Players []struct {
+		PUUID         string `json:"puuid"`
+		TeamID        string `json:"teamId"`
+		CharacterName string `json:"character"`
+		Stats         struct {
+			Kills   int `json:"kills"`
+			Deaths  int `json:"deaths"`
+			Assists int `json:"assists"`
+		} `json:"stats"`
+	} `json:"players"`
+}
+
+// recordValorantMatches stores the matches given to processValorantMatches
+// for the activity feed, logging any failure
+func (h *Handler) recordValorantMatches(ctx context.Context, matches []fetchedMatch) {
+	stored := make([]models.StoredMatch, 0, len(matches))
+	for _, fetched := range matches {
+		var record valorantMatchRecord
+		if err := json.Unmarshal(fetched.Body, &record); err != nil {
+			slog.WarnContext(ctx, "Failed to decode Valorant match", "match_id", fetched.ID, "error", err)
+			continue
+		}
+
+		match := models.StoredMatch{
+			Game:     "valorant",
+			MatchID:  fetched.ID,
+			QueueID:  record.MatchInfo.QueueID,
+			PlayedAt: time.UnixMilli(record.MatchInfo.StartTime),
+		}
+		teamWon := make(map[string]bool)
+		for _, team := range record.MatchInfo.Teams {
+			teamWon[team.TeamID] = team.Won
+		}
+		for _, player := range record.Players {
+			match.Participants = append(match.Participants, models.MatchParticipant{
+				PUUID:     player.PUUID,
+				TeamID:    player.TeamID,
+				Character: player.CharacterName,
+				Win:       teamWon[player.TeamID],
+				Kills:     player.Stats.Kills,
+				Deaths:    player.Stats.Deaths,
+				Assists:   player.Stats.Assists,
+			})
+		}
+		stored = append(stored, match)
+	}
+
+	if err := h.feed.RecordMatches(ctx, stored); err != nil {
+		slog.WarnContext(ctx, "Failed to record matches", "game", "valorant", "error", err)
+	}
+}
+
+// calculateAgentStats calculates statistics by agent from the matches fetched
+// for processValorantMatches
+func calculateAgentStats(puuid string, matches []fetchedMatch) ([]AgentStats, error) {
 	// TODO: Implement real agent stats calculation from match data.
 	// For now, return empty slice if not implemented
 	return []AgentStats{}, nil
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"elo-insight/backend/database"
+)
+
+// Database checks that the database answers a ping
+func Database() Check {
+	return Check{
+		Name:     "database",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			if err := database.Ping(ctx); err != nil {
+				return "", err
+			}
+			return database.DB.Dialector.Name(), nil
+		},
+	}
+}
+
+// Migrations checks that every migration in the binary has been applied
+func Migrations() Check {
+	return Check{
+		Name:     "migrations",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			current, latest, err := database.SchemaVersion(ctx)
+			if err != nil {
+				return "", err
+			}
+			detail := fmt.Sprintf("version %d of %d", current, latest)
+			if current < latest {
+				return detail, fmt.Errorf("schema is at version %d but this build needs %d; run `migrate up`", current, latest)
+			}
+			return detail, nil
+		},
+	}
+}
+
+// OTLPExporter checks that the trace collector accepts connections. Spans are
+// only buffered while it is down, so this is not critical.
+func OTLPExporter(endpoint string) Check {
+	return Check{
+		Name: "otlp_exporter",
+		Run: func(ctx context.Context) (string, error) {
+			if endpoint == "" {
+				return "", errors.New("no OTLP endpoint configured")
+			}
+			var dialer net.Dialer
+			conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+			if err != nil {
+				return "", err
+			}
+			conn.Close()
+			return endpoint, nil
+		},
+	}
+}
@@ -0,0 +1,114 @@
+// Package health runs the dependency checks behind the readiness endpoint
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Check statuses
+const (
+	StatusOK          = "ok"
+	StatusDegraded    = "degraded"    // A non-critical check failed; still serving
+	StatusUnavailable = "unavailable" // A critical check failed; take out of rotation
+	StatusFail        = "fail"
+)
+
+// Check is one dependency probe. Run returns a short detail on success.
+// A failing critical check makes the service unready; a failing
+// non-critical one only marks it degraded.
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) (detail string, err error)
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Critical   bool   `json:"critical"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// Report is the outcome of every check
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Ready reports whether every critical check passed
+func (r Report) Ready() bool {
+	return r.Status != StatusUnavailable
+}
+
+// Checker runs a fixed set of checks
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+}
+
+// NewChecker returns a checker giving each run at most timeout
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+// Run runs every check concurrently and reports the results in registration order
+func (c *Checker) Run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: results}
+	for _, result := range results {
+		if result.Status == StatusOK {
+			continue
+		}
+		if result.Critical {
+			report.Status = StatusUnavailable
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// run runs a single check, giving up when ctx expires even if the check ignores it
+func run(ctx context.Context, check Check) Result {
+	type outcome struct {
+		detail string
+		err    error
+	}
+	start := time.Now()
+	done := make(chan outcome, 1)
+	go func() {
+		detail, err := check.Run(ctx)
+		done <- outcome{detail, err}
+	}()
+
+	result := Result{Name: check.Name, Critical: check.Critical, Status: StatusOK}
+	select {
+	case o := <-done:
+		result.Detail = o.detail
+		if o.err != nil {
+			result.Status, result.Error = StatusFail, o.err.Error()
+		}
+	case <-ctx.Done():
+		result.Status, result.Error = StatusFail, "timed out"
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+	return result
+}
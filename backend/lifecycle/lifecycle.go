@@ -0,0 +1,164 @@
+// Package lifecycle runs the HTTP server and background workers, and shuts
+// everything down in order when the process is asked to stop: the server
+// stops accepting connections and drains in-flight requests, workers are
+// cancelled and waited for, then shutdown hooks (flushing telemetry, closing
+// the database) run in reverse order of registration. Each hook gets its own
+// time budget, so a slow drain can't leave telemetry unflushed.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// hook is a named shutdown step
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// App owns the server, workers and shutdown hooks for one process
+type App struct {
+	server          *http.Server
+	shutdownTimeout time.Duration
+	hookTimeout     time.Duration
+
+	// Cancelled when shutdown starts so workers can stop
+	workerCtx    context.Context
+	stopWorkers  context.CancelFunc
+	workers      sync.WaitGroup
+	mu           sync.Mutex
+	hooks        []hook
+	shutdownOnce sync.Once
+}
+
+// New returns an App serving server. Draining requests and stopping workers
+// may take shutdownTimeout together; each shutdown hook then gets hookTimeout.
+func New(server *http.Server, shutdownTimeout, hookTimeout time.Duration) *App {
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	return &App{
+		server:          server,
+		shutdownTimeout: shutdownTimeout,
+		hookTimeout:     hookTimeout,
+		workerCtx:       workerCtx,
+		stopWorkers:     stopWorkers,
+	}
+}
+
+// Go runs a background worker. Its context is cancelled when shutdown starts,
+// and shutdown waits for it to return after in-flight requests have drained.
+func (a *App) Go(name string, fn func(ctx context.Context)) {
+	a.workers.Add(1)
+	go func() {
+		defer a.workers.Done()
+		fn(a.workerCtx)
+		slog.Info("Worker stopped", "worker", name)
+	}()
+}
+
+// OnShutdown registers a step to run after the server and workers have stopped.
+// Steps run last-registered first, so resources close in the reverse order they were opened.
+func (a *App) OnShutdown(name string, fn func(ctx context.Context) error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.hooks = append(a.hooks, hook{name: name, fn: fn})
+}
+
+// Run listens on the server's address and serves until ctx is cancelled, then shuts down
+func (a *App) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", a.server.Addr)
+	if err != nil {
+		// Still release whatever was opened before the server
+		return errors.Join(err, a.Shutdown())
+	}
+	return a.Serve(ctx, listener)
+}
+
+// Serve serves on listener until ctx is cancelled or the server fails, then
+// shuts down. It returns the server error, if any, joined with shutdown errors.
+func (a *App) Serve(ctx context.Context, listener net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- a.server.Serve(listener)
+	}()
+
+	var err error
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutdown requested, draining in-flight requests")
+	case err = <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+		if err != nil {
+			slog.Error("Server stopped", "error", err)
+		} else {
+			slog.Info("Server stopped")
+		}
+	}
+	return errors.Join(err, a.Shutdown())
+}
+
+// Shutdown stops the server, waits for workers and runs the shutdown hooks.
+// Only the first call does anything.
+func (a *App) Shutdown() error {
+	var err error
+	a.shutdownOnce.Do(func() {
+		err = a.shutdown()
+	})
+	return err
+}
+
+func (a *App) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+
+	// Stop accepting connections and wait for in-flight requests
+	if err := a.server.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("draining requests: %w", err))
+		// Requests still running past the deadline are cut off
+		a.server.Close()
+	}
+
+	// Then stop background workers
+	a.stopWorkers()
+	done := make(chan struct{})
+	go func() {
+		a.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("waiting for workers: %w", ctx.Err()))
+	}
+
+	// Finally release resources, newest first. Each hook gets a fresh budget:
+	// the time left after draining says nothing about how long a flush needs.
+	a.mu.Lock()
+	hooks := append([]hook(nil), a.hooks...)
+	a.mu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := a.runHook(hooks[i]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", hooks[i].name, err))
+			continue
+		}
+		slog.Info("Shutdown step finished", "step", hooks[i].name)
+	}
+	return errors.Join(errs...)
+}
+
+// runHook runs one shutdown hook with its own timeout
+func (a *App) runHook(h hook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), a.hookTimeout)
+	defer cancel()
+	return h.fn(ctx)
+}
@@ -0,0 +1,179 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"elo-insight/backend/lifecycle"
+)
+
+// steps records what happened during a test, in order
+type steps struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (s *steps) add(step string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list = append(s.list, step)
+}
+
+func (s *steps) get() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.list)
+}
+
+// serve runs app on a local listener until the returned stop is called,
+// which shuts the app down and returns what Serve returned
+func serve(t *testing.T, app *lifecycle.App) (url string, stop func() error) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- app.Serve(ctx, listener)
+	}()
+	return "http://" + listener.Addr().String(), func() error {
+		cancel()
+		select {
+		case err := <-served:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("Serve did not return")
+			return nil
+		}
+	}
+}
+
+// slowRequest starts a request to url and waits until it is being handled
+func slowRequest(t *testing.T, url string, handling <-chan struct{}) {
+	t.Helper()
+	go func() {
+		if resp, err := http.Get(url); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	select {
+	case <-handling:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request never reached the handler")
+	}
+}
+
+func TestShutdownOrder(t *testing.T) {
+	var got steps
+	handling := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(handling)
+		time.Sleep(50 * time.Millisecond)
+		got.add("request finished")
+	})}
+	app := lifecycle.New(server, 5*time.Second, time.Second)
+	app.Go("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		got.add("worker cancelled")
+	})
+	for _, name := range []string{"close database", "flush traces"} {
+		app.OnShutdown(name, func(ctx context.Context) error {
+			got.add(name)
+			return nil
+		})
+	}
+
+	url, stop := serve(t, app)
+	slowRequest(t, url, handling)
+	if err := stop(); err != nil {
+		t.Fatalf("Serve = %v, want nil", err)
+	}
+
+	// Requests drain before workers stop, then hooks run newest first
+	want := []string{"request finished", "worker cancelled", "flush traces", "close database"}
+	if steps := got.get(); !slices.Equal(steps, want) {
+		t.Errorf("steps = %v, want %v", steps, want)
+	}
+	if err := app.Shutdown(); err != nil {
+		t.Errorf("second Shutdown = %v, want nil", err)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	var got steps
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	handling := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(handling)
+		<-release
+	})}
+	app := lifecycle.New(server, 50*time.Millisecond, 50*time.Millisecond)
+	app.Go("stuck worker", func(ctx context.Context) {
+		<-release
+	})
+	// Registered first, so it runs after the hung hook below
+	app.OnShutdown("flush traces", func(ctx context.Context) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		got.add("flush traces")
+		return nil
+	})
+	app.OnShutdown("close database", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	url, stop := serve(t, app)
+	slowRequest(t, url, handling)
+	err := stop()
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Serve = %v, want a deadline error", err)
+	}
+	for _, step := range []string{"draining requests", "waiting for workers", "close database"} {
+		if !strings.Contains(err.Error(), step) {
+			t.Errorf("Serve = %v, want it to report %q", err, step)
+		}
+	}
+	// Neither the slow drain nor the hung hook used up the flush's budget
+	if steps := got.get(); !slices.Equal(steps, []string{"flush traces"}) {
+		t.Errorf("steps = %v, want [flush traces]", steps)
+	}
+}
+
+func TestServeShutsDownWhenServerFails(t *testing.T) {
+	var got steps
+	app := lifecycle.New(&http.Server{}, time.Second, time.Second)
+	app.Go("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		got.add("worker cancelled")
+	})
+	app.OnShutdown("close database", func(ctx context.Context) error {
+		got.add("close database")
+		return nil
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	listener.Close()
+	if err := app.Serve(context.Background(), listener); err == nil {
+		t.Fatal("Serve on a closed listener = nil, want an error")
+	}
+	want := []string{"worker cancelled", "close database"}
+	if steps := got.get(); !slices.Equal(steps, want) {
+		t.Errorf("steps = %v, want %v", steps, want)
+	}
+}
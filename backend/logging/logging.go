@@ -0,0 +1,79 @@
+// Package logging sets up the process-wide slog logger. Records are written as
+// JSON, carry the trace and span IDs and request fields from their context,
+// and pass through Redact so keys, tokens, passwords and emails never reach
+// the log pipeline. Setup also routes the standard log package through it.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"elo-insight/backend/config"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// contextKey keys the request fields stored in a context
+type contextKey struct{}
+
+// Setup installs the default logger for the configured level and format
+func Setup(cfg config.Logging, w io.Writer) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		level = slog.LevelInfo
+	}
+
+	options := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
+	var inner slog.Handler
+	if cfg.Format == "text" {
+		inner = slog.NewTextHandler(w, options)
+	} else {
+		inner = slog.NewJSONHandler(w, options)
+	}
+
+	logger := slog.New(&handler{inner: inner})
+	slog.SetDefault(logger)
+	return logger
+}
+
+// With returns a context whose log records carry attrs, on top of any it already has
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing, _ := ctx.Value(contextKey{}).([]slog.Attr)
+	combined := make([]slog.Attr, 0, len(existing)+len(attrs))
+	combined = append(combined, existing...)
+	combined = append(combined, attrs...)
+	return context.WithValue(ctx, contextKey{}, combined)
+}
+
+// handler adds trace and request fields from the context and redacts the message
+type handler struct {
+	inner slog.Handler
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	record.Message = Redact(record.Message)
+
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+	if attrs, ok := ctx.Value(contextKey{}).([]slog.Attr); ok {
+		record.AddAttrs(attrs...)
+	}
+	return h.inner.Handle(ctx, record)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{inner: h.inner.WithAttrs(attrs)}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{inner: h.inner.WithGroup(name)}
+}
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces values that must not be logged
+const Redacted = "[REDACTED]"
+
+// Attribute keys whose values are always secret or personal; player IDs link
+// log lines to real accounts, so they count as personal too
+var sensitiveKeys = []string{"password", "passwd", "secret", "token", "api_key", "apikey", "authorization", "cookie", "email",
+	"puuid", "riot_id", "steam_id"}
+
+// Patterns for secrets embedded in free text, with what to replace them with
+var redactions = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// Query parameters such as ?key=... in Steam URLs and ?state=<jwt> in Riot sign-on
+	{regexp.MustCompile(`(?i)([?&](?:key|api_key|apikey|token|access_token|state|code|password|secret)=)[^&\s"']+`), "${1}" + Redacted},
+	// "password": "..." in JSON bodies and password=... in key/value text
+	{regexp.MustCompile(`(?i)("(?:password|secret|api_?key|access_token|refresh_token|token)"\s*:\s*")[^"]*`), "${1}" + Redacted},
+	{regexp.MustCompile(`(?i)\b((?:password|secret|api_?key|access_token|refresh_token|token)=)[^&\s,"']+`), "${1}" + Redacted},
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`), "${1}" + Redacted},
+	// JWTs and Riot API keys wherever they appear
+	{regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), Redacted},
+	{regexp.MustCompile(`RGAPI-[0-9A-Fa-f-]+`), Redacted},
+	// Email addresses
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), Redacted},
+}
+
+// Redact masks API keys, tokens, passwords and email addresses in s
+func Redact(s string) string {
+	for _, r := range redactions {
+		s = r.pattern.ReplaceAllString(s, r.replacement)
+	}
+	return s
+}
+
+// isSensitiveKey reports whether an attribute key names a secret or personal value
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	if key == "key" {
+		return true
+	}
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactAttr is the handlers' ReplaceAttr hook
+func redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	if isSensitiveKey(attr.Key) {
+		return slog.String(attr.Key, Redacted)
+	}
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, Redact(attr.Value.String()))
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			return slog.String(attr.Key, Redact(err.Error()))
+		}
+	}
+	return attr
+}
+
+// maxBodySnippet is how much of an upstream response body error logs keep
+const maxBodySnippet = 256
+
+// BodySnippet returns the start of a response body for error logs, so a
+// provider's error message is visible without dumping whole payloads
+func BodySnippet(body []byte) string {
+	if len(body) > maxBodySnippet {
+		return string(body[:maxBodySnippet]) + "..."
+	}
+	return string(body)
+}
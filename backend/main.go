@@ -3,16 +3,28 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"elo-insight/backend/cache"
+	"elo-insight/backend/config"
 	"elo-insight/backend/database"
+	"elo-insight/backend/health"
+	"elo-insight/backend/lifecycle"
+	"elo-insight/backend/logging"
 	"elo-insight/backend/middleware"
+	"elo-insight/backend/ratelimit"
 	"elo-insight/backend/routes"
+	"elo-insight/backend/store"
 	"elo-insight/backend/telemetry"
+	"elo-insight/backend/upstream"
+	"elo-insight/backend/validation"
 
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.opentelemetry.io/otel"
@@ -23,19 +35,48 @@ import (
 )
 
 func main() {
-	// --- OpenTelemetry Initialization ---
-	ctx := context.Background()
-	otelEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-	if otelEndpoint == "" {
-		otelEndpoint = "otel-collector:4317"
+	// `main migrate ...` manages the database schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
+	// Load and check the configuration before starting anything
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	// From here on, log and slog both write redacted JSON
+	logging.Setup(cfg.Logging, os.Stdout)
+	cfg.LogSummary()
+
+	// Cancelled on Ctrl-C or SIGTERM, which starts a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	app := lifecycle.New(server, cfg.Server.ShutdownTimeout, cfg.Server.HookTimeout)
+
+	// --- OpenTelemetry Initialization ---
 	exp, err := otlptracegrpc.New(ctx,
-		otlptracegrpc.WithEndpoint(otelEndpoint),
+		otlptracegrpc.WithEndpoint(cfg.Telemetry.OTLPEndpoint),
 		otlptracegrpc.WithInsecure(),
 	)
 	if err != nil {
-		log.Fatalf("failed to create OTLP trace exporter: %v", err)
+		slog.Error("Failed to create OTLP trace exporter", "error", err)
+		os.Exit(1)
 	}
 
 	res, err := resource.New(ctx,
@@ -44,7 +85,8 @@ func main() {
 		),
 	)
 	if err != nil {
-		log.Fatalf("failed to create OTEL resource: %v", err)
+		slog.Error("Failed to create OTEL resource", "error", err)
+		os.Exit(1)
 	}
 
 	tp := trace.NewTracerProvider(
@@ -52,25 +94,71 @@ func main() {
 		trace.WithResource(res),
 	)
 	otel.SetTracerProvider(tp)
-	defer func() {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-		defer cancel()
-		if err := tp.Shutdown(ctx); err != nil {
-			log.Printf("Error shutting down tracer provider: %v", err)
+	// Registered first so it runs last and flushes spans from the rest of shutdown
+	app.OnShutdown("flush traces", tp.Shutdown)
+
+	// From here on, failing to start still runs the shutdown hooks, so the
+	// spans explaining the failure are flushed
+	fatal := func(msg string, err error) {
+		slog.Error(msg, "error", err)
+		if err := app.Shutdown(); err != nil {
+			slog.Error("Shutdown failed", "error", err)
 		}
-	}()
+		os.Exit(1)
+	}
+
+	// Metrics are pulled by Prometheus from /metrics rather than pushed
+	metricsHandler, shutdownMetrics, err := telemetry.InitMetrics(res)
+	if err != nil {
+		fatal("Failed to create Prometheus metrics exporter", err)
+	}
+	app.OnShutdown("stop metrics", shutdownMetrics)
 	// --- End OpenTelemetry Initialization ---
 
 	// Initialize our telemetry package
 	telemetry.Initialize("elo-insight-backend")
 
-	middleware.Init() // Load environment variables
+	middleware.Init(cfg)
+	upstream.Init(cfg)
+	if err := validation.Register(); err != nil {
+		fatal("Failed to register request validators", err)
+	}
 
 	// Connect to the database
-	database.ConnectDB()
+	if err := database.ConnectDB(cfg.Database); err != nil {
+		fatal("Failed to start the database", err)
+	}
+	app.OnShutdown("close database", func(ctx context.Context) error {
+		return database.Close()
+	})
+
+	// Cache upstream responses in the configured backend
+	cache.Init(cfg.Cache, database.DB)
+	app.Go("purge expired cache entries", func(ctx context.Context) {
+		cache.Default.PurgeExpired(ctx, 15*time.Minute)
+	})
+
+	// Count requests against the rate limits in the configured backend
+	ratelimit.Init(cfg.RateLimit, database.DB)
+	app.Go("purge expired rate limit counts", func(ctx context.Context) {
+		ratelimit.Default.PurgeExpired(ctx, time.Minute)
+	})
+
+	// Enable debug mode for development; set before the router is created
+	if cfg.IsDevelopment() {
+		gin.SetMode(gin.DebugMode)
+	} else {
+		gin.SetMode(gin.ReleaseMode)
+	}
 
-	// Create a new router
-	r := gin.Default()
+	// Create a new router; requests are logged by middleware.RequestLogger instead of gin's text logger
+	r := gin.New()
+	// Only trust X-Forwarded-For from the configured proxies, so clients can't
+	// pick the IP they are rate limited by
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		fatal("Invalid trusted proxies", err)
+	}
+	r.Use(gin.Recovery())
 	// Add OTEL middleware for Gin with improved configuration
 	r.Use(otelgin.Middleware(
 		"elo-insight-backend",
@@ -83,47 +171,50 @@ func main() {
 	))
 	// Add our custom trace context middleware to enhance spans
 	r.Use(middleware.TraceContext())
+	// Tag log lines with the request and log each request once it finishes
+	r.Use(middleware.RequestLogger())
+	// Record request rate, errors and latency per route
+	r.Use(middleware.Metrics())
 
-	// Set up CORS middleware with enhanced configuration
-	r.Use(func(c *gin.Context) {
-		// Get the origin from the request
-		origin := c.Request.Header.Get("Origin")
-		if origin == "" {
-			// If no origin is provided, allow localhost:3000 by default
-			origin = "http://localhost:3000"
-		}
+	// Set up CORS for the configured origins
+	origins, err := middleware.NewOriginPolicy(cfg.CORS)
+	if err != nil {
+		fatal("Invalid CORS configuration", err)
+	}
+	r.Use(middleware.CORS(origins))
 
-		// Pre-flight request handling - allow the actual requesting origin
-		c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Trace-ID, X-Span-ID, X-Registration-Time-Ms, X-Form-Interaction-Time-Ms, X-Username-Time-Ms, X-Username-Focus-Count, X-Username-Edit-Count, X-Email-Time-Ms, X-Email-Focus-Count, X-Email-Edit-Count, X-Password-Time-Ms, X-Password-Focus-Count, X-Password-Edit-Count, traceparent, tracestate")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
-		c.Writer.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
-		
-		// Handle pre-flight OPTIONS requests
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent) // 204
-			return
-		}
+	// Turn errors handlers and middleware attach with c.Error into the JSON
+	// error envelope; after CORS so error responses carry CORS headers
+	r.Use(middleware.Errors())
 
-		c.Next()
-	})
+	// Reject oversized request bodies
+	r.Use(middleware.LimitBody(cfg.Server.MaxBodyBytes))
+
+	// Report upstream cache hits and misses in X-Cache headers
+	r.Use(middleware.CacheStatus())
 
-	// Enable debug mode for development
-	gin.SetMode(gin.DebugMode)
+	// Readiness checks behind /readyz
+	checker := health.NewChecker(2*time.Second,
+		health.Database(),
+		health.Migrations(),
+		health.OTLPExporter(cfg.Telemetry.OTLPEndpoint),
+	)
 
 	// Set up routes AFTER applying CORS
-	routes.SetupRoutes(r)
+	if err := routes.SetupRoutes(r, store.NewGormStore(database.DB), cfg, checker, metricsHandler); err != nil {
+		fatal("Failed to set up routes", err)
+	}
 
 	for _, route := range r.Routes() {
-		log.Println("Registered Route:", route.Method, route.Path)
+		slog.Debug("Registered route", "method", route.Method, "path", route.Path)
 	}
 
-	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Start server and block until it is shut down
+	server.Handler = r
+	slog.Info("Server running", "port", cfg.Port)
+	if err := app.Run(ctx); err != nil {
+		slog.Error("Server shut down with errors", "error", err)
+		os.Exit(1)
 	}
-	log.Println("🚀 Server running on port:", port)
-	log.Fatal(r.Run(":" + port))
+	slog.Info("Server shut down cleanly")
 }
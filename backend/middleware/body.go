@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"elo-insight/backend/apperrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LimitBody rejects request bodies larger than maxBytes. Requests that
+// declare a larger Content-Length get 413 straight away; for the rest the
+// body stops reading at the limit, so binding fails instead of buffering it all.
+func LimitBody(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			c.Error(apperrors.PayloadTooLarge("Request body too large"))
+			c.Abort()
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
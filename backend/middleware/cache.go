@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"elo-insight/backend/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Response headers describing how upstream data was served
+const (
+	CacheHeader        = "X-Cache"         // HIT, STALE, MISS or BYPASS; see cache.Recorder.Summary
+	CacheLookupsHeader = "X-Cache-Lookups" // e.g. "hit=3, miss=1"
+)
+
+// CacheStatus reports the request's upstream cache lookups in response headers
+func CacheStatus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, recorder := cache.WithRecorder(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		c.Writer = &cacheStatusWriter{ResponseWriter: c.Writer, recorder: recorder}
+		c.Next()
+	}
+}
+
+// cacheStatusWriter adds the headers just before the response is written,
+// once every lookup has happened
+type cacheStatusWriter struct {
+	gin.ResponseWriter
+	recorder *cache.Recorder
+	done     bool
+}
+
+func (w *cacheStatusWriter) setHeaders() {
+	if w.done || w.Written() {
+		return
+	}
+	w.done = true
+	if summary := w.recorder.Summary(); summary != "" {
+		w.Header().Set(CacheHeader, string(summary))
+		w.Header().Set(CacheLookupsHeader, w.recorder.Counts())
+	}
+}
+
+func (w *cacheStatusWriter) WriteHeaderNow() {
+	w.setHeaders()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *cacheStatusWriter) Write(data []byte) (int, error) {
+	w.setHeaders()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *cacheStatusWriter) WriteString(s string) (int, error) {
+	w.setHeaders()
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"elo-insight/backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenCookie is the name of the HttpOnly cookie holding the JWT
+const TokenCookie = "token"
+
+// Cookie attributes for the auth cookie, set by Init
+var cookiePolicy = config.Cookie{SameSite: "lax"}
+
+// sameSiteModes maps the configured SameSite value to net/http's
+var sameSiteModes = map[string]http.SameSite{
+	"lax":    http.SameSiteLaxMode,
+	"strict": http.SameSiteStrictMode,
+	"none":   http.SameSiteNoneMode,
+}
+
+// SetTokenCookie stores the JWT in the auth cookie for maxAge seconds
+func SetTokenCookie(c *gin.Context, token string, maxAge int) {
+	c.SetSameSite(sameSiteModes[cookiePolicy.SameSite])
+	secure := cookiePolicy.Secure != nil && *cookiePolicy.Secure
+	c.SetCookie(TokenCookie, token, maxAge, "/", cookiePolicy.Domain, secure, true)
+}
+
+// ClearTokenCookie expires the auth cookie; it must use the attributes it was set with
+func ClearTokenCookie(c *gin.Context) {
+	SetTokenCookie(c, "", -1)
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"elo-insight/backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// loadConfig loads and validates the configuration for env, with the
+// settings outside development requires plus env vars
+func loadConfig(t *testing.T, env string, vars map[string]string) (*config.Config, error) {
+	t.Helper()
+	t.Setenv("APP_ENV", env)
+	t.Setenv("JWT_SECRET", "a-test-secret-that-is-long-enough-1234")
+	t.Setenv("FRONTEND_URL", "https://app.example.com")
+	t.Setenv("DATABASE_URL", "postgres://elo:secret@db:5432/elo")
+	for name, value := range vars {
+		t.Setenv(name, value)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	return cfg, cfg.Validate()
+}
+
+// tokenCookie returns the auth cookie SetTokenCookie sends
+func tokenCookie(t *testing.T) *http.Cookie {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+	SetTokenCookie(c, "jwt", 60)
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != TokenCookie {
+		t.Fatalf("cookies = %v, want only %s", cookies, TokenCookie)
+	}
+	return cookies[0]
+}
+
+func TestTokenCookiePerEnvironment(t *testing.T) {
+	tests := []struct {
+		env          string
+		vars         map[string]string
+		wantSecure   bool
+		wantSameSite http.SameSite
+	}{
+		{env: config.EnvDevelopment, wantSecure: false, wantSameSite: http.SameSiteLaxMode},
+		{env: config.EnvTest, wantSecure: false, wantSameSite: http.SameSiteLaxMode},
+		{env: config.EnvProduction, wantSecure: true, wantSameSite: http.SameSiteLaxMode},
+		{env: config.EnvDevelopment, vars: map[string]string{"COOKIE_SECURE": "true"},
+			wantSecure: true, wantSameSite: http.SameSiteLaxMode},
+		{env: config.EnvProduction, vars: map[string]string{"COOKIE_SAMESITE": "Strict"},
+			wantSecure: true, wantSameSite: http.SameSiteStrictMode},
+		{env: config.EnvProduction, vars: map[string]string{"COOKIE_SAMESITE": "none"},
+			wantSecure: true, wantSameSite: http.SameSiteNoneMode},
+	}
+	defer func(saved config.Cookie) { cookiePolicy = saved }(cookiePolicy)
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			cfg, err := loadConfig(t, tt.env, tt.vars)
+			if err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			Init(cfg)
+
+			cookie := tokenCookie(t)
+			if cookie.Secure != tt.wantSecure {
+				t.Errorf("Secure = %v, want %v", cookie.Secure, tt.wantSecure)
+			}
+			if cookie.SameSite != tt.wantSameSite {
+				t.Errorf("SameSite = %v, want %v", cookie.SameSite, tt.wantSameSite)
+			}
+			if !cookie.HttpOnly || cookie.Path != "/" {
+				t.Errorf("HttpOnly = %v, Path = %q, want an HttpOnly cookie for /", cookie.HttpOnly, cookie.Path)
+			}
+		})
+	}
+}
+
+func TestInsecureCookieSettingsRejected(t *testing.T) {
+	tests := []struct {
+		env     string
+		vars    map[string]string
+		wantErr string
+	}{
+		{config.EnvProduction, map[string]string{"COOKIE_SECURE": "false"}, "COOKIE_SECURE cannot be false"},
+		{config.EnvDevelopment, map[string]string{"COOKIE_SAMESITE": "none"}, "COOKIE_SAMESITE=none requires COOKIE_SECURE=true"},
+		{config.EnvProduction, map[string]string{"COOKIE_SAMESITE": "loose"}, "COOKIE_SAMESITE must be lax, strict or none"},
+		{config.EnvProduction, map[string]string{"CORS_ALLOWED_ORIGINS": "*"}, "cannot allow every origin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			_, err := loadConfig(t, tt.env, tt.vars)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validate with %v: err = %v, want %q", tt.vars, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClearTokenCookieKeepsAttributes(t *testing.T) {
+	cfg, err := loadConfig(t, config.EnvProduction, map[string]string{"COOKIE_SAMESITE": "none", "COOKIE_DOMAIN": "example.com"})
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	defer func(saved config.Cookie) { cookiePolicy = saved }(cookiePolicy)
+	Init(cfg)
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	ClearTokenCookie(c)
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("cookies = %v, want one", cookies)
+	}
+	cookie := cookies[0]
+	if cookie.MaxAge >= 0 || cookie.Value != "" {
+		t.Errorf("MaxAge = %d, Value = %q, want an expired, empty cookie", cookie.MaxAge, cookie.Value)
+	}
+	if !cookie.Secure || cookie.SameSite != http.SameSiteNoneMode || cookie.Domain != "example.com" {
+		t.Errorf("cookie = %+v, want the attributes it was set with", cookie)
+	}
+}
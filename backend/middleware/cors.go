@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"elo-insight/backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Request headers browsers may send cross-origin, including the frontend's form timing headers
+const corsAllowedHeaders = "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Trace-ID, X-Span-ID, X-Request-ID, X-Registration-Time-Ms, X-Form-Interaction-Time-Ms, X-Username-Time-Ms, X-Username-Focus-Count, X-Username-Edit-Count, X-Email-Time-Ms, X-Email-Focus-Count, X-Email-Edit-Count, X-Password-Time-Ms, X-Password-Focus-Count, X-Password-Edit-Count, traceparent, tracestate"
+
+const corsAllowedMethods = "POST, OPTIONS, GET, PUT, DELETE, PATCH"
+
+// Response headers the frontend may read cross-origin
+const corsExposedHeaders = "X-Request-ID, Retry-After, Link, " + CacheHeader + ", " + CacheLookupsHeader + ", " + DeprecationHeader + ", " + SunsetHeader +
+	", " + RateLimitLimitHeader + ", " + RateLimitRemainingHeader + ", " + RateLimitResetHeader + ", " + RateLimitPolicyHeader
+
+// originRule is one entry of the allowed-origins list
+type originRule struct {
+	any    bool   // "*" allows every origin
+	scheme string // "http" or "https"
+	host   string // Exact host, or the parent domain when subdomains is set
+	port   string // Explicit port, if any
+	// subdomains matches any subdomain of host, but not host itself
+	subdomains bool
+}
+
+// parseOriginRule parses an allowed origin such as https://*.example.com:8443
+func parseOriginRule(origin string) (originRule, error) {
+	if origin == "*" {
+		return originRule{any: true}, nil
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return originRule{}, fmt.Errorf("allowed origin %q must look like https://host[:port]", origin)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return originRule{}, fmt.Errorf("allowed origin %q must not have a path, query or credentials", origin)
+	}
+
+	rule := originRule{scheme: u.Scheme, host: strings.ToLower(u.Hostname()), port: u.Port()}
+	if parent, ok := strings.CutPrefix(rule.host, "*."); ok {
+		if parent == "" || strings.Contains(parent, "*") {
+			return originRule{}, fmt.Errorf("allowed origin %q has an invalid wildcard", origin)
+		}
+		rule.host, rule.subdomains = parent, true
+	} else if strings.Contains(rule.host, "*") {
+		return originRule{}, fmt.Errorf("allowed origin %q may only use a wildcard as the first label, like https://*.example.com", origin)
+	}
+	return rule, nil
+}
+
+// matches reports whether a request Origin header is allowed by the rule
+func (r originRule) matches(origin *url.URL) bool {
+	if r.any {
+		return true
+	}
+	if origin.Scheme != r.scheme || origin.Port() != r.port {
+		return false
+	}
+	host := strings.ToLower(origin.Hostname())
+	if r.subdomains {
+		return strings.HasSuffix(host, "."+r.host)
+	}
+	return host == r.host
+}
+
+// OriginPolicy decides which origins may make credentialed cross-origin requests
+type OriginPolicy struct {
+	rules []originRule
+}
+
+// NewOriginPolicy parses the configured allowed origins
+func NewOriginPolicy(cfg config.CORS) (*OriginPolicy, error) {
+	policy := &OriginPolicy{}
+	for _, origin := range cfg.AllowedOrigins {
+		rule, err := parseOriginRule(strings.TrimSpace(origin))
+		if err != nil {
+			return nil, err
+		}
+		policy.rules = append(policy.rules, rule)
+	}
+	return policy, nil
+}
+
+// Allows reports whether the value of an Origin header is on the list
+func (p *OriginPolicy) Allows(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	for _, rule := range p.rules {
+		if rule.matches(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS answers preflight requests and adds CORS headers for allowed origins.
+// It runs before every other handler, so error responses, including ones
+// from RequireAuth, carry the same headers as successful ones.
+func CORS(policy *OriginPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.Request.Header.Get("Origin")
+		// Responses differ by Origin, so caches must not share them across origins
+		c.Writer.Header().Add("Vary", "Origin")
+
+		allowed := policy.Allows(origin)
+		if allowed {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+			c.Writer.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		}
+
+		// Handle pre-flight OPTIONS requests
+		if c.Request.Method == http.MethodOptions && c.Request.Header.Get("Access-Control-Request-Method") != "" {
+			if !allowed {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Writer.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			c.Writer.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+			c.Writer.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"elo-insight/backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseOriginRule(t *testing.T) {
+	tests := []struct {
+		origin  string
+		want    originRule
+		wantErr bool
+	}{
+		{origin: "*", want: originRule{any: true}},
+		{origin: "https://example.com", want: originRule{scheme: "https", host: "example.com"}},
+		{origin: "https://example.com/", want: originRule{scheme: "https", host: "example.com"}},
+		{origin: "http://localhost:3000", want: originRule{scheme: "http", host: "localhost", port: "3000"}},
+		{origin: "https://App.Example.com", want: originRule{scheme: "https", host: "app.example.com"}},
+		{origin: "https://*.example.com:8443", want: originRule{scheme: "https", host: "example.com", port: "8443", subdomains: true}},
+
+		{origin: "example.com", wantErr: true},
+		{origin: "ftp://example.com", wantErr: true},
+		{origin: "https://", wantErr: true},
+		{origin: "https://example.com/app", wantErr: true},
+		{origin: "https://example.com?x=1", wantErr: true},
+		{origin: "https://user@example.com", wantErr: true},
+		{origin: "https://*.", wantErr: true},
+		{origin: "https://*.*.example.com", wantErr: true},
+		{origin: "https://app.*.example.com", wantErr: true},
+		{origin: "https://*example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseOriginRule(tt.origin)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseOriginRule(%q) err = %v, want error %v", tt.origin, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseOriginRule(%q) = %+v, want %+v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestOriginPolicyAllows(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{"exact match", []string{"https://example.com"}, "https://example.com", true},
+		{"host is case-insensitive", []string{"https://example.com"}, "https://EXAMPLE.com", true},
+		{"second entry", []string{"https://a.example", "https://b.example"}, "https://b.example", true},
+		{"other host", []string{"https://example.com"}, "https://example.org", false},
+		{"suffix of host", []string{"https://example.com"}, "https://evilexample.com", false},
+		{"subdomain of exact host", []string{"https://example.com"}, "https://app.example.com", false},
+		{"scheme mismatch", []string{"https://example.com"}, "http://example.com", false},
+		{"port mismatch", []string{"http://localhost:3000"}, "http://localhost:3001", false},
+		{"port missing", []string{"http://localhost:3000"}, "http://localhost", false},
+		{"unexpected port", []string{"https://example.com"}, "https://example.com:8443", false},
+
+		{"wildcard subdomain", []string{"https://*.example.com"}, "https://app.example.com", true},
+		{"wildcard nested subdomain", []string{"https://*.example.com"}, "https://a.b.example.com", true},
+		{"wildcard excludes parent", []string{"https://*.example.com"}, "https://example.com", false},
+		{"wildcard suffix without dot", []string{"https://*.example.com"}, "https://evilexample.com", false},
+		{"wildcard scheme mismatch", []string{"https://*.example.com"}, "http://app.example.com", false},
+		{"wildcard with port", []string{"https://*.example.com:8443"}, "https://app.example.com:8443", true},
+		{"wildcard port mismatch", []string{"https://*.example.com:8443"}, "https://app.example.com", false},
+
+		{"any origin", []string{"*"}, "https://anything.example", true},
+		{"no origin header", []string{"*"}, "", false},
+		{"opaque origin", []string{"*"}, "null", false},
+		{"nothing allowed", nil, "https://example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewOriginPolicy(config.CORS{AllowedOrigins: tt.allowed})
+			if err != nil {
+				t.Fatalf("NewOriginPolicy(%q): %v", tt.allowed, err)
+			}
+			if got := policy.Allows(tt.origin); got != tt.want {
+				t.Errorf("Allows(%q) with %q = %v, want %v", tt.origin, tt.allowed, got, tt.want)
+			}
+		})
+	}
+}
+
+// A bare "*" still answers credentialed requests with the caller's origin,
+// since browsers refuse a literal * alongside credentials
+func TestCORSAnyOriginWithCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy, err := NewOriginPolicy(config.CORS{AllowedOrigins: []string{"*"}})
+	if err != nil {
+		t.Fatalf("NewOriginPolicy: %v", err)
+	}
+	r := gin.New()
+	r.Use(CORS(policy))
+	r.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, method := range []string{http.MethodGet, http.MethodOptions} {
+		req := httptest.NewRequest(method, "/ping", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q, want the request origin", method, got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+			t.Errorf("%s: Access-Control-Allow-Credentials = %q, want true", method, got)
+		}
+		if got := w.Header().Get("Vary"); got != "Origin" {
+			t.Errorf("%s: Vary = %q, want Origin", method, got)
+		}
+	}
+}
+
+func TestCORSRefusesPreflightFromOtherOrigins(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy, err := NewOriginPolicy(config.CORS{AllowedOrigins: []string{"https://example.com"}})
+	if err != nil {
+		t.Fatalf("NewOriginPolicy: %v", err)
+	}
+	r := gin.New()
+	r.Use(CORS(policy))
+
+	req := httptest.NewRequest(http.MethodOptions, "/ping", nil)
+	req.Header.Set("Origin", "https://example.org")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want none", got)
+	}
+}
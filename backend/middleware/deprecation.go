@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Response headers announcing that a route is going away
+const (
+	DeprecationHeader = "Deprecation" // RFC 9745, e.g. "@1790467200"
+	SunsetHeader      = "Sunset"      // RFC 8594, an HTTP date
+)
+
+// Deprecated marks the responses of the routes it wraps as deprecated since
+// since and due to be removed at sunset. successor maps the request path to
+// its replacement, which is linked with rel="successor-version".
+func Deprecated(since, sunset time.Time, successor func(path string) string) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(since.Unix(), 10)
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set(DeprecationHeader, deprecation)
+		header.Set(SunsetHeader, sunsetDate)
+		header.Add("Link", "<"+successor(c.Request.URL.Path)+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"elo-insight/backend/apperrors"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrorResponse is the body of every error written by Errors
+type ErrorResponse struct {
+	Code    apperrors.Code `json:"code"`
+	Error   string         `json:"error"` // The message; kept under "error" for clients that only read that
+	Details map[string]any `json:"details,omitempty"`
+	TraceID string         `json:"trace_id,omitempty"` // Quote it when reporting a problem
+}
+
+// Errors writes the error envelope for the last error a handler attached
+// with c.Error, unless the handler already wrote a response. Errors that
+// aren't an *apperrors.Error are reported as internal. It runs inside
+// RequestLogger and Metrics so they see the final status.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := apperrors.From(c.Errors.Last().Err)
+
+		ctx := c.Request.Context()
+		switch {
+		case err.Status >= http.StatusInternalServerError:
+			slog.ErrorContext(ctx, "Request failed", "code", err.Code, "error", err)
+		case err.Err != nil:
+			// e.g. a provider's 404 or 429; keep what it said
+			slog.WarnContext(ctx, "Request rejected", "code", err.Code, "error", err)
+		default:
+			slog.DebugContext(ctx, "Request rejected", "code", err.Code, "error", err)
+		}
+
+		response := ErrorResponse{Code: err.Code, Error: err.Message, Details: err.Details}
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+			response.TraceID = spanContext.TraceID().String()
+		}
+		if err.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(err.RetryAfter.Seconds()))))
+		}
+		c.JSON(err.Status, response)
+	}
+}
@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"elo-insight/backend/apperrors"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestErrors(t *testing.T) {
+	traced := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x0a, 0xf7, 0x65, 0x19, 0x16, 0xcd, 0x43, 0xdd, 0x84, 0x48, 0xeb, 0x21, 0x1c, 0x80, 0x31, 0x9c},
+		SpanID:     trace.SpanID{0xb7, 0xad, 0x6b, 0x71, 0x69, 0x20, 0x33, 0x31},
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	tests := []struct {
+		name           string
+		handler        gin.HandlerFunc
+		wantStatus     int
+		wantBody       string
+		wantRetryAfter string
+	}{
+		{
+			name: "validation with fields",
+			handler: func(c *gin.Context) {
+				c.Error(apperrors.Validation("Invalid request", map[string]string{"username": "required"}))
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":"validation_failed","error":"Invalid request","details":{"fields":{"username":"required"}}}`,
+		},
+		{
+			name:       "no details",
+			handler:    func(c *gin.Context) { c.Error(apperrors.NotFound("Player not found")) },
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"code":"not_found","error":"Player not found"}`,
+		},
+		{
+			name:       "details",
+			handler:    func(c *gin.Context) { c.Error(apperrors.NotLinked("riot", "Link your Riot account first")) },
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"code":"not_linked","error":"Link your Riot account first","details":{"platform":"riot"}}`,
+		},
+		{
+			name: "upstream failure hides the cause",
+			handler: func(c *gin.Context) {
+				c.Error(apperrors.UpstreamUnavailable("riot", "Riot Games is unavailable, try again later", errors.New("status 503 from 10.0.0.7")))
+			},
+			wantStatus: http.StatusBadGateway,
+			wantBody:   `{"code":"upstream_unavailable","error":"Riot Games is unavailable, try again later","details":{"provider":"riot"}}`,
+		},
+		{
+			name: "wrapped",
+			handler: func(c *gin.Context) {
+				c.Error(fmt.Errorf("join squad: %w", apperrors.Forbidden("Only the owner can invite")))
+			},
+			wantStatus: http.StatusForbidden,
+			wantBody:   `{"code":"forbidden","error":"Only the owner can invite"}`,
+		},
+		{
+			name:       "unclassified error is internal",
+			handler:    func(c *gin.Context) { c.Error(errors.New("pq: connection refused")) },
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"code":"internal","error":"Something went wrong"}`,
+		},
+		{
+			name: "last error wins",
+			handler: func(c *gin.Context) {
+				c.Error(apperrors.NotFound("Player not found"))
+				c.Error(apperrors.Conflict("Username is taken"))
+			},
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"code":"conflict","error":"Username is taken"}`,
+		},
+
+		// Retry-After is whole seconds, rounded up
+		{
+			name:           "rate limited",
+			handler:        func(c *gin.Context) { c.Error(apperrors.RateLimited("Too many requests", 1500*time.Millisecond)) },
+			wantStatus:     http.StatusTooManyRequests,
+			wantBody:       `{"code":"rate_limited","error":"Too many requests"}`,
+			wantRetryAfter: "2",
+		},
+		{
+			name:           "rate limited for whole seconds",
+			handler:        func(c *gin.Context) { c.Error(apperrors.RateLimited("Too many requests", time.Minute)) },
+			wantStatus:     http.StatusTooManyRequests,
+			wantBody:       `{"code":"rate_limited","error":"Too many requests"}`,
+			wantRetryAfter: "60",
+		},
+		{
+			name:       "rate limited without a retry time",
+			handler:    func(c *gin.Context) { c.Error(apperrors.RateLimited("Too many requests", 0)) },
+			wantStatus: http.StatusTooManyRequests,
+			wantBody:   `{"code":"rate_limited","error":"Too many requests"}`,
+		},
+
+		{
+			name: "trace ID",
+			handler: func(c *gin.Context) {
+				c.Request = c.Request.WithContext(trace.ContextWithSpanContext(c.Request.Context(), traced))
+				c.Error(apperrors.Internal(errors.New("boom")))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"code":"internal","error":"Something went wrong","trace_id":"0af7651916cd43dd8448eb211c80319c"}`,
+		},
+		{
+			name: "response already written",
+			handler: func(c *gin.Context) {
+				c.String(http.StatusAccepted, "queued")
+				c.Error(errors.New("failed after responding"))
+			},
+			wantStatus: http.StatusAccepted,
+			wantBody:   "queued",
+		},
+		{
+			name:       "no error",
+			handler:    func(c *gin.Context) { c.String(http.StatusOK, "ok") },
+			wantStatus: http.StatusOK,
+			wantBody:   "ok",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(Errors())
+			r.GET("/", tt.handler)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if w.Code != tt.wantStatus || w.Body.String() != tt.wantBody {
+				t.Errorf("response = %d %s, want %d %s", w.Code, w.Body, tt.wantStatus, tt.wantBody)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestErrorsAfterAbort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Errors())
+	reached := false
+	r.GET("/", func(c *gin.Context) {
+		c.Error(apperrors.Unauthorized("Sign in first"))
+		c.Abort()
+	}, func(c *gin.Context) {
+		reached = true
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if want := `{"code":"unauthorized","error":"Sign in first"}`; w.Code != http.StatusUnauthorized || w.Body.String() != want {
+		t.Errorf("response = %d %s, want 401 %s", w.Code, w.Body, want)
+	}
+	if reached {
+		t.Error("the handler after the aborting middleware ran")
+	}
+}
@@ -1,87 +1,100 @@
 package middleware
 
 import (
+	"context"
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/config"
+	"elo-insight/backend/logging"
 	"elo-insight/backend/models"
-	"log"
-	"net/http"
-	"os"
+	"errors"
+	"log/slog"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/joho/godotenv"
 )
 
 var jwtSecret []byte
 
-func Init() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("WARNING: No .env file found, using system environment variables.")
-	}
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		log.Println("WARNING: JWT_SECRET is not set! Using a default value (not secure).")
-		secret = "default-secret-key"
-	}
-	jwtSecret = []byte(secret)
+// Init sets the token secret and cookie policy; cfg must already be validated
+func Init(cfg *config.Config) {
+	jwtSecret = []byte(cfg.JWTSecret)
+	cookiePolicy = cfg.Cookie
 }
 
+// Reasons a token is refused
+var (
+	errInvalidToken   = errors.New("Invalid or expired token")
+	errInvalidPayload = errors.New("Invalid token payload")
+)
+
 // RequireAuth is middleware to protect routes with JWT
 func RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Debug logging for troubleshooting
-		log.Printf("[DEBUG] Auth middleware triggered for path: %s", c.Request.URL.Path)
-
-		// Extract and log headers for debugging
-		log.Printf("[DEBUG] Authorization header: %s", c.GetHeader("Authorization"))
-		log.Printf("[DEBUG] Cookie header: %s", c.GetHeader("Cookie"))
+		ctx := c.Request.Context()
 
 		tokenString := ExtractToken(c)
 		if tokenString == "" {
-			log.Printf("[DEBUG] No token found for request to: %s", c.Request.URL.Path)
-			// Add CORS headers before aborting
-			c.Writer.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
-			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			slog.DebugContext(ctx, "No token found")
+			c.Error(apperrors.Unauthorized("Unauthorized"))
 			c.Abort()
 			return
 		}
 
-		claims := jwt.MapClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
-
-		if err != nil || !token.Valid {
-			log.Printf("[DEBUG] Invalid token: %v", err)
-			// Add CORS headers before aborting
-			c.Writer.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
-			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		userID, err := parseToken(ctx, tokenString)
+		if err != nil {
+			c.Error(apperrors.Unauthorized(err.Error()))
 			c.Abort()
 			return
 		}
 
-		userID, ok := claims["sub"].(float64)
-		if !ok {
-			log.Printf("[DEBUG] Invalid token payload - sub claim missing or not a number")
-			// Add CORS headers before aborting
-			c.Writer.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
-			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token payload"})
-			c.Abort()
-			return
-		}
+		setUser(c, userID)
+		c.Next()
+	}
+}
 
-		// Attach user ID to the context
-		c.Set("userID", uint(userID))
+// OptionalAuth identifies the user like RequireAuth when a valid token is
+// sent, and lets the request through either way. Public routes use it to
+// treat signed-in users as themselves, e.g. for rate limits.
+func OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tokenString := ExtractToken(c); tokenString != "" {
+			if userID, err := parseToken(c.Request.Context(), tokenString); err == nil {
+				setUser(c, userID)
+			}
+		}
 		c.Next()
 	}
 }
 
+// parseToken returns the user a token was issued to
+func parseToken(ctx context.Context, tokenString string) (uint, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	})
+	if err != nil || !token.Valid {
+		slog.DebugContext(ctx, "Invalid token", "error", err)
+		return 0, errInvalidToken
+	}
+
+	userID, ok := claims["sub"].(float64)
+	if !ok {
+		slog.DebugContext(ctx, "Invalid token payload: sub claim missing or not a number")
+		return 0, errInvalidPayload
+	}
+	return uint(userID), nil
+}
+
+// setUser attaches the user ID to the context, and to every log line for the request
+func setUser(c *gin.Context, userID uint) {
+	c.Set("userID", userID)
+	c.Request = c.Request.WithContext(logging.With(c.Request.Context(), slog.Uint64("user_id", uint64(userID))))
+}
+
 func ExtractToken(c *gin.Context) string {
 	// Try to get token from HttpOnly cookie first
-	token, err := c.Cookie("token")
+	token, err := c.Cookie(TokenCookie)
 	if err == nil {
 		return token
 	}
@@ -105,7 +118,7 @@ func GenerateJWT(user models.User) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	signedToken, err := token.SignedString(jwtSecret)
 	if err != nil {
-		log.Println("ERROR: Failed to sign JWT:", err)
+		slog.Error("Failed to sign JWT", "error", err)
 	}
 	return signedToken, err
 }
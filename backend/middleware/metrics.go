@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"time"
+
+	"elo-insight/backend/telemetry"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics records the rate, errors and duration of every request by route
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		done := telemetry.TrackActiveRequest(c.Request.Context(), c.Request.Method)
+		defer done()
+
+		c.Next()
+
+		// Unknown paths share one label so scanners can't create new series
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		telemetry.RecordHTTPRequest(c.Request.Context(), c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/config"
+	"elo-insight/backend/ratelimit"
+	"elo-insight/backend/telemetry"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rate limit headers, as in the IETF RateLimit header fields draft
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"     // Requests allowed per window
+	RateLimitRemainingHeader = "RateLimit-Remaining" // Requests left in the window
+	RateLimitResetHeader     = "RateLimit-Reset"     // Seconds until the window ends
+	RateLimitPolicyHeader    = "RateLimit-Policy"    // The limit and window, e.g. 30;w=60
+)
+
+// APITokenHeader carries one of the tokens in RATE_LIMIT_API_TOKENS
+const APITokenHeader = "X-API-Token"
+
+// RatePolicy is the limit on a group of routes
+type RatePolicy struct {
+	Name  string // Routes with the same name share each client's count
+	Rate  config.Rate
+	PerIP bool // Count per IP even for signed-in users, e.g. on sign-in routes
+}
+
+// RateLimits identifies the client of each request, and knows which clients
+// are internal and never limited
+type RateLimits struct {
+	tokens           map[[sha256.Size]byte]string // Token hash to its name
+	internalTokens   map[string]bool
+	internalNetworks []*net.IPNet
+}
+
+// NewRateLimits prepares the API tokens and internal callers in cfg
+func NewRateLimits(cfg config.RateLimit) (*RateLimits, error) {
+	limits := &RateLimits{
+		tokens:         make(map[[sha256.Size]byte]string, len(cfg.APITokens)),
+		internalTokens: make(map[string]bool, len(cfg.InternalTokens)),
+	}
+	for name, token := range cfg.APITokens {
+		limits.tokens[sha256.Sum256([]byte(token))] = name
+	}
+	for _, name := range cfg.InternalTokens {
+		limits.internalTokens[name] = true
+	}
+	for _, cidr := range cfg.InternalNetworks {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid internal network %q: %w", cidr, err)
+		}
+		limits.internalNetworks = append(limits.internalNetworks, network)
+	}
+	return limits, nil
+}
+
+// Limit counts each client's requests to the routes it is used on against
+// policy, and rejects them with 429 once the window's requests are used up.
+// Clients are API tokens, then signed-in users, then IPs, so it must run
+// after RequireAuth or OptionalAuth to count users. Responses carry the
+// RateLimit headers, except for internal callers, which aren't counted.
+func (l *RateLimits) Limit(policy RatePolicy) gin.HandlerFunc {
+	window := strconv.Itoa(int(policy.Rate.Window.Seconds()))
+	return func(c *gin.Context) {
+		limiter := ratelimit.Default
+		if !limiter.Enabled() {
+			c.Next()
+			return
+		}
+		client, internal := l.client(c, policy.PerIP)
+		if internal {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		result := limiter.Allow(ctx, policy.Name, client, policy.Rate)
+		reset := strconv.Itoa(int(math.Ceil(result.Reset.Seconds())))
+		c.Header(RateLimitLimitHeader, strconv.Itoa(result.Limit))
+		c.Header(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+		c.Header(RateLimitResetHeader, reset)
+		c.Header(RateLimitPolicyHeader, strconv.Itoa(result.Limit)+";w="+window)
+		if result.Allowed {
+			c.Next()
+			return
+		}
+
+		slog.InfoContext(ctx, "Rate limit exceeded", "policy", policy.Name, "client", client)
+		telemetry.CountRateLimited(ctx, policy.Name)
+		c.Error(apperrors.RateLimited("Too many requests; try again in "+reset+"s", max(result.Reset, time.Second)))
+		c.Abort()
+	}
+}
+
+// InternalOnly rejects everyone but internal callers with 403, for routes
+// such as /metrics that must stay off the public API. It doesn't count requests.
+func (l *RateLimits) InternalOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, internal := l.client(c, true); !internal {
+			c.Error(apperrors.Forbidden("Only internal callers may use this route"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// client names who is making the request, and reports whether they are internal
+func (l *RateLimits) client(c *gin.Context, perIP bool) (string, bool) {
+	if token := c.GetHeader(APITokenHeader); token != "" {
+		if name, ok := l.tokens[sha256.Sum256([]byte(token))]; ok {
+			return "token:" + name, l.internalTokens[name]
+		}
+		slog.DebugContext(c.Request.Context(), "Unknown API token; limiting by user or IP")
+	}
+
+	ip := c.ClientIP()
+	if parsed := net.ParseIP(ip); parsed != nil {
+		for _, network := range l.internalNetworks {
+			if network.Contains(parsed) {
+				return "ip:" + ip, true
+			}
+		}
+	}
+
+	if userID, ok := c.Get("userID"); ok && !perIP {
+		return fmt.Sprintf("user:%d", userID), false
+	}
+	return "ip:" + ip, false
+}
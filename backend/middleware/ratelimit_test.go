@@ -0,0 +1,213 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/config"
+	"elo-insight/backend/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// limitedRequest is who sends a request to a rate limited route
+type limitedRequest struct {
+	ip     string
+	userID uint // Signed in if not 0
+	token  string
+}
+
+// newLimitedRouter serves /limited behind policy, counting in a fresh memory
+// store, with a stand-in for RequireAuth signing in limitedRequest.userID. It
+// also serves /internal to internal callers only.
+func newLimitedRouter(t *testing.T, policy RatePolicy) *gin.Engine {
+	t.Helper()
+	saved := ratelimit.Default
+	ratelimit.Default = ratelimit.New(ratelimit.NewMemory(), "memory")
+	t.Cleanup(func() { ratelimit.Default = saved })
+
+	limits, err := NewRateLimits(config.RateLimit{
+		APITokens:        map[string]string{"partner": "partner-token", "other": "other-token", "ops": "ops-token"},
+		InternalTokens:   []string{"ops"},
+		InternalNetworks: []string{"10.0.0.0/8"},
+	})
+	if err != nil {
+		t.Fatalf("rate limits: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Errors())
+	r.GET("/limited", func(c *gin.Context) {
+		if id, err := strconv.ParseUint(c.GetHeader("X-Test-User"), 10, 32); err == nil {
+			c.Set("userID", uint(id))
+		}
+	}, limits.Limit(policy), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	r.GET("/internal", limits.InternalOnly(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return r
+}
+
+func (l limitedRequest) send(r *gin.Engine) *httptest.ResponseRecorder {
+	return l.get(r, "/limited")
+}
+
+func (l limitedRequest) get(r *gin.Engine, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = l.ip + ":40000"
+	if l.userID != 0 {
+		req.Header.Set("X-Test-User", strconv.FormatUint(uint64(l.userID), 10))
+	}
+	if l.token != "" {
+		req.Header.Set(APITokenHeader, l.token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitClient(t *testing.T) {
+	const ip1, ip2, internalIP = "192.0.2.1", "192.0.2.2", "10.1.2.3"
+	tests := []struct {
+		name          string
+		perIP         bool
+		first, second limitedRequest
+		sameClient    bool // The second request is counted with the first, so it is limited
+	}{
+		{"same IP", false, limitedRequest{ip: ip1}, limitedRequest{ip: ip1}, true},
+		{"other IP", false, limitedRequest{ip: ip1}, limitedRequest{ip: ip2}, false},
+		{"same user from another IP", false, limitedRequest{ip: ip1, userID: 1}, limitedRequest{ip: ip2, userID: 1}, true},
+		{"other user on the same IP", false, limitedRequest{ip: ip1, userID: 1}, limitedRequest{ip: ip1, userID: 2}, false},
+		{"user and signed out on the same IP", false, limitedRequest{ip: ip1, userID: 1}, limitedRequest{ip: ip1}, false},
+		{"per IP: other user on the same IP", true, limitedRequest{ip: ip1, userID: 1}, limitedRequest{ip: ip1, userID: 2}, true},
+		{"per IP: same user from another IP", true, limitedRequest{ip: ip1, userID: 1}, limitedRequest{ip: ip2, userID: 1}, false},
+		{"same token for other users", false, limitedRequest{ip: ip1, userID: 1, token: "partner-token"}, limitedRequest{ip: ip2, userID: 2, token: "partner-token"}, true},
+		{"per IP: token still wins", true, limitedRequest{ip: ip1, token: "partner-token"}, limitedRequest{ip: ip2, token: "partner-token"}, true},
+		{"other token for the same user", false, limitedRequest{ip: ip1, userID: 1, token: "partner-token"}, limitedRequest{ip: ip1, userID: 1, token: "other-token"}, false},
+		{"unknown token counts the user", false, limitedRequest{ip: ip1, userID: 1, token: "made-up"}, limitedRequest{ip: ip2, userID: 1, token: "also-made-up"}, true},
+		{"internal network", false, limitedRequest{ip: internalIP}, limitedRequest{ip: internalIP}, false},
+		{"internal network, signed in", false, limitedRequest{ip: internalIP, userID: 1}, limitedRequest{ip: internalIP, userID: 1}, false},
+		{"internal token", false, limitedRequest{ip: ip1, token: "ops-token"}, limitedRequest{ip: ip1, token: "ops-token"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newLimitedRouter(t, RatePolicy{Name: "api", Rate: config.Rate{Requests: 1, Window: time.Hour}, PerIP: tt.perIP})
+
+			if w := tt.first.send(r); w.Code != http.StatusNoContent {
+				t.Fatalf("first request: status %d, want %d", w.Code, http.StatusNoContent)
+			}
+			want := http.StatusNoContent
+			if tt.sameClient {
+				want = http.StatusTooManyRequests
+			}
+			if w := tt.second.send(r); w.Code != want {
+				t.Errorf("second request: status %d, want %d", w.Code, want)
+			}
+		})
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	r := newLimitedRouter(t, RatePolicy{Name: "stats", Rate: config.Rate{Requests: 2, Window: time.Hour}})
+	client := limitedRequest{ip: "192.0.2.1", userID: 1}
+
+	for i, wantRemaining := range []string{"1", "0", "0"} {
+		w := client.send(r)
+		if got := w.Header().Get(RateLimitLimitHeader); got != "2" {
+			t.Errorf("request %d: %s = %q, want 2", i+1, RateLimitLimitHeader, got)
+		}
+		if got := w.Header().Get(RateLimitRemainingHeader); got != wantRemaining {
+			t.Errorf("request %d: %s = %q, want %s", i+1, RateLimitRemainingHeader, got, wantRemaining)
+		}
+		if got := w.Header().Get(RateLimitPolicyHeader); got != "2;w=3600" {
+			t.Errorf("request %d: %s = %q, want 2;w=3600", i+1, RateLimitPolicyHeader, got)
+		}
+		reset, err := strconv.Atoi(w.Header().Get(RateLimitResetHeader))
+		if err != nil || reset < 1 || reset > 3600 {
+			t.Errorf("request %d: %s = %q, want 1 to 3600 seconds", i+1, RateLimitResetHeader, w.Header().Get(RateLimitResetHeader))
+		}
+
+		if i < 2 {
+			if w.Code != http.StatusNoContent || w.Header().Get("Retry-After") != "" {
+				t.Errorf("request %d: status %d, Retry-After %q; want %d and none", i+1, w.Code, w.Header().Get("Retry-After"), http.StatusNoContent)
+			}
+			continue
+		}
+		var body ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusTooManyRequests || body.Code != apperrors.CodeRateLimited {
+			t.Fatalf("request %d: status %d, body %s; want %d %s", i+1, w.Code, w.Body, http.StatusTooManyRequests, apperrors.CodeRateLimited)
+		}
+		if got := w.Header().Get("Retry-After"); got != strconv.Itoa(reset) {
+			t.Errorf("Retry-After = %q, want %s to match %s", got, strconv.Itoa(reset), RateLimitResetHeader)
+		}
+	}
+}
+
+func TestRateLimitSkipsHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+		client  limitedRequest
+	}{
+		{"internal caller", "memory", limitedRequest{ip: "10.1.2.3"}},
+		{"limits switched off", "none", limitedRequest{ip: "192.0.2.1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newLimitedRouter(t, RatePolicy{Name: "api", Rate: config.Rate{Requests: 1, Window: time.Minute}})
+			ratelimit.Init(config.RateLimit{Backend: tt.backend}, nil)
+
+			for i := range 3 {
+				w := tt.client.send(r)
+				if w.Code != http.StatusNoContent {
+					t.Errorf("request %d: status %d, want %d", i+1, w.Code, http.StatusNoContent)
+				}
+				if got := w.Header().Get(RateLimitLimitHeader); got != "" {
+					t.Errorf("request %d: %s = %q, want none", i+1, RateLimitLimitHeader, got)
+				}
+			}
+		})
+	}
+}
+
+func TestInternalOnly(t *testing.T) {
+	tests := []struct {
+		name   string
+		client limitedRequest
+		want   int
+	}{
+		{"internal network", limitedRequest{ip: "10.1.2.3"}, http.StatusNoContent},
+		{"internal token", limitedRequest{ip: "192.0.2.1", token: "ops-token"}, http.StatusNoContent},
+		{"public IP", limitedRequest{ip: "192.0.2.1"}, http.StatusForbidden},
+		{"signed-in user", limitedRequest{ip: "192.0.2.1", userID: 1}, http.StatusForbidden},
+		{"token that isn't internal", limitedRequest{ip: "192.0.2.1", token: "partner-token"}, http.StatusForbidden},
+		{"unknown token", limitedRequest{ip: "192.0.2.1", token: "ops"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newLimitedRouter(t, RatePolicy{Name: "api", Rate: config.Rate{Requests: 1, Window: time.Hour}})
+
+			// Repeated requests aren't counted against any limit
+			for i := range 2 {
+				w := tt.client.get(r, "/internal")
+				if w.Code != tt.want {
+					t.Fatalf("request %d: status %d, want %d", i+1, w.Code, tt.want)
+				}
+				if tt.want == http.StatusForbidden {
+					var body ErrorResponse
+					if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Code != apperrors.CodeForbidden {
+						t.Errorf("request %d: body %s, want code %s", i+1, w.Body, apperrors.CodeForbidden)
+					}
+				}
+			}
+		})
+	}
+}
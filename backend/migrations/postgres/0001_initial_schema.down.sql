@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS friendships;
+DROP TABLE IF EXISTS user_stats;
+DROP TABLE IF EXISTS users;
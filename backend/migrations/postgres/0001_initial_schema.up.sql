@@ -0,0 +1,46 @@
+-- Schema as it was created by GORM AutoMigrate before versioned migrations.
+-- IF NOT EXISTS lets databases created by AutoMigrate adopt this history.
+
+CREATE TABLE IF NOT EXISTS users (
+    id             bigserial PRIMARY KEY,
+    created_at     timestamptz,
+    updated_at     timestamptz,
+    deleted_at     timestamptz,
+    username       text NOT NULL,
+    email          text NOT NULL,
+    password       text NOT NULL,
+    steam_id       text DEFAULT '',
+    riot_id        text DEFAULT '',
+    riot_game_name text DEFAULT '',
+    riot_tagline   text DEFAULT '',
+    riot_puuid     text DEFAULT '',
+    ea_username    text DEFAULT '',
+    xbox_id        text DEFAULT '',
+    playstation_id text DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS user_stats (
+    id         bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id    bigint NOT NULL,
+    game       text NOT NULL,
+    platform   text NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_user_stats_deleted_at ON user_stats (deleted_at);
+
+CREATE TABLE IF NOT EXISTS friendships (
+    id           bigserial PRIMARY KEY,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    deleted_at   timestamptz,
+    user_id      bigint NOT NULL,
+    friend_id    bigint NOT NULL,
+    status       text NOT NULL DEFAULT 'pending',
+    requested_by bigint NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_friendships_deleted_at ON friendships (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_friendships_user_id ON friendships (user_id);
+CREATE INDEX IF NOT EXISTS idx_friendships_friend_id ON friendships (friend_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_username;
+DROP INDEX IF EXISTS idx_users_email;
+DROP INDEX IF EXISTS idx_users_ea_username;
+DROP INDEX IF EXISTS idx_users_riot_puuid;
+DROP INDEX IF EXISTS idx_users_steam_id;
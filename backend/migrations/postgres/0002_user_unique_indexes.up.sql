@@ -0,0 +1,27 @@
+-- Older databases carry table-wide UNIQUE constraints on the platform columns,
+-- which reject every user after the first one with an empty value.
+ALTER TABLE users DROP CONSTRAINT IF EXISTS uni_users_steam_id;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS uni_users_riot_id;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS uni_users_ea_username;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS uni_users_username;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS uni_users_email;
+
+-- Postgres has no partial UNIQUE constraints, so uniqueness that ignores
+-- empty values and soft-deleted users is expressed as partial unique indexes.
+-- The platform columns are skipped if an earlier build already moved them
+-- into platform_links.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'users' AND column_name = 'steam_id') THEN
+        CREATE UNIQUE INDEX IF NOT EXISTS idx_users_steam_id ON users (steam_id)
+            WHERE steam_id <> '' AND deleted_at IS NULL;
+        CREATE UNIQUE INDEX IF NOT EXISTS idx_users_riot_puuid ON users (riot_puuid)
+            WHERE riot_puuid <> '' AND deleted_at IS NULL;
+        CREATE UNIQUE INDEX IF NOT EXISTS idx_users_ea_username ON users (ea_username)
+            WHERE ea_username <> '' AND deleted_at IS NULL;
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username) WHERE deleted_at IS NULL;
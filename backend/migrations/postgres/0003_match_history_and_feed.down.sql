@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS feed_comments;
+DROP TABLE IF EXISTS feed_reactions;
+DROP TABLE IF EXISTS feed_events;
+DROP TABLE IF EXISTS rank_snapshots;
+DROP TABLE IF EXISTS match_participants;
+DROP TABLE IF EXISTS stored_matches;
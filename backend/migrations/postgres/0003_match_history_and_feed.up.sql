@@ -0,0 +1,104 @@
+-- Stored matches and the activity feed generated from them
+
+CREATE TABLE IF NOT EXISTS stored_matches (
+    id         bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    game       text NOT NULL,
+    match_id   text NOT NULL,
+    queue_id   text DEFAULT '',
+    played_at  timestamptz NOT NULL,
+    duration   bigint DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_stored_matches_deleted_at ON stored_matches (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stored_matches_game_match ON stored_matches (game, match_id);
+CREATE INDEX IF NOT EXISTS idx_stored_matches_played_at ON stored_matches (played_at);
+
+CREATE TABLE IF NOT EXISTS match_participants (
+    id              bigserial PRIMARY KEY,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    deleted_at      timestamptz,
+    stored_match_id bigint NOT NULL,
+    puuid           text NOT NULL,
+    team_id         text NOT NULL,
+    character       text DEFAULT '',
+    role            text DEFAULT '',
+    win             boolean NOT NULL,
+    kills           bigint NOT NULL DEFAULT 0,
+    deaths          bigint NOT NULL DEFAULT 0,
+    assists         bigint NOT NULL DEFAULT 0,
+    penta_kills     bigint NOT NULL DEFAULT 0,
+    CONSTRAINT fk_stored_matches_participants FOREIGN KEY (stored_match_id)
+        REFERENCES stored_matches (id) ON DELETE CASCADE
+);
+-- AutoMigrate named the PUUID column p_uuid
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'match_participants' AND column_name = 'p_uuid') THEN
+        ALTER TABLE match_participants RENAME COLUMN p_uuid TO puuid;
+        DROP INDEX IF EXISTS idx_match_participants_p_uuid;
+    END IF;
+END $$;
+CREATE INDEX IF NOT EXISTS idx_match_participants_deleted_at ON match_participants (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_match_participants_stored_match_id ON match_participants (stored_match_id);
+CREATE INDEX IF NOT EXISTS idx_match_participants_puuid ON match_participants (puuid);
+
+CREATE TABLE IF NOT EXISTS rank_snapshots (
+    id            bigserial PRIMARY KEY,
+    created_at    timestamptz,
+    updated_at    timestamptz,
+    deleted_at    timestamptz,
+    user_id       bigint NOT NULL,
+    game          text NOT NULL,
+    queue         text NOT NULL,
+    tier          text NOT NULL,
+    division      text DEFAULT '',
+    league_points bigint DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_rank_snapshots_deleted_at ON rank_snapshots (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_rank_snapshots_user_game_queue ON rank_snapshots (user_id, game, queue);
+
+CREATE TABLE IF NOT EXISTS feed_events (
+    id          bigserial PRIMARY KEY,
+    created_at  timestamptz,
+    updated_at  timestamptz,
+    deleted_at  timestamptz,
+    user_id     bigint NOT NULL,
+    type        text NOT NULL,
+    game        text NOT NULL,
+    match_id    text DEFAULT '',
+    summary     text NOT NULL,
+    details     text DEFAULT '',
+    occurred_at timestamptz NOT NULL,
+    dedupe_key  text NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_feed_events_deleted_at ON feed_events (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_feed_events_user_occurred ON feed_events (user_id, occurred_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_feed_events_dedupe_key ON feed_events (dedupe_key);
+
+CREATE TABLE IF NOT EXISTS feed_reactions (
+    id            bigserial PRIMARY KEY,
+    created_at    timestamptz,
+    updated_at    timestamptz,
+    deleted_at    timestamptz,
+    feed_event_id bigint NOT NULL,
+    user_id       bigint NOT NULL,
+    kind          text NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_feed_reactions_deleted_at ON feed_reactions (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_feed_reactions_event_user_kind ON feed_reactions (feed_event_id, user_id, kind);
+
+CREATE TABLE IF NOT EXISTS feed_comments (
+    id            bigserial PRIMARY KEY,
+    created_at    timestamptz,
+    updated_at    timestamptz,
+    deleted_at    timestamptz,
+    feed_event_id bigint NOT NULL,
+    user_id       bigint NOT NULL,
+    body          text NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_feed_comments_deleted_at ON feed_comments (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_feed_comments_feed_event_id ON feed_comments (feed_event_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS squad_invites;
+DROP TABLE IF EXISTS squad_members;
+DROP TABLE IF EXISTS squads;
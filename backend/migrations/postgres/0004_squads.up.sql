@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS squads (
+    id         bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name       text NOT NULL,
+    owner_id   bigint NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_squads_deleted_at ON squads (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_squads_owner_id ON squads (owner_id);
+
+CREATE TABLE IF NOT EXISTS squad_members (
+    id         bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    squad_id   bigint NOT NULL,
+    user_id    bigint NOT NULL,
+    role       text NOT NULL DEFAULT 'member',
+    CONSTRAINT fk_squads_members FOREIGN KEY (squad_id) REFERENCES squads (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_squad_members_squad_user ON squad_members (squad_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_squad_members_user_id ON squad_members (user_id);
+
+CREATE TABLE IF NOT EXISTS squad_invites (
+    id         bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    squad_id   bigint NOT NULL,
+    inviter_id bigint NOT NULL,
+    invitee_id bigint NOT NULL,
+    status     text NOT NULL DEFAULT 'pending'
+);
+CREATE INDEX IF NOT EXISTS idx_squad_invites_deleted_at ON squad_invites (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_squad_invites_squad_id ON squad_invites (squad_id);
+CREATE INDEX IF NOT EXISTS idx_squad_invites_invitee_id ON squad_invites (invitee_id);
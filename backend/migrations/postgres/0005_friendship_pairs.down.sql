@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_friendships_pair;
+
+UPDATE friendships SET status = 'rejected' WHERE status = 'declined';
+
+ALTER TABLE friendships
+    DROP COLUMN IF EXISTS responded_at,
+    DROP COLUMN IF EXISTS pair_high,
+    DROP COLUMN IF EXISTS pair_low;
@@ -0,0 +1,32 @@
+-- One friendship row per pair of users, identified by the canonical
+-- (pair_low, pair_high) pair, moving between pending, accepted, declined and
+-- cancelled instead of new rows being created.
+
+ALTER TABLE friendships
+    ADD COLUMN IF NOT EXISTS pair_low bigint,
+    ADD COLUMN IF NOT EXISTS pair_high bigint,
+    ADD COLUMN IF NOT EXISTS responded_at timestamptz;
+
+-- Soft-deleted rows would otherwise block the pair forever
+DELETE FROM friendships WHERE deleted_at IS NOT NULL OR user_id = friend_id;
+
+UPDATE friendships SET status = 'declined' WHERE status = 'rejected';
+
+UPDATE friendships SET pair_low = LEAST(user_id, friend_id), pair_high = GREATEST(user_id, friend_id)
+WHERE pair_low IS NULL OR pair_high IS NULL;
+
+-- Keep one row per pair, preferring an accepted friendship and then the newest
+DELETE FROM friendships WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (
+            PARTITION BY pair_low, pair_high
+            ORDER BY (status = 'accepted') DESC, updated_at DESC, id DESC
+        ) AS rn FROM friendships
+    ) ranked WHERE rn > 1
+);
+
+ALTER TABLE friendships
+    ALTER COLUMN pair_low SET NOT NULL,
+    ALTER COLUMN pair_high SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_friendships_pair ON friendships (pair_low, pair_high);
@@ -0,0 +1,37 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS steam_id text DEFAULT '',
+    ADD COLUMN IF NOT EXISTS riot_id text DEFAULT '',
+    ADD COLUMN IF NOT EXISTS riot_game_name text DEFAULT '',
+    ADD COLUMN IF NOT EXISTS riot_tagline text DEFAULT '',
+    ADD COLUMN IF NOT EXISTS riot_puuid text DEFAULT '',
+    ADD COLUMN IF NOT EXISTS ea_username text DEFAULT '',
+    ADD COLUMN IF NOT EXISTS xbox_id text DEFAULT '',
+    ADD COLUMN IF NOT EXISTS playstation_id text DEFAULT '';
+
+UPDATE users SET steam_id = l.external_id
+FROM platform_links l WHERE l.user_id = users.id AND l.platform = 'steam';
+
+UPDATE users SET
+    riot_puuid = CASE WHEN l.verification = 'verified' AND l.display_name = '' THEN '' ELSE l.external_id END,
+    riot_id = CASE WHEN l.verification = 'verified' AND l.display_name = '' THEN l.external_id ELSE '' END,
+    riot_game_name = split_part(l.display_name, '#', 1),
+    riot_tagline = split_part(l.display_name, '#', 2)
+FROM platform_links l WHERE l.user_id = users.id AND l.platform = 'riot';
+
+UPDATE users SET ea_username = l.external_id
+FROM platform_links l WHERE l.user_id = users.id AND l.platform = 'ea';
+
+UPDATE users SET xbox_id = l.external_id
+FROM platform_links l WHERE l.user_id = users.id AND l.platform = 'xbox';
+
+UPDATE users SET playstation_id = l.external_id
+FROM platform_links l WHERE l.user_id = users.id AND l.platform = 'playstation';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_steam_id ON users (steam_id)
+    WHERE steam_id <> '' AND deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_riot_puuid ON users (riot_puuid)
+    WHERE riot_puuid <> '' AND deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_ea_username ON users (ea_username)
+    WHERE ea_username <> '' AND deleted_at IS NULL;
+
+DROP TABLE IF EXISTS platform_links;
@@ -0,0 +1,73 @@
+-- Linked gaming accounts move out of the per-platform columns on users into
+-- platform_links, one row per (user, platform).
+
+CREATE TABLE IF NOT EXISTS platform_links (
+    id           bigserial PRIMARY KEY,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    deleted_at   timestamptz,
+    user_id      bigint NOT NULL,
+    platform     text NOT NULL,
+    external_id  text NOT NULL,
+    display_name text DEFAULT '',
+    region       text DEFAULT '',
+    verification text NOT NULL DEFAULT 'unverified',
+    linked_at    timestamptz NOT NULL,
+    CONSTRAINT fk_users_platform_links FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_platform_links_deleted_at ON platform_links (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_platform_links_user_platform ON platform_links (user_id, platform);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_platform_links_external ON platform_links (platform, external_id);
+
+-- Copy accounts from the old columns, if this database still has them
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_schema = current_schema() AND table_name = 'users' AND column_name = 'steam_id') THEN
+        RETURN;
+    END IF;
+
+    -- The old Steam callback took the SteamID from the query without checking
+    -- the OpenID assertion, so these links prove nothing until signed in again
+    INSERT INTO platform_links (created_at, updated_at, user_id, platform, external_id, display_name, region, verification, linked_at)
+    SELECT NOW(), NOW(), id, 'steam', steam_id, '', '', 'unverified', updated_at
+    FROM users WHERE deleted_at IS NULL AND COALESCE(steam_id, '') <> ''
+    ON CONFLICT DO NOTHING;
+
+    -- riot_id was only ever set by Riot sign-on; riot_puuid came from a Riot ID lookup
+    INSERT INTO platform_links (created_at, updated_at, user_id, platform, external_id, display_name, region, verification, linked_at)
+    SELECT NOW(), NOW(), id, 'riot',
+        COALESCE(NULLIF(riot_puuid, ''), riot_id),
+        CASE WHEN COALESCE(riot_game_name, '') <> '' THEN riot_game_name || '#' || COALESCE(riot_tagline, '') ELSE '' END,
+        CASE WHEN COALESCE(riot_puuid, '') <> '' THEN 'americas' ELSE '' END,
+        CASE WHEN COALESCE(riot_puuid, '') = '' THEN 'verified' ELSE 'unverified' END,
+        updated_at
+    FROM users WHERE deleted_at IS NULL AND (COALESCE(riot_puuid, '') <> '' OR COALESCE(riot_id, '') <> '')
+    ON CONFLICT DO NOTHING;
+
+    INSERT INTO platform_links (created_at, updated_at, user_id, platform, external_id, display_name, region, verification, linked_at)
+    SELECT NOW(), NOW(), id, 'ea', ea_username, ea_username, '', 'unverified', updated_at
+    FROM users WHERE deleted_at IS NULL AND COALESCE(ea_username, '') <> ''
+    ON CONFLICT DO NOTHING;
+
+    INSERT INTO platform_links (created_at, updated_at, user_id, platform, external_id, display_name, region, verification, linked_at)
+    SELECT NOW(), NOW(), id, 'xbox', xbox_id, xbox_id, '', 'unverified', updated_at
+    FROM users WHERE deleted_at IS NULL AND COALESCE(xbox_id, '') <> ''
+    ON CONFLICT DO NOTHING;
+
+    INSERT INTO platform_links (created_at, updated_at, user_id, platform, external_id, display_name, region, verification, linked_at)
+    SELECT NOW(), NOW(), id, 'playstation', playstation_id, playstation_id, '', 'unverified', updated_at
+    FROM users WHERE deleted_at IS NULL AND COALESCE(playstation_id, '') <> ''
+    ON CONFLICT DO NOTHING;
+END $$;
+
+-- Dropping the columns also drops their partial unique indexes
+ALTER TABLE users
+    DROP COLUMN IF EXISTS steam_id,
+    DROP COLUMN IF EXISTS riot_id,
+    DROP COLUMN IF EXISTS riot_game_name,
+    DROP COLUMN IF EXISTS riot_tagline,
+    DROP COLUMN IF EXISTS riot_puuid,
+    DROP COLUMN IF EXISTS ea_username,
+    DROP COLUMN IF EXISTS xbox_id,
+    DROP COLUMN IF EXISTS playstation_id;
@@ -0,0 +1,11 @@
+-- Upstream API responses cached by the cache package's database backend, so
+-- replicas share them and they survive restarts.
+
+CREATE TABLE IF NOT EXISTS cache_entries (
+    key         text PRIMARY KEY,
+    value       bytea NOT NULL,
+    stored_at   timestamptz NOT NULL,
+    fresh_until timestamptz NOT NULL,
+    expires_at  timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_cache_entries_expires_at ON cache_entries (expires_at);
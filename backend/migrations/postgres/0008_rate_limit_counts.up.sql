@@ -0,0 +1,9 @@
+-- Request counts kept by the ratelimit package's database store, one row per
+-- client, route group and window, so replicas share them.
+
+CREATE TABLE IF NOT EXISTS rate_limit_counts (
+    key        text PRIMARY KEY,
+    count      integer NOT NULL,
+    expires_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_rate_limit_counts_expires_at ON rate_limit_counts (expires_at);
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS squad_invites;
+DROP TABLE IF EXISTS squad_members;
+DROP TABLE IF EXISTS squads;
+DROP TABLE IF EXISTS feed_comments;
+DROP TABLE IF EXISTS feed_reactions;
+DROP TABLE IF EXISTS feed_events;
+DROP TABLE IF EXISTS rank_snapshots;
+DROP TABLE IF EXISTS match_participants;
+DROP TABLE IF EXISTS stored_matches;
+DROP TABLE IF EXISTS friendships;
+DROP TABLE IF EXISTS user_stats;
+DROP TABLE IF EXISTS platform_links;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,190 @@
+-- SQLite support was added at version 6, so its history starts with the
+-- schema as it stands after the Postgres migrations up to 0006. From here on
+-- every migration has a Postgres and a SQLite version with the same number.
+
+CREATE TABLE users (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    username   text NOT NULL,
+    email      text NOT NULL,
+    password   text NOT NULL
+);
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+CREATE UNIQUE INDEX idx_users_email ON users (email) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_users_username ON users (username) WHERE deleted_at IS NULL;
+
+CREATE TABLE platform_links (
+    id           integer PRIMARY KEY AUTOINCREMENT,
+    created_at   datetime,
+    updated_at   datetime,
+    deleted_at   datetime,
+    user_id      integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    platform     text NOT NULL,
+    external_id  text NOT NULL,
+    display_name text DEFAULT '',
+    region       text DEFAULT '',
+    verification text NOT NULL DEFAULT 'unverified',
+    linked_at    datetime NOT NULL
+);
+CREATE INDEX idx_platform_links_deleted_at ON platform_links (deleted_at);
+CREATE UNIQUE INDEX idx_platform_links_user_platform ON platform_links (user_id, platform);
+CREATE UNIQUE INDEX idx_platform_links_external ON platform_links (platform, external_id);
+
+CREATE TABLE user_stats (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    user_id    integer NOT NULL,
+    game       text NOT NULL,
+    platform   text NOT NULL
+);
+CREATE INDEX idx_user_stats_deleted_at ON user_stats (deleted_at);
+
+CREATE TABLE friendships (
+    id           integer PRIMARY KEY AUTOINCREMENT,
+    created_at   datetime,
+    updated_at   datetime,
+    deleted_at   datetime,
+    user_id      integer NOT NULL,
+    friend_id    integer NOT NULL,
+    status       text NOT NULL DEFAULT 'pending',
+    requested_by integer NOT NULL,
+    pair_low     integer NOT NULL,
+    pair_high    integer NOT NULL,
+    responded_at datetime
+);
+CREATE INDEX idx_friendships_deleted_at ON friendships (deleted_at);
+CREATE INDEX idx_friendships_user_id ON friendships (user_id);
+CREATE INDEX idx_friendships_friend_id ON friendships (friend_id);
+CREATE UNIQUE INDEX idx_friendships_pair ON friendships (pair_low, pair_high);
+
+CREATE TABLE stored_matches (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    game       text NOT NULL,
+    match_id   text NOT NULL,
+    queue_id   text DEFAULT '',
+    played_at  datetime NOT NULL,
+    duration   integer DEFAULT 0
+);
+CREATE INDEX idx_stored_matches_deleted_at ON stored_matches (deleted_at);
+CREATE UNIQUE INDEX idx_stored_matches_game_match ON stored_matches (game, match_id);
+CREATE INDEX idx_stored_matches_played_at ON stored_matches (played_at);
+
+CREATE TABLE match_participants (
+    id              integer PRIMARY KEY AUTOINCREMENT,
+    created_at      datetime,
+    updated_at      datetime,
+    deleted_at      datetime,
+    stored_match_id integer NOT NULL REFERENCES stored_matches (id) ON DELETE CASCADE,
+    puuid           text NOT NULL,
+    team_id         text NOT NULL,
+    character       text DEFAULT '',
+    role            text DEFAULT '',
+    win             numeric NOT NULL,
+    kills           integer NOT NULL DEFAULT 0,
+    deaths          integer NOT NULL DEFAULT 0,
+    assists         integer NOT NULL DEFAULT 0,
+    penta_kills     integer NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_match_participants_deleted_at ON match_participants (deleted_at);
+CREATE INDEX idx_match_participants_stored_match_id ON match_participants (stored_match_id);
+CREATE INDEX idx_match_participants_puuid ON match_participants (puuid);
+
+CREATE TABLE rank_snapshots (
+    id            integer PRIMARY KEY AUTOINCREMENT,
+    created_at    datetime,
+    updated_at    datetime,
+    deleted_at    datetime,
+    user_id       integer NOT NULL,
+    game          text NOT NULL,
+    queue         text NOT NULL,
+    tier          text NOT NULL,
+    division      text DEFAULT '',
+    league_points integer DEFAULT 0
+);
+CREATE INDEX idx_rank_snapshots_deleted_at ON rank_snapshots (deleted_at);
+CREATE INDEX idx_rank_snapshots_user_game_queue ON rank_snapshots (user_id, game, queue);
+
+CREATE TABLE feed_events (
+    id          integer PRIMARY KEY AUTOINCREMENT,
+    created_at  datetime,
+    updated_at  datetime,
+    deleted_at  datetime,
+    user_id     integer NOT NULL,
+    type        text NOT NULL,
+    game        text NOT NULL,
+    match_id    text DEFAULT '',
+    summary     text NOT NULL,
+    details     text DEFAULT '',
+    occurred_at datetime NOT NULL,
+    dedupe_key  text NOT NULL
+);
+CREATE INDEX idx_feed_events_deleted_at ON feed_events (deleted_at);
+CREATE INDEX idx_feed_events_user_occurred ON feed_events (user_id, occurred_at);
+CREATE UNIQUE INDEX idx_feed_events_dedupe_key ON feed_events (dedupe_key);
+
+CREATE TABLE feed_reactions (
+    id            integer PRIMARY KEY AUTOINCREMENT,
+    created_at    datetime,
+    updated_at    datetime,
+    deleted_at    datetime,
+    feed_event_id integer NOT NULL,
+    user_id       integer NOT NULL,
+    kind          text NOT NULL
+);
+CREATE INDEX idx_feed_reactions_deleted_at ON feed_reactions (deleted_at);
+CREATE UNIQUE INDEX idx_feed_reactions_event_user_kind ON feed_reactions (feed_event_id, user_id, kind);
+
+CREATE TABLE feed_comments (
+    id            integer PRIMARY KEY AUTOINCREMENT,
+    created_at    datetime,
+    updated_at    datetime,
+    deleted_at    datetime,
+    feed_event_id integer NOT NULL,
+    user_id       integer NOT NULL,
+    body          text NOT NULL
+);
+CREATE INDEX idx_feed_comments_deleted_at ON feed_comments (deleted_at);
+CREATE INDEX idx_feed_comments_feed_event_id ON feed_comments (feed_event_id);
+
+CREATE TABLE squads (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    name       text NOT NULL,
+    owner_id   integer NOT NULL
+);
+CREATE INDEX idx_squads_deleted_at ON squads (deleted_at);
+CREATE INDEX idx_squads_owner_id ON squads (owner_id);
+
+CREATE TABLE squad_members (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    squad_id   integer NOT NULL REFERENCES squads (id) ON DELETE CASCADE,
+    user_id    integer NOT NULL,
+    role       text NOT NULL DEFAULT 'member'
+);
+CREATE UNIQUE INDEX idx_squad_members_squad_user ON squad_members (squad_id, user_id);
+CREATE INDEX idx_squad_members_user_id ON squad_members (user_id);
+
+CREATE TABLE squad_invites (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    squad_id   integer NOT NULL,
+    inviter_id integer NOT NULL,
+    invitee_id integer NOT NULL,
+    status     text NOT NULL DEFAULT 'pending'
+);
+CREATE INDEX idx_squad_invites_deleted_at ON squad_invites (deleted_at);
+CREATE INDEX idx_squad_invites_squad_id ON squad_invites (squad_id);
+CREATE INDEX idx_squad_invites_invitee_id ON squad_invites (invitee_id);
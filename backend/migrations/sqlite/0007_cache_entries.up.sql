@@ -0,0 +1,10 @@
+-- Upstream API responses cached by the cache package's database backend
+
+CREATE TABLE cache_entries (
+    key         text PRIMARY KEY,
+    value       blob NOT NULL,
+    stored_at   datetime NOT NULL,
+    fresh_until datetime NOT NULL,
+    expires_at  datetime NOT NULL
+);
+CREATE INDEX idx_cache_entries_expires_at ON cache_entries (expires_at);
@@ -0,0 +1,8 @@
+-- Request counts kept by the ratelimit package's database store
+
+CREATE TABLE rate_limit_counts (
+    key        text PRIMARY KEY,
+    count      integer NOT NULL,
+    expires_at datetime NOT NULL
+);
+CREATE INDEX idx_rate_limit_counts_expires_at ON rate_limit_counts (expires_at);
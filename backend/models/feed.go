@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Feed event types
+const (
+	FeedEventRankPromotion = "rank_promotion"
+	FeedEventPentakill     = "pentakill"
+	FeedEventWinStreak     = "win_streak"
+	FeedEventPersonalBest  = "personal_best"
+)
+
+// FeedEvent is a notable thing a user did, shown to their friends
+type FeedEvent struct {
+	gorm.Model
+	UserID     uint      `gorm:"not null;index:idx_feed_events_user_occurred"` // The user the event is about
+	Type       string    `gorm:"not null"`                                     // One of the FeedEvent* constants
+	Game       string    `gorm:"not null"`                                     // "lol", "valorant"
+	MatchID    string    `gorm:"default:''"`                                   // Upstream match ID, if any
+	Summary    string    `gorm:"not null"`                                     // Human readable headline
+	Details    string    `gorm:"type:text;default:''"`                         // JSON payload for the frontend
+	OccurredAt time.Time `gorm:"not null;index:idx_feed_events_user_occurred"` // When it happened in game
+	DedupeKey  string    `gorm:"not null;uniqueIndex"`                         // Prevents duplicate events on re-ingest
+}
+
+// FeedReaction is a single reaction a user left on a feed event
+type FeedReaction struct {
+	gorm.Model
+	FeedEventID uint   `gorm:"not null;uniqueIndex:idx_feed_reactions_event_user_kind"`
+	UserID      uint   `gorm:"not null;uniqueIndex:idx_feed_reactions_event_user_kind"`
+	Kind        string `gorm:"not null;uniqueIndex:idx_feed_reactions_event_user_kind"` // e.g. "gg", "fire"
+}
+
+// FeedComment is a comment left on a feed event
+type FeedComment struct {
+	gorm.Model
+	FeedEventID uint   `gorm:"not null;index"`
+	UserID      uint   `gorm:"not null"`
+	Body        string `gorm:"type:text;not null"`
+}
+
+// FeedEventResponse is used for returning feed events with the actor's details
+type FeedEventResponse struct {
+	ID           uint           `json:"id"`
+	UserID       uint           `json:"user_id"`
+	Username     string         `json:"username"`
+	Type         string         `json:"type"`
+	Game         string         `json:"game"`
+	MatchID      string         `json:"match_id,omitempty"`
+	Summary      string         `json:"summary"`
+	Details      string         `json:"details,omitempty"`
+	OccurredAt   string         `json:"occurred_at"`
+	Reactions    map[string]int `json:"reactions"`
+	CommentCount int            `json:"comment_count"`
+}
+
+// FeedCommentResponse is used for returning comments with the author's username
+type FeedCommentResponse struct {
+	ID        uint   `json:"id"`
+	UserID    uint   `json:"user_id"`
+	Username  string `json:"username"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"created_at"`
+}
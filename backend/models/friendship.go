@@ -1,26 +1,185 @@
 package models
 
 import (
+	"errors"
+	"time"
+
 	"gorm.io/gorm"
 )
 
-// Friendship represents a friendship relationship between users
+// Friendship statuses
+const (
+	FriendshipPending   = "pending"
+	FriendshipAccepted  = "accepted"
+	FriendshipDeclined  = "declined"
+	FriendshipCancelled = "cancelled"
+)
+
+// Actions that move a friendship between statuses
+const (
+	FriendshipActionRequest = "request"
+	FriendshipActionAccept  = "accept"
+	FriendshipActionDecline = "decline"
+	FriendshipActionCancel  = "cancel"
+)
+
+// FriendRequestCooldown is how long a declined sender must wait before asking again
+var FriendRequestCooldown = 7 * 24 * time.Hour
+
+// Friendship transition errors
+var (
+	ErrFriendRequestToSelf      = errors.New("cannot send a friend request to yourself")
+	ErrAlreadyFriends           = errors.New("users are already friends")
+	ErrFriendRequestCooldown    = errors.New("friend request was declined recently")
+	ErrNotFriendRequestTarget   = errors.New("only the recipient can respond to a friend request")
+	ErrNotFriendRequestSender   = errors.New("only the sender can cancel a friend request")
+	ErrNotFriendshipParticipant = errors.New("user is not part of this friendship")
+	ErrInvalidFriendshipAction  = errors.New("action is not allowed in the current friendship status")
+)
+
+// Friendship represents a friendship relationship between users.
+// There is exactly one row per pair of users, enforced by the canonical
+// (PairLow, PairHigh) unique index; the row moves between statuses instead
+// of new rows being created.
 type Friendship struct {
 	gorm.Model
-	UserID      uint   `gorm:"not null;index"`          // The user who has the friend
-	FriendID    uint   `gorm:"not null;index"`          // The user who is the friend
-	Status      string `gorm:"not null;default:'pending'"` // "pending", "accepted", "rejected"
-	RequestedBy uint   `gorm:"not null"`                // Which user initiated the request
+	UserID      uint       `gorm:"not null;index"`                            // The user who sent the latest request
+	FriendID    uint       `gorm:"not null;index"`                            // The user who received the latest request
+	Status      string     `gorm:"not null;default:'pending'"`                // "pending", "accepted", "declined", "cancelled"
+	RequestedBy uint       `gorm:"not null"`                                  // Which user initiated the request
+	PairLow     uint       `gorm:"not null;uniqueIndex:idx_friendships_pair"` // Smaller of the two user IDs
+	PairHigh    uint       `gorm:"not null;uniqueIndex:idx_friendships_pair"` // Larger of the two user IDs
+	RespondedAt *time.Time // When the status last changed away from pending
+}
+
+// FriendshipPair returns the two user IDs in canonical order
+func FriendshipPair(a, b uint) (uint, uint) {
+	if a < b {
+		return a, b
+	}
+	return b, a
+}
+
+// BeforeSave keeps the canonical pair in sync with the participants
+func (f *Friendship) BeforeSave(tx *gorm.DB) error {
+	f.PairLow, f.PairHigh = FriendshipPair(f.UserID, f.FriendID)
+	return nil
+}
+
+// NewFriendRequest builds a pending friendship from one user to another
+func NewFriendRequest(fromID, toID uint) (*Friendship, error) {
+	if fromID == toID {
+		return nil, ErrFriendRequestToSelf
+	}
+	low, high := FriendshipPair(fromID, toID)
+	return &Friendship{
+		UserID:      fromID,
+		FriendID:    toID,
+		Status:      FriendshipPending,
+		RequestedBy: fromID,
+		PairLow:     low,
+		PairHigh:    high,
+	}, nil
+}
+
+// OtherUser returns the participant that isn't userID
+func (f *Friendship) OtherUser(userID uint) uint {
+	if f.UserID == userID {
+		return f.FriendID
+	}
+	return f.UserID
+}
+
+// Transition applies an action by actorID to the friendship.
+// It returns false without an error when the action is a repeat of one
+// already applied, so callers can treat retries as success.
+func (f *Friendship) Transition(actorID uint, action string, now time.Time) (bool, error) {
+	if actorID != f.UserID && actorID != f.FriendID {
+		return false, ErrNotFriendshipParticipant
+	}
+	isSender := actorID == f.RequestedBy
+
+	switch action {
+	case FriendshipActionRequest:
+		switch f.Status {
+		case FriendshipAccepted:
+			return false, ErrAlreadyFriends
+		case FriendshipPending:
+			if isSender {
+				// Sending the same request twice is a no-op
+				return false, nil
+			}
+			// Both users asked each other, so accept
+			f.Status = FriendshipAccepted
+			f.RespondedAt = &now
+			return true, nil
+		case FriendshipDeclined:
+			// The sender has to wait; the user who declined can change their mind at any time
+			if isSender && f.RespondedAt != nil && now.Sub(*f.RespondedAt) < FriendRequestCooldown {
+				return false, ErrFriendRequestCooldown
+			}
+		}
+		// Declined after cooldown, or cancelled: start a fresh request from the actor
+		f.UserID, f.FriendID = actorID, f.OtherUser(actorID)
+		f.RequestedBy = actorID
+		f.Status = FriendshipPending
+		f.RespondedAt = nil
+		return true, nil
+
+	case FriendshipActionAccept, FriendshipActionDecline:
+		target := FriendshipAccepted
+		if action == FriendshipActionDecline {
+			target = FriendshipDeclined
+		}
+		if f.Status == target && !isSender {
+			return false, nil
+		}
+		if f.Status != FriendshipPending {
+			return false, ErrInvalidFriendshipAction
+		}
+		if isSender {
+			return false, ErrNotFriendRequestTarget
+		}
+		f.Status = target
+		f.RespondedAt = &now
+		return true, nil
+
+	case FriendshipActionCancel:
+		if f.Status == FriendshipCancelled && isSender {
+			return false, nil
+		}
+		if f.Status != FriendshipPending {
+			return false, ErrInvalidFriendshipAction
+		}
+		if !isSender {
+			return false, ErrNotFriendRequestSender
+		}
+		f.Status = FriendshipCancelled
+		f.RespondedAt = &now
+		return true, nil
+	}
+
+	return false, ErrInvalidFriendshipAction
 }
 
 // FriendshipResponse is used for returning friendship data with user details
 type FriendshipResponse struct {
-	ID           uint   `json:"id"`
-	UserID       uint   `json:"user_id"`
-	FriendID     uint   `json:"friend_id"`
-	Username     string `json:"username"`     // Friend's username
-	Email        string `json:"email"`        // Friend's email
-	Status       string `json:"status"`       // Friendship status
-	RequestedBy  uint   `json:"requested_by"` // Who initiated the request
-	CreatedAt    string `json:"created_at"`
+	ID          uint   `json:"id"`
+	UserID      uint   `json:"user_id"`
+	FriendID    uint   `json:"friend_id"`
+	Username    string `json:"username"`     // Friend's username
+	Email       string `json:"email"`        // Friend's email
+	Status      string `json:"status"`       // Friendship status
+	RequestedBy uint   `json:"requested_by"` // Who initiated the request
+	CreatedAt   string `json:"created_at"`
+}
+
+// FriendSuggestionResponse is a suggested friend with the reasons we suggested them
+type FriendSuggestionResponse struct {
+	UserID        uint     `json:"user_id"`
+	Username      string   `json:"username"`
+	Score         int      `json:"score"`
+	MutualFriends int      `json:"mutual_friends"`
+	RecentMatches int      `json:"recent_matches"`
+	Reasons       []string `json:"reasons"`
 }
@@ -0,0 +1,138 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"elo-insight/backend/models"
+)
+
+func TestNewFriendRequest(t *testing.T) {
+	f, err := models.NewFriendRequest(2, 1)
+	if err != nil {
+		t.Fatalf("NewFriendRequest: %v", err)
+	}
+	if f.Status != models.FriendshipPending || f.UserID != 2 || f.FriendID != 1 || f.RequestedBy != 2 {
+		t.Errorf("request = %+v, want pending from 2 to 1", f)
+	}
+	if f.PairLow != 1 || f.PairHigh != 2 {
+		t.Errorf("pair = (%d, %d), want (1, 2)", f.PairLow, f.PairHigh)
+	}
+
+	if _, err := models.NewFriendRequest(1, 1); !errors.Is(err, models.ErrFriendRequestToSelf) {
+		t.Errorf("request to self: err = %v, want %v", err, models.ErrFriendRequestToSelf)
+	}
+}
+
+func TestFriendshipTransition(t *testing.T) {
+	const sender, recipient, stranger = 1, 2, 3
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	cooldown := models.FriendRequestCooldown
+
+	tests := []struct {
+		name      string
+		status    string        // Status of a request from sender to recipient
+		responded time.Duration // How long before now the status changed, if it isn't pending
+		actor     uint
+		action    string
+
+		wantChanged bool
+		wantErr     error
+		wantStatus  string
+		wantFrom    uint // The sender of the request afterwards
+	}{
+		// Requests
+		{name: "repeated request", status: models.FriendshipPending, actor: sender, action: models.FriendshipActionRequest,
+			wantStatus: models.FriendshipPending, wantFrom: sender},
+		{name: "mutual request accepts", status: models.FriendshipPending, actor: recipient, action: models.FriendshipActionRequest,
+			wantChanged: true, wantStatus: models.FriendshipAccepted, wantFrom: sender},
+		{name: "request between friends", status: models.FriendshipAccepted, responded: time.Hour, actor: recipient, action: models.FriendshipActionRequest,
+			wantErr: models.ErrAlreadyFriends, wantStatus: models.FriendshipAccepted, wantFrom: sender},
+		{name: "request during cooldown", status: models.FriendshipDeclined, responded: cooldown - time.Minute, actor: sender, action: models.FriendshipActionRequest,
+			wantErr: models.ErrFriendRequestCooldown, wantStatus: models.FriendshipDeclined, wantFrom: sender},
+		{name: "request after cooldown", status: models.FriendshipDeclined, responded: cooldown, actor: sender, action: models.FriendshipActionRequest,
+			wantChanged: true, wantStatus: models.FriendshipPending, wantFrom: sender},
+		{name: "decliner asks during cooldown", status: models.FriendshipDeclined, responded: time.Minute, actor: recipient, action: models.FriendshipActionRequest,
+			wantChanged: true, wantStatus: models.FriendshipPending, wantFrom: recipient},
+		{name: "request after cancel", status: models.FriendshipCancelled, responded: time.Minute, actor: recipient, action: models.FriendshipActionRequest,
+			wantChanged: true, wantStatus: models.FriendshipPending, wantFrom: recipient},
+
+		// Accept and decline
+		{name: "accept", status: models.FriendshipPending, actor: recipient, action: models.FriendshipActionAccept,
+			wantChanged: true, wantStatus: models.FriendshipAccepted, wantFrom: sender},
+		{name: "repeated accept", status: models.FriendshipAccepted, responded: time.Minute, actor: recipient, action: models.FriendshipActionAccept,
+			wantStatus: models.FriendshipAccepted, wantFrom: sender},
+		{name: "sender accepts", status: models.FriendshipPending, actor: sender, action: models.FriendshipActionAccept,
+			wantErr: models.ErrNotFriendRequestTarget, wantStatus: models.FriendshipPending, wantFrom: sender},
+		{name: "decline", status: models.FriendshipPending, actor: recipient, action: models.FriendshipActionDecline,
+			wantChanged: true, wantStatus: models.FriendshipDeclined, wantFrom: sender},
+		{name: "repeated decline", status: models.FriendshipDeclined, responded: time.Minute, actor: recipient, action: models.FriendshipActionDecline,
+			wantStatus: models.FriendshipDeclined, wantFrom: sender},
+		{name: "sender declines", status: models.FriendshipPending, actor: sender, action: models.FriendshipActionDecline,
+			wantErr: models.ErrNotFriendRequestTarget, wantStatus: models.FriendshipPending, wantFrom: sender},
+		{name: "decline after accept", status: models.FriendshipAccepted, responded: time.Minute, actor: recipient, action: models.FriendshipActionDecline,
+			wantErr: models.ErrInvalidFriendshipAction, wantStatus: models.FriendshipAccepted, wantFrom: sender},
+		{name: "accept after cancel", status: models.FriendshipCancelled, responded: time.Minute, actor: recipient, action: models.FriendshipActionAccept,
+			wantErr: models.ErrInvalidFriendshipAction, wantStatus: models.FriendshipCancelled, wantFrom: sender},
+
+		// Cancel
+		{name: "cancel", status: models.FriendshipPending, actor: sender, action: models.FriendshipActionCancel,
+			wantChanged: true, wantStatus: models.FriendshipCancelled, wantFrom: sender},
+		{name: "repeated cancel", status: models.FriendshipCancelled, responded: time.Minute, actor: sender, action: models.FriendshipActionCancel,
+			wantStatus: models.FriendshipCancelled, wantFrom: sender},
+		{name: "recipient cancels", status: models.FriendshipPending, actor: recipient, action: models.FriendshipActionCancel,
+			wantErr: models.ErrNotFriendRequestSender, wantStatus: models.FriendshipPending, wantFrom: sender},
+		{name: "cancel after accept", status: models.FriendshipAccepted, responded: time.Minute, actor: sender, action: models.FriendshipActionCancel,
+			wantErr: models.ErrInvalidFriendshipAction, wantStatus: models.FriendshipAccepted, wantFrom: sender},
+
+		// Anything else
+		{name: "stranger", status: models.FriendshipPending, actor: stranger, action: models.FriendshipActionAccept,
+			wantErr: models.ErrNotFriendshipParticipant, wantStatus: models.FriendshipPending, wantFrom: sender},
+		{name: "unknown action", status: models.FriendshipPending, actor: recipient, action: "block",
+			wantErr: models.ErrInvalidFriendshipAction, wantStatus: models.FriendshipPending, wantFrom: sender},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := models.NewFriendRequest(sender, recipient)
+			if err != nil {
+				t.Fatalf("NewFriendRequest: %v", err)
+			}
+			f.Status = tt.status
+			if tt.status != models.FriendshipPending {
+				responded := now.Add(-tt.responded)
+				f.RespondedAt = &responded
+			}
+			before := *f
+
+			changed, err := f.Transition(tt.actor, tt.action, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if f.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", f.Status, tt.wantStatus)
+			}
+			if f.RequestedBy != tt.wantFrom || f.UserID != tt.wantFrom || f.FriendID != f.OtherUser(tt.wantFrom) {
+				t.Errorf("request is from %d to %d (requested by %d), want from %d", f.UserID, f.FriendID, f.RequestedBy, tt.wantFrom)
+			}
+
+			switch {
+			case !changed:
+				if *f != before {
+					t.Errorf("friendship changed without a transition: %+v, was %+v", f, before)
+				}
+			case tt.wantStatus == models.FriendshipPending:
+				if f.RespondedAt != nil {
+					t.Errorf("RespondedAt = %v on a fresh request, want nil", f.RespondedAt)
+				}
+			default:
+				if f.RespondedAt == nil || !f.RespondedAt.Equal(now) {
+					t.Errorf("RespondedAt = %v, want %v", f.RespondedAt, now)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StoredMatch is a match we have already downloaded from an upstream API
+type StoredMatch struct {
+	gorm.Model
+	Game         string             `gorm:"not null;uniqueIndex:idx_stored_matches_game_match"` // "lol", "valorant"
+	MatchID      string             `gorm:"not null;uniqueIndex:idx_stored_matches_game_match"` // Upstream match ID
+	QueueID      string             `gorm:"default:''"`                                         // Queue or game mode
+	PlayedAt     time.Time          `gorm:"not null;index"`                                     // When the match started
+	Duration     int                `gorm:"default:0"`                                          // Match length in seconds
+	Participants []MatchParticipant `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// MatchParticipant is one player's line in a stored match
+type MatchParticipant struct {
+	gorm.Model
+	StoredMatchID uint   `gorm:"not null;index"`
+	PUUID         string `gorm:"column:puuid;not null;index"` // Riot PUUID of the player
+	TeamID        string `gorm:"not null"`                    // Team identifier as reported upstream
+	Character     string `gorm:"default:''"`                  // Champion or agent name
+	Role          string `gorm:"default:''"`                  // Lane/role when the game has one
+	Win           bool   `gorm:"not null"`
+	Kills         int    `gorm:"not null;default:0"`
+	Deaths        int    `gorm:"not null;default:0"`
+	Assists       int    `gorm:"not null;default:0"`
+	PentaKills    int    `gorm:"not null;default:0"`
+}
+
+// RankSnapshot records a player's rank in a queue at a point in time
+type RankSnapshot struct {
+	gorm.Model
+	UserID       uint   `gorm:"not null;index:idx_rank_snapshots_user_game_queue"`
+	Game         string `gorm:"not null;index:idx_rank_snapshots_user_game_queue"`
+	Queue        string `gorm:"not null;index:idx_rank_snapshots_user_game_queue"` // e.g. "RANKED_SOLO_5x5"
+	Tier         string `gorm:"not null"`                                          // e.g. "GOLD"
+	Division     string `gorm:"default:''"`                                        // e.g. "II"
+	LeaguePoints int    `gorm:"default:0"`
+}
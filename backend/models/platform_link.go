@@ -0,0 +1,65 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Supported gaming platforms
+const (
+	PlatformSteam       = "steam"
+	PlatformRiot        = "riot"
+	PlatformEA          = "ea"
+	PlatformXbox        = "xbox"
+	PlatformPlayStation = "playstation"
+)
+
+// Verification states of a platform link
+const (
+	LinkUnverified = "unverified" // The user typed the account in; we have not proven they own it
+	LinkVerified   = "verified"   // Ownership was proven by the platform's sign-in (Steam OpenID, Riot RSO)
+)
+
+// PlatformLink connects a user to one account on an external gaming platform.
+// A user has at most one link per platform, and an external account can only
+// be linked to one user.
+type PlatformLink struct {
+	gorm.Model
+	UserID       uint      `gorm:"not null;uniqueIndex:idx_platform_links_user_platform"`
+	Platform     string    `gorm:"not null;uniqueIndex:idx_platform_links_user_platform;uniqueIndex:idx_platform_links_external"` // "steam", "riot", "ea", "xbox", "playstation"
+	ExternalID   string    `gorm:"not null;uniqueIndex:idx_platform_links_external"`                                              // SteamID64, Riot PUUID, gamertag, ...
+	DisplayName  string    `gorm:"default:''"`                                                                                    // Riot "GameName#Tagline", gamertag, ...
+	Region       string    `gorm:"default:''"`                                                                                    // Platform region or routing value, if any
+	Verification string    `gorm:"not null;default:'unverified'"`                                                                 // "unverified", "verified"
+	LinkedAt     time.Time `gorm:"not null"`
+}
+
+// RiotNameAndTag splits a Riot display name of the form "GameName#Tagline"
+func (l *PlatformLink) RiotNameAndTag() (string, string) {
+	name, tag, _ := strings.Cut(l.DisplayName, "#")
+	return name, tag
+}
+
+// PlatformLinkResponse is used for returning a linked account
+type PlatformLinkResponse struct {
+	Platform     string `json:"platform"`
+	ExternalID   string `json:"external_id"`
+	DisplayName  string `json:"display_name"`
+	Region       string `json:"region"`
+	Verification string `json:"verification"`
+	LinkedAt     string `json:"linked_at"`
+}
+
+// Response converts the link to its API representation
+func (l *PlatformLink) Response() PlatformLinkResponse {
+	return PlatformLinkResponse{
+		Platform:     l.Platform,
+		ExternalID:   l.ExternalID,
+		DisplayName:  l.DisplayName,
+		Region:       l.Region,
+		Verification: l.Verification,
+		LinkedAt:     l.LinkedAt.Format(time.RFC3339),
+	}
+}
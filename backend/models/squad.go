@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Squad member roles
+const (
+	SquadRoleOwner  = "owner"
+	SquadRoleMember = "member"
+)
+
+// Squad is a named group of friends who play together
+type Squad struct {
+	gorm.Model
+	Name    string        `gorm:"not null"`
+	OwnerID uint          `gorm:"not null;index"`
+	Members []SquadMember `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// SquadMember links a user to a squad with a role. Memberships aren't soft
+// deleted: a removed member's row is gone, so the user can be invited back.
+type SquadMember struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	SquadID   uint   `gorm:"not null;uniqueIndex:idx_squad_members_squad_user"`
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_squad_members_squad_user;index"`
+	Role      string `gorm:"not null;default:'member'"` // "owner", "member"
+}
+
+// SquadInvite is a pending invitation to join a squad
+type SquadInvite struct {
+	gorm.Model
+	SquadID   uint   `gorm:"not null;index"`
+	InviterID uint   `gorm:"not null"`
+	InviteeID uint   `gorm:"not null;index"`
+	Status    string `gorm:"not null;default:'pending'"` // "pending", "accepted", "declined"
+}
+
+// SquadResponse is used for returning a squad with its members
+type SquadResponse struct {
+	ID        uint                  `json:"id"`
+	Name      string                `json:"name"`
+	OwnerID   uint                  `json:"owner_id"`
+	Members   []SquadMemberResponse `json:"members"`
+	CreatedAt string                `json:"created_at"`
+}
+
+// SquadMemberResponse is used for returning a squad member with user details
+type SquadMemberResponse struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// SquadInviteResponse is used for returning an invite with squad and inviter details
+type SquadInviteResponse struct {
+	ID              uint   `json:"id"`
+	SquadID         uint   `json:"squad_id"`
+	SquadName       string `json:"squad_name"`
+	InviterID       uint   `json:"inviter_id"`
+	InviterUsername string `json:"inviter_username"`
+	Status          string `json:"status"`
+	CreatedAt       string `json:"created_at"`
+}
@@ -8,17 +8,10 @@ import (
 // User represents a user in the database
 type User struct {
 	gorm.Model
-	Username      string `gorm:"not null" json:"username"`
-	Email         string `gorm:"not null" json:"email"`
-	Password      string `gorm:"not null" json:"password"`
-	SteamID       string `gorm:"default:''" json:"steam_id"`
-	RiotID        string `gorm:"default:''" json:"riot_id"` // Deprecated, use RiotGameName, RiotTagline, RiotPUUID
-	RiotGameName  string `gorm:"default:''" json:"riot_game_name"`
-	RiotTagline   string `gorm:"default:''" json:"riot_tagline"`
-	RiotPUUID     string `gorm:"default:''" json:"riot_puuid"`
-	EAUsername    string `gorm:"default:''" json:"ea_username"` // EA account username for Apex Legends
-	XboxID        string `gorm:"default:''" json:"xbox_id"` // Xbox Live Gamertag
-	PlayStationID string `gorm:"default:''" json:"playstation_id"` // PlayStation Network ID
+	Username      string         `gorm:"not null" json:"username"`
+	Email         string         `gorm:"not null" json:"email"`
+	Password      string         `gorm:"not null" json:"password"`
+	PlatformLinks []PlatformLink `gorm:"constraint:OnDelete:CASCADE" json:"platform_links,omitempty"` // Linked gaming accounts
 }
 
 // Hashes the user's password before storing it
@@ -0,0 +1,408 @@
+// Package openapi builds the API's OpenAPI 3 document from metadata given
+// alongside each route, with schemas generated from the Go request and
+// response types, and serves it with a docs UI.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"maps"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Route describes one operation
+type Route struct {
+	Summary     string
+	Description string
+	Tag         string
+	Auth        bool        // Requires a session; adds the 401 response
+	Query       any         // Struct whose form tags are the query parameters
+	Params      []Parameter // Query parameters not in a struct
+	Body        any         // JSON request body
+	Status      int         // Success status, 200 if unset
+	Response    any         // Success body: a Go value, Object or *Schema; nil for none
+	ContentType string      // Of the success body, application/json if unset
+	Errors      []int       // Statuses answered with the error envelope
+	Other       map[int]any // Further statuses with their own body, e.g. 503 from /readyz
+	Paginated   bool        // Answers with a page and Link headers to the next and previous ones
+	RateLimited bool        // Counts against a rate limit; adds the RateLimit headers and the 429 response
+	Deprecated  bool
+}
+
+// Document is an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Tags       []Tag               `json:"tags,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+
+	schemas     *schemas
+	errorSchema *Schema
+	operations  map[string]bool // "METHOD /gin/:path"
+
+	once sync.Once
+	body []byte
+	err  error
+}
+
+// Info is the document's metadata
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Tag groups operations in the docs UI
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds a path's operations keyed by lowercase method
+type PathItem map[string]*Operation
+
+// Operation is one method on a path
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is an operation's JSON body
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is one status an operation can answer with
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]*Header   `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header is a response header
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType is the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the schemas operations refer to
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is a way to authenticate
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// New starts a document. errorBody is the envelope every error response uses;
+// sessionCookie is the cookie that authenticates a signed-in user.
+func New(info Info, errorBody any, sessionCookie string) *Document {
+	s := &schemas{byName: map[string]*Schema{}, names: map[reflect.Type]string{}, formats: map[string]string{}}
+	return &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: s.byName,
+			SecuritySchemes: map[string]*SecurityScheme{
+				"cookieAuth": {Type: "apiKey", In: "cookie", Name: sessionCookie, Description: "Set by login"},
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+		schemas:     s,
+		errorSchema: s.of(errorBody),
+		operations:  map[string]bool{},
+	}
+}
+
+// Schema returns the component schema generated for a type, so callers can
+// add what reflection can't see, such as an enum of error codes
+func (d *Document) Schema(name string) *Schema {
+	return d.Components.Schemas[name]
+}
+
+// AddFormat names a custom binding rule as a string format. Call it before
+// adding the routes that use the rule.
+func (d *Document) AddFormat(tag, description string) {
+	d.schemas.formats[tag] = description
+}
+
+// AddTag describes a tag used by routes
+func (d *Document) AddTag(name, description string) {
+	d.Tags = append(d.Tags, Tag{Name: name, Description: description})
+}
+
+var pathParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// Add documents a route by its Gin method and path, e.g. "/squads/:id"
+func (d *Document) Add(method, path string, route Route) {
+	d.operations[method+" "+path] = true
+
+	op := &Operation{
+		OperationID: operationID(method, path),
+		Summary:     route.Summary,
+		Description: route.Description,
+		Responses:   map[string]*Response{},
+		Deprecated:  route.Deprecated,
+	}
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+	}
+
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   pathParamSchema(match[1]),
+		})
+	}
+	if route.Query != nil {
+		op.Parameters = append(op.Parameters, d.queryParams(route.Query)...)
+	}
+	for _, param := range route.Params {
+		param.In = "query"
+		op.Parameters = append(op.Parameters, param)
+	}
+
+	if route.Body != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: d.schemas.of(route.Body)}},
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	if route.Response != nil {
+		contentType := route.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		success.Content = map[string]MediaType{contentType: {Schema: d.schemas.of(route.Response)}}
+	}
+	if status >= 300 && status < 400 {
+		success.Headers = map[string]*Header{"Location": {Schema: &Schema{Type: "string", Format: "uri"}}}
+	}
+	if route.Paginated {
+		success.Headers = map[string]*Header{"Link": {
+			Description: `URLs of the next and previous pages, with rel="next" and rel="prev"; absent at either end`,
+			Schema:      &Schema{Type: "string"},
+		}}
+	}
+	if route.RateLimited {
+		if success.Headers == nil {
+			success.Headers = map[string]*Header{}
+		}
+		maps.Copy(success.Headers, rateLimitHeaders)
+	}
+	op.Responses[strconv.Itoa(status)] = success
+	for status, body := range route.Other {
+		op.Responses[strconv.Itoa(status)] = &Response{
+			Description: http.StatusText(status),
+			Content:     map[string]MediaType{"application/json": {Schema: d.schemas.of(body)}},
+		}
+	}
+
+	errors := append([]int{}, route.Errors...)
+	if route.Auth {
+		op.Security = []map[string][]string{{"cookieAuth": {}}, {"bearerAuth": {}}}
+		errors = append(errors, http.StatusUnauthorized)
+	}
+	if route.RateLimited {
+		errors = append(errors, http.StatusTooManyRequests)
+	}
+	for _, status := range errors {
+		op.Responses[strconv.Itoa(status)] = d.errorResponse(status)
+	}
+	if limited := op.Responses[strconv.Itoa(http.StatusTooManyRequests)]; limited != nil && route.RateLimited {
+		maps.Copy(limited.Headers, rateLimitHeaders)
+	}
+
+	path = pathParam.ReplaceAllString(path, "{$1}")
+	if d.Paths[path] == nil {
+		d.Paths[path] = PathItem{}
+	}
+	d.Paths[path][strings.ToLower(method)] = op
+}
+
+// rateLimitHeaders are sent by rate-limited routes, except to internal callers
+var rateLimitHeaders = map[string]*Header{
+	"RateLimit-Limit":     {Description: "Requests allowed per window", Schema: &Schema{Type: "integer"}},
+	"RateLimit-Remaining": {Description: "Requests left in the current window", Schema: &Schema{Type: "integer"}},
+	"RateLimit-Reset":     {Description: "Seconds until the current window ends", Schema: &Schema{Type: "integer"}},
+	"RateLimit-Policy":    {Description: "The limit and window length in seconds, e.g. 30;w=60", Schema: &Schema{Type: "string"}},
+}
+
+func (d *Document) errorResponse(status int) *Response {
+	response := &Response{
+		Description: http.StatusText(status),
+		Content:     map[string]MediaType{"application/json": {Schema: d.errorSchema}},
+	}
+	if status == http.StatusTooManyRequests {
+		response.Headers = map[string]*Header{
+			"Retry-After": {Description: "Seconds to wait before retrying", Schema: &Schema{Type: "integer"}},
+		}
+	}
+	return response
+}
+
+// queryParams describes the form-tagged fields of a query struct, including
+// those of untagged embedded structs like gin binds them
+func (d *Document) queryParams(query any) []Parameter {
+	return d.queryFields(reflect.TypeOf(query))
+}
+
+func (d *Document) queryFields(t reflect.Type) []Parameter {
+	var params []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			params = append(params, d.queryFields(field.Type)...)
+			continue
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+		schema := d.schemas.forType(field.Type)
+		params = append(params, Parameter{
+			Name:     name,
+			In:       "query",
+			Required: d.schemas.applyBinding(schema, field.Tag.Get("binding")),
+			Schema:   schema,
+		})
+	}
+	return params
+}
+
+// pathParamSchema types IDs as integers and anything else as a string
+func pathParamSchema(name string) *Schema {
+	if name == "id" || strings.HasSuffix(name, "ID") {
+		return &Schema{Type: "integer", Minimum: ptr(1.0)}
+	}
+	return &Schema{Type: "string"}
+}
+
+// operationID names an operation after its method and path, e.g.
+// "getSquadsIdStats" for GET /squads/:id/stats
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == ':' || r == '*' || r == '-' || r == '.' || r == '_'
+	}) {
+		b.WriteString(exported(part))
+	}
+	return b.String()
+}
+
+// Missing compares the document with the routes Gin registered. It returns
+// "METHOD /path" for each route without documentation and each documented
+// route that isn't registered, sorted.
+func (d *Document) Missing(routes gin.RoutesInfo) []string {
+	registered := map[string]bool{}
+	var missing []string
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if !d.operations[key] {
+			missing = append(missing, key+" (undocumented)")
+		}
+	}
+	for key := range d.operations {
+		if !registered[key] {
+			missing = append(missing, key+" (not registered)")
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// JSON returns the encoded document. The document must not change after the first call.
+func (d *Document) JSON() ([]byte, error) {
+	d.once.Do(func() {
+		d.body, d.err = json.MarshalIndent(d, "", "  ")
+	})
+	return d.body, d.err
+}
+
+// Handler serves the document
+func (d *Document) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := d.JSON()
+		if err != nil {
+			c.Error(fmt.Errorf("failed to encode OpenAPI document: %w", err))
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+	}
+}
+
+// DocsHandler serves Swagger UI for the document at specURL
+func DocsHandler(title, specURL string) gin.HandlerFunc {
+	page := fmt.Sprintf(docsPage, html.EscapeString(title), strconv.Quote(specURL))
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+	}
+}
+
+const docsPage = `<!doctype html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>%s</title>
+  <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: %s, dom_id: "#swagger-ui", withCredentials: true });
+  </script>
+</body>
+</html>
+`
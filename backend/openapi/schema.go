@@ -0,0 +1,249 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Schema is an OpenAPI schema object, limited to what the API uses
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+// Object describes a JSON object built with gin.H. Values are Go values
+// whose type gives the property's schema, or *Schema.
+type Object map[string]any
+
+// Any is a schema that allows any JSON value
+func Any(description string) *Schema {
+	return &Schema{Description: description}
+}
+
+// String is a string schema
+func String(description string) *Schema {
+	return &Schema{Type: "string", Description: description}
+}
+
+// Integer is an integer schema
+func Integer(description string) *Schema {
+	return &Schema{Type: "integer", Description: description}
+}
+
+// Types whose JSON form isn't what their Go structure suggests
+var knownTypes = map[reflect.Type]func() *Schema{
+	reflect.TypeOf(time.Time{}):       func() *Schema { return &Schema{Type: "string", Format: "date-time"} },
+	reflect.TypeOf(gorm.DeletedAt{}):  func() *Schema { return &Schema{Type: "string", Format: "date-time", Nullable: true} },
+	reflect.TypeOf(json.RawMessage{}): func() *Schema { return &Schema{} },
+}
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// schemas generates component schemas for Go types
+type schemas struct {
+	byName  map[string]*Schema
+	names   map[reflect.Type]string
+	formats map[string]string // Custom binding tag to its description
+}
+
+// of returns the schema for v, which may be a Go value, an Object or a *Schema.
+// Named struct types become components and are returned as references.
+func (s *schemas) of(v any) *Schema {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case *Schema:
+		return v
+	case Object:
+		schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		for name, value := range v {
+			schema.Properties[name] = s.of(value)
+		}
+		return schema
+	}
+	return s.forType(reflect.TypeOf(v))
+}
+
+func (s *schemas) forType(t reflect.Type) *Schema {
+	if known, ok := knownTypes[t]; ok {
+		return known()
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := s.forType(t.Elem())
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: ptr(0.0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.forType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.forType(t.Elem())}
+	case reflect.Struct:
+		if t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+			return &Schema{}
+		}
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return s.component(t)
+	}
+	// Interfaces and anything else
+	return &Schema{}
+}
+
+// component registers a named struct type once and returns a reference to it
+func (s *schemas) component(t reflect.Type) *Schema {
+	name, ok := s.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := s.byName[name]; taken {
+			// Same name in another package, e.g. handlers.RiotAccount
+			name = exported(packageName(t)) + name
+		}
+		s.names[t] = name
+		s.byName[name] = &Schema{} // Placeholder so recursive types terminate
+		s.byName[name] = s.object(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// object describes a struct the way encoding/json marshals it
+func (s *schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	s.addFields(schema, t)
+	return schema
+}
+
+func (s *schemas) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		// Untagged embedded structs are flattened, like encoding/json does
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct && knownTypes[embedded] == nil {
+				s.addFields(schema, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := s.forType(field.Type)
+		if s.applyBinding(property, field.Tag.Get("binding")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+}
+
+// applyBinding copies the validator rules that OpenAPI can express onto
+// schema, and reports whether the field is required
+func (s *schemas) applyBinding(schema *Schema, binding string) (required bool) {
+	if binding == "" || schema.Ref != "" {
+		return strings.Contains(binding, "required")
+	}
+	for _, rule := range strings.Split(binding, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		case "min", "max", "gt":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			if schema.Type == "string" {
+				if name == "max" {
+					schema.MaxLength = ptr(n)
+				} else {
+					schema.MinLength = ptr(n)
+				}
+				continue
+			}
+			switch name {
+			case "max":
+				schema.Maximum = ptr(float64(n))
+			case "min":
+				schema.Minimum = ptr(float64(n))
+			case "gt":
+				schema.Minimum = ptr(float64(n + 1))
+			}
+		default:
+			// Custom rules are named as formats, e.g. "riot_id"
+			if description, ok := s.formats[name]; ok && schema.Type == "string" {
+				schema.Format = name
+				schema.Description = description
+			}
+		}
+	}
+	return required
+}
+
+// packageName is the last element of t's package path
+func packageName(t reflect.Type) string {
+	path := t.PkgPath()
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// exported capitalises the first letter of name
+func exported(name string) string {
+	if name == "" {
+		return name
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
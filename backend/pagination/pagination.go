@@ -0,0 +1,252 @@
+// Package pagination pages through list endpoints with opaque cursors. A
+// handler turns the client's query into a Keyset with Order.Keyset, has the
+// store fetch the rows it selects, and builds the page from them with
+// Order.Page; small lists already in memory can go through Paginate instead.
+// The page's next and prev cursors go back to the client as Link headers.
+// Cursors point between two items rather than at an offset, so items added or
+// removed while a client pages don't shift the pages after it.
+package pagination
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"elo-insight/backend/apperrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Page sizes
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// ErrInvalidCursor is the cause of the error Paginate returns for a cursor it
+// didn't issue, or issued for another sort or filter
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Query is the pagination part of a list endpoint's query parameters. Embed it
+// in the endpoint's query struct next to its sort and filters.
+type Query struct {
+	Limit  int    `form:"limit" binding:"omitempty,min=1"` // At most MaxLimit; larger values are capped
+	Cursor string `form:"cursor" binding:"omitempty,max=512"`
+}
+
+// Order is how a list is sorted
+type Order[T any] struct {
+	// Sort is the sort parameter, a field name with a leading - for descending
+	Sort string
+	// Filter describes the filters applied, so a cursor only works with them
+	Filter string
+	// Key is an item's value of the sort field, in a form that compares as it
+	// should sort; see TimeKey and TextKey
+	Key func(T) string
+	// ID breaks ties between items with the same key
+	ID func(T) uint
+}
+
+// Page is one page of a list, with cursors for the pages around it
+type Page[T any] struct {
+	Items []T
+	Next  string // Empty on the last page
+	Prev  string // Empty on the first page
+}
+
+// cursor is a position between two items, encoded into the opaque string
+// clients pass back
+type cursor struct {
+	Sort   string `json:"s"`
+	Filter string `json:"f,omitempty"`
+	Key    string `json:"k"`
+	ID     uint   `json:"i"`
+	Before bool   `json:"b,omitempty"` // Page back from the position instead of forward
+}
+
+// Keyset is the part of a page request a store applies in its query: the
+// rows strictly after a position in the sort, at most Limit of them. Paging
+// back, they are the rows strictly before the position, fetched in reverse.
+type Keyset struct {
+	Field      string    // The sort field, without the leading -
+	Descending bool      // The list's order; see Reversed for the query's
+	After      *Position // Nil on the first page
+	Backward   bool      // Page back from After instead of forward
+	Limit      int       // One more than the page size, to tell whether there's another page
+}
+
+// Position is an item's place in the sort: its key, then its ID to break ties
+type Position struct {
+	Key string
+	ID  uint
+}
+
+// Reversed reports whether the query sorts descending, which it does for a
+// descending list paged forward or an ascending one paged back. Rows are
+// then the ones with a smaller key and ID than After.
+func (k Keyset) Reversed() bool {
+	return k.Descending != k.Backward
+}
+
+// Keyset decodes q's cursor for a list sorted by o. An invalid cursor is
+// reported as an apperrors validation error.
+func (o Order[T]) Keyset(q Query) (Keyset, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	keyset := Keyset{
+		Field:      strings.TrimPrefix(o.Sort, "-"),
+		Descending: strings.HasPrefix(o.Sort, "-"),
+		Limit:      min(limit, MaxLimit) + 1,
+	}
+	if q.Cursor != "" {
+		at, err := decode(q.Cursor)
+		if err != nil || at.Sort != o.Sort || at.Filter != o.Filter {
+			return Keyset{}, invalidCursor(err)
+		}
+		keyset.After = &Position{Key: at.Key, ID: at.ID}
+		keyset.Backward = at.Before
+	}
+	return keyset, nil
+}
+
+// Page builds the page from the rows a store fetched for keyset, in the
+// order it fetched them
+func (o Order[T]) Page(rows []T, keyset Keyset) Page[T] {
+	size := keyset.Limit - 1
+	more := len(rows) > size
+	items := slices.Clone(rows[:min(len(rows), size)])
+	if keyset.Backward {
+		slices.Reverse(items)
+	}
+
+	var page Page[T]
+	page.Items = items
+	if len(items) == 0 {
+		return page
+	}
+	// Forward there's a next page if the store found more rows, and a
+	// previous one if the page started from a cursor; paging back it's the
+	// other way round
+	hasNext, hasPrev := more, keyset.After != nil
+	if keyset.Backward {
+		hasNext, hasPrev = keyset.After != nil, more
+	}
+	if hasNext {
+		page.Next = o.cursor(items[len(items)-1], false)
+	}
+	if hasPrev {
+		page.Prev = o.cursor(items[0], true)
+	}
+	return page
+}
+
+// Select picks the rows keyset asks for out of items, as a query would, for
+// stores and lists kept in memory
+func (o Order[T]) Select(items []T, keyset Keyset) []T {
+	compare := func(a, b Position) int {
+		c := strings.Compare(a.Key, b.Key)
+		if c == 0 {
+			c = cmp.Compare(a.ID, b.ID)
+		}
+		if keyset.Reversed() {
+			return -c
+		}
+		return c
+	}
+	position := func(item T) Position { return Position{Key: o.Key(item), ID: o.ID(item)} }
+
+	rows := make([]T, 0, len(items))
+	for _, item := range items {
+		if keyset.After == nil || compare(position(item), *keyset.After) > 0 {
+			rows = append(rows, item)
+		}
+	}
+	slices.SortFunc(rows, func(a, b T) int { return compare(position(a), position(b)) })
+	return rows[:min(len(rows), keyset.Limit)]
+}
+
+// Paginate returns the page of items q asks for, sorted by order. An invalid
+// cursor is reported as an apperrors validation error.
+func Paginate[T any](items []T, q Query, order Order[T]) (Page[T], error) {
+	keyset, err := order.Keyset(q)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	return order.Page(order.Select(items, keyset), keyset), nil
+}
+
+// cursor is the position of item, for the page after it or, with before, the one before it
+func (o Order[T]) cursor(item T, before bool) string {
+	return encode(cursor{Sort: o.Sort, Filter: o.Filter, Key: o.Key(item), ID: o.ID(item), Before: before})
+}
+
+// SetLinks adds a Link header with the request's URL for the next and
+// previous pages, leaving the other query parameters as they were
+func (p Page[T]) SetLinks(c *gin.Context) {
+	var links []string
+	for _, link := range []struct{ rel, cursor string }{{"next", p.Next}, {"prev", p.Prev}} {
+		if link.cursor == "" {
+			continue
+		}
+		u := *c.Request.URL
+		query := u.Query()
+		query.Set("cursor", link.cursor)
+		u.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf("<%s>; rel=%q", u.RequestURI(), link.rel))
+	}
+	// Added rather than set, so the Link of middleware.Deprecated is kept
+	if len(links) > 0 {
+		c.Writer.Header().Add("Link", strings.Join(links, ", "))
+	}
+}
+
+// TimeKey is the sort key of a time, which compares in time order
+func TimeKey(t time.Time) string {
+	return fmt.Sprintf("%020d", t.UnixNano())
+}
+
+// ParseTimeKey returns the time a TimeKey was made from, for stores to
+// compare with a column. A key that isn't one is reported like an invalid
+// cursor, so handlers should pass store errors through apperrors.From.
+func ParseTimeKey(key string) (time.Time, error) {
+	nanos, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return time.Time{}, invalidCursor(err)
+	}
+	return time.Unix(0, nanos), nil
+}
+
+// TextKey is the sort key of text, which compares case-insensitively. Stores
+// compare it with LOWER() of the column.
+func TextKey(s string) string {
+	return strings.ToLower(s)
+}
+
+func encode(c cursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decode(s string) (cursor, error) {
+	var c cursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(raw, &c)
+	return c, err
+}
+
+func invalidCursor(err error) *apperrors.Error {
+	validationErr := apperrors.Validation("Invalid cursor", map[string]string{"cursor": "must come from a Link header of this list, with the same sort and filters"})
+	validationErr.Err = errors.Join(ErrInvalidCursor, err)
+	return validationErr
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Database is a Store counting in the rate_limit_counts table, so every
+// replica shares the counts
+type Database struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// rateLimitCount is a row of rate_limit_counts
+type rateLimitCount struct {
+	Key       string `gorm:"primaryKey"`
+	Count     int
+	ExpiresAt time.Time
+}
+
+func (rateLimitCount) TableName() string { return "rate_limit_counts" }
+
+// NewDatabase returns a store using db
+func NewDatabase(db *gorm.DB) *Database {
+	return &Database{db: db, now: time.Now}
+}
+
+// Hit counts in one statement, so concurrent requests on any replica can't
+// both read the same count
+func (d *Database) Hit(ctx context.Context, key string, expiresAt time.Time) (int, error) {
+	var count int
+	err := d.db.WithContext(ctx).Raw(`INSERT INTO rate_limit_counts (key, count, expires_at) VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET count = rate_limit_counts.count + 1
+		RETURNING count`, key, expiresAt).Scan(&count).Error
+	return count, err
+}
+
+// Purge deletes expired counts, returning how many were removed
+func (d *Database) Purge(ctx context.Context) (int64, error) {
+	result := d.db.WithContext(ctx).Where("expires_at <= ?", d.now()).Delete(&rateLimitCount{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDatabaseHit(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	store := newTestDatabase(t, clock)
+	expiresAt := clock.now().Add(time.Minute)
+
+	// Concurrent hits each get their own count
+	const hits = 20
+	counts := make([]int, hits)
+	var wg sync.WaitGroup
+	for i := range hits {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			count, err := store.Hit(ctx, "api:user:1:1", expiresAt)
+			if err != nil {
+				t.Errorf("hit: %v", err)
+			}
+			counts[i] = count
+		}()
+	}
+	wg.Wait()
+	slices.Sort(counts)
+	for i, count := range counts {
+		if count != i+1 {
+			t.Fatalf("counts = %v, want 1 to %d", counts, hits)
+		}
+	}
+
+	// Later hits keep the window's expiry and don't touch other keys
+	if count, err := store.Hit(ctx, "api:user:1:1", expiresAt.Add(time.Hour)); err != nil || count != hits+1 {
+		t.Errorf("next hit = %d, %v; want %d", count, err, hits+1)
+	}
+	if count, err := store.Hit(ctx, "api:user:2:1", expiresAt); err != nil || count != 1 {
+		t.Errorf("other key = %d, %v; want 1", count, err)
+	}
+	var row rateLimitCount
+	if err := store.db.Where("key = ?", "api:user:1:1").Take(&row).Error; err != nil {
+		t.Fatalf("read row: %v", err)
+	}
+	if !row.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("expires_at = %v, want %v from the first hit", row.ExpiresAt, expiresAt)
+	}
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is a Store counting in this process, so each replica limits on its own
+type Memory struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	counts map[string]*memoryCount
+}
+
+type memoryCount struct {
+	count     int
+	expiresAt time.Time
+}
+
+// NewMemory returns an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{now: time.Now, counts: make(map[string]*memoryCount)}
+}
+
+func (m *Memory) Hit(ctx context.Context, key string, expiresAt time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count, ok := m.counts[key]
+	if !ok || !m.now().Before(count.expiresAt) {
+		count = &memoryCount{expiresAt: expiresAt}
+		m.counts[key] = count
+	}
+	count.count++
+	return count.count, nil
+}
+
+// Purge deletes expired counts, returning how many were removed
+func (m *Memory) Purge(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	var removed int64
+	for key, count := range m.counts {
+		if !now.Before(count.expiresAt) {
+			delete(m.counts, key)
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,115 @@
+// Package ratelimit counts each client's requests to a group of routes in
+// fixed windows, so no one can use up our provider quotas through the public
+// endpoints. A window's count is kept in a Store: in memory by default, or in
+// the database so replicas share the counts.
+//
+// Fixed windows are cheap to keep in a single row per client, at the cost of
+// allowing up to twice the rate across a window boundary.
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"elo-insight/backend/config"
+
+	"gorm.io/gorm"
+)
+
+// Store counts requests. Errors are logged and the request is allowed, so a
+// broken store doesn't take the API down with it.
+type Store interface {
+	// Hit adds a request to key's count and returns the new count. The count
+	// may be dropped after expiresAt.
+	Hit(ctx context.Context, key string, expiresAt time.Time) (int, error)
+}
+
+// Result is the outcome of counting a request
+type Result struct {
+	Allowed   bool
+	Limit     int           // Requests allowed per window
+	Remaining int           // Requests left in the window
+	Reset     time.Duration // Until the window ends
+}
+
+// Limiter applies rates to clients using a Store
+type Limiter struct {
+	store Store // nil allows every request
+	name  string
+	now   func() time.Time
+}
+
+// New returns a limiter counting in store, or one that allows everything if store is nil
+func New(store Store, name string) *Limiter {
+	return &Limiter{store: store, name: name, now: time.Now}
+}
+
+// Default is the limiter the middleware uses
+var Default = New(NewMemory(), "memory")
+
+// Init replaces Default with the configured store
+func Init(cfg config.RateLimit, db *gorm.DB) {
+	switch cfg.Backend {
+	case "none":
+		Default = New(nil, "none")
+	case "database":
+		Default = New(NewDatabase(db), "database")
+	default:
+		Default = New(NewMemory(), "memory")
+	}
+}
+
+// Enabled reports whether requests are counted at all
+func (l *Limiter) Enabled() bool {
+	return l.store != nil
+}
+
+// Allow counts a request from client to the routes of policy against rate
+func (l *Limiter) Allow(ctx context.Context, policy, client string, rate config.Rate) Result {
+	now := l.now()
+	start := now.Truncate(rate.Window)
+	end := start.Add(rate.Window)
+	result := Result{Allowed: true, Limit: rate.Requests, Remaining: rate.Requests, Reset: end.Sub(now)}
+	if l.store == nil {
+		return result
+	}
+
+	key := policy + ":" + client + ":" + strconv.FormatInt(start.Unix(), 10)
+	count, err := l.store.Hit(ctx, key, end)
+	if err != nil {
+		slog.WarnContext(ctx, "Rate limit store failed, allowing the request", "backend", l.name, "policy", policy, "error", err)
+		return result
+	}
+	result.Remaining = max(rate.Requests-count, 0)
+	result.Allowed = count <= rate.Requests
+	return result
+}
+
+// purger is a Store that must delete expired counts itself
+type purger interface {
+	Purge(ctx context.Context) (int64, error)
+}
+
+// PurgeExpired deletes expired counts every interval until ctx is done
+func (l *Limiter) PurgeExpired(ctx context.Context, interval time.Duration) {
+	store, ok := l.store.(purger)
+	if !ok {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if removed, err := store.Purge(ctx); err != nil {
+				slog.WarnContext(ctx, "Failed to purge expired rate limit counts", "error", err)
+			} else if removed > 0 {
+				slog.DebugContext(ctx, "Purged expired rate limit counts", "count", removed)
+			}
+		}
+	}
+}
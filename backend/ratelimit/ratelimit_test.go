@@ -0,0 +1,154 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"elo-insight/backend/config"
+	"elo-insight/backend/database"
+)
+
+// fakeClock is a clock that only moves when told to
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// newTestDatabase returns a Database store on a migrated, throwaway in-memory database
+func newTestDatabase(t *testing.T, clock *fakeClock) *Database {
+	t.Helper()
+	db, err := database.Open(config.Database{URL: "sqlite://:memory:"})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	store := NewDatabase(db)
+	store.now = clock.now
+	return store
+}
+
+// purgingStore is a Store that deletes its expired counts itself, as both do
+type purgingStore interface {
+	Store
+	purger
+}
+
+// storeNames lists the stores every test runs against
+var storeNames = []string{"memory", "database"}
+
+// newStore returns the named Store on the clock
+func newStore(t *testing.T, name string, clock *fakeClock) purgingStore {
+	if name == "database" {
+		return newTestDatabase(t, clock)
+	}
+	memory := NewMemory()
+	memory.now = clock.now
+	return memory
+}
+
+func TestAllow(t *testing.T) {
+	rate := config.Rate{Requests: 3, Window: time.Minute}
+	steps := []struct {
+		advance        time.Duration
+		policy, client string
+		want           Result
+	}{
+		{0, "api", "user:1", Result{Allowed: true, Limit: 3, Remaining: 2, Reset: 50 * time.Second}},
+		{0, "api", "user:1", Result{Allowed: true, Limit: 3, Remaining: 1, Reset: 50 * time.Second}},
+		{20 * time.Second, "api", "user:1", Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 30 * time.Second}},
+		{0, "api", "user:1", Result{Allowed: false, Limit: 3, Remaining: 0, Reset: 30 * time.Second}},
+		// Other clients and other policies have their own counts
+		{0, "api", "user:2", Result{Allowed: true, Limit: 3, Remaining: 2, Reset: 30 * time.Second}},
+		{0, "stats", "user:1", Result{Allowed: true, Limit: 3, Remaining: 2, Reset: 30 * time.Second}},
+		// The next window starts over
+		{30 * time.Second, "api", "user:1", Result{Allowed: true, Limit: 3, Remaining: 2, Reset: time.Minute}},
+	}
+
+	for _, name := range storeNames {
+		t.Run(name, func(t *testing.T) {
+			clock := &fakeClock{t: time.Date(2026, 5, 1, 12, 0, 10, 0, time.UTC)}
+			limiter := New(newStore(t, name, clock), name)
+			limiter.now = clock.now
+
+			for i, step := range steps {
+				clock.advance(step.advance)
+				if got := limiter.Allow(context.Background(), step.policy, step.client, rate); got != step.want {
+					t.Errorf("step %d: Allow(%s, %s) = %+v, want %+v", i, step.policy, step.client, got, step.want)
+				}
+			}
+		})
+	}
+}
+
+// failingStore is a Store that is always down
+type failingStore struct{}
+
+func (failingStore) Hit(ctx context.Context, key string, expiresAt time.Time) (int, error) {
+	return 0, errors.New("connection refused")
+}
+
+func TestAllowWithoutCounting(t *testing.T) {
+	rate := config.Rate{Requests: 1, Window: time.Minute}
+	tests := []struct {
+		name  string
+		store Store
+	}{
+		{"no store", nil},
+		{"store failing", failingStore{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := New(tt.store, tt.name)
+			for i := range 3 {
+				if got := limiter.Allow(context.Background(), "api", "ip:192.0.2.1", rate); !got.Allowed || got.Remaining != 1 {
+					t.Errorf("request %d: Allow = %+v, want allowed with 1 remaining", i+1, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPurge(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range storeNames {
+		t.Run(name, func(t *testing.T) {
+			clock := &fakeClock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+			store := newStore(t, name, clock)
+			start := clock.now()
+			store.Hit(ctx, name+":short", start.Add(time.Minute))
+			store.Hit(ctx, name+":long", start.Add(time.Hour))
+
+			clock.advance(time.Minute)
+			if removed, err := store.Purge(ctx); err != nil || removed != 1 {
+				t.Errorf("Purge = %d, %v; want 1 removed", removed, err)
+			}
+			for key, want := range map[string]int{name + ":short": 1, name + ":long": 2} {
+				if count, err := store.Hit(ctx, key, start.Add(time.Hour)); err != nil || count != want {
+					t.Errorf("Hit(%s) after purge = %d, %v; want %d", key, count, err, want)
+				}
+			}
+		})
+	}
+}
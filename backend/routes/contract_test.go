@@ -0,0 +1,319 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"elo-insight/backend/config"
+	"elo-insight/backend/middleware"
+	"elo-insight/backend/models"
+	"elo-insight/backend/ratelimit"
+	"elo-insight/backend/store"
+	"elo-insight/backend/telemetry"
+	"elo-insight/backend/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// update rewrites the golden files with the current responses:
+//
+//	go test ./routes -run TestV1Contract -update
+var update = flag.Bool("update", false, "rewrite testdata/*.golden with the current responses")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	gin.SetMode(gin.TestMode)
+	telemetry.Initialize("routes-test")
+	if err := validation.Register(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// volatileFields are response fields set from the clock when a record is
+// stored or read. Golden files hold a placeholder, so only their presence is pinned.
+var volatileFields = map[string]bool{
+	"created_at":  true,
+	"CreatedAt":   true,
+	"UpdatedAt":   true,
+	"linked_at":   true,
+	"next_cursor": true,
+}
+
+// played is when the seeded match was played and the seeded feed event happened
+var played = time.Date(2025, 3, 1, 18, 30, 0, 0, time.UTC)
+
+// contractAPI is the full router on an in-memory store seeded with a user,
+// "me", who has a friend with a friend of their own, requests both ways, a
+// stat card, a squad, an invite, a stored match with the friend and a
+// friend's feed event with a comment
+type contractAPI struct {
+	t      *testing.T
+	router *gin.Engine
+	ids    map[string]uint // Seeded records by name
+	tokens map[string]string
+}
+
+func newContractAPI(t *testing.T) *contractAPI {
+	t.Helper()
+	t.Setenv("APP_ENV", config.EnvTest)
+	t.Setenv("DATABASE_URL", "sqlite://:memory:")
+	t.Setenv("JWT_SECRET", "contract-test-secret-that-is-long-enough")
+	t.Setenv("RATE_LIMIT_BACKEND", "none") // The responses, not the RateLimit headers, are under test
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("validate config: %v", err)
+	}
+	middleware.Init(cfg)
+	ratelimit.Init(cfg.RateLimit, nil)
+
+	s := store.NewMemoryStore()
+	r := gin.New()
+	r.Use(middleware.Errors())
+	if _, err := setupRoutes(r, s, cfg, nil, http.NotFoundHandler()); err != nil {
+		t.Fatalf("set up routes: %v", err)
+	}
+
+	api := &contractAPI{t: t, router: r, ids: map[string]uint{}, tokens: map[string]string{}}
+	api.seed(s)
+	return api
+}
+
+func (a *contractAPI) seed(s *store.Store) {
+	ctx := context.Background()
+	check := func(what string, err error) {
+		if err != nil {
+			a.t.Fatalf("seed %s: %v", what, err)
+		}
+	}
+
+	users := map[string]models.User{}
+	for _, name := range []string{"me", "bob", "carol", "dave", "eve"} {
+		user := models.User{Username: name, Email: name + "@example.com", Password: "not-a-real-hash"}
+		check(name, s.Users.Create(ctx, &user))
+		token, err := middleware.GenerateJWT(user)
+		check(name+"'s token", err)
+		users[name], a.ids[name], a.tokens[name] = user, user.ID, token
+	}
+	for _, name := range []string{"me", "bob"} {
+		link := models.PlatformLink{UserID: a.ids[name], Platform: models.PlatformRiot, ExternalID: "puuid-" + name,
+			DisplayName: name + "#EUW", Region: "europe", Verification: models.LinkVerified}
+		check(name+"'s Riot link", s.PlatformLinks.Save(ctx, &link))
+	}
+
+	now := time.Now()
+	friendship, err := s.Friendships.Request(ctx, a.ids["me"], a.ids["bob"], now)
+	check("friendship", err)
+	_, err = s.Friendships.Transition(ctx, friendship.ID, a.ids["bob"], models.FriendshipActionAccept, now)
+	check("friendship", err)
+	a.ids["friendship"] = friendship.ID
+	_, err = s.Friendships.Request(ctx, a.ids["carol"], a.ids["me"], now)
+	check("incoming request", err)
+	_, err = s.Friendships.Request(ctx, a.ids["me"], a.ids["dave"], now)
+	check("outgoing request", err)
+	friendOfFriend, err := s.Friendships.Request(ctx, a.ids["bob"], a.ids["eve"], now)
+	check("friend of a friend", err)
+	_, err = s.Friendships.Transition(ctx, friendOfFriend.ID, a.ids["eve"], models.FriendshipActionAccept, now)
+	check("friend of a friend", err)
+
+	check("stat card", s.StatCards.Create(ctx, &models.UserStat{UserID: a.ids["me"], Game: "League of Legends", Platform: "Riot"}))
+
+	squad := models.Squad{Name: "Duo", OwnerID: a.ids["me"], Members: []models.SquadMember{
+		{UserID: a.ids["me"], Role: models.SquadRoleOwner},
+		{UserID: a.ids["bob"], Role: models.SquadRoleMember},
+	}}
+	check("squad", s.Squads.Create(ctx, &squad))
+	a.ids["squad"] = squad.ID
+	daves := models.Squad{Name: "Dave's", OwnerID: a.ids["dave"], Members: []models.SquadMember{{UserID: a.ids["dave"], Role: models.SquadRoleOwner}}}
+	check("dave's squad", s.Squads.Create(ctx, &daves))
+	check("invite", s.Squads.CreateInvite(ctx, &models.SquadInvite{SquadID: daves.ID, InviterID: a.ids["dave"], InviteeID: a.ids["me"], Status: "pending"}))
+
+	match := models.StoredMatch{Game: "lol", MatchID: "EUW1_1", QueueID: "420", PlayedAt: played, Duration: 1800,
+		Participants: []models.MatchParticipant{
+			{PUUID: "puuid-me", TeamID: "100", Character: "Ahri", Role: "MIDDLE", Win: true, Kills: 10, Deaths: 2, Assists: 8},
+			{PUUID: "puuid-bob", TeamID: "100", Character: "Lee Sin", Role: "JUNGLE", Win: true, Kills: 6, Deaths: 3, Assists: 12},
+			{PUUID: "puuid-other", TeamID: "200", Character: "Zed", Role: "MIDDLE", Win: false, Kills: 4, Deaths: 9, Assists: 3},
+		}}
+	_, err = s.Matches.Save(ctx, &match)
+	check("match", err)
+
+	event := models.FeedEvent{UserID: a.ids["bob"], Type: models.FeedEventPentakill, Game: "lol", MatchID: "EUW1_1",
+		Summary: "bob got a pentakill as Lee Sin", OccurredAt: played, DedupeKey: "pentakill:EUW1_1:bob"}
+	check("feed event", s.Feed.CreateEvent(ctx, &event))
+	a.ids["event"] = event.ID
+	check("reaction", s.Feed.AddReaction(ctx, &models.FeedReaction{FeedEventID: event.ID, UserID: a.ids["me"], Kind: "fire"}))
+	check("comment", s.Feed.CreateComment(ctx, &models.FeedComment{FeedEventID: event.ID, UserID: a.ids["me"], Body: "gg"}))
+}
+
+// do sends a request, signed in as the user if as isn't empty
+func (a *contractAPI) do(as, method, path string, body any) *httptest.ResponseRecorder {
+	a.t.Helper()
+	var encoded []byte
+	if body != nil {
+		var err error
+		if encoded, err = json.Marshal(body); err != nil {
+			a.t.Fatalf("encode body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(encoded))
+	req.Header.Set("Content-Type", "application/json")
+	if as != "" {
+		req.AddCookie(&http.Cookie{Name: middleware.TokenCookie, Value: a.tokens[as]})
+	}
+	w := httptest.NewRecorder()
+	a.router.ServeHTTP(w, req)
+	return w
+}
+
+// normalize re-encodes a JSON body with sorted keys and placeholders for volatileFields
+func normalize(t *testing.T, body []byte) []byte {
+	t.Helper()
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		t.Fatalf("decode response: %v; body: %s", err, body)
+	}
+	var walk func(v any) any
+	walk = func(v any) any {
+		switch v := v.(type) {
+		case map[string]any:
+			for key, field := range v {
+				if s, ok := field.(string); ok && volatileFields[key] && s != "" {
+					v[key] = "<" + key + ">"
+				} else {
+					v[key] = walk(field)
+				}
+			}
+		case []any:
+			for i := range v {
+				v[i] = walk(v[i])
+			}
+		}
+		return v
+	}
+	var normalized bytes.Buffer
+	encoder := json.NewEncoder(&normalized)
+	encoder.SetEscapeHTML(false) // Keep the placeholders readable
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(walk(value)); err != nil {
+		t.Fatalf("encode response: %v", err)
+	}
+	return normalized.Bytes()
+}
+
+// contractCase is a request whose response is pinned by testdata/<golden>.golden
+type contractCase struct {
+	golden     string
+	as         string // The signed-in user, if any
+	method     string
+	path       string
+	body       any
+	wantStatus int
+}
+
+// v1Contract lists the pinned v1 responses
+func v1Contract(ids map[string]uint) []contractCase {
+	return []contractCase{
+		{"profile", "me", http.MethodGet, v1Prefix + "/user/profile", nil, http.StatusOK},
+		{"links", "me", http.MethodGet, v1Prefix + "/link", nil, http.StatusOK},
+		{"stat_cards", "me", http.MethodGet, v1Prefix + "/user/stats/", nil, http.StatusOK},
+		{"friends", "me", http.MethodGet, v1Prefix + "/friends/", nil, http.StatusOK},
+		{"friend_requests", "me", http.MethodGet, v1Prefix + "/friends/requests", nil, http.StatusOK},
+		{"friend_requests_outgoing", "me", http.MethodGet, v1Prefix + "/friends/requests?direction=outgoing", nil, http.StatusOK},
+		{"friend_search", "me", http.MethodGet, v1Prefix + "/friends/search?q=a&limit=1", nil, http.StatusOK},
+		{"friend_suggestions", "me", http.MethodGet, v1Prefix + "/friends/suggestions", nil, http.StatusOK},
+		{"feed", "me", http.MethodGet, v1Prefix + "/feed/", nil, http.StatusOK},
+		{"feed_comments", "me", http.MethodGet, fmt.Sprintf("%s/feed/%d/comments", v1Prefix, ids["event"]), nil, http.StatusOK},
+		{"squads", "me", http.MethodGet, v1Prefix + "/squads/", nil, http.StatusOK},
+		{"squad", "me", http.MethodGet, fmt.Sprintf("%s/squads/%d", v1Prefix, ids["squad"]), nil, http.StatusOK},
+		{"squad_invites", "me", http.MethodGet, v1Prefix + "/squads/invites", nil, http.StatusOK},
+		{"squad_stats", "me", http.MethodGet, fmt.Sprintf("%s/squads/%d/stats?game=lol", v1Prefix, ids["squad"]), nil, http.StatusOK},
+		{"synergy", "me", http.MethodGet, fmt.Sprintf("%s/synergy?with=%d&game=lol", v1Prefix, ids["bob"]), nil, http.StatusOK},
+		{"match_history", "me", http.MethodGet, matchHistoryPath, nil, http.StatusOK},
+
+		// The error envelope
+		{"error_unauthorized", "", http.MethodGet, v1Prefix + "/friends/", nil, http.StatusUnauthorized},
+		{"error_not_found", "me", http.MethodGet, v1Prefix + "/squads/999", nil, http.StatusNotFound},
+		{"error_validation", "", http.MethodPost, v1Prefix + "/auth/register", map[string]string{"username": "x", "email": "nope"}, http.StatusBadRequest},
+		{"error_conflict", "bob", http.MethodPost, v1Prefix + "/friends/request", map[string]uint{"friend_id": ids["me"]}, http.StatusConflict},
+	}
+}
+
+func TestV1Contract(t *testing.T) {
+	api := newContractAPI(t)
+	for _, tt := range v1Contract(api.ids) {
+		t.Run(tt.golden, func(t *testing.T) {
+			w := api.do(tt.as, tt.method, tt.path, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("%s %s: status = %d, want %d; body: %s", tt.method, tt.path, w.Code, tt.wantStatus, w.Body)
+			}
+			got := normalize(t, w.Body.Bytes())
+
+			golden := filepath.Join("testdata", tt.golden+".golden")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatalf("write %s: %v", golden, err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read %s: %v; run with -update to create it", golden, err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s %s no longer matches %s; if the change is intended, run with -update.\ngot:\n%s\nwant:\n%s",
+					tt.method, tt.path, golden, got, want)
+			}
+		})
+	}
+}
+
+// The deprecated unversioned aliases answer exactly like v1, with the deprecation headers
+func TestLegacyAliasesMatchV1(t *testing.T) {
+	api := newContractAPI(t)
+	for _, tt := range v1Contract(api.ids) {
+		alias, ok := legacyAlias(tt.path)
+		if !ok || tt.method != http.MethodGet { // Only reads, so both see the same seeded state
+			continue
+		}
+		t.Run(tt.golden, func(t *testing.T) {
+			v1 := api.do(tt.as, tt.method, tt.path, tt.body)
+			old := api.do(tt.as, tt.method, alias, tt.body)
+
+			if old.Code != v1.Code || !bytes.Equal(normalize(t, old.Body.Bytes()), normalize(t, v1.Body.Bytes())) {
+				t.Errorf("%s = %d %s, want %s's %d %s", alias, old.Code, old.Body, tt.path, v1.Code, v1.Body)
+			}
+			if old.Header().Get("Deprecation") == "" || old.Header().Get("Sunset") == "" {
+				t.Errorf("%s headers = %v, want Deprecation and Sunset", alias, old.Header())
+			}
+			if v1.Header().Get("Deprecation") != "" {
+				t.Errorf("%s is marked deprecated", tt.path)
+			}
+		})
+	}
+}
+
+// legacyAlias is the unversioned path serving a v1 path, the reverse of
+// successorPath. Paths only v1 has have none.
+func legacyAlias(path string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, v1Prefix)
+	if !ok || path == matchHistoryPath {
+		return "", false
+	}
+	if strings.HasPrefix(rest, "/stats/") || strings.HasPrefix(rest, "/synergy") {
+		return "/api" + rest, true
+	}
+	return rest, true
+}
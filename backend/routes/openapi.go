@@ -0,0 +1,142 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"elo-insight/backend/apperrors"
+	"elo-insight/backend/middleware"
+	"elo-insight/backend/openapi"
+	"elo-insight/backend/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Where the OpenAPI document and its docs UI are served
+const (
+	openAPIPath = "/openapi.json"
+	docsPath    = "/docs"
+)
+
+// Error statuses shared by many routes
+var (
+	internal     = []int{http.StatusInternalServerError}
+	invalid      = []int{http.StatusBadRequest, http.StatusInternalServerError}
+	missing      = []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError}
+	providerErrs = []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict,
+		http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway}
+	friendErrs = []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict,
+		http.StatusInternalServerError}
+)
+
+// limitParam is a page size of up to max
+func limitParam(max int) openapi.Parameter {
+	minimum, maximum := 1.0, float64(max)
+	return openapi.Parameter{Name: "limit", Schema: &openapi.Schema{Type: "integer", Minimum: &minimum, Maximum: &maximum}}
+}
+
+// newDocument starts the OpenAPI document; routes add themselves as they are registered
+func newDocument() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "elo-insight API",
+		Version:     "1.0.0",
+		Description: "Game stats, friends, squads and the activity feed. Errors use the ErrorResponse envelope.",
+	}, middleware.ErrorResponse{}, middleware.TokenCookie)
+
+	codes := doc.Schema("ErrorResponse").Properties["code"]
+	for _, code := range apperrors.Codes {
+		codes.Enum = append(codes.Enum, string(code))
+	}
+	for tag, description := range validation.Formats() {
+		doc.AddFormat(tag, description)
+	}
+
+	doc.AddTag("health", "Liveness, readiness and provider status")
+	doc.AddTag("auth", "Accounts and sessions")
+	doc.AddTag("platforms", "Linked gaming accounts")
+	doc.AddTag("stats", "Game stats from Steam, Riot and tracker.gg")
+	doc.AddTag("friends", "Friends and friend requests")
+	doc.AddTag("feed", "Friends' notable matches")
+	doc.AddTag("squads", "Squads and squad stats")
+	doc.AddTag("graphql", "Dashboard data in one round-trip")
+	doc.AddTag("meta", "This document and operational endpoints")
+	return doc
+}
+
+// group is a Gin route group that documents each route it registers, so a
+// route's handlers and its OpenAPI metadata are given in one call
+type group struct {
+	gin   *gin.RouterGroup
+	doc   *openapi.Document
+	limit bool   // Documents every route as rate limited
+	alias string // Sunset date of a group of deprecated unversioned aliases
+}
+
+func newGroup(r *gin.Engine, doc *openapi.Document) group {
+	return group{gin: &r.RouterGroup, doc: doc}
+}
+
+// Group is a subgroup at path using middleware
+func (g group) Group(path string, middleware ...gin.HandlerFunc) group {
+	g.gin = g.gin.Group(path, middleware...)
+	return g
+}
+
+// limited documents the group's routes as rate limited
+func (g group) limited() group {
+	g.limit = true
+	return g
+}
+
+// deprecated documents the group's routes as aliases of their v1 paths,
+// to be removed on sunset
+func (g group) deprecated(sunset time.Time) group {
+	g.alias = sunset.Format(time.DateOnly)
+	return g
+}
+
+// handle registers a route and documents it at its full path
+func (g group) handle(method, path string, route openapi.Route, handlers ...gin.HandlerFunc) {
+	g.gin.Handle(method, path, handlers...)
+
+	full := joinPaths(g.gin.BasePath(), path)
+	route.RateLimited = route.RateLimited || g.limit
+	if g.alias != "" {
+		route.Deprecated = true
+		route.Description = strings.TrimSpace(fmt.Sprintf("Deprecated alias of %s %s, to be removed on %s. %s",
+			method, successorPath(full), g.alias, route.Description))
+	}
+	g.doc.Add(method, full, route)
+}
+
+func (g group) GET(path string, route openapi.Route, handlers ...gin.HandlerFunc) {
+	g.handle(http.MethodGet, path, route, handlers...)
+}
+
+func (g group) POST(path string, route openapi.Route, handlers ...gin.HandlerFunc) {
+	g.handle(http.MethodPost, path, route, handlers...)
+}
+
+func (g group) PUT(path string, route openapi.Route, handlers ...gin.HandlerFunc) {
+	g.handle(http.MethodPut, path, route, handlers...)
+}
+
+func (g group) DELETE(path string, route openapi.Route, handlers ...gin.HandlerFunc) {
+	g.handle(http.MethodDelete, path, route, handlers...)
+}
+
+// joinPaths joins a group's base path and a route's path like Gin does,
+// keeping a trailing slash
+func joinPaths(base, relative string) string {
+	if relative == "" {
+		return base
+	}
+	joined := path.Join(base, relative)
+	if strings.HasSuffix(relative, "/") && !strings.HasSuffix(joined, "/") {
+		return joined + "/"
+	}
+	return joined
+}
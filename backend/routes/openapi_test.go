@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+
+	"elo-insight/backend/config"
+	"elo-insight/backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestEveryRouteIsDocumented fails when a route is registered without going
+// through group, or the document lists a route Gin doesn't serve
+func TestEveryRouteIsDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	r := gin.New()
+	doc, err := setupRoutes(r, store.NewMemoryStore(), cfg, nil, http.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("set up routes: %v", err)
+	}
+	for _, problem := range doc.Missing(r.Routes()) {
+		t.Errorf("OpenAPI document is out of date with the routes: %s", problem)
+	}
+}
+
+func TestJoinPaths(t *testing.T) {
+	tests := []struct {
+		base, relative, want string
+	}{
+		{"/", "/healthz", "/healthz"},
+		{"/api/v1", "", "/api/v1"},
+		{"/api/v1/", "/user/profile", "/api/v1/user/profile"},
+		{"/api/v1/friends", "/", "/api/v1/friends/"},
+		{"/", "/api/stats", "/api/stats"},
+	}
+	for _, tt := range tests {
+		if got := joinPaths(tt.base, tt.relative); got != tt.want {
+			t.Errorf("joinPaths(%q, %q) = %q, want %q", tt.base, tt.relative, got, tt.want)
+		}
+	}
+}
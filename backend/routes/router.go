@@ -1,63 +1,334 @@
 package routes
 
 import (
+	"net/http"
+	"strings"
+
+	"elo-insight/backend/config"
+	"elo-insight/backend/graph"
 	"elo-insight/backend/handlers"
+	"elo-insight/backend/health"
 	"elo-insight/backend/middleware"
+	"elo-insight/backend/models"
+	"elo-insight/backend/openapi"
+	"elo-insight/backend/store"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Set up routes for the application
-func SetupRoutes(r *gin.Engine) {
+// v1Prefix is where version 1 of the API is served, with the paths only it has
+const (
+	v1Prefix         = "/api/v1"
+	graphQLPath      = v1Prefix + "/graphql"
+	matchHistoryPath = v1Prefix + "/matches/history"
+)
+
+// Set up routes for the application, with /metrics served by metrics
+func SetupRoutes(r *gin.Engine, s *store.Store, cfg *config.Config, checker *health.Checker, metrics http.Handler) error {
+	_, err := setupRoutes(r, s, cfg, checker, metrics)
+	return err
+}
+
+// setupRoutes registers the routes and returns the OpenAPI document describing them
+func setupRoutes(r *gin.Engine, s *store.Store, cfg *config.Config, checker *health.Checker, metrics http.Handler) (*openapi.Document, error) {
+	h := handlers.New(s, cfg, checker)
+	limits, err := newRouteLimits(cfg.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+	doc := newDocument()
+	root := newGroup(r, doc)
+
+	// Health routes (Public) for orchestrators and load balancers
+	root.GET("/healthz", openapi.Route{Tag: "health", Summary: "Process is alive",
+		Response: handlers.StatusResponse{}}, h.Healthz)
+	root.GET("/readyz", openapi.Route{Tag: "health", Summary: "Dependencies are reachable",
+		Response: health.Report{}, Other: map[int]any{http.StatusServiceUnavailable: health.Report{}}}, h.Readyz)
+
+	// Version 1 of the API
+	apiRoutes(root.Group(v1Prefix), h, limits, "")
+
+	// Routes new in v1, so they have no unversioned alias (Requires JWT)
+	gql, err := graph.New(h, s, cfg.GraphQL)
+	if err != nil {
+		return nil, err
+	}
+	root.POST(graphQLPath, openapi.Route{Tag: "graphql", Summary: "Query dashboard data with GraphQL", Auth: true,
+		Description: "The signed-in user, linked accounts, stat cards, game stats, friends and comparisons in one request; " +
+			"see the schema by introspection. Field errors are listed in errors with a code from ErrorResponse. " +
+			"Queries that don't parse, are invalid or exceed the depth or complexity limit get 400 with a GraphQL response; " +
+			"a body without a query gets 400 with ErrorResponse.",
+		Body:     graph.QueryRequest{},
+		Response: graph.QueryResponse{},
+		Other:    map[int]any{http.StatusBadRequest: graph.QueryResponse{}},
+		Errors:   internal, RateLimited: true},
+		middleware.RequireAuth(), limits.graphQL, gql.Handler())
+	root.GET(matchHistoryPath, openapi.Route{Tag: "stats", Summary: "Stored matches of the linked Riot account", Auth: true,
+		Description: "Matches are stored when the user's League or Valorant stats are fetched",
+		Query:       handlers.MatchHistoryQuery{}, Paginated: true,
+		Response: []handlers.MatchHistoryEntry{},
+		Errors:   []int{http.StatusBadRequest, http.StatusConflict, http.StatusInternalServerError}, RateLimited: true},
+		middleware.RequireAuth(), limits.api, h.GetMatchHistory)
+
+	// The same routes at their unversioned paths, kept as deprecated aliases until the sunset
+	legacy := root.Group("/", middleware.Deprecated(cfg.API.LegacyDeprecatedAt, cfg.API.LegacySunset, successorPath))
+	apiRoutes(legacy.deprecated(cfg.API.LegacySunset), h, limits, "/api")
+
+	// Prometheus scrape endpoint (Internal callers only)
+	root.GET("/metrics", openapi.Route{Tag: "meta", Summary: "Prometheus metrics",
+		Description: "Only for internal callers: IPs in RATE_LIMIT_INTERNAL_NETWORKS, or an X-API-Token named in RATE_LIMIT_INTERNAL_TOKENS",
+		Response:    openapi.String("Prometheus text exposition format"), ContentType: "text/plain",
+		Errors: []int{http.StatusForbidden}},
+		limits.internal, gin.WrapH(metrics))
+
+	// API documentation (Public)
+	root.GET(openAPIPath, openapi.Route{Tag: "meta", Summary: "This document",
+		Response: openapi.Any("OpenAPI 3 document")}, doc.Handler())
+	root.GET(docsPath, openapi.Route{Tag: "meta", Summary: "Docs UI for this document",
+		Response: openapi.String("HTML page"), ContentType: "text/html"}, openapi.DocsHandler(doc.Info.Title, openAPIPath))
+	return doc, nil
+}
+
+// apiRoutes registers the API on g and documents it. Before versioning, game
+// stats and synergy were under /api and everything else at the root, so the
+// legacy aliases pass "/api" as statsPrefix.
+func apiRoutes(g group, h *handlers.Handler, limits *routeLimits, statsPrefix string) {
+	g = g.limited() // Every group below has a rate limit
+
 	// Auth routes (Public)
-	auth := r.Group("/auth")
+	auth := g.Group("/auth", limits.auth) // Per IP, against password guessing
 	{
-		auth.POST("/register", handlers.Register)
-		auth.POST("/login", handlers.Login)
-		auth.GET("/steam/callback", handlers.SteamCallback)
-		auth.GET("/riot/callback", handlers.RiotCallback)
-		auth.POST("/logout", handlers.Logout)
+		auth.POST("/register", openapi.Route{Tag: "auth", Summary: "Create an account",
+			Body: handlers.RegisterRequest{}, Status: http.StatusCreated, Response: handlers.MessageResponse{},
+			Errors: []int{http.StatusBadRequest, http.StatusConflict, http.StatusInternalServerError}},
+			h.Register)
+		auth.POST("/login", openapi.Route{Tag: "auth", Summary: "Sign in",
+			Description: "Sets the session cookie on success",
+			Body:        handlers.LoginRequest{}, Response: handlers.MessageResponse{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError}},
+			h.Login)
+		auth.GET("/steam/callback", openapi.Route{Tag: "auth", Summary: "Steam OpenID callback",
+			Description: "Links the Steam account and redirects back to the frontend",
+			Params:      []openapi.Parameter{{Name: "openid.claimed_id", Schema: openapi.String("Set by Steam")}},
+			Status:      http.StatusFound,
+			Errors:      []int{http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError}},
+			h.SteamCallback)
+		auth.GET("/riot/callback", openapi.Route{Tag: "auth", Summary: "Riot sign-on callback",
+			Description: "Links the Riot account and redirects back to the frontend",
+			Params: []openapi.Parameter{
+				{Name: "code", Required: true, Schema: openapi.String("Set by Riot")},
+				{Name: "state", Required: true, Schema: openapi.String("Set by Riot")},
+			},
+			Status: http.StatusFound,
+			Errors: []int{http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError}},
+			h.RiotCallback)
+		auth.POST("/logout", openapi.Route{Tag: "auth", Summary: "Sign out", Response: handlers.MessageResponse{}},
+			h.Logout)
 	}
 	// Protected routes (Requires JWT)
-	protected := r.Group("/")
-	protected.Use(middleware.RequireAuth())
+	protected := g.Group("/", middleware.RequireAuth(), limits.api)
 	{
-		protected.GET("/user/profile", handlers.GetProfile)
-		protected.GET("/steam/login", handlers.SteamLogin)
-		protected.GET("/riot/login", handlers.RiotLogin)
-		protected.POST("/link/ea", handlers.LinkEAAccount) // EA account linking endpoint
-		protected.POST("/link/xbox", handlers.LinkXboxAccount) // Xbox account linking endpoint
-		protected.POST("/link/playstation", handlers.LinkPlayStationAccount) // PlayStation account linking endpoint
-		protected.POST("/link/riot", handlers.LinkRiotAccount) // Riot account linking endpoint
-	}
-	// User stats routes
-	stats := r.Group("/user/stats")
-	stats.Use(middleware.RequireAuth()) // Requires authentication
+		protected.GET("/user/profile", openapi.Route{Tag: "platforms", Summary: "The signed-in user and their linked accounts", Auth: true,
+			Response: handlers.ProfileResponse{},
+			Errors:   []int{http.StatusNotFound, http.StatusInternalServerError}},
+			h.GetProfile)
+		protected.GET("/steam/login", openapi.Route{Tag: "auth", Summary: "Start linking a Steam account", Auth: true,
+			Status: http.StatusFound, Errors: []int{http.StatusInternalServerError, http.StatusBadGateway}},
+			h.SteamLogin)
+		protected.GET("/riot/login", openapi.Route{Tag: "auth", Summary: "Start linking a Riot account", Auth: true,
+			Params: []openapi.Parameter{{Name: "token", Required: true, Schema: openapi.String("The session token, passed through Riot as state")}},
+			Status: http.StatusFound, Errors: []int{http.StatusBadGateway}},
+			h.RiotLogin)
+		protected.GET("/link", openapi.Route{Tag: "platforms", Summary: "List linked gaming accounts", Auth: true,
+			Response: []models.PlatformLinkResponse{}, Errors: internal},
+			h.GetPlatformLinks)
+		protected.POST("/link/:platform", openapi.Route{Tag: "platforms", Summary: "Link a riot, ea, xbox or playstation account", Auth: true,
+			Body:     handlers.LinkPlatformRequest{},
+			Response: handlers.LinkPlatformResponse{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusBadGateway}},
+			h.LinkPlatform)
+		protected.DELETE("/link/:platform", openapi.Route{Tag: "platforms", Summary: "Unlink an account", Auth: true,
+			Response: handlers.MessageResponse{}, Errors: missing},
+			h.UnlinkPlatform)
+		protected.GET("/status/providers", openapi.Route{Tag: "health", Summary: "External API health", Auth: true,
+			Response: handlers.ProviderStatusListResponse{}},
+			h.GetProviderStatus)
+	}
+	// User stats routes (Requires authentication)
+	stats := g.Group("/user/stats", middleware.RequireAuth(), limits.api)
 	{
-		stats.POST("/save", handlers.SaveStatSelection) // Save selected game & platform
-		stats.GET("/", handlers.GetUserStats)           // Fetch saved stat cards
-		stats.DELETE("/:id", handlers.DeleteStatCard)   // Delete a stat card
+		stats.POST("/save", openapi.Route{Tag: "stats", Summary: "Save a stat card", Auth: true,
+			Body: handlers.SaveStatRequest{}, Response: handlers.MessageResponse{}, Errors: invalid},
+			h.SaveStatSelection)
+		stats.GET("/", openapi.Route{Tag: "stats", Summary: "List saved stat cards", Auth: true,
+			Query: handlers.StatCardsQuery{}, Paginated: true,
+			Response: []models.UserStat{}, Errors: invalid},
+			h.GetUserStats)
+		stats.DELETE("/:id", openapi.Route{Tag: "stats", Summary: "Delete a stat card", Auth: true,
+			Response: handlers.MessageResponse{}, Errors: missing},
+			h.DeleteStatCard)
 	}
-	// Friends routes
-	friends := r.Group("/friends")
-	friends.Use(middleware.RequireAuth()) // Requires authentication
+	// Friends routes (Requires authentication)
+	friends := g.Group("/friends", middleware.RequireAuth(), limits.api)
 	{
-		friends.GET("/", handlers.GetFriends)                      // Get friends list
-		friends.GET("/requests", handlers.GetFriendRequests)        // Get friend requests
-		friends.POST("/request", handlers.SendFriendRequest)        // Send friend request
-		friends.PUT("/request/:id", handlers.RespondToFriendRequest) // Accept/reject request
-		friends.DELETE("/:id", handlers.RemoveFriend)              // Remove friend
-		friends.GET("/search", handlers.SearchUsers)               // Search users to add
-	}
-	// Public test route for debugging
-	r.GET("/friends-test", func(c *gin.Context) {
-		c.JSON(200, gin.H{"message": "Friends test endpoint working"})
-	})
-
-	// Public stats routes
-	r.GET("/api/stats/cs2", handlers.GetCS2Stats)
-	r.GET("/api/stats/apex", handlers.GetApexStats) // Apex Legends stats from tracker.gg API
-	r.GET("/api/stats/lol", handlers.GetLeagueOfLegendsStats) // League of Legends stats from Riot API
-	r.GET("/api/stats/valorant", handlers.GetValorantStats) // Valorant stats from Riot API
-	r.GET("/api/stats/dota2", handlers.GetDota2Stats) // Dota 2 stats with fallback to mock data
+		friends.GET("/", openapi.Route{Tag: "friends", Summary: "List friends", Auth: true,
+			Query: handlers.FriendsQuery{}, Paginated: true,
+			Response: []models.FriendshipResponse{}, Errors: invalid},
+			h.GetFriends)
+		friends.GET("/requests", openapi.Route{Tag: "friends", Summary: "List friend requests", Auth: true,
+			Description: "Pending requests sent to the user, unless status or direction say otherwise; username is the other user's",
+			Query:       handlers.FriendRequestsQuery{}, Paginated: true,
+			Response: []models.FriendshipResponse{}, Errors: invalid},
+			h.GetFriendRequests)
+		friends.POST("/request", openapi.Route{Tag: "friends", Summary: "Send a friend request", Auth: true,
+			Description: "Accepts the other user's pending request instead, if there is one",
+			Body:        handlers.NewFriendRequest{}, Response: handlers.FriendRequestResponse{}, Errors: friendErrs},
+			h.SendFriendRequest)
+		friends.PUT("/request/:id", openapi.Route{Tag: "friends", Summary: "Accept or decline a friend request", Auth: true,
+			Body: handlers.FriendActionRequest{}, Response: handlers.FriendRequestResponse{}, Errors: friendErrs},
+			h.RespondToFriendRequest)
+		friends.DELETE("/request/:id", openapi.Route{Tag: "friends", Summary: "Cancel a sent friend request", Auth: true,
+			Response: handlers.FriendRequestResponse{}, Errors: friendErrs},
+			h.CancelFriendRequest)
+		friends.DELETE("/:id", openapi.Route{Tag: "friends", Summary: "Remove a friend", Auth: true,
+			Response: handlers.MessageResponse{}, Errors: missing},
+			h.RemoveFriend)
+		friends.GET("/search", openapi.Route{Tag: "friends", Summary: "Search users to add", Auth: true,
+			Query: handlers.UserSearchQuery{}, Paginated: true,
+			Response: []handlers.UserResponse{}, Errors: invalid},
+			h.SearchUsers)
+		friends.GET("/suggestions", openapi.Route{Tag: "friends", Summary: "Suggested friends", Auth: true,
+			Query:    handlers.FriendSuggestionsQuery{},
+			Response: []models.FriendSuggestionResponse{}, Errors: invalid},
+			h.GetFriendSuggestions)
+	}
+	// Activity feed routes (Requires authentication)
+	feed := g.Group("/feed", middleware.RequireAuth(), limits.api)
+	{
+		feed.GET("/", openapi.Route{Tag: "feed", Summary: "Friends' notable matches, newest first", Auth: true,
+			Params: []openapi.Parameter{
+				limitParam(50),
+				{Name: "cursor", Schema: openapi.String("next_cursor from the previous page, or the cursor in a Link header")},
+			},
+			Paginated: true,
+			Response:  handlers.FeedPageResponse{},
+			Errors:    invalid},
+			h.GetFeed)
+		feed.POST("/:id/reactions", openapi.Route{Tag: "feed", Summary: "React to an event", Auth: true,
+			Body: handlers.FeedReactionRequest{}, Response: handlers.MessageResponse{}, Errors: missing},
+			h.ReactToFeedEvent)
+		feed.DELETE("/:id/reactions/:reaction", openapi.Route{Tag: "feed", Summary: "Remove a reaction", Auth: true,
+			Response: handlers.MessageResponse{}, Errors: missing},
+			h.RemoveFeedReaction)
+		feed.GET("/:id/comments", openapi.Route{Tag: "feed", Summary: "List comments", Auth: true,
+			Response: []models.FeedCommentResponse{}, Errors: missing},
+			h.GetFeedComments)
+		feed.POST("/:id/comments", openapi.Route{Tag: "feed", Summary: "Add a comment", Auth: true,
+			Body: handlers.FeedCommentRequest{}, Status: http.StatusCreated, Response: handlers.CreatedResponse{}, Errors: missing},
+			h.CommentOnFeedEvent)
+	}
+	// Squad routes (Requires authentication)
+	squads := g.Group("/squads", middleware.RequireAuth(), limits.api)
+	{
+		squads.POST("/", openapi.Route{Tag: "squads", Summary: "Create a squad", Auth: true,
+			Body: handlers.CreateSquadRequest{}, Status: http.StatusCreated, Response: handlers.CreatedResponse{}, Errors: invalid},
+			h.CreateSquad)
+		squads.GET("/", openapi.Route{Tag: "squads", Summary: "List my squads", Auth: true,
+			Response: []models.SquadResponse{}, Errors: internal},
+			h.GetSquads)
+		squads.GET("/invites", openapi.Route{Tag: "squads", Summary: "Pending squad invites", Auth: true,
+			Response: []models.SquadInviteResponse{}, Errors: internal},
+			h.GetSquadInvites)
+		squads.PUT("/invites/:id", openapi.Route{Tag: "squads", Summary: "Accept or decline a squad invite", Auth: true,
+			Body: handlers.InviteActionRequest{}, Response: handlers.MessageResponse{},
+			Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError}},
+			h.RespondToSquadInvite)
+		squads.GET("/:id", openapi.Route{Tag: "squads", Summary: "Squad details", Auth: true,
+			Response: models.SquadResponse{}, Errors: missing},
+			h.GetSquad)
+		squads.DELETE("/:id", openapi.Route{Tag: "squads", Summary: "Disband a squad (owner)", Auth: true,
+			Response: handlers.MessageResponse{}, Errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}},
+			h.DeleteSquad)
+		squads.POST("/:id/invites", openapi.Route{Tag: "squads", Summary: "Invite a friend (owner)", Auth: true,
+			Body: handlers.SquadInviteRequest{}, Response: handlers.MessageResponse{},
+			Errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError}},
+			h.InviteToSquad)
+		squads.DELETE("/:id/members/:userID", openapi.Route{Tag: "squads", Summary: "Remove a member, or leave", Auth: true,
+			Response: handlers.MessageResponse{}, Errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}},
+			h.RemoveSquadMember)
+		squads.GET("/:id/stats", openapi.Route{Tag: "squads", Summary: "Aggregated squad stats", Auth: true,
+			Query: handlers.StoredGameQuery{}, Response: handlers.SquadStats{}, Errors: missing},
+			h.GetSquadStats)
+	}
+	// Duo synergy from stored matches (Requires authentication)
+	g.GET(statsPrefix+"/synergy", openapi.Route{Tag: "stats", Summary: "Duo synergy with a friend from stored matches", Auth: true,
+		Query:    handlers.SynergyQuery{},
+		Response: handlers.SynergyStats{},
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}},
+		middleware.RequireAuth(), limits.api, h.GetSynergy)
+
+	// Public stats routes, limited per user when signed in and per IP otherwise,
+	// as each request calls the providers
+	games := g.Group(statsPrefix+"/stats", middleware.OptionalAuth(), limits.stats)
+	{
+		games.GET("/cs2", openapi.Route{Tag: "stats", Summary: "Counter-Strike 2 stats",
+			Query:    handlers.SteamStatsQuery{},
+			Response: map[string]any{}, Errors: providerErrs},
+			h.GetCS2Stats)
+		games.GET("/apex", openapi.Route{Tag: "stats", Summary: "Apex Legends stats",
+			Description: "Without a username the signed-in user's linked EA account is used",
+			Query:       handlers.ApexStatsQuery{},
+			Response:    openapi.Any("The tracker.gg profile, passed through unchanged"), Errors: providerErrs},
+			h.GetApexStats)
+		games.GET("/lol", openapi.Route{Tag: "stats", Summary: "League of Legends stats",
+			Description: "Without a Riot ID the signed-in user's linked Riot account is used",
+			Query:       handlers.LeagueStatsQuery{},
+			Response:    handlers.LeagueStatsResponse{},
+			Errors:      providerErrs},
+			h.GetLeagueOfLegendsStats)
+		games.GET("/valorant", openapi.Route{Tag: "stats", Summary: "Valorant stats",
+			Query:    handlers.ValorantStatsQuery{},
+			Response: handlers.ValorantStatsResponse{},
+			Errors:   providerErrs},
+			h.GetValorantStats)
+		games.GET("/dota2", openapi.Route{Tag: "stats", Summary: "Dota 2 stats",
+			Description: "Falls back to sample data when Steam has none",
+			Query:       handlers.SteamStatsQuery{},
+			Response:    map[string]any{}, Errors: providerErrs},
+			h.GetDota2Stats)
+	}
+}
+
+// routeLimits are the rate limits of the route groups. The v1 routes and
+// their legacy aliases share them, so the aliases don't double a client's rate.
+type routeLimits struct {
+	auth    gin.HandlerFunc // Sign-in, registration and account linking callbacks
+	api     gin.HandlerFunc // Signed-in routes reading and writing our own data
+	stats   gin.HandlerFunc // Routes calling the game providers
+	graphQL gin.HandlerFunc
+	// Not a limit: lets only internal callers through, e.g. to /metrics
+	internal gin.HandlerFunc
+}
+
+func newRouteLimits(cfg config.RateLimit) (*routeLimits, error) {
+	limits, err := middleware.NewRateLimits(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &routeLimits{
+		auth:     limits.Limit(middleware.RatePolicy{Name: "auth", Rate: cfg.Auth, PerIP: true}),
+		api:      limits.Limit(middleware.RatePolicy{Name: "api", Rate: cfg.API}),
+		stats:    limits.Limit(middleware.RatePolicy{Name: "stats", Rate: cfg.Stats}),
+		graphQL:  limits.Limit(middleware.RatePolicy{Name: "graphql", Rate: cfg.GraphQL}),
+		internal: limits.InternalOnly(),
+	}, nil
+}
+
+// successorPath is the v1 path replacing an unversioned one, e.g.
+// /api/stats/lol becomes /api/v1/stats/lol and /friends/ /api/v1/friends/
+func successorPath(path string) string {
+	if rest, ok := strings.CutPrefix(path, "/api/"); ok {
+		return v1Prefix + "/" + rest
+	}
+	return v1Prefix + path
 }
@@ -0,0 +1,60 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"elo-insight/backend/config"
+	"elo-insight/backend/middleware"
+	"elo-insight/backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetricsIsInternal(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("RATE_LIMIT_API_TOKENS", "prometheus:scrape-token,partner:partner-token")
+	t.Setenv("RATE_LIMIT_INTERNAL_TOKENS", "prometheus")
+	t.Setenv("RATE_LIMIT_INTERNAL_NETWORKS", "10.0.0.0/8")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	r := gin.New()
+	r.Use(middleware.Errors())
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("go_goroutines 8\n"))
+	})
+	if _, err := setupRoutes(r, store.NewMemoryStore(), cfg, nil, metrics); err != nil {
+		t.Fatalf("set up routes: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		ip    string
+		token string
+		want  int
+	}{
+		{"scraper on the internal network", "10.0.0.5", "", http.StatusOK},
+		{"scraper with an internal token", "203.0.113.7", "scrape-token", http.StatusOK},
+		{"public caller", "203.0.113.7", "", http.StatusForbidden},
+		{"partner token", "203.0.113.7", "partner-token", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.RemoteAddr = tt.ip + ":40000"
+			if tt.token != "" {
+				req.Header.Set(middleware.APITokenHeader, tt.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d; body: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
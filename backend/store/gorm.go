@@ -0,0 +1,62 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"elo-insight/backend/pagination"
+
+	"gorm.io/gorm"
+)
+
+// NewGormStore returns stores backed by the database
+func NewGormStore(db *gorm.DB) *Store {
+	return &Store{
+		Users:         &gormUsers{db: db},
+		PlatformLinks: &gormPlatformLinks{db: db},
+		StatCards:     &gormStatCards{db: db},
+		Friendships:   &gormFriendships{db: db},
+		Matches:       &gormMatches{db: db},
+		Feed:          &gormFeed{db: db},
+		Squads:        &gormSquads{db: db},
+	}
+}
+
+// translate maps GORM's errors to the store's
+func translate(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	}
+	return err
+}
+
+// keysetPage applies keyset to a query sorted by column, then by idColumn to
+// break ties. keyOf turns the cursor's key into a value to compare with column.
+func keysetPage(query *gorm.DB, keyset pagination.Keyset, column, idColumn string, keyOf func(string) (any, error)) (*gorm.DB, error) {
+	direction, past := "ASC", ">"
+	if keyset.Reversed() {
+		direction, past = "DESC", "<"
+	}
+	if keyset.After != nil {
+		key, err := keyOf(keyset.After.Key)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(fmt.Sprintf("(%[1]s %[3]s ? OR (%[1]s = ? AND %[2]s %[3]s ?))", column, idColumn, past),
+			key, key, keyset.After.ID)
+	}
+	return query.Order(column + " " + direction + ", " + idColumn + " " + direction).Limit(keyset.Limit), nil
+}
+
+// timeKey compares a pagination.TimeKey with a timestamp column
+func timeKey(key string) (any, error) {
+	return pagination.ParseTimeKey(key)
+}
+
+// textKey compares a pagination.TextKey with LOWER() of a text column
+func textKey(key string) (any, error) {
+	return key, nil
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+
+	"elo-insight/backend/models"
+	"elo-insight/backend/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormFeed struct {
+	db *gorm.DB
+}
+
+func (s *gormFeed) CreateEvent(ctx context.Context, event *models.FeedEvent) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(event).Error
+}
+
+func (s *gormFeed) GetEvent(ctx context.Context, id uint) (*models.FeedEvent, error) {
+	var event models.FeedEvent
+	if err := s.db.WithContext(ctx).First(&event, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &event, nil
+}
+
+func (s *gormFeed) ListEvents(ctx context.Context, userIDs []uint, keyset pagination.Keyset) ([]models.FeedEvent, error) {
+	var events []models.FeedEvent
+	if len(userIDs) == 0 {
+		return events, nil
+	}
+
+	query, err := keysetPage(s.db.WithContext(ctx).Where("user_id IN ?", userIDs), keyset, "occurred_at", "id", timeKey)
+	if err != nil {
+		return nil, err
+	}
+	err = query.Find(&events).Error
+	return events, err
+}
+
+func (s *gormFeed) AddReaction(ctx context.Context, reaction *models.FeedReaction) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(reaction).Error
+}
+
+func (s *gormFeed) RemoveReaction(ctx context.Context, eventID, userID uint, kind string) error {
+	return s.db.WithContext(ctx).Unscoped().
+		Where("feed_event_id = ? AND user_id = ? AND kind = ?", eventID, userID, kind).
+		Delete(&models.FeedReaction{}).Error
+}
+
+func (s *gormFeed) ReactionCounts(ctx context.Context, eventIDs []uint) (map[uint]map[string]int, error) {
+	reactions := make(map[uint]map[string]int)
+	if len(eventIDs) == 0 {
+		return reactions, nil
+	}
+
+	var rows []struct {
+		FeedEventID uint
+		Kind        string
+		Count       int
+	}
+	if err := s.db.WithContext(ctx).Model(&models.FeedReaction{}).
+		Select("feed_event_id, kind, COUNT(*) AS count").
+		Where("feed_event_id IN ?", eventIDs).
+		Group("feed_event_id, kind").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if reactions[row.FeedEventID] == nil {
+			reactions[row.FeedEventID] = make(map[string]int)
+		}
+		reactions[row.FeedEventID][row.Kind] = row.Count
+	}
+	return reactions, nil
+}
+
+func (s *gormFeed) CreateComment(ctx context.Context, comment *models.FeedComment) error {
+	return s.db.WithContext(ctx).Create(comment).Error
+}
+
+func (s *gormFeed) ListComments(ctx context.Context, eventID uint) ([]models.FeedComment, error) {
+	var comments []models.FeedComment
+	err := s.db.WithContext(ctx).Where("feed_event_id = ?", eventID).Order("created_at ASC").Find(&comments).Error
+	return comments, err
+}
+
+func (s *gormFeed) CommentCounts(ctx context.Context, eventIDs []uint) (map[uint]int, error) {
+	counts := make(map[uint]int)
+	if len(eventIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		FeedEventID uint
+		Count       int
+	}
+	if err := s.db.WithContext(ctx).Model(&models.FeedComment{}).
+		Select("feed_event_id, COUNT(*) AS count").
+		Where("feed_event_id IN ?", eventIDs).
+		Group("feed_event_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.FeedEventID] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,166 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"elo-insight/backend/models"
+	"elo-insight/backend/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormFriendships struct {
+	db *gorm.DB
+}
+
+func (s *gormFriendships) ListForUser(ctx context.Context, userID uint) ([]models.Friendship, error) {
+	var friendships []models.Friendship
+	err := s.db.WithContext(ctx).Where("user_id = ? OR friend_id = ?", userID, userID).Find(&friendships).Error
+	return friendships, err
+}
+
+func (s *gormFriendships) ListAccepted(ctx context.Context, userID uint) ([]models.Friendship, error) {
+	var friendships []models.Friendship
+	err := s.db.WithContext(ctx).Where(
+		"(user_id = ? OR friend_id = ?) AND status = ?",
+		userID, userID, models.FriendshipAccepted).Find(&friendships).Error
+	return friendships, err
+}
+
+func (s *gormFriendships) Page(ctx context.Context, userID uint, filter FriendshipFilter, keyset pagination.Keyset) ([]models.Friendship, error) {
+	query := s.db.WithContext(ctx).Select("friendships.*").
+		Joins("JOIN users other ON other.id = CASE WHEN friendships.user_id = ? THEN friendships.friend_id ELSE friendships.user_id END AND other.deleted_at IS NULL", userID).
+		Where("(friendships.user_id = ? OR friendships.friend_id = ?) AND friendships.status = ?", userID, userID, filter.Status)
+	switch filter.Direction {
+	case FriendshipsIncoming:
+		query = query.Where("friendships.requested_by <> ?", userID)
+	case FriendshipsOutgoing:
+		query = query.Where("friendships.requested_by = ?", userID)
+	}
+
+	column, keyOf := "friendships.created_at", timeKey
+	if keyset.Field == "username" {
+		column, keyOf = "LOWER(other.username)", textKey
+	}
+	query, err := keysetPage(query, keyset, column, "friendships.id", keyOf)
+	if err != nil {
+		return nil, err
+	}
+
+	var friendships []models.Friendship
+	err = query.Find(&friendships).Error
+	return friendships, err
+}
+
+func (s *gormFriendships) ListAcceptedAmong(ctx context.Context, userIDs []uint) ([]models.Friendship, error) {
+	var friendships []models.Friendship
+	if len(userIDs) == 0 {
+		return friendships, nil
+	}
+	err := s.db.WithContext(ctx).Where(
+		"(user_id IN ? OR friend_id IN ?) AND status = ?",
+		userIDs, userIDs, models.FriendshipAccepted).Find(&friendships).Error
+	return friendships, err
+}
+
+func (s *gormFriendships) ListPendingFor(ctx context.Context, userID uint) ([]models.Friendship, error) {
+	var friendships []models.Friendship
+	err := s.db.WithContext(ctx).Where(
+		"friend_id = ? AND status = ?",
+		userID, models.FriendshipPending).Find(&friendships).Error
+	return friendships, err
+}
+
+// Request locks the pair's row so concurrent requests in either direction are
+// serialised; if both sides insert at once, the loser retries and sees the
+// other request, which turns it into an accept.
+func (s *gormFriendships) Request(ctx context.Context, fromID, toID uint, now time.Time) (*models.Friendship, error) {
+	var friendship *models.Friendship
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		friendship, err = s.tryRequest(ctx, fromID, toID, now)
+		if !errors.Is(err, errFriendshipInsertRace) {
+			break
+		}
+	}
+	return friendship, err
+}
+
+// tryRequest is one attempt of Request
+func (s *gormFriendships) tryRequest(ctx context.Context, fromID, toID uint, now time.Time) (*models.Friendship, error) {
+	low, high := models.FriendshipPair(fromID, toID)
+	var friendship models.Friendship
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("pair_low = ? AND pair_high = ?", low, high).
+			First(&friendship).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			request, err := models.NewFriendRequest(fromID, toID)
+			if err != nil {
+				return err
+			}
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(request)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errFriendshipInsertRace
+			}
+			friendship = *request
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		changed, err := friendship.Transition(fromID, models.FriendshipActionRequest, now)
+		if err != nil || !changed {
+			return err
+		}
+		return tx.Save(&friendship).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &friendship, nil
+}
+
+func (s *gormFriendships) Transition(ctx context.Context, id, actorID uint, action string, now time.Time) (*models.Friendship, error) {
+	var friendship models.Friendship
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND (user_id = ? OR friend_id = ?)", id, actorID, actorID).
+			First(&friendship).Error; err != nil {
+			return translate(err)
+		}
+
+		changed, err := friendship.Transition(actorID, action, now)
+		if err != nil || !changed {
+			return err
+		}
+		return tx.Save(&friendship).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &friendship, nil
+}
+
+func (s *gormFriendships) DeleteAccepted(ctx context.Context, id, userID uint) error {
+	// Hard delete so the pair's unique index is free for a future request
+	result := s.db.WithContext(ctx).Unscoped().Where(
+		"id = ? AND (user_id = ? OR friend_id = ?) AND status = ?",
+		id, userID, userID, models.FriendshipAccepted).Delete(&models.Friendship{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
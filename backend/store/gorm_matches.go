@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"elo-insight/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormMatches struct {
+	db *gorm.DB
+}
+
+func (s *gormMatches) Save(ctx context.Context, match *models.StoredMatch) (bool, error) {
+	// Insert the match unless we already have it
+	inserted := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit("Participants").Create(match)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		for i := range match.Participants {
+			match.Participants[i].StoredMatchID = match.ID
+		}
+		if len(match.Participants) > 0 {
+			if err := tx.Create(&match.Participants).Error; err != nil {
+				return err
+			}
+		}
+		inserted = true
+		return nil
+	})
+	return inserted, err
+}
+
+func (s *gormMatches) Participations(ctx context.Context, puuids []string, game string) ([]Participation, error) {
+	var rows []Participation
+	if len(puuids) == 0 {
+		return rows, nil
+	}
+
+	err := s.db.WithContext(ctx).Model(&models.MatchParticipant{}).
+		Select(`match_participants.stored_match_id, stored_matches.match_id AS upstream_match_id,
+			stored_matches.played_at, match_participants.puuid, match_participants.team_id,
+			match_participants.character, match_participants.role, match_participants.win,
+			match_participants.kills, match_participants.deaths, match_participants.assists`).
+		Joins("JOIN stored_matches ON stored_matches.id = match_participants.stored_match_id").
+		Where("match_participants.puuid IN ? AND stored_matches.game = ?", puuids, game).
+		Where("stored_matches.deleted_at IS NULL").
+		Order("stored_matches.played_at DESC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (s *gormMatches) PlayerHistory(ctx context.Context, puuid, game string, before time.Time, limit int) ([]models.MatchParticipant, error) {
+	var history []models.MatchParticipant
+	err := s.db.WithContext(ctx).
+		Joins("JOIN stored_matches ON stored_matches.id = match_participants.stored_match_id").
+		Where("match_participants.puuid = ? AND stored_matches.game = ?", puuid, game).
+		Where("stored_matches.played_at < ?", before).
+		Order("stored_matches.played_at DESC").
+		Limit(limit).
+		Find(&history).Error
+	return history, err
+}
+
+func (s *gormMatches) ParticipantsInMatches(ctx context.Context, storedMatchIDs []uint) ([]models.MatchParticipant, error) {
+	var participants []models.MatchParticipant
+	if len(storedMatchIDs) == 0 {
+		return participants, nil
+	}
+	err := s.db.WithContext(ctx).Where("stored_match_id IN ?", storedMatchIDs).Find(&participants).Error
+	return participants, err
+}
+
+func (s *gormMatches) LatestRankSnapshot(ctx context.Context, userID uint, game, queue string) (*models.RankSnapshot, error) {
+	var snapshot models.RankSnapshot
+	err := s.db.WithContext(ctx).Where("user_id = ? AND game = ? AND queue = ?", userID, game, queue).
+		Order("created_at DESC").
+		First(&snapshot).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &snapshot, nil
+}
+
+func (s *gormMatches) CreateRankSnapshot(ctx context.Context, snapshot *models.RankSnapshot) error {
+	return s.db.WithContext(ctx).Create(snapshot).Error
+}
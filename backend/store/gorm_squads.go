@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+
+	"elo-insight/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Squad invite statuses
+const (
+	invitePending  = "pending"
+	inviteAccepted = "accepted"
+	inviteDeclined = "declined"
+)
+
+type gormSquads struct {
+	db *gorm.DB
+}
+
+func (s *gormSquads) Create(ctx context.Context, squad *models.Squad) error {
+	return s.db.WithContext(ctx).Create(squad).Error
+}
+
+func (s *gormSquads) Get(ctx context.Context, id uint) (*models.Squad, error) {
+	var squad models.Squad
+	if err := s.db.WithContext(ctx).Preload("Members").First(&squad, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &squad, nil
+}
+
+func (s *gormSquads) ListForMember(ctx context.Context, userID uint) ([]models.Squad, error) {
+	db := s.db.WithContext(ctx)
+	var squads []models.Squad
+	err := db.
+		Where("id IN (?)", db.Model(&models.SquadMember{}).Select("squad_id").Where("user_id = ?", userID)).
+		Preload("Members").
+		Find(&squads).Error
+	return squads, err
+}
+
+func (s *gormSquads) Delete(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("squad_id = ?", id).Delete(&models.SquadMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("squad_id = ?", id).Delete(&models.SquadInvite{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Squad{}, id).Error
+	})
+}
+
+func (s *gormSquads) RemoveMember(ctx context.Context, squadID, userID uint) error {
+	result := s.db.WithContext(ctx).Where("squad_id = ? AND user_id = ?", squadID, userID).Delete(&models.SquadMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *gormSquads) CreateInvite(ctx context.Context, invite *models.SquadInvite) error {
+	return s.db.WithContext(ctx).Create(invite).Error
+}
+
+func (s *gormSquads) FindPendingInvite(ctx context.Context, squadID, inviteeID uint) (*models.SquadInvite, error) {
+	var invite models.SquadInvite
+	if err := s.db.WithContext(ctx).Where("squad_id = ? AND invitee_id = ? AND status = ?", squadID, inviteeID, invitePending).
+		First(&invite).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &invite, nil
+}
+
+func (s *gormSquads) GetPendingInvite(ctx context.Context, id, inviteeID uint) (*models.SquadInvite, error) {
+	var invite models.SquadInvite
+	if err := s.db.WithContext(ctx).Where("id = ? AND invitee_id = ? AND status = ?", id, inviteeID, invitePending).
+		First(&invite).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &invite, nil
+}
+
+func (s *gormSquads) ListPendingInvites(ctx context.Context, inviteeID uint) ([]models.SquadInvite, error) {
+	var invites []models.SquadInvite
+	err := s.db.WithContext(ctx).Where("invitee_id = ? AND status = ?", inviteeID, invitePending).Find(&invites).Error
+	return invites, err
+}
+
+func (s *gormSquads) RespondToInvite(ctx context.Context, invite *models.SquadInvite, accept bool, maxSize int) error {
+	status := inviteDeclined
+	if accept {
+		status = inviteAccepted
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the squad so concurrent accepts count its members one at a time
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Squad{}, invite.SquadID).Error; err != nil {
+			return translate(err)
+		}
+		if err := tx.Model(invite).Update("status", status).Error; err != nil {
+			return err
+		}
+		if !accept {
+			return nil
+		}
+
+		var memberCount int64
+		if err := tx.Model(&models.SquadMember{}).Where("squad_id = ?", invite.SquadID).Count(&memberCount).Error; err != nil {
+			return err
+		}
+		if memberCount >= int64(maxSize) {
+			return ErrSquadFull
+		}
+
+		return tx.Create(&models.SquadMember{
+			SquadID: invite.SquadID,
+			UserID:  invite.InviteeID,
+			Role:    models.SquadRoleMember,
+		}).Error
+	})
+}
@@ -0,0 +1,319 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"elo-insight/backend/config"
+	"elo-insight/backend/database"
+	"elo-insight/backend/models"
+	"elo-insight/backend/pagination"
+	"elo-insight/backend/store"
+)
+
+// newSQLiteStore returns a GORM store on a migrated, throwaway in-memory database
+func newSQLiteStore(t *testing.T) *store.Store {
+	t.Helper()
+	db, err := database.Open(config.Database{URL: "sqlite://:memory:"})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return store.NewGormStore(db)
+}
+
+func TestGormSquadsRejoinAfterRemoval(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteStore(t)
+
+	squad := &models.Squad{Name: "Duo", OwnerID: 1, Members: []models.SquadMember{
+		{UserID: 1, Role: models.SquadRoleOwner},
+		{UserID: 2, Role: models.SquadRoleMember},
+	}}
+	if err := s.Squads.Create(ctx, squad); err != nil {
+		t.Fatalf("create squad: %v", err)
+	}
+	if err := s.Squads.RemoveMember(ctx, squad.ID, 2); err != nil {
+		t.Fatalf("remove member: %v", err)
+	}
+
+	invite := &models.SquadInvite{SquadID: squad.ID, InviterID: 1, InviteeID: 2, Status: "pending"}
+	if err := s.Squads.CreateInvite(ctx, invite); err != nil {
+		t.Fatalf("create invite: %v", err)
+	}
+	if err := s.Squads.RespondToInvite(ctx, invite, true, 5); err != nil {
+		t.Fatalf("accept after removal: %v", err)
+	}
+
+	got, err := s.Squads.Get(ctx, squad.ID)
+	if err != nil {
+		t.Fatalf("get squad: %v", err)
+	}
+	if len(got.Members) != 2 {
+		t.Errorf("squad has %d members after rejoining, want 2", len(got.Members))
+	}
+}
+
+func TestGormSquadsRespondToInviteWhenFull(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteStore(t)
+
+	squad := &models.Squad{Name: "Solo", OwnerID: 1, Members: []models.SquadMember{{UserID: 1, Role: models.SquadRoleOwner}}}
+	if err := s.Squads.Create(ctx, squad); err != nil {
+		t.Fatalf("create squad: %v", err)
+	}
+	invite := &models.SquadInvite{SquadID: squad.ID, InviterID: 1, InviteeID: 2, Status: "pending"}
+	if err := s.Squads.CreateInvite(ctx, invite); err != nil {
+		t.Fatalf("create invite: %v", err)
+	}
+
+	if err := s.Squads.RespondToInvite(ctx, invite, true, 1); !errors.Is(err, store.ErrSquadFull) {
+		t.Fatalf("accept into a full squad: got %v, want ErrSquadFull", err)
+	}
+	// The whole answer is rolled back, so the invite can still be accepted later
+	if _, err := s.Squads.GetPendingInvite(ctx, invite.ID, 2); err != nil {
+		t.Errorf("invite no longer pending: %v", err)
+	}
+}
+
+// stores returns each implementation, so a test can check they agree
+func stores(t *testing.T) map[string]*store.Store {
+	return map[string]*store.Store{"gorm": newSQLiteStore(t), "memory": store.NewMemoryStore()}
+}
+
+func TestSearchPagesPastEveryMatch(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			// More matches than the largest page, in no particular order
+			const matches = pagination.MaxLimit + 30
+			for i := range matches {
+				user := &models.User{
+					Username: fmt.Sprintf("Player%03d", (i*37)%matches),
+					Email:    fmt.Sprintf("user%d@example.com", i),
+					Password: "x",
+				}
+				if err := s.Users.Create(ctx, user); err != nil {
+					t.Fatalf("create user: %v", err)
+				}
+			}
+
+			order := pagination.Order[models.User]{
+				Sort:   "username",
+				Filter: "q=player",
+				Key:    func(user models.User) string { return pagination.TextKey(user.Username) },
+				ID:     func(user models.User) uint { return user.ID },
+			}
+			var seen []string
+			var pages []pagination.Page[models.User]
+			query := pagination.Query{Limit: 40}
+			for {
+				keyset, err := order.Keyset(query)
+				if err != nil {
+					t.Fatalf("keyset: %v", err)
+				}
+				users, err := s.Users.Search(ctx, "PLAYER", 1, keyset)
+				if err != nil {
+					t.Fatalf("search: %v", err)
+				}
+				page := order.Page(users, keyset)
+				pages = append(pages, page)
+				for _, user := range page.Items {
+					seen = append(seen, user.Username)
+				}
+				if page.Next == "" {
+					break
+				}
+				query.Cursor = page.Next
+			}
+
+			// Everyone but user 1, who is searching
+			if len(seen) != matches-1 {
+				t.Fatalf("paged through %d users, want %d", len(seen), matches-1)
+			}
+			if !slices.IsSorted(seen) {
+				t.Errorf("users aren't sorted by username: %v", seen)
+			}
+
+			// Paging back from the last page gives the one before it
+			last, previous := pages[len(pages)-1], pages[len(pages)-2]
+			keyset, err := order.Keyset(pagination.Query{Limit: 40, Cursor: last.Prev})
+			if err != nil {
+				t.Fatalf("keyset: %v", err)
+			}
+			users, err := s.Users.Search(ctx, "player", 1, keyset)
+			if err != nil {
+				t.Fatalf("search back: %v", err)
+			}
+			back := order.Page(users, keyset)
+			sameUser := func(a, b models.User) bool { return a.ID == b.ID }
+			if !slices.EqualFunc(back.Items, previous.Items, sameUser) {
+				t.Errorf("paging back gave %d users, want the previous page's %d", len(back.Items), len(previous.Items))
+			}
+			if back.Next == "" || back.Prev == "" {
+				t.Errorf("page in the middle has next %q and prev %q, want both", back.Next, back.Prev)
+			}
+		})
+	}
+}
+
+func TestFriendshipPageFiltersAndSorts(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, username := range []string{"me", "carol", "alice", "bob", "dave"} {
+				user := &models.User{Username: username, Email: username + "@example.com", Password: "x"}
+				if err := s.Users.Create(ctx, user); err != nil {
+					t.Fatalf("create user: %v", err)
+				}
+			}
+			// 1 is friends with carol (2), alice (3) and bob (4), and has sent dave (5) a request
+			for friend := uint(2); friend <= 4; friend++ {
+				request, err := s.Friendships.Request(ctx, 1, friend, now)
+				if err != nil {
+					t.Fatalf("request: %v", err)
+				}
+				if _, err := s.Friendships.Transition(ctx, request.ID, friend, models.FriendshipActionAccept, now); err != nil {
+					t.Fatalf("accept: %v", err)
+				}
+			}
+			if _, err := s.Friendships.Request(ctx, 1, 5, now); err != nil {
+				t.Fatalf("request: %v", err)
+			}
+
+			keyset := pagination.Keyset{Field: "username", Descending: true, Limit: 3}
+			friendships, err := s.Friendships.Page(ctx, 1, store.FriendshipFilter{Status: models.FriendshipAccepted}, keyset)
+			if err != nil {
+				t.Fatalf("page: %v", err)
+			}
+			var others []uint
+			for _, friendship := range friendships {
+				others = append(others, friendship.OtherUser(1))
+			}
+			// carol, bob, alice
+			if want := []uint{2, 4, 3}; !slices.Equal(others, want) {
+				t.Errorf("friends by -username are %v, want %v", others, want)
+			}
+
+			keyset = pagination.Keyset{Field: "created_at", Limit: 3}
+			filter := store.FriendshipFilter{Status: models.FriendshipPending, Direction: store.FriendshipsOutgoing}
+			pending, err := s.Friendships.Page(ctx, 1, filter, keyset)
+			if err != nil {
+				t.Fatalf("page: %v", err)
+			}
+			if len(pending) != 1 || pending[0].OtherUser(1) != 5 {
+				t.Errorf("outgoing requests are %v, want the one to dave", pending)
+			}
+			filter.Direction = store.FriendshipsIncoming
+			if incoming, err := s.Friendships.Page(ctx, 1, filter, keyset); err != nil || len(incoming) != 0 {
+				t.Errorf("incoming requests are %v (error %v), want none", incoming, err)
+			}
+		})
+	}
+}
+
+func TestFeedEventsPageNewestFirst(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.Local)
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			// Two events share each time, so ties are broken by ID
+			for i := range 7 {
+				event := &models.FeedEvent{
+					UserID:     uint(1 + i%2),
+					Type:       models.FeedEventPentakill,
+					Game:       "lol",
+					Summary:    "Pentakill",
+					OccurredAt: start.Add(time.Duration(i/2) * time.Hour),
+					DedupeKey:  fmt.Sprintf("event-%d", i),
+				}
+				if err := s.Feed.CreateEvent(ctx, event); err != nil {
+					t.Fatalf("create event: %v", err)
+				}
+			}
+
+			order := pagination.Order[models.FeedEvent]{
+				Sort: "-occurred_at",
+				Key:  func(event models.FeedEvent) string { return pagination.TimeKey(event.OccurredAt) },
+				ID:   func(event models.FeedEvent) uint { return event.ID },
+			}
+			var ids []uint
+			query := pagination.Query{Limit: 3}
+			for {
+				keyset, err := order.Keyset(query)
+				if err != nil {
+					t.Fatalf("keyset: %v", err)
+				}
+				events, err := s.Feed.ListEvents(ctx, []uint{1, 2}, keyset)
+				if err != nil {
+					t.Fatalf("list events: %v", err)
+				}
+				page := order.Page(events, keyset)
+				for _, event := range page.Items {
+					ids = append(ids, event.ID)
+				}
+				if page.Next == "" {
+					break
+				}
+				query.Cursor = page.Next
+			}
+			if want := []uint{7, 6, 5, 4, 3, 2, 1}; !slices.Equal(ids, want) {
+				t.Errorf("events newest first are %v, want %v", ids, want)
+			}
+		})
+	}
+}
+
+func TestPlatformLinkOwnership(t *testing.T) {
+	ctx := context.Background()
+	// Each step runs on the links the previous ones left
+	steps := []struct {
+		name         string
+		userID       uint
+		verification string
+		wantErr      error
+		wantOwner    uint // Of the account after the step
+	}{
+		{"typed in", 1, models.LinkUnverified, nil, 1},
+		{"typed in by another user", 2, models.LinkUnverified, store.ErrPlatformAccountTaken, 1},
+		{"verified by another user", 2, models.LinkVerified, nil, 2},
+		{"typed in after verification", 1, models.LinkUnverified, store.ErrPlatformAccountTaken, 2},
+		{"verified again elsewhere", 3, models.LinkVerified, store.ErrPlatformAccountTaken, 2},
+	}
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, username := range []string{"alice", "bob", "carol"} {
+				user := &models.User{Username: username, Email: username + "@example.com", Password: "x"}
+				if err := s.Users.Create(ctx, user); err != nil {
+					t.Fatalf("create user: %v", err)
+				}
+			}
+			for _, step := range steps {
+				link := &models.PlatformLink{UserID: step.userID, Platform: models.PlatformSteam,
+					ExternalID: "76561197960287930", Verification: step.verification}
+				if err := s.PlatformLinks.Save(ctx, link); !errors.Is(err, step.wantErr) {
+					t.Fatalf("%s: got %v, want %v", step.name, err, step.wantErr)
+				}
+				links, err := s.PlatformLinks.FindByExternalIDs(ctx, models.PlatformSteam, []string{"76561197960287930"})
+				if err != nil {
+					t.Fatalf("%s: find: %v", step.name, err)
+				}
+				if len(links) != 1 || links[0].UserID != step.wantOwner {
+					t.Fatalf("%s: links are %+v, want one held by %d", step.name, links, step.wantOwner)
+				}
+			}
+		})
+	}
+}
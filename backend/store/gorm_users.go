@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"elo-insight/backend/models"
+	"elo-insight/backend/pagination"
+
+	"gorm.io/gorm"
+)
+
+type gormUsers struct {
+	db *gorm.DB
+}
+
+func (s *gormUsers) Create(ctx context.Context, user *models.User) error {
+	return translate(s.db.WithContext(ctx).Create(user).Error)
+}
+
+func (s *gormUsers) Get(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (s *gormUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (s *gormUsers) ListByIDs(ctx context.Context, ids []uint) ([]models.User, error) {
+	var users []models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := s.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
+func (s *gormUsers) Search(ctx context.Context, query string, excludeID uint, keyset pagination.Keyset) ([]models.User, error) {
+	var users []models.User
+	// LOWER() LIKE instead of ILIKE, which SQLite doesn't have
+	pattern := "%" + strings.ToLower(query) + "%"
+	search := s.db.WithContext(ctx).Select("id, username, email").
+		Where("(LOWER(username) LIKE ? OR LOWER(email) LIKE ?) AND id != ?", pattern, pattern, excludeID)
+	search, err := keysetPage(search, keyset, "LOWER(username)", "id", textKey)
+	if err != nil {
+		return nil, err
+	}
+	err = search.Find(&users).Error
+	return users, err
+}
+
+type gormPlatformLinks struct {
+	db *gorm.DB
+}
+
+func (s *gormPlatformLinks) ListByUser(ctx context.Context, userID uint) ([]models.PlatformLink, error) {
+	var links []models.PlatformLink
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("platform").Find(&links).Error
+	return links, err
+}
+
+func (s *gormPlatformLinks) Get(ctx context.Context, userID uint, platform string) (*models.PlatformLink, error) {
+	var link models.PlatformLink
+	if err := s.db.WithContext(ctx).Where("user_id = ? AND platform = ?", userID, platform).First(&link).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &link, nil
+}
+
+func (s *gormPlatformLinks) Save(ctx context.Context, link *models.PlatformLink) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The external account can only belong to one user
+		var owner models.PlatformLink
+		err := tx.Where("platform = ? AND external_id = ? AND user_id <> ?", link.Platform, link.ExternalID, link.UserID).
+			First(&owner).Error
+		switch {
+		case err == nil && !replacesOwner(link, &owner):
+			return ErrPlatformAccountTaken
+		case err == nil:
+			if err := tx.Unscoped().Delete(&owner).Error; err != nil {
+				return err
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+
+		var existing models.PlatformLink
+		err = tx.Where("user_id = ? AND platform = ?", link.UserID, link.Platform).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			MergePlatformLink(link, nil, time.Now())
+			return tx.Create(link).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		MergePlatformLink(link, &existing, time.Now())
+		return tx.Save(link).Error
+	})
+	// Another save of the account committed between the check and the write
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrPlatformAccountTaken
+	}
+	return err
+}
+
+func (s *gormPlatformLinks) Delete(ctx context.Context, userID uint, platform string) error {
+	// Hard delete so the external account can be linked again later
+	result := s.db.WithContext(ctx).Unscoped().
+		Where("user_id = ? AND platform = ?", userID, platform).
+		Delete(&models.PlatformLink{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *gormPlatformLinks) FindByExternalIDs(ctx context.Context, platform string, externalIDs []string) ([]models.PlatformLink, error) {
+	var links []models.PlatformLink
+	if len(externalIDs) == 0 {
+		return links, nil
+	}
+	err := s.db.WithContext(ctx).Where("platform = ? AND external_id IN ?", platform, externalIDs).Find(&links).Error
+	return links, err
+}
+
+func (s *gormPlatformLinks) ListByUsers(ctx context.Context, platform string, userIDs []uint) ([]models.PlatformLink, error) {
+	var links []models.PlatformLink
+	if len(userIDs) == 0 {
+		return links, nil
+	}
+	query := s.db.WithContext(ctx).Where("user_id IN ?", userIDs)
+	if platform != "" {
+		query = query.Where("platform = ?", platform)
+	}
+	err := query.Find(&links).Error
+	return links, err
+}
+
+type gormStatCards struct {
+	db *gorm.DB
+}
+
+func (s *gormStatCards) Create(ctx context.Context, stat *models.UserStat) error {
+	return s.db.WithContext(ctx).Create(stat).Error
+}
+
+func (s *gormStatCards) ListByUser(ctx context.Context, userID uint) ([]models.UserStat, error) {
+	var stats []models.UserStat
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&stats).Error
+	return stats, err
+}
+
+func (s *gormStatCards) Delete(ctx context.Context, id, userID uint) error {
+	result := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.UserStat{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
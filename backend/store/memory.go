@@ -0,0 +1,78 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"elo-insight/backend/models"
+)
+
+// memoryDB holds every table of the in-memory store behind one lock, so
+// operations that touch several tables are atomic like a transaction
+type memoryDB struct {
+	mu     sync.Mutex
+	nextID uint
+
+	users        map[uint]models.User
+	links        map[uint]models.PlatformLink
+	stats        map[uint]models.UserStat
+	friendships  map[uint]models.Friendship
+	matches      map[uint]models.StoredMatch
+	participants map[uint]models.MatchParticipant
+	snapshots    map[uint]models.RankSnapshot
+	events       map[uint]models.FeedEvent
+	reactions    map[uint]models.FeedReaction
+	comments     map[uint]models.FeedComment
+	squads       map[uint]models.Squad
+	members      map[uint]models.SquadMember
+	invites      map[uint]models.SquadInvite
+}
+
+// NewMemoryStore returns stores that keep everything in memory. Records are
+// copied in and out, so callers can't change stored data without saving it.
+func NewMemoryStore() *Store {
+	db := &memoryDB{
+		users:        make(map[uint]models.User),
+		links:        make(map[uint]models.PlatformLink),
+		stats:        make(map[uint]models.UserStat),
+		friendships:  make(map[uint]models.Friendship),
+		matches:      make(map[uint]models.StoredMatch),
+		participants: make(map[uint]models.MatchParticipant),
+		snapshots:    make(map[uint]models.RankSnapshot),
+		events:       make(map[uint]models.FeedEvent),
+		reactions:    make(map[uint]models.FeedReaction),
+		comments:     make(map[uint]models.FeedComment),
+		squads:       make(map[uint]models.Squad),
+		members:      make(map[uint]models.SquadMember),
+		invites:      make(map[uint]models.SquadInvite),
+	}
+	return &Store{
+		Users:         &memoryUsers{db},
+		PlatformLinks: &memoryPlatformLinks{db},
+		StatCards:     &memoryStatCards{db},
+		Friendships:   &memoryFriendships{db},
+		Matches:       &memoryMatches{db},
+		Feed:          &memoryFeed{db},
+		Squads:        &memorySquads{db},
+	}
+}
+
+// stamp assigns the next ID and sets the timestamps like GORM does on create
+func (db *memoryDB) stamp(id *uint, createdAt, updatedAt *time.Time) {
+	db.nextID++
+	*id = db.nextID
+	now := time.Now()
+	if createdAt.IsZero() {
+		*createdAt = now
+	}
+	*updatedAt = now
+}
+
+// idsOf turns a list of IDs into a set
+func idsOf(ids []uint) map[uint]bool {
+	set := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
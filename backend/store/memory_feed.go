@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"elo-insight/backend/models"
+	"elo-insight/backend/pagination"
+)
+
+type memoryFeed struct {
+	db *memoryDB
+}
+
+func (s *memoryFeed) CreateEvent(ctx context.Context, event *models.FeedEvent) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, existing := range s.db.events {
+		if existing.DedupeKey == event.DedupeKey {
+			return nil
+		}
+	}
+	s.db.stamp(&event.ID, &event.CreatedAt, &event.UpdatedAt)
+	s.db.events[event.ID] = *event
+	return nil
+}
+
+func (s *memoryFeed) GetEvent(ctx context.Context, id uint) (*models.FeedEvent, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	event, ok := s.db.events[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &event, nil
+}
+
+func (s *memoryFeed) ListEvents(ctx context.Context, userIDs []uint, keyset pagination.Keyset) ([]models.FeedEvent, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	wanted := idsOf(userIDs)
+	var events []models.FeedEvent
+	for _, event := range s.db.events {
+		if wanted[event.UserID] {
+			events = append(events, event)
+		}
+	}
+	return pagination.Order[models.FeedEvent]{
+		Key: func(event models.FeedEvent) string { return pagination.TimeKey(event.OccurredAt) },
+		ID:  func(event models.FeedEvent) uint { return event.ID },
+	}.Select(events, keyset), nil
+}
+
+func (s *memoryFeed) AddReaction(ctx context.Context, reaction *models.FeedReaction) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, existing := range s.db.reactions {
+		if existing.FeedEventID == reaction.FeedEventID && existing.UserID == reaction.UserID && existing.Kind == reaction.Kind {
+			return nil
+		}
+	}
+	s.db.stamp(&reaction.ID, &reaction.CreatedAt, &reaction.UpdatedAt)
+	s.db.reactions[reaction.ID] = *reaction
+	return nil
+}
+
+func (s *memoryFeed) RemoveReaction(ctx context.Context, eventID, userID uint, kind string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for id, reaction := range s.db.reactions {
+		if reaction.FeedEventID == eventID && reaction.UserID == userID && reaction.Kind == kind {
+			delete(s.db.reactions, id)
+		}
+	}
+	return nil
+}
+
+func (s *memoryFeed) ReactionCounts(ctx context.Context, eventIDs []uint) (map[uint]map[string]int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	wanted := idsOf(eventIDs)
+	reactions := make(map[uint]map[string]int)
+	for _, reaction := range s.db.reactions {
+		if !wanted[reaction.FeedEventID] {
+			continue
+		}
+		if reactions[reaction.FeedEventID] == nil {
+			reactions[reaction.FeedEventID] = make(map[string]int)
+		}
+		reactions[reaction.FeedEventID][reaction.Kind]++
+	}
+	return reactions, nil
+}
+
+func (s *memoryFeed) CreateComment(ctx context.Context, comment *models.FeedComment) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.stamp(&comment.ID, &comment.CreatedAt, &comment.UpdatedAt)
+	s.db.comments[comment.ID] = *comment
+	return nil
+}
+
+func (s *memoryFeed) ListComments(ctx context.Context, eventID uint) ([]models.FeedComment, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var comments []models.FeedComment
+	for _, comment := range s.db.comments {
+		if comment.FeedEventID == eventID {
+			comments = append(comments, comment)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].ID < comments[j].ID })
+	return comments, nil
+}
+
+func (s *memoryFeed) CommentCounts(ctx context.Context, eventIDs []uint) (map[uint]int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	wanted := idsOf(eventIDs)
+	counts := make(map[uint]int)
+	for _, comment := range s.db.comments {
+		if wanted[comment.FeedEventID] {
+			counts[comment.FeedEventID]++
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"elo-insight/backend/models"
+	"elo-insight/backend/pagination"
+)
+
+type memoryFriendships struct {
+	db *memoryDB
+}
+
+// list returns the friendships matching keep, oldest first; the caller holds the lock
+func (s *memoryFriendships) list(keep func(f models.Friendship) bool) []models.Friendship {
+	var friendships []models.Friendship
+	for _, friendship := range s.db.friendships {
+		if keep(friendship) {
+			friendships = append(friendships, friendship)
+		}
+	}
+	sort.Slice(friendships, func(i, j int) bool { return friendships[i].ID < friendships[j].ID })
+	return friendships
+}
+
+func (s *memoryFriendships) ListForUser(ctx context.Context, userID uint) ([]models.Friendship, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return s.list(func(f models.Friendship) bool {
+		return f.UserID == userID || f.FriendID == userID
+	}), nil
+}
+
+func (s *memoryFriendships) ListAccepted(ctx context.Context, userID uint) ([]models.Friendship, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return s.list(func(f models.Friendship) bool {
+		return (f.UserID == userID || f.FriendID == userID) && f.Status == models.FriendshipAccepted
+	}), nil
+}
+
+func (s *memoryFriendships) Page(ctx context.Context, userID uint, filter FriendshipFilter, keyset pagination.Keyset) ([]models.Friendship, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	friendships := s.list(func(f models.Friendship) bool {
+		if (f.UserID != userID && f.FriendID != userID) || f.Status != filter.Status {
+			return false
+		}
+		if _, ok := s.db.users[f.OtherUser(userID)]; !ok {
+			return false
+		}
+		switch filter.Direction {
+		case FriendshipsIncoming:
+			return f.RequestedBy != userID
+		case FriendshipsOutgoing:
+			return f.RequestedBy == userID
+		}
+		return true
+	})
+
+	order := pagination.Order[models.Friendship]{
+		Key: func(f models.Friendship) string { return pagination.TimeKey(f.CreatedAt) },
+		ID:  func(f models.Friendship) uint { return f.ID },
+	}
+	if keyset.Field == "username" {
+		order.Key = func(f models.Friendship) string {
+			return pagination.TextKey(s.db.users[f.OtherUser(userID)].Username)
+		}
+	}
+	return order.Select(friendships, keyset), nil
+}
+
+func (s *memoryFriendships) ListAcceptedAmong(ctx context.Context, userIDs []uint) ([]models.Friendship, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	wanted := idsOf(userIDs)
+	return s.list(func(f models.Friendship) bool {
+		return (wanted[f.UserID] || wanted[f.FriendID]) && f.Status == models.FriendshipAccepted
+	}), nil
+}
+
+func (s *memoryFriendships) ListPendingFor(ctx context.Context, userID uint) ([]models.Friendship, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return s.list(func(f models.Friendship) bool {
+		return f.FriendID == userID && f.Status == models.FriendshipPending
+	}), nil
+}
+
+func (s *memoryFriendships) Request(ctx context.Context, fromID, toID uint, now time.Time) (*models.Friendship, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	low, high := models.FriendshipPair(fromID, toID)
+	for _, friendship := range s.db.friendships {
+		if friendship.PairLow != low || friendship.PairHigh != high {
+			continue
+		}
+		changed, err := friendship.Transition(fromID, models.FriendshipActionRequest, now)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			friendship.UpdatedAt = now
+			s.db.friendships[friendship.ID] = friendship
+		}
+		return &friendship, nil
+	}
+
+	request, err := models.NewFriendRequest(fromID, toID)
+	if err != nil {
+		return nil, err
+	}
+	s.db.stamp(&request.ID, &request.CreatedAt, &request.UpdatedAt)
+	s.db.friendships[request.ID] = *request
+	return request, nil
+}
+
+func (s *memoryFriendships) Transition(ctx context.Context, id, actorID uint, action string, now time.Time) (*models.Friendship, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	friendship, ok := s.db.friendships[id]
+	if !ok || (friendship.UserID != actorID && friendship.FriendID != actorID) {
+		return nil, ErrNotFound
+	}
+	changed, err := friendship.Transition(actorID, action, now)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		friendship.UpdatedAt = now
+		s.db.friendships[id] = friendship
+	}
+	return &friendship, nil
+}
+
+func (s *memoryFriendships) DeleteAccepted(ctx context.Context, id, userID uint) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	friendship, ok := s.db.friendships[id]
+	if !ok || (friendship.UserID != userID && friendship.FriendID != userID) ||
+		friendship.Status != models.FriendshipAccepted {
+		return ErrNotFound
+	}
+	delete(s.db.friendships, id)
+	return nil
+}
@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"elo-insight/backend/models"
+)
+
+type memoryMatches struct {
+	db *memoryDB
+}
+
+func (s *memoryMatches) Save(ctx context.Context, match *models.StoredMatch) (bool, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	// Insert the match unless we already have it
+	for _, existing := range s.db.matches {
+		if existing.Game == match.Game && existing.MatchID == match.MatchID {
+			return false, nil
+		}
+	}
+
+	s.db.stamp(&match.ID, &match.CreatedAt, &match.UpdatedAt)
+	stored := *match
+	stored.Participants = nil
+	s.db.matches[match.ID] = stored
+
+	for i := range match.Participants {
+		p := &match.Participants[i]
+		p.StoredMatchID = match.ID
+		s.db.stamp(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+		s.db.participants[p.ID] = *p
+	}
+	return true, nil
+}
+
+func (s *memoryMatches) Participations(ctx context.Context, puuids []string, game string) ([]Participation, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	wanted := make(map[string]bool, len(puuids))
+	for _, puuid := range puuids {
+		wanted[puuid] = true
+	}
+
+	var rows []Participation
+	for _, p := range s.db.participants {
+		match, ok := s.db.matches[p.StoredMatchID]
+		if !ok || match.Game != game || !wanted[p.PUUID] {
+			continue
+		}
+		rows = append(rows, Participation{
+			StoredMatchID:   match.ID,
+			UpstreamMatchID: match.MatchID,
+			PlayedAt:        match.PlayedAt,
+			PUUID:           p.PUUID,
+			TeamID:          p.TeamID,
+			Character:       p.Character,
+			Role:            p.Role,
+			Win:             p.Win,
+			Kills:           p.Kills,
+			Deaths:          p.Deaths,
+			Assists:         p.Assists,
+		})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if !rows[i].PlayedAt.Equal(rows[j].PlayedAt) {
+			return rows[i].PlayedAt.After(rows[j].PlayedAt)
+		}
+		return rows[i].StoredMatchID > rows[j].StoredMatchID
+	})
+	return rows, nil
+}
+
+func (s *memoryMatches) PlayerHistory(ctx context.Context, puuid, game string, before time.Time, limit int) ([]models.MatchParticipant, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var history []models.MatchParticipant
+	for _, p := range s.db.participants {
+		if match, ok := s.db.matches[p.StoredMatchID]; ok && match.Game == game && p.PUUID == puuid && match.PlayedAt.Before(before) {
+			history = append(history, p)
+		}
+	}
+	sort.Slice(history, func(i, j int) bool {
+		a, b := s.db.matches[history[i].StoredMatchID], s.db.matches[history[j].StoredMatchID]
+		if !a.PlayedAt.Equal(b.PlayedAt) {
+			return a.PlayedAt.After(b.PlayedAt)
+		}
+		return a.ID > b.ID
+	})
+	if len(history) > limit {
+		history = history[:limit]
+	}
+	return history, nil
+}
+
+func (s *memoryMatches) ParticipantsInMatches(ctx context.Context, storedMatchIDs []uint) ([]models.MatchParticipant, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	wanted := idsOf(storedMatchIDs)
+	var participants []models.MatchParticipant
+	for _, p := range s.db.participants {
+		if wanted[p.StoredMatchID] {
+			participants = append(participants, p)
+		}
+	}
+	return participants, nil
+}
+
+func (s *memoryMatches) LatestRankSnapshot(ctx context.Context, userID uint, game, queue string) (*models.RankSnapshot, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var latest *models.RankSnapshot
+	for _, snapshot := range s.db.snapshots {
+		if snapshot.UserID != userID || snapshot.Game != game || snapshot.Queue != queue {
+			continue
+		}
+		// IDs break ties between snapshots taken in the same instant
+		if latest == nil || snapshot.CreatedAt.After(latest.CreatedAt) ||
+			(snapshot.CreatedAt.Equal(latest.CreatedAt) && snapshot.ID > latest.ID) {
+			snapshot := snapshot
+			latest = &snapshot
+		}
+	}
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+	return latest, nil
+}
+
+func (s *memoryMatches) CreateRankSnapshot(ctx context.Context, snapshot *models.RankSnapshot) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.stamp(&snapshot.ID, &snapshot.CreatedAt, &snapshot.UpdatedAt)
+	s.db.snapshots[snapshot.ID] = *snapshot
+	return nil
+}
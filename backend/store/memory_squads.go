@@ -0,0 +1,185 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"elo-insight/backend/models"
+)
+
+type memorySquads struct {
+	db *memoryDB
+}
+
+// withMembers returns a copy of the squad with its members attached; the caller holds the lock
+func (s *memorySquads) withMembers(squad models.Squad) models.Squad {
+	squad.Members = nil
+	for _, member := range s.db.members {
+		if member.SquadID == squad.ID {
+			squad.Members = append(squad.Members, member)
+		}
+	}
+	sort.Slice(squad.Members, func(i, j int) bool { return squad.Members[i].ID < squad.Members[j].ID })
+	return squad
+}
+
+func (s *memorySquads) Create(ctx context.Context, squad *models.Squad) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.stamp(&squad.ID, &squad.CreatedAt, &squad.UpdatedAt)
+	stored := *squad
+	stored.Members = nil
+	s.db.squads[squad.ID] = stored
+
+	for i := range squad.Members {
+		member := &squad.Members[i]
+		member.SquadID = squad.ID
+		s.db.stamp(&member.ID, &member.CreatedAt, &member.UpdatedAt)
+		s.db.members[member.ID] = *member
+	}
+	return nil
+}
+
+func (s *memorySquads) Get(ctx context.Context, id uint) (*models.Squad, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	squad, ok := s.db.squads[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	squad = s.withMembers(squad)
+	return &squad, nil
+}
+
+func (s *memorySquads) ListForMember(ctx context.Context, userID uint) ([]models.Squad, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var squads []models.Squad
+	for _, member := range s.db.members {
+		if member.UserID != userID {
+			continue
+		}
+		if squad, ok := s.db.squads[member.SquadID]; ok {
+			squads = append(squads, s.withMembers(squad))
+		}
+	}
+	sort.Slice(squads, func(i, j int) bool { return squads[i].ID < squads[j].ID })
+	return squads, nil
+}
+
+func (s *memorySquads) Delete(ctx context.Context, id uint) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for memberID, member := range s.db.members {
+		if member.SquadID == id {
+			delete(s.db.members, memberID)
+		}
+	}
+	for inviteID, invite := range s.db.invites {
+		if invite.SquadID == id {
+			delete(s.db.invites, inviteID)
+		}
+	}
+	delete(s.db.squads, id)
+	return nil
+}
+
+func (s *memorySquads) RemoveMember(ctx context.Context, squadID, userID uint) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for id, member := range s.db.members {
+		if member.SquadID == squadID && member.UserID == userID {
+			delete(s.db.members, id)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *memorySquads) CreateInvite(ctx context.Context, invite *models.SquadInvite) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.stamp(&invite.ID, &invite.CreatedAt, &invite.UpdatedAt)
+	s.db.invites[invite.ID] = *invite
+	return nil
+}
+
+func (s *memorySquads) FindPendingInvite(ctx context.Context, squadID, inviteeID uint) (*models.SquadInvite, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, invite := range s.db.invites {
+		if invite.SquadID == squadID && invite.InviteeID == inviteeID && invite.Status == invitePending {
+			return &invite, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memorySquads) GetPendingInvite(ctx context.Context, id, inviteeID uint) (*models.SquadInvite, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	invite, ok := s.db.invites[id]
+	if !ok || invite.InviteeID != inviteeID || invite.Status != invitePending {
+		return nil, ErrNotFound
+	}
+	return &invite, nil
+}
+
+func (s *memorySquads) ListPendingInvites(ctx context.Context, inviteeID uint) ([]models.SquadInvite, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var invites []models.SquadInvite
+	for _, invite := range s.db.invites {
+		if invite.InviteeID == inviteeID && invite.Status == invitePending {
+			invites = append(invites, invite)
+		}
+	}
+	sort.Slice(invites, func(i, j int) bool { return invites[i].ID < invites[j].ID })
+	return invites, nil
+}
+
+func (s *memorySquads) RespondToInvite(ctx context.Context, invite *models.SquadInvite, accept bool, maxSize int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored, ok := s.db.invites[invite.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	// Check for room first so a full squad leaves the invite untouched, like a rolled back transaction
+	if accept {
+		memberCount := 0
+		for _, member := range s.db.members {
+			if member.SquadID == invite.SquadID {
+				memberCount++
+			}
+		}
+		if memberCount >= maxSize {
+			return ErrSquadFull
+		}
+
+		member := models.SquadMember{SquadID: invite.SquadID, UserID: invite.InviteeID, Role: models.SquadRoleMember}
+		s.db.stamp(&member.ID, &member.CreatedAt, &member.UpdatedAt)
+		s.db.members[member.ID] = member
+	}
+
+	stored.Status = inviteDeclined
+	if accept {
+		stored.Status = inviteAccepted
+	}
+	stored.UpdatedAt = time.Now()
+	s.db.invites[invite.ID] = stored
+	*invite = stored
+	return nil
+}
@@ -0,0 +1,232 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"elo-insight/backend/models"
+	"elo-insight/backend/pagination"
+)
+
+type memoryUsers struct {
+	db *memoryDB
+}
+
+func (s *memoryUsers) Create(ctx context.Context, user *models.User) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, existing := range s.db.users {
+		if existing.Email == user.Email || existing.Username == user.Username {
+			return ErrDuplicate
+		}
+	}
+	s.db.stamp(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	stored := *user
+	stored.PlatformLinks = nil
+	s.db.users[user.ID] = stored
+	return nil
+}
+
+func (s *memoryUsers) Get(ctx context.Context, id uint) (*models.User, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	user, ok := s.db.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (s *memoryUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, user := range s.db.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memoryUsers) ListByIDs(ctx context.Context, ids []uint) ([]models.User, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var users []models.User
+	for _, id := range ids {
+		if user, ok := s.db.users[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (s *memoryUsers) Search(ctx context.Context, query string, excludeID uint, keyset pagination.Keyset) ([]models.User, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	query = strings.ToLower(query)
+	var users []models.User
+	for _, user := range s.db.users {
+		if user.ID == excludeID {
+			continue
+		}
+		if strings.Contains(strings.ToLower(user.Username), query) || strings.Contains(strings.ToLower(user.Email), query) {
+			users = append(users, user)
+		}
+	}
+	return pagination.Order[models.User]{
+		Key: func(user models.User) string { return pagination.TextKey(user.Username) },
+		ID:  func(user models.User) uint { return user.ID },
+	}.Select(users, keyset), nil
+}
+
+type memoryPlatformLinks struct {
+	db *memoryDB
+}
+
+func (s *memoryPlatformLinks) ListByUser(ctx context.Context, userID uint) ([]models.PlatformLink, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var links []models.PlatformLink
+	for _, link := range s.db.links {
+		if link.UserID == userID {
+			links = append(links, link)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].Platform < links[j].Platform })
+	return links, nil
+}
+
+func (s *memoryPlatformLinks) Get(ctx context.Context, userID uint, platform string) (*models.PlatformLink, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if link := s.find(userID, platform); link != nil {
+		return link, nil
+	}
+	return nil, ErrNotFound
+}
+
+// find returns a copy of the user's link for a platform, or nil; the caller holds the lock
+func (s *memoryPlatformLinks) find(userID uint, platform string) *models.PlatformLink {
+	for _, link := range s.db.links {
+		if link.UserID == userID && link.Platform == platform {
+			return &link
+		}
+	}
+	return nil
+}
+
+func (s *memoryPlatformLinks) Save(ctx context.Context, link *models.PlatformLink) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	// The external account can only belong to one user
+	for id, owner := range s.db.links {
+		if owner.Platform == link.Platform && owner.ExternalID == link.ExternalID && owner.UserID != link.UserID {
+			if !replacesOwner(link, &owner) {
+				return ErrPlatformAccountTaken
+			}
+			delete(s.db.links, id)
+		}
+	}
+
+	existing := s.find(link.UserID, link.Platform)
+	MergePlatformLink(link, existing, time.Now())
+	if existing == nil {
+		s.db.stamp(&link.ID, &link.CreatedAt, &link.UpdatedAt)
+	} else {
+		link.UpdatedAt = time.Now()
+	}
+	s.db.links[link.ID] = *link
+	return nil
+}
+
+func (s *memoryPlatformLinks) Delete(ctx context.Context, userID uint, platform string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	link := s.find(userID, platform)
+	if link == nil {
+		return ErrNotFound
+	}
+	delete(s.db.links, link.ID)
+	return nil
+}
+
+func (s *memoryPlatformLinks) FindByExternalIDs(ctx context.Context, platform string, externalIDs []string) ([]models.PlatformLink, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	wanted := make(map[string]bool, len(externalIDs))
+	for _, id := range externalIDs {
+		wanted[id] = true
+	}
+	var links []models.PlatformLink
+	for _, link := range s.db.links {
+		if link.Platform == platform && wanted[link.ExternalID] {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+func (s *memoryPlatformLinks) ListByUsers(ctx context.Context, platform string, userIDs []uint) ([]models.PlatformLink, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	wanted := idsOf(userIDs)
+	var links []models.PlatformLink
+	for _, link := range s.db.links {
+		if (platform == "" || link.Platform == platform) && wanted[link.UserID] {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+type memoryStatCards struct {
+	db *memoryDB
+}
+
+func (s *memoryStatCards) Create(ctx context.Context, stat *models.UserStat) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.stamp(&stat.ID, &stat.CreatedAt, &stat.UpdatedAt)
+	s.db.stats[stat.ID] = *stat
+	return nil
+}
+
+func (s *memoryStatCards) ListByUser(ctx context.Context, userID uint) ([]models.UserStat, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var stats []models.UserStat
+	for _, stat := range s.db.stats {
+		if stat.UserID == userID {
+			stats = append(stats, stat)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID < stats[j].ID })
+	return stats, nil
+}
+
+func (s *memoryStatCards) Delete(ctx context.Context, id, userID uint) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stat, ok := s.db.stats[id]
+	if !ok || stat.UserID != userID {
+		return ErrNotFound
+	}
+	delete(s.db.stats, id)
+	return nil
+}
@@ -0,0 +1,203 @@
+// Package store is the data access layer. Handlers depend on the interfaces
+// here instead of the database, with a GORM implementation for production and
+// an in-memory implementation for tests and local experiments.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"elo-insight/backend/models"
+	"elo-insight/backend/pagination"
+)
+
+// Errors returned by every implementation
+var (
+	ErrNotFound             = errors.New("record not found")
+	ErrDuplicate            = errors.New("record already exists")
+	ErrPlatformAccountTaken = errors.New("platform account is already linked to another user")
+	ErrSquadFull            = errors.New("squad is full")
+	errFriendshipInsertRace = errors.New("friendship row created concurrently")
+)
+
+// Store groups the stores a handler needs
+type Store struct {
+	Users         UserStore
+	PlatformLinks PlatformLinkStore
+	StatCards     StatCardStore
+	Friendships   FriendshipStore
+	Matches       MatchStore
+	Feed          FeedStore
+	Squads        SquadStore
+}
+
+// UserStore manages user accounts
+type UserStore interface {
+	Create(ctx context.Context, user *models.User) error
+	Get(ctx context.Context, id uint) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	ListByIDs(ctx context.Context, ids []uint) ([]models.User, error)
+	// Search returns the rows keyset selects from the users whose username or
+	// email matches case-insensitively, excluding one user, sorted by username
+	Search(ctx context.Context, query string, excludeID uint, keyset pagination.Keyset) ([]models.User, error)
+}
+
+// PlatformLinkStore manages linked gaming accounts
+type PlatformLinkStore interface {
+	ListByUser(ctx context.Context, userID uint) ([]models.PlatformLink, error)
+	Get(ctx context.Context, userID uint, platform string) (*models.PlatformLink, error)
+	// Save creates or replaces the user's link for link.Platform; see
+	// MergePlatformLink. It returns ErrPlatformAccountTaken if another user
+	// holds the account, unless link is verified and theirs isn't.
+	Save(ctx context.Context, link *models.PlatformLink) error
+	Delete(ctx context.Context, userID uint, platform string) error
+	// FindByExternalIDs returns the links for any of the accounts on a platform
+	FindByExternalIDs(ctx context.Context, platform string, externalIDs []string) ([]models.PlatformLink, error)
+	// ListByUsers returns the users' links on one platform, or on all of them if platform is empty
+	ListByUsers(ctx context.Context, platform string, userIDs []uint) ([]models.PlatformLink, error)
+}
+
+// StatCardStore manages the stat cards users pin to their dashboard
+type StatCardStore interface {
+	Create(ctx context.Context, stat *models.UserStat) error
+	ListByUser(ctx context.Context, userID uint) ([]models.UserStat, error)
+	// Delete removes one of the user's stat cards
+	Delete(ctx context.Context, id, userID uint) error
+}
+
+// Directions of a friend request from a user's side
+const (
+	FriendshipsIncoming = "incoming" // Requests sent to the user
+	FriendshipsOutgoing = "outgoing" // Requests the user sent
+)
+
+// FriendshipFilter selects the friendships FriendshipStore.Page lists
+type FriendshipFilter struct {
+	Status    string
+	Direction string // FriendshipsIncoming, FriendshipsOutgoing, or empty for both
+}
+
+// FriendshipStore manages friendships and friend requests
+type FriendshipStore interface {
+	// ListForUser returns every friendship row involving the user, in any status
+	ListForUser(ctx context.Context, userID uint) ([]models.Friendship, error)
+	ListAccepted(ctx context.Context, userID uint) ([]models.Friendship, error)
+	// Page returns the rows keyset selects from the user's friendships that
+	// match filter, sorted by created_at or the other user's username.
+	// Friendships with a deleted user are left out.
+	Page(ctx context.Context, userID uint, filter FriendshipFilter, keyset pagination.Keyset) ([]models.Friendship, error)
+	// ListAcceptedAmong returns accepted friendships involving any of the users
+	ListAcceptedAmong(ctx context.Context, userIDs []uint) ([]models.Friendship, error)
+	// ListPendingFor returns requests waiting for the user to respond
+	ListPendingFor(ctx context.Context, userID uint) ([]models.Friendship, error)
+	// Request applies a friend request atomically, creating the pair's row if needed
+	Request(ctx context.Context, fromID, toID uint, now time.Time) (*models.Friendship, error)
+	// Transition applies accept, decline or cancel to a friendship the actor is part of
+	Transition(ctx context.Context, id, actorID uint, action string, now time.Time) (*models.Friendship, error)
+	// DeleteAccepted removes an accepted friendship the user is part of
+	DeleteAccepted(ctx context.Context, id, userID uint) error
+}
+
+// Participation is a stored match participant joined with its match
+type Participation struct {
+	StoredMatchID   uint
+	UpstreamMatchID string
+	PlayedAt        time.Time
+	PUUID           string
+	TeamID          string
+	Character       string
+	Role            string
+	Win             bool
+	Kills           int
+	Deaths          int
+	Assists         int
+}
+
+// MatchStore manages stored matches and rank snapshots
+type MatchStore interface {
+	// Save stores a match and its participants, returning false if it was already stored
+	Save(ctx context.Context, match *models.StoredMatch) (bool, error)
+	// Participations returns every participation for the PUUIDs in a game, newest first
+	Participations(ctx context.Context, puuids []string, game string) ([]Participation, error)
+	// PlayerHistory returns a player's most recent participations in a game
+	// played before a time, newest first
+	PlayerHistory(ctx context.Context, puuid, game string, before time.Time, limit int) ([]models.MatchParticipant, error)
+	// ParticipantsInMatches returns every participant of the stored matches
+	ParticipantsInMatches(ctx context.Context, storedMatchIDs []uint) ([]models.MatchParticipant, error)
+	LatestRankSnapshot(ctx context.Context, userID uint, game, queue string) (*models.RankSnapshot, error)
+	CreateRankSnapshot(ctx context.Context, snapshot *models.RankSnapshot) error
+}
+
+// FeedStore manages activity feed events, reactions and comments
+type FeedStore interface {
+	// CreateEvent stores an event unless one with the same DedupeKey exists
+	CreateEvent(ctx context.Context, event *models.FeedEvent) error
+	GetEvent(ctx context.Context, id uint) (*models.FeedEvent, error)
+	// ListEvents returns the rows keyset selects from the users' events, sorted by occurred_at
+	ListEvents(ctx context.Context, userIDs []uint, keyset pagination.Keyset) ([]models.FeedEvent, error)
+	// AddReaction stores a reaction; adding the same reaction twice is a no-op
+	AddReaction(ctx context.Context, reaction *models.FeedReaction) error
+	RemoveReaction(ctx context.Context, eventID, userID uint, kind string) error
+	// ReactionCounts returns counts per event and reaction kind
+	ReactionCounts(ctx context.Context, eventIDs []uint) (map[uint]map[string]int, error)
+	CreateComment(ctx context.Context, comment *models.FeedComment) error
+	// ListComments returns an event's comments, oldest first
+	ListComments(ctx context.Context, eventID uint) ([]models.FeedComment, error)
+	CommentCounts(ctx context.Context, eventIDs []uint) (map[uint]int, error)
+}
+
+// SquadStore manages squads, members and invites
+type SquadStore interface {
+	// Create stores a squad together with its initial members
+	Create(ctx context.Context, squad *models.Squad) error
+	// Get loads a squad with its members
+	Get(ctx context.Context, id uint) (*models.Squad, error)
+	// ListForMember returns the squads the user belongs to, with members
+	ListForMember(ctx context.Context, userID uint) ([]models.Squad, error)
+	// Delete removes a squad with its members and invites
+	Delete(ctx context.Context, id uint) error
+	RemoveMember(ctx context.Context, squadID, userID uint) error
+	CreateInvite(ctx context.Context, invite *models.SquadInvite) error
+	// FindPendingInvite returns the pending invite for a user to a squad
+	FindPendingInvite(ctx context.Context, squadID, inviteeID uint) (*models.SquadInvite, error)
+	// GetPendingInvite returns a pending invite addressed to the user
+	GetPendingInvite(ctx context.Context, id, inviteeID uint) (*models.SquadInvite, error)
+	ListPendingInvites(ctx context.Context, inviteeID uint) ([]models.SquadInvite, error)
+	// RespondToInvite records the answer and, on accept, adds the member if the squad has room
+	RespondToInvite(ctx context.Context, invite *models.SquadInvite, accept bool, maxSize int) error
+}
+
+// replacesOwner reports whether link may take its account from owner, another
+// user's link to it. Proving ownership beats a link anyone could have typed in.
+func replacesOwner(link, owner *models.PlatformLink) bool {
+	return link.Verification == models.LinkVerified && owner.Verification != models.LinkVerified
+}
+
+// MergePlatformLink prepares link to replace existing, the user's current link
+// for the platform (nil if there is none). Re-linking the same account keeps
+// details the new link doesn't know about and never downgrades a verified link.
+func MergePlatformLink(link, existing *models.PlatformLink, now time.Time) {
+	if existing == nil {
+		link.LinkedAt = now
+		return
+	}
+
+	if existing.ExternalID == link.ExternalID {
+		if link.DisplayName == "" {
+			link.DisplayName = existing.DisplayName
+		}
+		if link.Region == "" {
+			link.Region = existing.Region
+		}
+		if existing.Verification == models.LinkVerified {
+			link.Verification = models.LinkVerified
+		}
+		link.LinkedAt = existing.LinkedAt
+	} else {
+		link.LinkedAt = now
+	}
+
+	link.ID = existing.ID
+	link.CreatedAt = existing.CreatedAt
+}
@@ -0,0 +1,135 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// Latency buckets in seconds, from fast cache hits to slow third-party APIs
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Instruments are created from the global meter provider, which forwards to
+// the real one once InitMetrics sets it, so recording before then is safe.
+var (
+	meter = otel.Meter("elo-insight-backend")
+
+	httpDuration       = must(meter.Float64Histogram("http.server.request.duration", metric.WithUnit("s"), metric.WithDescription("Duration of HTTP requests by route and status"), metric.WithExplicitBucketBoundaries(durationBuckets...)))
+	httpActive         = must(meter.Int64UpDownCounter("http.server.active_requests", metric.WithDescription("HTTP requests currently being served")))
+	upstreamDuration   = must(meter.Float64Histogram("upstream.request.duration", metric.WithUnit("s"), metric.WithDescription("Duration of calls to third-party APIs by provider and status"), metric.WithExplicitBucketBoundaries(durationBuckets...)))
+	jobDuration        = must(meter.Float64Histogram("job.duration", metric.WithUnit("s"), metric.WithDescription("Duration of background jobs by name and outcome"), metric.WithExplicitBucketBoundaries(durationBuckets...)))
+	registrations      = must(meter.Int64Counter("users.registrations", metric.WithDescription("Users registered")))
+	platformLinks      = must(meter.Int64Counter("platform.links", metric.WithDescription("Gaming accounts linked or unlinked, by platform")))
+	friendRequestCount = must(meter.Int64Counter("friend.requests", metric.WithDescription("Friend requests by action")))
+	rateLimited        = must(meter.Int64Counter("http.server.rate_limited", metric.WithDescription("Requests rejected by a rate limit, by policy")))
+)
+
+// must panics if an instrument can't be created, which only happens for invalid names
+func must[T any](instrument T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return instrument
+}
+
+// InitMetrics installs a meter provider that exports to Prometheus and returns
+// the handler for /metrics plus a shutdown function. The registry also
+// carries Go runtime and process metrics.
+func InitMetrics(res *resource.Resource) (http.Handler, func(context.Context) error, error) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	exporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+	if err != nil {
+		return nil, nil, err
+	}
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(exporter),
+		sdkmetric.WithResource(res),
+	)
+	otel.SetMeterProvider(provider)
+
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{}), provider.Shutdown, nil
+}
+
+// Meter returns the application's meter, for packages that register their own observable instruments
+func Meter() metric.Meter {
+	return meter
+}
+
+// RecordHTTPRequest records one served request. route is the route pattern,
+// not the raw path, so IDs in URLs don't explode the number of series.
+func RecordHTTPRequest(ctx context.Context, method, route string, status int, elapsed time.Duration) {
+	httpDuration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(
+		attribute.String("http.request.method", method),
+		attribute.String("http.route", route),
+		attribute.Int("http.response.status_code", status),
+	))
+}
+
+// TrackActiveRequest counts a request as in flight until the returned function is called
+func TrackActiveRequest(ctx context.Context, method string) func() {
+	attrs := metric.WithAttributes(attribute.String("http.request.method", method))
+	httpActive.Add(ctx, 1, attrs)
+	return func() { httpActive.Add(ctx, -1, attrs) }
+}
+
+// RecordUpstreamRequest records one call to a third-party API. status is the
+// HTTP status code, or "error" when no response arrived.
+func RecordUpstreamRequest(ctx context.Context, provider, status string, elapsed time.Duration) {
+	upstreamDuration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(
+		attribute.String("provider", provider),
+		attribute.String("status", status),
+	))
+}
+
+// TimeJob runs a background job and records how long it took and whether it failed
+func TimeJob(ctx context.Context, name string, job func() error) error {
+	start := time.Now()
+	err := job()
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	jobDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+		attribute.String("job", name),
+		attribute.String("outcome", outcome),
+	))
+	return err
+}
+
+// CountRegistration counts a newly registered user
+func CountRegistration(ctx context.Context) {
+	registrations.Add(ctx, 1)
+}
+
+// CountPlatformLink counts an account being linked or unlinked ("link" or "unlink")
+func CountPlatformLink(ctx context.Context, platform, action string) {
+	platformLinks.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("platform", platform),
+		attribute.String("action", action),
+	))
+}
+
+// CountFriendRequest counts a friend request being sent, accepted, declined or cancelled
+func CountFriendRequest(ctx context.Context, action string) {
+	friendRequestCount.Add(ctx, 1, metric.WithAttributes(attribute.String("action", action)))
+}
+
+// CountRateLimited counts a request rejected by a rate limit policy
+func CountRateLimited(ctx context.Context, policy string) {
+	rateLimited.Add(ctx, 1, metric.WithAttributes(attribute.String("policy", policy)))
+}
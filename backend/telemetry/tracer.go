@@ -2,7 +2,7 @@ package telemetry
 
 import (
 	"context"
-	"log"
+	"log/slog"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -43,7 +43,7 @@ func RecordError(ctx context.Context, err error, options ...trace.EventOption) {
 
 // LogAndRecordError logs an error and records it on the span
 func LogAndRecordError(ctx context.Context, message string, err error) {
-	log.Printf("%s: %v", message, err)
+	slog.ErrorContext(ctx, message, "error", err)
 	RecordError(ctx, err)
 }
 
@@ -20,11 +20,10 @@ const (
 )
 
 // TraceRegistrationFlow creates detailed spans and events for the user registration process
-func TraceRegistrationFlow(ctx context.Context, email, username string) context.Context {
+func TraceRegistrationFlow(ctx context.Context, username string) context.Context {
 	// Start the parent span for the entire registration process
 	ctx, span := StartSpan(ctx, "user.registration.flow")
 	span.SetAttributes(
-		attribute.String("user.email", email),
 		attribute.String("user.username", username),
 		attribute.String("event.name", "user_registration"),
 		attribute.String("event.domain", "auth"),
@@ -0,0 +1,114 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the host while its breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// outcome is how an attempt counts towards a breaker
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	outcomeIgnored // e.g. the caller gave up; says nothing about the host
+)
+
+// breaker stops calls to a host after repeated failures. After a cooldown it
+// lets one trial request through: success closes it, failure reopens it.
+type breaker struct {
+	host, provider string
+	registry       *Registry
+	now            func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	trial    bool // A half-open trial request is in flight
+}
+
+// allow reports whether a request may go to the host
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < breakerCooldown {
+			return fmt.Errorf("%s: %w", b.host, ErrCircuitOpen)
+		}
+		b.setState(BreakerHalfOpen)
+		b.trial = true
+		return nil
+	case BreakerHalfOpen:
+		if b.trial {
+			return fmt.Errorf("%s: %w", b.host, ErrCircuitOpen)
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+// record updates the breaker with the outcome of an allowed request
+func (b *breaker) record(result outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasTrial := b.trial
+	b.trial = false
+	switch {
+	case result == outcomeIgnored:
+	case result == outcomeSuccess:
+		b.failures = 0
+		b.setState(BreakerClosed)
+	case wasTrial || b.state == BreakerHalfOpen:
+		b.openedAt = b.now()
+		b.setState(BreakerOpen)
+	default:
+		b.failures++
+		if b.failures >= breakerThreshold {
+			b.openedAt = b.now()
+			b.setState(BreakerOpen)
+		}
+	}
+}
+
+// setState changes state and reports it; the caller holds the lock
+func (b *breaker) setState(state string) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	if b.provider != "" {
+		b.registry.SetBreakerState(b.provider, b.host, state)
+	}
+}
+
+// breakers holds one breaker per host
+type breakers struct {
+	registry *Registry
+	now      func() time.Time
+	mu       sync.Mutex
+	byHost   map[string]*breaker
+}
+
+// forHost returns the host's breaker, creating a closed one on first use
+func (bs *breakers) forHost(host, provider string) *breaker {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if b, ok := bs.byHost[host]; ok {
+		return b
+	}
+	if bs.byHost == nil {
+		bs.byHost = make(map[string]*breaker)
+	}
+	b := &breaker{host: host, provider: provider, registry: bs.registry, now: bs.now, state: BreakerClosed}
+	bs.byHost[host] = b
+	return b
+}
@@ -0,0 +1,142 @@
+package upstream
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	// Each step either waits, asks allow, or records an outcome, then checks the state
+	type step struct {
+		wait      time.Duration
+		allow     bool
+		wantDeny  bool // allow returned ErrCircuitOpen
+		record    *outcome
+		wantState string
+	}
+	success, failure, ignored := outcomeSuccess, outcomeFailure, outcomeIgnored
+	failures := func(n int, state string) []step {
+		steps := make([]step, n)
+		for i := range steps {
+			steps[i] = step{allow: true, record: &failure, wantState: BreakerClosed}
+		}
+		steps[n-1].wantState = state
+		return steps
+	}
+	opened := func() []step {
+		return append(failures(breakerThreshold-1, BreakerClosed), step{allow: true, record: &failure, wantState: BreakerOpen})
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"failures below the threshold", failures(breakerThreshold-1, BreakerClosed)},
+		{"a success resets the count", append(append(failures(breakerThreshold-1, BreakerClosed),
+			step{allow: true, record: &success, wantState: BreakerClosed}),
+			failures(breakerThreshold-1, BreakerClosed)...)},
+		{"threshold opens it", opened()},
+		{"open fails fast until the cooldown", append(opened(),
+			step{allow: true, wantDeny: true, wantState: BreakerOpen},
+			step{wait: breakerCooldown - time.Second, allow: true, wantDeny: true, wantState: BreakerOpen},
+			step{wait: time.Second, allow: true, wantState: BreakerHalfOpen},
+		)},
+		{"one trial at a time", append(opened(),
+			step{wait: breakerCooldown, allow: true, wantState: BreakerHalfOpen},
+			step{allow: true, wantDeny: true, wantState: BreakerHalfOpen},
+		)},
+		{"failed trial reopens for another cooldown", append(opened(),
+			step{wait: breakerCooldown, allow: true, record: &failure, wantState: BreakerOpen},
+			step{wait: breakerCooldown - time.Second, allow: true, wantDeny: true, wantState: BreakerOpen},
+			step{wait: time.Second, allow: true, wantState: BreakerHalfOpen},
+		)},
+		{"successful trial closes it", append(opened(),
+			step{wait: breakerCooldown, allow: true, record: &success, wantState: BreakerClosed},
+			step{allow: true, wantState: BreakerClosed},
+		)},
+		{"ignored trial allows another", append(opened(),
+			step{wait: breakerCooldown, allow: true, record: &ignored, wantState: BreakerHalfOpen},
+			step{allow: true, record: &success, wantState: BreakerClosed},
+		)},
+		{"ignored outcomes don't count", append(failures(breakerThreshold-1, BreakerClosed),
+			step{allow: true, record: &ignored, wantState: BreakerClosed},
+			step{allow: true, record: &failure, wantState: BreakerOpen},
+		)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock()
+			registry := NewRegistry()
+			b := (&breakers{registry: registry, now: clock.now}).forHost("europe.api.riotgames.com", Riot)
+
+			for i, s := range tt.steps {
+				clock.advance(s.wait)
+				if s.allow {
+					err := b.allow()
+					if denied := errors.Is(err, ErrCircuitOpen); denied != s.wantDeny {
+						t.Fatalf("step %d: allow = %v, want denied: %v", i, err, s.wantDeny)
+					}
+				}
+				if s.record != nil {
+					b.record(*s.record)
+				}
+				if b.state != s.wantState {
+					t.Fatalf("step %d: state = %s, want %s", i, b.state, s.wantState)
+				}
+			}
+
+			// The provider's status shows the host's state
+			if got := breakerStateOf(registry, Riot); got != b.state {
+				t.Errorf("registry breaker state = %s, want %s", got, b.state)
+			}
+		})
+	}
+}
+
+// breakerStateOf returns the breaker state a registry reports for a provider
+func breakerStateOf(registry *Registry, provider string) string {
+	for _, status := range registry.Snapshot() {
+		if status.Provider == provider {
+			return status.BreakerState
+		}
+	}
+	return ""
+}
+
+func TestBreakerInTransport(t *testing.T) {
+	withDefaultPolicy(t, func(p *Policy) { p.MaxRetries = 2 })
+	clock := newFakeClock()
+	upstream := &fakeUpstream{replies: []reply{status(500)}}
+	transport := newTestTransport(upstream, clock)
+	get := func() (*http.Response, error) {
+		req, _ := http.NewRequest(http.MethodGet, testURL, nil)
+		resp, err := transport.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	// Three failed attempts, then two more open the breaker mid-retry
+	if resp, err := get(); err != nil || resp.StatusCode != 500 {
+		t.Fatalf("first request = %v, %v; want 500", resp, err)
+	}
+	if _, err := get(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second request = %v, want ErrCircuitOpen", err)
+	}
+	if upstream.attempts != breakerThreshold {
+		t.Errorf("attempts = %d, want %d", upstream.attempts, breakerThreshold)
+	}
+
+	// After the cooldown a trial gets through and closes it
+	clock.advance(breakerCooldown)
+	upstream.replies = []reply{status(200)}
+	if resp, err := get(); err != nil || resp.StatusCode != 200 {
+		t.Fatalf("request after cooldown = %v, %v; want 200", resp, err)
+	}
+	if upstream.attempts != breakerThreshold+1 {
+		t.Errorf("attempts = %d, want %d", upstream.attempts, breakerThreshold+1)
+	}
+}
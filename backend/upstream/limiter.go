@@ -0,0 +1,77 @@
+package upstream
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limiter keeps calls to one provider under its rate limit. A 429's
+// Retry-After pauses every caller, not just the one that got it.
+type limiter struct {
+	rate *rate.Limiter
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+// wait blocks until a request may be sent or ctx is done
+func (l *limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	pause := time.Until(l.pausedUntil)
+	l.mu.Unlock()
+
+	if pause > 0 {
+		timer := time.NewTimer(pause)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return l.rate.Wait(ctx)
+}
+
+// pause holds back every request until the given time
+func (l *limiter) pause(until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*limiter{}
+)
+
+// limiterFor returns the provider's limiter, or nil if its calls aren't limited
+func limiterFor(provider string) *limiter {
+	policy := policyFor(provider)
+	if provider == "" || policy.RequestsPerSecond <= 0 {
+		return nil
+	}
+
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	l, ok := limiters[provider]
+	if !ok {
+		l = &limiter{rate: rate.NewLimiter(rate.Limit(policy.RequestsPerSecond), policy.Burst)}
+		limiters[provider] = l
+	}
+	return l
+}
+
+// updateLimiter applies a changed policy to the provider's limiter, if it has one yet
+func updateLimiter(provider string, policy Policy) {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	if l, ok := limiters[provider]; ok {
+		l.rate.SetLimit(rate.Limit(policy.RequestsPerSecond))
+		l.rate.SetBurst(policy.Burst)
+	}
+}
@@ -0,0 +1,83 @@
+package upstream
+
+import (
+	"sync"
+	"time"
+
+	"elo-insight/backend/config"
+)
+
+// Policy is how calls to one provider are paced, timed out and retried
+type Policy struct {
+	Timeout           time.Duration // Per attempt, including reading the body
+	MaxRetries        int           // Retries after the first attempt, for idempotent requests only
+	BaseBackoff       time.Duration // Backoff before the first retry; doubles each time, with jitter
+	MaxBackoff        time.Duration // Longest wait between attempts, including Retry-After
+	RequestsPerSecond float64       // Attempts are held back beyond this rate; zero means unlimited
+	Burst             int           // Attempts allowed at once before the rate applies
+}
+
+// Circuit breaker settings, shared by every host
+const (
+	breakerThreshold = 5                // Consecutive failures that open the breaker
+	breakerCooldown  = 30 * time.Second // How long it stays open before a trial request
+)
+
+// defaultPolicy applies to hosts that don't belong to a known provider
+var defaultPolicy = Policy{Timeout: 10 * time.Second, MaxRetries: 2, BaseBackoff: 200 * time.Millisecond, MaxBackoff: 5 * time.Second}
+
+var (
+	policiesMu sync.RWMutex
+	policies   = map[string]Policy{
+		Steam: {Timeout: 10 * time.Second, MaxRetries: 2, BaseBackoff: 200 * time.Millisecond, MaxBackoff: 5 * time.Second,
+			RequestsPerSecond: 10, Burst: 10},
+		// Riot development keys allow 20 requests per second
+		Riot: {Timeout: 10 * time.Second, MaxRetries: 2, BaseBackoff: 200 * time.Millisecond, MaxBackoff: 5 * time.Second,
+			RequestsPerSecond: 20, Burst: 20},
+		Tracker: {Timeout: 15 * time.Second, MaxRetries: 2, BaseBackoff: 500 * time.Millisecond, MaxBackoff: 5 * time.Second,
+			RequestsPerSecond: 2, Burst: 4},
+	}
+)
+
+// Init applies the configured per-provider timeouts and the Riot rate limit
+func Init(cfg *config.Config) {
+	timeouts := map[string]time.Duration{
+		Steam:   cfg.Steam.Timeout,
+		Riot:    cfg.Riot.Timeout,
+		Tracker: cfg.Tracker.Timeout,
+	}
+	for provider, timeout := range timeouts {
+		if timeout > 0 {
+			SetPolicy(provider, func(p *Policy) { p.Timeout = timeout })
+		}
+	}
+	if cfg.Riot.RateLimit > 0 {
+		SetPolicy(Riot, func(p *Policy) {
+			p.RequestsPerSecond = float64(cfg.Riot.RateLimit)
+			p.Burst = cfg.Riot.RateLimit
+		})
+	}
+}
+
+// SetPolicy changes a provider's policy
+func SetPolicy(provider string, change func(p *Policy)) {
+	policiesMu.Lock()
+	defer policiesMu.Unlock()
+	policy, ok := policies[provider]
+	if !ok {
+		policy = defaultPolicy
+	}
+	change(&policy)
+	policies[provider] = policy
+	updateLimiter(provider, policy)
+}
+
+// policyFor returns the provider's policy, or the default for unknown hosts
+func policyFor(provider string) Policy {
+	policiesMu.RLock()
+	defer policiesMu.RUnlock()
+	if policy, ok := policies[provider]; ok {
+		return policy
+	}
+	return defaultPolicy
+}
@@ -0,0 +1,177 @@
+package upstream
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// resilientTransport paces attempts to the provider's rate limit, gives every
+// attempt the provider's timeout, retries idempotent requests that failed with
+// a transport error, 5xx or 429, and stops calling hosts whose breaker is open
+type resilientTransport struct {
+	next     http.RoundTripper
+	breakers *breakers
+	now      func() time.Time
+	sleep    func(ctx context.Context, d time.Duration) error // Waits between attempts
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+	provider := ProviderForHost(host)
+	policy := policyFor(provider)
+	breaker := t.breakers.forHost(host, provider)
+
+	limiter := limiterFor(provider)
+
+	for attempt := 0; ; attempt++ {
+		if limiter != nil {
+			if err := limiter.wait(req.Context()); err != nil {
+				return nil, err
+			}
+		}
+		if err := breaker.allow(); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.attempt(req, policy.Timeout)
+		breaker.record(classify(req, resp, err))
+		if limiter != nil && resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+			if retryAt := parseRetryAfter(resp.Header, t.now()); retryAt != nil {
+				limiter.pause(*retryAt)
+			}
+		}
+
+		if attempt >= policy.MaxRetries || !retryable(req, resp, err) {
+			return resp, err
+		}
+		wait, ok := backoff(policy, attempt, resp, t.now())
+		if !ok {
+			// The provider asked us to wait longer than we are willing to
+			return resp, err
+		}
+
+		trace.SpanFromContext(req.Context()).AddEvent("upstream.retry", trace.WithAttributes(
+			attribute.Int("attempt", attempt+1),
+			attribute.String("wait", wait.String()),
+			attribute.String("reason", retryReason(resp, err)),
+		))
+		if resp != nil {
+			// Drain so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		if err := t.sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// attempt sends one copy of req with its own deadline. The deadline is
+// released when the response body is closed, not when headers arrive.
+func (t *resilientTransport) attempt(req *http.Request, timeout time.Duration) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	attemptReq := req.Clone(ctx)
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		attemptReq.Body = body
+	}
+
+	resp, err := t.next.RoundTrip(attemptReq)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// classify decides how an attempt counts towards the host's breaker. 429s
+// mean we are going too fast, not that the host is down.
+func classify(req *http.Request, resp *http.Response, err error) outcome {
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		return outcomeIgnored
+	case err != nil, resp.StatusCode >= 500:
+		return outcomeFailure
+	default:
+		return outcomeSuccess
+	}
+}
+
+// retryable reports whether another attempt might succeed and is safe to send
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		// Only if the caller is still waiting
+		return req.Context().Err() == nil
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// backoff returns how long to wait before the next attempt: exponential with
+// full jitter, or the provider's Retry-After. ok is false if Retry-After is
+// longer than the policy allows.
+func backoff(policy Policy, attempt int, resp *http.Response, now time.Time) (wait time.Duration, ok bool) {
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		if retryAt := parseRetryAfter(resp.Header, now); retryAt != nil {
+			wait = max(retryAt.Sub(now), 0)
+			return wait, wait <= policy.MaxBackoff
+		}
+	}
+
+	ceiling := policy.BaseBackoff << attempt
+	if ceiling <= 0 || ceiling > policy.MaxBackoff {
+		ceiling = policy.MaxBackoff
+	}
+	return time.Duration(rand.Int64N(int64(ceiling) + 1)), true
+}
+
+// retryReason describes a failed attempt for the retry span event
+func retryReason(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return resp.Status
+}
+
+// cancelOnClose releases an attempt's deadline once the body has been read
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,291 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock that only moves when told to or when something sleeps
+type fakeClock struct {
+	mu    sync.Mutex
+	t     time.Time
+	slept []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func (c *fakeClock) sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.slept = append(c.slept, d)
+	c.mu.Unlock()
+	c.advance(d)
+	return nil
+}
+
+// reply answers one attempt
+type reply func(req *http.Request) (*http.Response, error)
+
+// fakeUpstream is a RoundTripper answering each attempt with the next reply,
+// repeating the last one once they run out
+type fakeUpstream struct {
+	replies  []reply
+	attempts int
+}
+
+func (f *fakeUpstream) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := f.replies[min(f.attempts, len(f.replies)-1)]
+	f.attempts++
+	return next(req)
+}
+
+// status replies with an empty response; header is name, value pairs
+func status(code int, header ...string) reply {
+	return func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{
+			StatusCode: code,
+			Status:     fmt.Sprintf("%d %s", code, http.StatusText(code)),
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			resp.Header.Set(header[i], header[i+1])
+		}
+		return resp, nil
+	}
+}
+
+func fail(err error) reply {
+	return func(req *http.Request) (*http.Response, error) {
+		return nil, err
+	}
+}
+
+// newTestTransport sends attempts to next on the fake clock
+func newTestTransport(next http.RoundTripper, clock *fakeClock) *resilientTransport {
+	return &resilientTransport{
+		next:     next,
+		breakers: &breakers{registry: NewRegistry(), now: clock.now},
+		now:      clock.now,
+		sleep:    clock.sleep,
+	}
+}
+
+// withDefaultPolicy changes the policy for unknown hosts until the test ends
+func withDefaultPolicy(t *testing.T, change func(p *Policy)) {
+	t.Helper()
+	saved := defaultPolicy
+	change(&defaultPolicy)
+	t.Cleanup(func() { defaultPolicy = saved })
+}
+
+// Requests go to an unknown host, so they get defaultPolicy and no rate limit
+const testURL = "http://api.example.com/players"
+
+func TestRetry(t *testing.T) {
+	withDefaultPolicy(t, func(p *Policy) {
+		*p = Policy{Timeout: time.Second, MaxRetries: 2, BaseBackoff: 100 * time.Millisecond, MaxBackoff: 5 * time.Second}
+	})
+	errReset := errors.New("connection reset by peer")
+	clock := newFakeClock()
+	httpDate := clock.now().Add(3 * time.Second).Format(http.TimeFormat)
+
+	tests := []struct {
+		name         string
+		method       string
+		replies      []reply
+		wantAttempts int
+		wantStatus   int // 0 if the request fails
+		// Each wait between attempts: the exact wait, or its ceiling if jittered
+		waits    []time.Duration
+		jittered bool
+	}{
+		{"success", http.MethodGet, []reply{status(200)}, 1, 200, nil, false},
+		{"5xx then success", http.MethodGet, []reply{status(503), status(200)}, 2, 200,
+			[]time.Duration{100 * time.Millisecond}, true},
+		{"transport error then success", http.MethodGet, []reply{fail(errReset), status(200)}, 2, 200,
+			[]time.Duration{100 * time.Millisecond}, true},
+		{"backoff doubles until retries run out", http.MethodGet, []reply{status(500)}, 3, 500,
+			[]time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, true},
+		{"transport error every time", http.MethodGet, []reply{fail(errReset)}, 3, 0,
+			[]time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, true},
+		{"4xx is final", http.MethodGet, []reply{status(404)}, 1, 404, nil, false},
+		{"POST is never retried", http.MethodPost, []reply{status(503)}, 1, 503, nil, false},
+		{"429 waits Retry-After seconds", http.MethodGet, []reply{status(429, "Retry-After", "2"), status(200)}, 2, 200,
+			[]time.Duration{2 * time.Second}, false},
+		{"429 waits until a Retry-After date", http.MethodGet, []reply{status(429, "Retry-After", httpDate), status(200)}, 2, 200,
+			[]time.Duration{3 * time.Second}, false},
+		{"429 without Retry-After backs off", http.MethodGet, []reply{status(429), status(200)}, 2, 200,
+			[]time.Duration{100 * time.Millisecond}, true},
+		{"Retry-After longer than MaxBackoff returns the 429", http.MethodGet, []reply{status(429, "Retry-After", "60")}, 1, 429, nil, false},
+		{"Retry-After on a 503 is ignored", http.MethodGet, []reply{status(503, "Retry-After", "60"), status(200)}, 2, 200,
+			[]time.Duration{100 * time.Millisecond}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock()
+			upstream := &fakeUpstream{replies: tt.replies}
+			req, _ := http.NewRequest(tt.method, testURL, nil)
+
+			resp, err := newTestTransport(upstream, clock).RoundTrip(req)
+			if tt.wantStatus == 0 {
+				if err == nil {
+					t.Errorf("status = %d, want an error", resp.StatusCode)
+				}
+			} else if err != nil {
+				t.Fatalf("RoundTrip: %v", err)
+			} else {
+				resp.Body.Close()
+				if resp.StatusCode != tt.wantStatus {
+					t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+				}
+			}
+
+			if upstream.attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", upstream.attempts, tt.wantAttempts)
+			}
+			if len(clock.slept) != len(tt.waits) {
+				t.Fatalf("waits = %v, want %d of them", clock.slept, len(tt.waits))
+			}
+			for i, wait := range clock.slept {
+				if tt.jittered && (wait < 0 || wait > tt.waits[i]) || !tt.jittered && wait != tt.waits[i] {
+					t.Errorf("wait %d = %v, want %v (jittered: %v)", i, wait, tt.waits[i], tt.jittered)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryStopsWhenCallerGivesUp(t *testing.T) {
+	clock := newFakeClock()
+	upstream := &fakeUpstream{replies: []reply{status(503)}}
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, testURL, nil)
+	transport := newTestTransport(upstream, clock)
+	transport.sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return clock.sleep(ctx, d)
+	}
+
+	if _, err := transport.RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("RoundTrip = %v, want context.Canceled", err)
+	}
+	if upstream.attempts != 1 {
+		t.Errorf("attempts = %d, want 1", upstream.attempts)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := Policy{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		attempt  int
+		resp     *http.Response
+		want     time.Duration // The exact wait, or its ceiling if jittered
+		jittered bool
+		wantOK   bool
+	}{
+		{"first retry", 0, nil, 100 * time.Millisecond, true, true},
+		{"third retry", 2, nil, 400 * time.Millisecond, true, true},
+		{"capped at MaxBackoff", 5, nil, time.Second, true, true},
+		{"shift overflow is capped", 70, nil, time.Second, true, true},
+		{"5xx ignores Retry-After", 0, &http.Response{StatusCode: 503, Header: http.Header{"Retry-After": {"1"}}}, 100 * time.Millisecond, true, true},
+		{"429 Retry-After seconds", 0, &http.Response{StatusCode: 429, Header: http.Header{"Retry-After": {"1"}}}, time.Second, false, true},
+		{"429 Retry-After in the past", 0, &http.Response{StatusCode: 429, Header: http.Header{"Retry-After": {now.Add(-time.Minute).Format(http.TimeFormat)}}}, 0, false, true},
+		{"429 Retry-After too long", 0, &http.Response{StatusCode: 429, Header: http.Header{"Retry-After": {"2"}}}, 2 * time.Second, false, false},
+		{"429 unparseable Retry-After", 1, &http.Response{StatusCode: 429, Header: http.Header{"Retry-After": {"soon"}}}, 200 * time.Millisecond, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := map[time.Duration]bool{}
+			for range 100 {
+				wait, ok := backoff(policy, tt.attempt, tt.resp, now)
+				if ok != tt.wantOK {
+					t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+				}
+				if tt.jittered && (wait < 0 || wait > tt.want) || !tt.jittered && wait != tt.want {
+					t.Fatalf("wait = %v, want %v (jittered: %v)", wait, tt.want, tt.jittered)
+				}
+				seen[wait] = true
+			}
+			if tt.jittered && len(seen) < 2 {
+				t.Errorf("100 waits were all %v, want them spread up to %v", seen, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttemptTimeout(t *testing.T) {
+	t.Run("lasts until the body is closed", func(t *testing.T) {
+		var attemptCtx context.Context
+		upstream := &fakeUpstream{replies: []reply{func(req *http.Request) (*http.Response, error) {
+			attemptCtx = req.Context()
+			return status(200)(req)
+		}}}
+		req, _ := http.NewRequest(http.MethodGet, testURL, nil)
+		start := time.Now()
+
+		resp, err := newTestTransport(upstream, newFakeClock()).RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip: %v", err)
+		}
+		deadline, ok := attemptCtx.Deadline()
+		if timeout := policyFor("").Timeout; !ok || deadline.Before(start.Add(timeout)) || deadline.After(time.Now().Add(timeout)) {
+			t.Errorf("attempt deadline = %v (set: %v), want the policy's %v from the start", deadline, ok, timeout)
+		}
+		// Still live while the caller reads the body
+		if err := attemptCtx.Err(); err != nil {
+			t.Fatalf("attempt context after headers = %v, want live", err)
+		}
+		resp.Body.Close()
+		if err := attemptCtx.Err(); !errors.Is(err, context.Canceled) {
+			t.Errorf("attempt context after Close = %v, want context.Canceled", err)
+		}
+	})
+
+	t.Run("ends a hung attempt and retries", func(t *testing.T) {
+		withDefaultPolicy(t, func(p *Policy) { p.Timeout = 10 * time.Millisecond })
+		upstream := &fakeUpstream{replies: []reply{
+			func(req *http.Request) (*http.Response, error) {
+				<-req.Context().Done()
+				return nil, req.Context().Err()
+			},
+			status(200),
+		}}
+		req, _ := http.NewRequest(http.MethodGet, testURL, nil)
+
+		resp, err := newTestTransport(upstream, newFakeClock()).RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip: %v", err)
+		}
+		resp.Body.Close()
+		if upstream.attempts != 2 || resp.StatusCode != 200 {
+			t.Errorf("attempts = %d, status = %d; want 2 and 200", upstream.attempts, resp.StatusCode)
+		}
+	})
+}
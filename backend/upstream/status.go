@@ -0,0 +1,229 @@
+package upstream
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"    // Requests flow normally
+	BreakerOpen     = "open"      // Requests fail fast without reaching the provider
+	BreakerHalfOpen = "half-open" // A trial request is allowed through
+)
+
+// RateLimit is the provider's rate-limit window with the least headroom, as of the last response
+type RateLimit struct {
+	Limit      int       `json:"limit"`
+	Remaining  int       `json:"remaining"`
+	Window     string    `json:"window,omitempty"` // e.g. "120s"
+	ObservedAt time.Time `json:"observedAt"`
+	// RetryAfter is when the provider said to try again after a 429
+	RetryAfter *time.Time `json:"retryAfter,omitempty"`
+}
+
+// ProviderStatus is what we know about one provider from recent calls
+type ProviderStatus struct {
+	Provider    string     `json:"provider"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	LastFailure *time.Time `json:"lastFailure,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+	Successes   int64      `json:"successes"`
+	Failures    int64      `json:"failures"`
+	RateLimit   *RateLimit `json:"rateLimit,omitempty"`
+	// BreakerState is the least healthy of the provider's hosts' breakers
+	BreakerState string            `json:"circuitBreaker"`
+	Breakers     map[string]string `json:"breakers,omitempty"` // Breaker state by host
+}
+
+// Registry tracks the status of each provider
+type Registry struct {
+	mu       sync.Mutex
+	statuses map[string]*ProviderStatus
+	now      func() time.Time
+}
+
+// Default is the registry Client reports to
+var Default = NewRegistry()
+
+// NewRegistry returns a registry with every provider's breaker closed
+func NewRegistry() *Registry {
+	r := &Registry{statuses: make(map[string]*ProviderStatus), now: time.Now}
+	for _, provider := range Providers {
+		r.statuses[provider] = &ProviderStatus{Provider: provider, BreakerState: BreakerClosed}
+	}
+	return r
+}
+
+// status returns the provider's entry, creating it if needed; the caller holds the lock
+func (r *Registry) status(provider string) *ProviderStatus {
+	status, ok := r.statuses[provider]
+	if !ok {
+		status = &ProviderStatus{Provider: provider, BreakerState: BreakerClosed}
+		r.statuses[provider] = status
+	}
+	return status
+}
+
+// Record notes the outcome of one call. Transport errors, 5xx and 429
+// responses count as failures; other responses mean the provider is up.
+func (r *Registry) Record(provider string, resp *http.Response, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	status := r.status(provider)
+	switch {
+	case err != nil:
+		status.Failures++
+		status.LastFailure = &now
+		status.LastError = err.Error()
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		status.Failures++
+		status.LastFailure = &now
+		status.LastError = resp.Status
+	default:
+		status.Successes++
+		status.LastSuccess = &now
+	}
+
+	if resp != nil {
+		if limit := parseRateLimit(resp.Header, now); limit != nil {
+			if resp.StatusCode == http.StatusTooManyRequests {
+				limit.RetryAfter = parseRetryAfter(resp.Header, now)
+			}
+			status.RateLimit = limit
+		} else if resp.StatusCode == http.StatusTooManyRequests && status.RateLimit != nil {
+			status.RateLimit.RetryAfter = parseRetryAfter(resp.Header, now)
+		}
+	}
+}
+
+// Breaker states from healthiest to least healthy
+var breakerSeverity = map[string]int{BreakerClosed: 0, BreakerHalfOpen: 1, BreakerOpen: 2}
+
+// SetBreakerState records the circuit breaker state of one of a provider's hosts
+func (r *Registry) SetBreakerState(provider, host, state string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := r.status(provider)
+	if status.Breakers == nil {
+		status.Breakers = make(map[string]string)
+	}
+	status.Breakers[host] = state
+
+	status.BreakerState = BreakerClosed
+	for _, hostState := range status.Breakers {
+		if breakerSeverity[hostState] > breakerSeverity[status.BreakerState] {
+			status.BreakerState = hostState
+		}
+	}
+}
+
+// Snapshot returns a copy of every provider's status, known providers first
+func (r *Registry) Snapshot() []ProviderStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	statuses := make([]ProviderStatus, 0, len(r.statuses))
+	for _, provider := range Providers {
+		statuses = append(statuses, copyStatus(r.statuses[provider]))
+	}
+	for provider, status := range r.statuses {
+		if !isKnown(provider) {
+			statuses = append(statuses, copyStatus(status))
+		}
+	}
+	return statuses
+}
+
+// isKnown reports whether provider is one of Providers
+func isKnown(provider string) bool {
+	for _, known := range Providers {
+		if known == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// copyStatus copies a status so callers can't race with Record
+func copyStatus(status *ProviderStatus) ProviderStatus {
+	copied := *status
+	if status.RateLimit != nil {
+		limit := *status.RateLimit
+		copied.RateLimit = &limit
+	}
+	if status.Breakers != nil {
+		copied.Breakers = make(map[string]string, len(status.Breakers))
+		for host, state := range status.Breakers {
+			copied.Breakers[host] = state
+		}
+	}
+	return copied
+}
+
+// parseRateLimit reads the rate-limit headers providers send and returns the
+// window with the least headroom, or nil if there are none. Riot sends
+// X-App-Rate-Limit: "20:1,100:120" (requests:seconds) with a matching
+// X-App-Rate-Limit-Count; others send X-RateLimit-Limit and X-RateLimit-Remaining.
+func parseRateLimit(header http.Header, now time.Time) *RateLimit {
+	if limits, counts := header.Get("X-App-Rate-Limit"), header.Get("X-App-Rate-Limit-Count"); limits != "" && counts != "" {
+		used := make(map[string]int)
+		for _, window := range strings.Split(counts, ",") {
+			count, seconds, ok := strings.Cut(strings.TrimSpace(window), ":")
+			if n, err := strconv.Atoi(count); ok && err == nil {
+				used[seconds] = n
+			}
+		}
+
+		var tightest *RateLimit
+		for _, window := range strings.Split(limits, ",") {
+			limitText, seconds, ok := strings.Cut(strings.TrimSpace(window), ":")
+			limit, err := strconv.Atoi(limitText)
+			if !ok || err != nil || limit <= 0 {
+				continue
+			}
+			candidate := &RateLimit{Limit: limit, Remaining: limit - used[seconds], Window: seconds + "s", ObservedAt: now}
+			if tightest == nil || headroom(candidate) < headroom(tightest) {
+				tightest = candidate
+			}
+		}
+		return tightest
+	}
+
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return nil
+	}
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return nil
+	}
+	return &RateLimit{Limit: limit, Remaining: remaining, ObservedAt: now}
+}
+
+// headroom is the fraction of a window still available
+func headroom(limit *RateLimit) float64 {
+	return float64(limit.Remaining) / float64(limit.Limit)
+}
+
+// parseRetryAfter reads Retry-After as seconds or an HTTP date
+func parseRetryAfter(header http.Header, now time.Time) *time.Time {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return nil
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		at := now.Add(time.Duration(seconds) * time.Second)
+		return &at
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return &at
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+// Package upstream is how the backend talks to third-party APIs. Every
+// outbound request goes through Client, whose transport stack, from the
+// outside in:
+//
+//   - traces the call as a child span of the inbound request (otelhttp)
+//   - applies the provider's per-attempt timeout, retries idempotent requests
+//     on transport errors, 5xx and 429 with jittered backoff, and fails fast
+//     while the host's circuit breaker is open
+//   - records each attempt's outcome and rate-limit headers per provider, for
+//     the provider status endpoint and metrics
+//
+// Requests should be built with http.NewRequestWithContext and the inbound
+// request's context, so a client that goes away cancels its upstream calls.
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"elo-insight/backend/logging"
+	"elo-insight/backend/telemetry"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Provider names
+const (
+	Steam   = "steam"
+	Riot    = "riot"
+	Tracker = "tracker.gg"
+)
+
+// Providers lists every provider in display order
+var Providers = []string{Steam, Riot, Tracker}
+
+// Client is the HTTP client for all third-party API calls
+var Client = &http.Client{Transport: newTransport(Default)}
+
+// newTransport builds the transport stack reporting to registry
+func newTransport(registry *Registry) http.RoundTripper {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.DialContext = (&net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	base.TLSHandshakeTimeout = 5 * time.Second
+	base.MaxIdleConnsPerHost = 20
+
+	resilient := &resilientTransport{
+		next:     &recordingTransport{base: base, registry: registry},
+		breakers: &breakers{registry: registry, now: time.Now},
+		now:      time.Now,
+		sleep:    sleep,
+	}
+	return otelhttp.NewTransport(resilient,
+		// Third parties don't take part in our traces, so don't send them trace headers
+		otelhttp.WithPropagators(propagation.NewCompositeTextMapPropagator()),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			provider := ProviderForHost(r.URL.Hostname())
+			if provider == "" {
+				provider = r.URL.Hostname()
+			}
+			return "upstream " + provider + " " + r.Method
+		}),
+	)
+}
+
+// Get is Client.Get bound to ctx
+func Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return Client.Do(req)
+}
+
+// PostForm is Client.PostForm bound to ctx
+func PostForm(ctx context.Context, url string, data neturl.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return Client.Do(req)
+}
+
+// StatusError is returned by GetBody and GetJSON when the provider answers with a non-2xx status
+type StatusError struct {
+	StatusCode int
+	Body       string        // The start of the body, for logs
+	RetryAfter time.Duration // From a 429's Retry-After, if any
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("upstream returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// GetBody fetches url with the given headers and returns the body of a 2xx response
+func GetBody(ctx context.Context, url string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		statusErr := &StatusError{StatusCode: resp.StatusCode, Body: logging.BodySnippet(body)}
+		if retryAt := parseRetryAfter(resp.Header, time.Now()); retryAt != nil {
+			statusErr.RetryAfter = max(time.Until(*retryAt), 0)
+		}
+		return nil, statusErr
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, nil
+}
+
+// GetJSON fetches url with the given headers and decodes a 2xx JSON response into out
+func GetJSON(ctx context.Context, url string, header http.Header, out interface{}) error {
+	body, err := GetBody(ctx, url, header)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// ProviderForHost maps an API host to its provider, or "" for unknown hosts
+func ProviderForHost(host string) string {
+	host = strings.ToLower(host)
+	switch {
+	case host == "api.steampowered.com" || host == "steamcommunity.com":
+		return Steam
+	case host == "riotgames.com" || strings.HasSuffix(host, ".riotgames.com"):
+		return Riot
+	case host == "tracker.gg" || strings.HasSuffix(host, ".tracker.gg"):
+		return Tracker
+	}
+	return ""
+}
+
+// recordingTransport records every response or error against the request's
+// provider, in the status registry and as metrics
+type recordingTransport struct {
+	base     http.RoundTripper
+	registry *Registry
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	provider := ProviderForHost(req.URL.Hostname())
+	if provider == "" {
+		return resp, err
+	}
+
+	t.registry.Record(provider, resp, err)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	telemetry.RecordUpstreamRequest(req.Context(), provider, status, time.Since(start))
+	return resp, err
+}
@@ -0,0 +1,215 @@
+// Package validation adds the custom rules request DTOs use in their binding
+// tags to Gin's validator, and turns binding failures into one message per
+// field for apperrors.Validation.
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Custom tags
+const (
+	TagUsername     = "username"      // 3-20 letters, digits, '.', '_' or '-'
+	TagPassword     = "password"      // The password policy
+	TagRiotID       = "riot_id"       // GameName#TAG
+	TagSteamID64    = "steam_id64"    // A 64-bit individual Steam account ID
+	TagEAID         = "ea_id"         // An EA account name
+	TagPSNID        = "psn_id"        // A PlayStation Network online ID
+	TagXboxGamertag = "xbox_gamertag" // An Xbox gamertag, with or without its #suffix
+)
+
+var (
+	usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{3,20}$`)
+	// Riot game names are 3-16 letters, digits or spaces; taglines 3-5 letters or digits
+	riotIDPattern = regexp.MustCompile(`^[\p{L}\p{N} ]{3,16}#[\p{L}\p{N}]{3,5}$`)
+	eaIDPattern   = regexp.MustCompile(`^[A-Za-z0-9._-]{4,16}$`)
+	// PSN online IDs start with a letter
+	psnIDPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{2,15}$`)
+	// Gamertags start with a letter; newer ones carry a numeric suffix
+	xboxGamertagPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9 ]{0,11}(#[0-9]{1,4})?$`)
+)
+
+// Individual Steam accounts: universe public, type individual, instance desktop
+const (
+	steamID64Min = 76561197960265729
+	steamID64Max = 76561202255233023
+)
+
+// Password policy
+const (
+	passwordMinLength = 8
+	passwordMaxBytes  = 72 // bcrypt ignores anything longer
+)
+
+// rule is a custom tag and the message shown when a field fails it
+type rule struct {
+	check   validator.Func
+	message string
+}
+
+var rules = map[string]rule{
+	TagUsername:     {matches(usernamePattern), "must be 3-20 letters, digits, '.', '_' or '-'"},
+	TagPassword:     {isStrongPassword, fmt.Sprintf("must be %d-%d characters with at least one letter and one digit", passwordMinLength, passwordMaxBytes)},
+	TagRiotID:       {matches(riotIDPattern), "must be a Riot ID like GameName#TAG"},
+	TagSteamID64:    {isSteamID64, "must be a 17-digit SteamID64"},
+	TagEAID:         {matches(eaIDPattern), "must be 4-16 letters, digits, '.', '_' or '-'"},
+	TagPSNID:        {matches(psnIDPattern), "must be 3-16 letters, digits, '_' or '-', starting with a letter"},
+	TagXboxGamertag: {isXboxGamertag, "must be a gamertag of up to 12 letters, digits or single spaces, starting with a letter"},
+}
+
+// Register adds the custom rules to Gin's validator and names fields by
+// their JSON keys or query parameters in errors. Call it once before serving requests.
+func Register() error {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("gin's validator is not go-playground/validator")
+	}
+	engine.RegisterTagNameFunc(fieldName)
+	for tag, rule := range rules {
+		if err := engine.RegisterValidation(tag, rule.check); err != nil {
+			return fmt.Errorf("failed to register %s: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+// Formats describes each custom tag, for API documentation
+func Formats() map[string]string {
+	formats := make(map[string]string, len(rules))
+	for tag, rule := range rules {
+		formats[tag] = rule.message
+	}
+	return formats
+}
+
+// Value checks one value against tag, returning its problem keyed by field, or nil
+func Value(field string, value any, tag string) map[string]string {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return nil
+	}
+	var fieldErrs validator.ValidationErrors
+	if err := engine.Var(value, tag); errors.As(err, &fieldErrs) {
+		return map[string]string{field: message(fieldErrs[0])}
+	}
+	return nil
+}
+
+// Fields describes what is wrong with each field of a request that failed to
+// bind. It returns nil if the body couldn't be parsed at all.
+func Fields(err error) map[string]string {
+	var fieldErrs validator.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		fields := make(map[string]string, len(fieldErrs))
+		for _, fieldErr := range fieldErrs {
+			// Report the first problem with each field
+			if _, ok := fields[fieldErr.Field()]; !ok {
+				fields[fieldErr.Field()] = message(fieldErr)
+			}
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return map[string]string{typeErr.Field: "must be a " + typeErr.Type.String()}
+	}
+	return nil
+}
+
+// message describes a failed rule
+func message(fieldErr validator.FieldError) string {
+	if rule, ok := rules[fieldErr.Tag()]; ok {
+		return rule.message
+	}
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		return lengthMessage(fieldErr, "at least")
+	case "max":
+		return lengthMessage(fieldErr, "at most")
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fieldErr.Param()), ", ")
+	case "alphanum", "alphanumunicode":
+		return "must contain only letters and digits"
+	case "gt":
+		return "must be greater than " + fieldErr.Param()
+	case "nefield":
+		return "must not be the same as " + strings.ToLower(fieldErr.Param())
+	}
+	return "is invalid"
+}
+
+// lengthMessage describes a min or max rule, which limits length for strings and size for numbers
+func lengthMessage(fieldErr validator.FieldError, bound string) string {
+	if fieldErr.Kind() == reflect.String {
+		return fmt.Sprintf("must be %s %s characters", bound, fieldErr.Param())
+	}
+	return fmt.Sprintf("must be %s %s", bound, fieldErr.Param())
+}
+
+// fieldName names a struct field by its JSON key, or its query parameter
+func fieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+		switch name {
+		case "-":
+			return ""
+		case "":
+			continue
+		}
+		return name
+	}
+	return field.Name
+}
+
+// matches is a rule that a string matches pattern
+func matches(pattern *regexp.Regexp) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		return pattern.MatchString(fl.Field().String())
+	}
+}
+
+func isStrongPassword(fl validator.FieldLevel) bool {
+	password := fl.Field().String()
+	if len([]rune(password)) < passwordMinLength || len(password) > passwordMaxBytes {
+		return false
+	}
+	var letter, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return letter && digit
+}
+
+func isSteamID64(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	// Every ID in range has 17 digits; the length check rules out leading zeros
+	id, err := strconv.ParseUint(value, 10, 64)
+	return err == nil && len(value) == 17 && id >= steamID64Min && id <= steamID64Max
+}
+
+func isXboxGamertag(fl validator.FieldLevel) bool {
+	gamertag := fl.Field().String()
+	name, _, _ := strings.Cut(gamertag, "#")
+	return xboxGamertagPattern.MatchString(gamertag) &&
+		!strings.HasSuffix(name, " ") && !strings.Contains(name, "  ")
+}
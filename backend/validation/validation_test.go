@@ -0,0 +1,101 @@
+package validation
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	if err := Register(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestCustomTags(t *testing.T) {
+	tests := []struct {
+		tag   string
+		value string
+		valid bool
+	}{
+		{TagUsername, "abc", true},
+		{TagUsername, "Player_One.2-x", true},
+		{TagUsername, strings.Repeat("a", 20), true},
+		{TagUsername, "ab", false},
+		{TagUsername, strings.Repeat("a", 21), false},
+		{TagUsername, "has space", false},
+		{TagUsername, "émile", false},
+		{TagUsername, "bob@example", false},
+
+		{TagPassword, "password1", true},
+		{TagPassword, "pässwörd1", true},
+		{TagPassword, "1234567a", true},
+		{TagPassword, "passwrd", false},
+		{TagPassword, "password", false},
+		{TagPassword, "12345678", false},
+		{TagPassword, "pass1", false},
+		{TagPassword, strings.Repeat("a", 71) + "1", true},
+		{TagPassword, strings.Repeat("a", 72) + "1", false},
+		// Eight characters but more bytes than bcrypt reads
+		{TagPassword, strings.Repeat("é", 36) + "1", false},
+
+		{TagRiotID, "Faker#KR1", true},
+		{TagRiotID, "Hide on bush#KR1", true},
+		{TagRiotID, "Ünïcödé#ÄBC", true},
+		{TagRiotID, "Faker", false},
+		{TagRiotID, "Fa#KR1", false},
+		{TagRiotID, "Faker#K1", false},
+		{TagRiotID, "Faker#KR1234", false},
+		{TagRiotID, "Faker#KR 1", false},
+		{TagRiotID, "Faker#KR1#2", false},
+		{TagRiotID, strings.Repeat("a", 17) + "#KR1", false},
+
+		{TagSteamID64, "76561197960287930", true},
+		{TagSteamID64, "76561197960265729", true},
+		{TagSteamID64, "76561202255233023", true},
+		{TagSteamID64, "76561197960265728", false},
+		{TagSteamID64, "76561202255233024", false},
+		{TagSteamID64, "76561197960287930 ", false},
+		{TagSteamID64, "076561197960287930", false},
+		{TagSteamID64, "+76561197960287930", false},
+		{TagSteamID64, "STEAM_0:0:11101", false},
+		{TagSteamID64, "", false},
+
+		{TagEAID, "Player", true},
+		{TagEAID, "a.b_c-d", true},
+		{TagEAID, "abc", false},
+		{TagEAID, strings.Repeat("a", 17), false},
+		{TagEAID, "no spaces", false},
+
+		{TagPSNID, "Kratos_99", true},
+		{TagPSNID, "abc", true},
+		{TagPSNID, "ab", false},
+		{TagPSNID, "9lives", false},
+		{TagPSNID, "_kratos", false},
+		{TagPSNID, strings.Repeat("a", 17), false},
+		{TagPSNID, "kratos.99", false},
+
+		{TagXboxGamertag, "Major Nelson", true},
+		{TagXboxGamertag, "MajorNelson#1234", true},
+		{TagXboxGamertag, "a", true},
+		{TagXboxGamertag, "1MajorNelson", false},
+		{TagXboxGamertag, "Major  Nelson", false},
+		{TagXboxGamertag, "MajorNelson ", false},
+		{TagXboxGamertag, "Major #123", false},
+		{TagXboxGamertag, "MajorNelson#12345", false},
+		{TagXboxGamertag, "ThirteenChars", false},
+		{TagXboxGamertag, "Major_Nelson", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag+"/"+tt.value, func(t *testing.T) {
+			problem := Value("field", tt.value, tt.tag)
+			if valid := problem == nil; valid != tt.valid {
+				t.Errorf("%s %q: problem %v, want valid %v", tt.tag, tt.value, problem, tt.valid)
+			}
+			if problem != nil && problem["field"] != rules[tt.tag].message {
+				t.Errorf("message = %q, want %q", problem["field"], rules[tt.tag].message)
+			}
+		})
+	}
+}